@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/hoophq/hoop/agent/egress"
 	"github.com/hoophq/hoop/common/clientconfig"
 	"github.com/hoophq/hoop/common/dsnkeys"
 	"github.com/hoophq/hoop/common/envloader"
@@ -18,12 +19,22 @@ type Config struct {
 	Name      string
 	Type      string
 	AgentMode string
-	insecure  bool
-	tlsCA     string
+	// EgressPolicy restricts the upstream addresses the agent is able to dial,
+	// a nil value allows any address.
+	EgressPolicy *egress.Policy
+	insecure     bool
+	tlsCA        string
+	// client certificate and key presented to the gateway (mTLS)
+	tlsCert string
+	tlsKey  string
 }
 
 // Load the configuration based on environment variable HOOP_KEY or HOOP_DSN (legacy).
 func Load() (*Config, error) {
+	egressPolicy, err := egress.Load()
+	if err != nil {
+		return nil, err
+	}
 	isLegacy, key := getEnvCredentials()
 	dsn, err := dsnkeys.Parse(key)
 	if err != nil && err != dsnkeys.ErrEmpty {
@@ -40,15 +51,32 @@ func Load() (*Config, error) {
 		if err != nil {
 			return nil, err
 		}
+		tlsCert, err := envloader.GetEnv("HOOP_TLSCERT")
+		if err != nil {
+			return nil, err
+		}
+		tlsKey, err := envloader.GetEnv("HOOP_TLSKEY")
+		if err != nil {
+			return nil, err
+		}
+		if (tlsCert == "") != (tlsKey == "") {
+			return nil, fmt.Errorf("HOOP_TLSCERT and HOOP_TLSKEY must be set together")
+		}
 		isInsecure := dsn.Scheme == "http" || dsn.Scheme == "grpc"
+		if isInsecure && tlsCert != "" {
+			return nil, fmt.Errorf("HOOP_TLSCERT requires a grpcs or https scheme in HOOP_KEY")
+		}
 		return &Config{
-			Name:      dsn.Name,
-			Type:      clientconfig.ModeDsn,
-			AgentMode: dsn.AgentMode,
-			Token:     dsn.Key(),
-			URL:       dsn.Address,
-			insecure:  isInsecure,
-			tlsCA:     tlsCA,
+			Name:         dsn.Name,
+			Type:         clientconfig.ModeDsn,
+			AgentMode:    dsn.AgentMode,
+			Token:        dsn.Key(),
+			URL:          dsn.Address,
+			EgressPolicy: egressPolicy,
+			insecure:     isInsecure,
+			tlsCA:        tlsCA,
+			tlsCert:      tlsCert,
+			tlsKey:       tlsKey,
 		}, nil
 	}
 	legacyToken := getLegacyHoopTokenCredentials()
@@ -56,11 +84,12 @@ func Load() (*Config, error) {
 	if legacyToken != "" && grpcURL != "" {
 		log.Warnf("HOOP_TOKEN and HOOP_GRPCURL environment variables are deprecated, create a new token to use the new format")
 		return &Config{
-			Type:      clientconfig.ModeEnv,
-			AgentMode: proto.AgentModeStandardType,
-			Token:     legacyToken,
-			URL:       grpcURL,
-			insecure:  grpcURL == grpc.LocalhostAddr}, nil
+			Type:         clientconfig.ModeEnv,
+			AgentMode:    proto.AgentModeStandardType,
+			Token:        legacyToken,
+			URL:          grpcURL,
+			EgressPolicy: egressPolicy,
+			insecure:     grpcURL == grpc.LocalhostAddr}, nil
 	}
 	return nil, fmt.Errorf("missing HOOP_KEY environment variable")
 }
@@ -73,6 +102,8 @@ func (c *Config) GrpcClientConfig() (grpc.ClientConfig, error) {
 		Insecure:      c.IsInsecure(),
 		TLSServerName: os.Getenv("HOOP_TLSSERVERNAME"),
 		TLSCA:         c.tlsCA,
+		TLSClientCert: c.tlsCert,
+		TLSClientKey:  c.tlsKey,
 	}, err
 }
 
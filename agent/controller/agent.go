@@ -14,6 +14,7 @@ import (
 
 	"github.com/getsentry/sentry-go"
 	"github.com/hoophq/hoop/agent/config"
+	"github.com/hoophq/hoop/agent/egress"
 	"github.com/hoophq/hoop/agent/secretsmanager"
 	term "github.com/hoophq/hoop/agent/terminal"
 	"github.com/hoophq/hoop/common/log"
@@ -46,6 +47,8 @@ type (
 		options           string
 		postgresSSLMode   string
 		connectionString  string
+		// all addresses of a connection string, it's only used by mongodb at the moment
+		addresses []string
 	}
 )
 
@@ -65,6 +68,14 @@ func (e *connEnv) Address() string {
 	return e.host + ":" + e.port
 }
 
+// Addresses returns all upstream addresses the agent could dial for this connection
+func (e *connEnv) Addresses() []string {
+	if len(e.addresses) > 0 {
+		return e.addresses
+	}
+	return []string{e.Address()}
+}
+
 func New(client pb.ClientTransport, cfg *config.Config, runtimeEnvs map[string]string) *Agent {
 	shutdownCtx, cancelFn := context.WithCancelCause(context.Background())
 	return &Agent{
@@ -219,6 +230,10 @@ func (a *Agent) processSessionOpen(pkt *pb.Packet) {
 
 	go func() {
 		if err := a.checkTCPLiveness(pkt, connParams.EnvVars); err != nil {
+			if errors.Is(err, egress.ErrDenied) {
+				a.sendClientSessionCloseEgressDenied(sessionIDKey, err)
+				return
+			}
 			_ = a.client.Send(&pb.Packet{
 				Type:    pbclient.SessionClose,
 				Payload: []byte(err.Error()),
@@ -229,6 +244,7 @@ func (a *Agent) processSessionOpen(pkt *pb.Packet) {
 			})
 		}
 		a.connStore.Set(string(sessionID), connParams)
+		a.setSessionLimiter(sessionIDKey, pb.ConnectionType(pkt.Spec[pb.SpecConnectionType]), connParams.SessionLimits)
 		_ = a.client.Send(&pb.Packet{
 			Type: pbclient.SessionOpenOK,
 			Spec: map[string][]byte{
@@ -309,6 +325,32 @@ func (a *Agent) sendClientSessionCloseWithExitCode(sessionID string, errMsg, exi
 	})
 }
 
+// checkEgressPolicy validates all upstream addresses of a connection
+// against the egress policy before dialing them
+func (a *Agent) checkEgressPolicy(connType pb.ConnectionType, env *connEnv) error {
+	for _, addr := range env.Addresses() {
+		if err := a.config.EgressPolicy.Check(connType.String(), addr, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendClientSessionCloseEgressDenied closes the session informing the gateway
+// that the upstream address was rejected by the egress policy of the agent
+func (a *Agent) sendClientSessionCloseEgressDenied(sessionID string, err error) {
+	log.With("sid", sessionID).Warnf("upstream connection rejected, reason=%v", err)
+	_ = a.client.Send(&pb.Packet{
+		Type:    pbclient.SessionClose,
+		Payload: []byte(err.Error()),
+		Spec: map[string][]byte{
+			pb.SpecGatewaySessionID:     []byte(sessionID),
+			pb.SpecClientExitCodeKey:    []byte(internalExitCode),
+			pb.SpecAgentEgressDeniedKey: []byte(err.Error()),
+		},
+	})
+}
+
 func (a *Agent) sendClientTCPConnectionClose(sessionID, connectionID string) {
 	_ = a.client.Send(&pb.Packet{
 		Type:    pbclient.TCPConnectionClose,
@@ -385,6 +427,9 @@ func (a *Agent) checkTCPLiveness(pkt *pb.Packet, envVars map[string]any) error {
 		if err != nil {
 			return err
 		}
+		if err := a.checkEgressPolicy(connType, connEnvVars); err != nil {
+			return err
+		}
 		if err := isPortActive(connEnvVars); err != nil {
 			msg := fmt.Sprintf("failed connecting to remote host=%s, port=%s, reason=%v",
 				connEnvVars.host, connEnvVars.port, err)
@@ -557,7 +602,11 @@ func parseConnectionEnvVars(envVars map[string]any, connType pb.ConnectionType)
 			if err != nil {
 				return nil, fmt.Errorf("failed parsing %v connection string", pb.ConnectionTypeMongoDB)
 			}
-			return &connEnv{connectionString: env.connectionString, address: connStr.Hosts[0]}, nil
+			return &connEnv{
+				connectionString: env.connectionString,
+				address:          connStr.Hosts[0],
+				addresses:        connStr.Hosts,
+			}, nil
 		}
 		// TODO: this usage should be deprecated, only connection string
 		// should be used
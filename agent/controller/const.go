@@ -3,10 +3,12 @@ package controller
 const (
 	execStoreKey               string = "exec:%s"
 	cmdStoreKey                string = "cmd:%s"
+	limiterStoreKey            string = "limiter:%s"
 	gcpJSONCredentialsKey      string = "gcp_credentials"
 	dlpProviderKey             string = "dlp_provider"
 	msPresidioAnalyzerURLKey   string = "mspresidio_analyzer_url"
 	msPresidioAnonymizerURLKey string = "mspresidio_anonymizer_url"
 	connEnvKey                 string = "connenv"
 	internalExitCode           string = "254"
+	sessionLimitExitCode       string = "124"
 )
@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hoophq/hoop/common/log"
+	pb "github.com/hoophq/hoop/common/proto"
+)
+
+// sessionLimiter enforces the session limits of a connection.
+// The methods are safe to be called with a nil limiter.
+type sessionLimiter struct {
+	connType   pb.ConnectionType
+	limits     pb.SessionLimits
+	bytesIn    atomic.Int64
+	bytesOut   atomic.Int64
+	respBytes  atomic.Int64
+	respRows   atomic.Int64
+	lastActive atomic.Int64
+	rowCounter pgRowCounter
+	mu         sync.Mutex
+	exceededFn func(reason string)
+	exceeded   atomic.Bool
+	closeOnce  sync.Once
+	doneC      chan struct{}
+}
+
+type limitWriter struct {
+	w       io.Writer
+	limiter *sessionLimiter
+}
+
+func hasSessionLimitsSupport(connType pb.ConnectionType) bool {
+	switch connType {
+	case pb.ConnectionTypePostgres,
+		pb.ConnectionTypeMySQL,
+		pb.ConnectionTypeMSSQL,
+		pb.ConnectionTypeMongoDB,
+		pb.ConnectionTypeTCP,
+		pb.ConnectionTypeSSH:
+		return true
+	}
+	return false
+}
+
+func newSessionLimiter(connType pb.ConnectionType, limits pb.SessionLimits, exceededFn func(reason string)) *sessionLimiter {
+	l := &sessionLimiter{
+		connType:   connType,
+		limits:     limits,
+		exceededFn: exceededFn,
+		doneC:      make(chan struct{}),
+	}
+	l.touch()
+	if limits.IdleTimeoutSec > 0 {
+		go l.watchIdle(time.Duration(limits.IdleTimeoutSec) * time.Second)
+	}
+	return l
+}
+
+// sessionLimiter returns the limiter of a session or nil if the session has no limits
+func (a *Agent) sessionLimiter(sessionID string) *sessionLimiter {
+	l, _ := a.connStore.Get(fmt.Sprintf(limiterStoreKey, sessionID)).(*sessionLimiter)
+	return l
+}
+
+func (a *Agent) setSessionLimiter(sessionID string, connType pb.ConnectionType, limits *pb.SessionLimits) {
+	if limits.IsEmpty() || !hasSessionLimitsSupport(connType) {
+		return
+	}
+	log.With("sid", sessionID).Infof("enforcing session limits, idle-timeout=%vs, max-bytes-in=%v, max-bytes-out=%v, "+
+		"max-response-rows=%v, max-response-bytes=%v", limits.IdleTimeoutSec, limits.MaxBytesIn, limits.MaxBytesOut,
+		limits.MaxResponseRows, limits.MaxResponseBytes)
+	limiter := newSessionLimiter(connType, *limits, func(reason string) {
+		log.With("sid", sessionID).Infof("closing session, %v", reason)
+		a.sendClientSessionCloseWithExitCode(sessionID, reason, sessionLimitExitCode)
+		a.sessionCleanup(sessionID)
+	})
+	a.connStore.Set(fmt.Sprintf(limiterStoreKey, sessionID), limiter)
+}
+
+// clientWrite accounts the bytes sent by the client. Every write from the client
+// starts a new response to be accounted from the upstream server.
+func (l *sessionLimiter) clientWrite(size int) error {
+	if l == nil {
+		return nil
+	}
+	if l.exceeded.Load() {
+		return fmt.Errorf("session limit exceeded")
+	}
+	l.touch()
+	l.respBytes.Store(0)
+	l.respRows.Store(0)
+	l.mu.Lock()
+	l.rowCounter.reset()
+	l.mu.Unlock()
+
+	bytesIn := l.bytesIn.Add(int64(size))
+	if l.limits.MaxBytesIn > 0 && bytesIn > l.limits.MaxBytesIn {
+		return l.exceed(fmt.Sprintf("session limit exceeded, reached the maximum of %v bytes sent to the server",
+			l.limits.MaxBytesIn))
+	}
+	return nil
+}
+
+// writer wraps the writer of the upstream server to account its responses
+func (l *sessionLimiter) writer(w io.Writer) io.Writer {
+	if l == nil {
+		return w
+	}
+	return &limitWriter{w: w, limiter: l}
+}
+
+func (w *limitWriter) Write(data []byte) (int, error) {
+	if err := w.limiter.serverWrite(data); err != nil {
+		return 0, err
+	}
+	return w.w.Write(data)
+}
+
+func (l *sessionLimiter) serverWrite(data []byte) error {
+	if l.exceeded.Load() {
+		return fmt.Errorf("session limit exceeded")
+	}
+	l.touch()
+	size := int64(len(data))
+	if bytesOut := l.bytesOut.Add(size); l.limits.MaxBytesOut > 0 && bytesOut > l.limits.MaxBytesOut {
+		return l.exceed(fmt.Sprintf("session limit exceeded, reached the maximum of %v bytes received from the server",
+			l.limits.MaxBytesOut))
+	}
+	if respBytes := l.respBytes.Add(size); l.limits.MaxResponseBytes > 0 && respBytes > l.limits.MaxResponseBytes {
+		return l.exceed(fmt.Sprintf("session limit exceeded, reached the maximum of %v bytes in a single response",
+			l.limits.MaxResponseBytes))
+	}
+	if l.limits.MaxResponseRows > 0 && l.connType == pb.ConnectionTypePostgres {
+		l.mu.Lock()
+		rows := l.rowCounter.count(data)
+		l.mu.Unlock()
+		if respRows := l.respRows.Add(rows); respRows > l.limits.MaxResponseRows {
+			return l.exceed(fmt.Sprintf("session limit exceeded, reached the maximum of %v rows in a single response",
+				l.limits.MaxResponseRows))
+		}
+	}
+	return nil
+}
+
+func (l *sessionLimiter) exceed(reason string) error {
+	if l.exceeded.CompareAndSwap(false, true) {
+		go l.exceededFn(reason)
+	}
+	return fmt.Errorf("%s", reason)
+}
+
+func (l *sessionLimiter) touch() { l.lastActive.Store(time.Now().UnixNano()) }
+
+func (l *sessionLimiter) watchIdle(timeout time.Duration) {
+	for {
+		lastActive := time.Unix(0, l.lastActive.Load())
+		wait := time.Until(lastActive.Add(timeout))
+		if wait <= 0 {
+			_ = l.exceed(fmt.Sprintf("session limit exceeded, idle for more than %v", timeout))
+			return
+		}
+		select {
+		case <-l.doneC:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Close stops watching the idle timeout of the session
+func (l *sessionLimiter) Close() error {
+	l.closeOnce.Do(func() { close(l.doneC) })
+	return nil
+}
+
+// pgRowCounter counts DataRow messages in a postgres server stream.
+// Messages could be split across multiple writes, the state of the
+// current message is kept between calls.
+type pgRowCounter struct {
+	header []byte
+	skip   int
+}
+
+func (c *pgRowCounter) reset() { c.header = c.header[:0]; c.skip = 0 }
+
+func (c *pgRowCounter) count(data []byte) (rows int64) {
+	for len(data) > 0 {
+		if c.skip > 0 {
+			n := min(c.skip, len(data))
+			c.skip -= n
+			data = data[n:]
+			continue
+		}
+		// type (1 byte) + length (4 bytes)
+		n := min(5-len(c.header), len(data))
+		c.header = append(c.header, data[:n]...)
+		data = data[n:]
+		if len(c.header) < 5 {
+			break
+		}
+		if c.header[0] == 'D' {
+			rows++
+		}
+		// the length includes itself
+		c.skip = max(int(binary.BigEndian.Uint32(c.header[1:5]))-4, 0)
+		c.header = c.header[:0]
+	}
+	return
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/hoophq/hoop/common/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPgMessage(msgType byte, body []byte) []byte {
+	msg := []byte{msgType, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(msg[1:], uint32(len(body)+4))
+	return append(msg, body...)
+}
+
+func TestPgRowCounter(t *testing.T) {
+	var stream []byte
+	stream = append(stream, newPgMessage('T', []byte("row-description"))...)
+	for i := 0; i < 3; i++ {
+		stream = append(stream, newPgMessage('D', []byte("data-row"))...)
+	}
+	stream = append(stream, newPgMessage('C', []byte("SELECT 3\x00"))...)
+	stream = append(stream, newPgMessage('Z', []byte("I"))...)
+
+	for _, chunkSize := range []int{1, 3, 7, len(stream)} {
+		var counter pgRowCounter
+		var rows int64
+		for data := stream; len(data) > 0; {
+			n := min(chunkSize, len(data))
+			rows += counter.count(data[:n])
+			data = data[n:]
+		}
+		assert.Equal(t, int64(3), rows, "chunk size %v", chunkSize)
+	}
+}
+
+func TestSessionLimiter(t *testing.T) {
+	for _, tt := range []struct {
+		msg      string
+		connType pb.ConnectionType
+		limits   pb.SessionLimits
+		run      func(l *sessionLimiter, w io.Writer) error
+		reason   string
+	}{
+		{
+			msg:      "it must exceed the max bytes sent by the client",
+			connType: pb.ConnectionTypeTCP,
+			limits:   pb.SessionLimits{MaxBytesIn: 10},
+			run: func(l *sessionLimiter, _ io.Writer) error {
+				_ = l.clientWrite(6)
+				return l.clientWrite(6)
+			},
+			reason: "maximum of 10 bytes sent to the server",
+		},
+		{
+			msg:      "it must exceed the max bytes sent by the server",
+			connType: pb.ConnectionTypeSSH,
+			limits:   pb.SessionLimits{MaxBytesOut: 10},
+			run: func(l *sessionLimiter, w io.Writer) error {
+				_, err := w.Write(make([]byte, 11))
+				return err
+			},
+			reason: "maximum of 10 bytes received from the server",
+		},
+		{
+			msg:      "it must reset the response bytes when the client writes",
+			connType: pb.ConnectionTypeMySQL,
+			limits:   pb.SessionLimits{MaxResponseBytes: 10},
+			run: func(l *sessionLimiter, w io.Writer) error {
+				_, _ = w.Write(make([]byte, 8))
+				_ = l.clientWrite(1)
+				_, err := w.Write(make([]byte, 8))
+				return err
+			},
+		},
+		{
+			msg:      "it must exceed the max rows of a postgres response",
+			connType: pb.ConnectionTypePostgres,
+			limits:   pb.SessionLimits{MaxResponseRows: 2},
+			run: func(l *sessionLimiter, w io.Writer) error {
+				var data []byte
+				for i := 0; i < 3; i++ {
+					data = append(data, newPgMessage('D', []byte("data-row"))...)
+				}
+				_, err := w.Write(data)
+				return err
+			},
+			reason: "maximum of 2 rows in a single response",
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			reasonC := make(chan string, 1)
+			l := newSessionLimiter(tt.connType, tt.limits, func(reason string) { reasonC <- reason })
+			defer l.Close()
+			err := tt.run(l, l.writer(&bytes.Buffer{}))
+			if tt.reason == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			select {
+			case reason := <-reasonC:
+				assert.True(t, strings.Contains(reason, tt.reason), "got reason=%v", reason)
+			case <-time.After(time.Second):
+				t.Fatal("timeout waiting for limit to be exceeded")
+			}
+		})
+	}
+}
+
+func TestSessionLimiterIdleTimeout(t *testing.T) {
+	reasonC := make(chan string, 1)
+	l := newSessionLimiter(pb.ConnectionTypeTCP, pb.SessionLimits{IdleTimeoutSec: 1},
+		func(reason string) { reasonC <- reason })
+	defer l.Close()
+	select {
+	case reason := <-reasonC:
+		assert.Contains(t, reason, "idle for more than 1s")
+	case <-time.After(time.Second * 3):
+		t.Fatal("timeout waiting for idle session to be closed")
+	}
+}
+
+func TestSessionLimiterNil(t *testing.T) {
+	var l *sessionLimiter
+	w := &bytes.Buffer{}
+	assert.NoError(t, l.clientWrite(100))
+	assert.Equal(t, w, l.writer(w))
+}
@@ -14,7 +14,12 @@ import (
 
 func (a *Agent) processMongoDBProtocol(pkt *pb.Packet) {
 	sid := string(pkt.Spec[pb.SpecGatewaySessionID])
-	streamClient := pb.NewStreamWriter(a.client, pbclient.MongoDBConnectionWrite, pkt.Spec)
+	limiter := a.sessionLimiter(sid)
+	if err := limiter.clientWrite(len(pkt.Payload)); err != nil {
+		log.With("sid", sid).Debugf("discarding client packet, reason=%v", err)
+		return
+	}
+	streamClient := limiter.writer(pb.NewStreamWriter(a.client, pbclient.MongoDBConnectionWrite, pkt.Spec))
 	connParams := a.connectionParams(sid)
 	if connParams == nil {
 		log.With("sid", sid).Errorf("connection params not found")
@@ -45,6 +50,10 @@ func (a *Agent) processMongoDBProtocol(pkt *pb.Packet) {
 		a.sendClientSessionClose(sid, "credentials are empty, contact the administrator")
 		return
 	}
+	if err := a.checkEgressPolicy(pb.ConnectionTypeMongoDB, connenv); err != nil {
+		a.sendClientSessionCloseEgressDenied(sid, err)
+		return
+	}
 
 	log.With("sid", sid, "conn", clientConnectionID, "legacy", connenv.connectionString == "").
 		Infof("starting mongodb connection at %v", connenv.Address())
@@ -13,7 +13,12 @@ import (
 
 func (a *Agent) processMySQLProtocol(pkt *pb.Packet) {
 	sessionID := string(pkt.Spec[pb.SpecGatewaySessionID])
-	streamClient := pb.NewStreamWriter(a.client, pbclient.MySQLConnectionWrite, pkt.Spec)
+	limiter := a.sessionLimiter(sessionID)
+	if err := limiter.clientWrite(len(pkt.Payload)); err != nil {
+		log.With("sid", sessionID).Debugf("discarding client packet, reason=%v", err)
+		return
+	}
+	streamClient := limiter.writer(pb.NewStreamWriter(a.client, pbclient.MySQLConnectionWrite, pkt.Spec))
 	connParams := a.connectionParams(sessionID)
 	if connParams == nil {
 		log.Errorf("session=%s - connection params not found", sessionID)
@@ -44,6 +49,10 @@ func (a *Agent) processMySQLProtocol(pkt *pb.Packet) {
 		a.sendClientSessionClose(sessionID, "credentials are empty, contact the administrator")
 		return
 	}
+	if err := a.checkEgressPolicy(pb.ConnectionTypeMySQL, connenv); err != nil {
+		a.sendClientSessionCloseEgressDenied(sessionID, err)
+		return
+	}
 
 	log.Infof("session=%v - starting mysql connection at %v:%v", sessionID, connenv.host, connenv.port)
 	opts := map[string]string{
@@ -14,7 +14,12 @@ import (
 
 func (a *Agent) processPGProtocol(pkt *pb.Packet) {
 	sessionID := string(pkt.Spec[pb.SpecGatewaySessionID])
-	streamClient := pb.NewStreamWriter(a.client, pbclient.PGConnectionWrite, pkt.Spec)
+	limiter := a.sessionLimiter(sessionID)
+	if err := limiter.clientWrite(len(pkt.Payload)); err != nil {
+		log.With("sid", sessionID).Debugf("discarding client packet, reason=%v", err)
+		return
+	}
+	streamClient := limiter.writer(pb.NewStreamWriter(a.client, pbclient.PGConnectionWrite, pkt.Spec))
 	connParams := a.connectionParams(sessionID)
 	if connParams == nil {
 		log.Errorf("session=%s - connection params not found", sessionID)
@@ -46,6 +51,10 @@ func (a *Agent) processPGProtocol(pkt *pb.Packet) {
 		a.sendClientSessionClose(sessionID, "credentials are empty, contact the administrator")
 		return
 	}
+	if err := a.checkEgressPolicy(pb.ConnectionTypePostgres, connenv); err != nil {
+		a.sendClientSessionCloseEgressDenied(sessionID, err)
+		return
+	}
 
 	log.Infof("session=%v - starting postgres connection at %v:%v", sessionID, connenv.host, connenv.port)
 	opts := map[string]string{
@@ -13,7 +13,12 @@ import (
 
 func (a *Agent) processSSHProtocol(pkt *pb.Packet) {
 	sid := string(pkt.Spec[pb.SpecGatewaySessionID])
-	streamClient := pb.NewStreamWriter(a.client, pbclient.SSHConnectionWrite, pkt.Spec)
+	limiter := a.sessionLimiter(sid)
+	if err := limiter.clientWrite(len(pkt.Payload)); err != nil {
+		log.With("sid", sid).Debugf("discarding client packet, reason=%v", err)
+		return
+	}
+	streamClient := limiter.writer(pb.NewStreamWriter(a.client, pbclient.SSHConnectionWrite, pkt.Spec))
 	connParams := a.connectionParams(sid)
 	if connParams == nil {
 		log.With("sid", sid).Errorf("connection params not found")
@@ -44,6 +49,10 @@ func (a *Agent) processSSHProtocol(pkt *pb.Packet) {
 		a.sendClientSessionClose(sid, "credentials are empty, contact the administrator")
 		return
 	}
+	if err := a.checkEgressPolicy(pb.ConnectionTypeSSH, connenv); err != nil {
+		a.sendClientSessionCloseEgressDenied(sid, err)
+		return
+	}
 
 	log.With("sid", sid, "conn", clientConnectionID).
 		Infof("starting SSH proxy connection at %v", connenv.Address())
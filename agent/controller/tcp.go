@@ -11,6 +11,11 @@ import (
 
 func (a *Agent) processTCPWriteServer(pkt *pb.Packet) {
 	sessionID := string(pkt.Spec[pb.SpecGatewaySessionID])
+	limiter := a.sessionLimiter(sessionID)
+	if err := limiter.clientWrite(len(pkt.Payload)); err != nil {
+		log.With("sid", sessionID).Debugf("discarding client packet, reason=%v", err)
+		return
+	}
 	clientConnectionID := string(pkt.Spec[pb.SpecClientConnectionID])
 	if clientConnectionID == "" {
 		log.Println("connection not found in packet specfication")
@@ -32,13 +37,17 @@ func (a *Agent) processTCPWriteServer(pkt *pb.Packet) {
 		}
 		return
 	}
-	tcpClient := pb.NewStreamWriter(a.client, pbclient.TCPConnectionWrite, pkt.Spec)
+	tcpClient := limiter.writer(pb.NewStreamWriter(a.client, pbclient.TCPConnectionWrite, pkt.Spec))
 	connenv, err := parseConnectionEnvVars(connParams.EnvVars, pb.ConnectionTypeTCP)
 	if err != nil {
 		log.Printf("session=%s - missing connection credentials in memory, err=%v", sessionID, err)
 		a.sendClientSessionClose(sessionID, "credentials are empty, contact the administrator")
 		return
 	}
+	if err := a.checkEgressPolicy(pb.ConnectionTypeTCP, connenv); err != nil {
+		a.sendClientSessionCloseEgressDenied(sessionID, err)
+		return
+	}
 	tcpServer, err := newTCPConn(connenv)
 	if err != nil {
 		log.Printf("session=%s - failed connecting to %v, err=%v", sessionID, connenv.host, err)
@@ -0,0 +1,187 @@
+package egress
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hoophq/hoop/common/envloader"
+)
+
+// ErrDenied is returned when an upstream address is not allowed by the policy
+var ErrDenied = errors.New("egress policy denied")
+
+// Policy is an allow-list of upstream addresses an agent is able to dial.
+// A nil policy allows any address.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule matches an upstream address when all non empty attributes match.
+type Rule struct {
+	// ConnectionTypes restricts the rule to the given types (postgres, mysql, tcp, etc)
+	ConnectionTypes []string `json:"connection_types"`
+	// CIDRs allows any host resolving only to addresses contained in these networks
+	CIDRs []string `json:"cidrs"`
+	// Hosts allows hostnames matching these patterns, e.g.: *.db.internal
+	Hosts []string `json:"hosts"`
+	// Ports allows a single port or a range, e.g.: 5432, 8000-9000
+	Ports []string `json:"ports"`
+
+	networks []*net.IPNet
+}
+
+// LookupIPFunc resolves a host to its ip addresses
+type LookupIPFunc func(host string) ([]net.IP, error)
+
+// Load the policy from the environment variable HOOP_EGRESS_POLICY.
+// It accepts the base64:// and file:// prefixes, see envloader.GetEnv.
+// It returns a nil policy if the environment variable is not set.
+func Load() (*Policy, error) {
+	data, err := envloader.GetEnv("HOOP_EGRESS_POLICY")
+	if err != nil {
+		return nil, fmt.Errorf("failed loading HOOP_EGRESS_POLICY, reason=%v", err)
+	}
+	if data == "" {
+		return nil, nil
+	}
+	return Parse([]byte(data))
+}
+
+// Parse decodes a json policy and validates its rules
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed decoding egress policy, reason=%v", err)
+	}
+	for i, rule := range p.Rules {
+		for _, cidr := range rule.CIDRs {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("rule %v: invalid cidr %q, reason=%v", i, cidr, err)
+			}
+			p.Rules[i].networks = append(p.Rules[i].networks, network)
+		}
+		for _, port := range rule.Ports {
+			if _, _, err := parsePortRange(port); err != nil {
+				return nil, fmt.Errorf("rule %v: %v", i, err)
+			}
+		}
+		for _, host := range rule.Hosts {
+			if _, err := path.Match(host, ""); err != nil {
+				return nil, fmt.Errorf("rule %v: invalid host pattern %q", i, host)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// Check validates if the address (host:port) of a connection type is allowed to be dialed.
+func (p *Policy) Check(connType, address string, lookupFn LookupIPFunc) error {
+	if p == nil {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: unable to parse address %q, reason=%v", ErrDenied, address, err)
+	}
+	if lookupFn == nil {
+		lookupFn = net.LookupIP
+	}
+	var addrs []net.IP
+	for _, rule := range p.Rules {
+		if len(rule.ConnectionTypes) > 0 && !slices.Contains(rule.ConnectionTypes, connType) {
+			continue
+		}
+		if len(rule.Ports) > 0 && !rule.matchPort(port) {
+			continue
+		}
+		if len(rule.Hosts) == 0 && len(rule.networks) == 0 {
+			return nil
+		}
+		if rule.matchHost(host) {
+			return nil
+		}
+		if len(rule.networks) == 0 {
+			continue
+		}
+		if addrs == nil {
+			if addrs, err = resolve(host, lookupFn); err != nil {
+				return fmt.Errorf("%w: unable to resolve host %q, reason=%v", ErrDenied, host, err)
+			}
+		}
+		if rule.matchNetworks(addrs) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: address %v is not allowed for connection type %v", ErrDenied, address, connType)
+}
+
+func (r *Rule) matchHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range r.Hosts {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchNetworks returns true only if all addresses are contained in the rule networks.
+// It prevents a hostname resolving to multiple addresses to escape the policy.
+func (r *Rule) matchNetworks(addrs []net.IP) bool {
+	for _, ip := range addrs {
+		var found bool
+		for _, network := range r.networks {
+			if network.Contains(ip) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(addrs) > 0
+}
+
+func (r *Rule) matchPort(port string) bool {
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		return false
+	}
+	for _, portRange := range r.Ports {
+		start, end, _ := parsePortRange(portRange)
+		if portNumber >= start && portNumber <= end {
+			return true
+		}
+	}
+	return false
+}
+
+func resolve(host string, lookupFn LookupIPFunc) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	return lookupFn(host)
+}
+
+func parsePortRange(v string) (start, end int, err error) {
+	startStr, endStr, isRange := strings.Cut(v, "-")
+	if !isRange {
+		endStr = startStr
+	}
+	start, err = strconv.Atoi(strings.TrimSpace(startStr))
+	if err == nil {
+		end, err = strconv.Atoi(strings.TrimSpace(endStr))
+	}
+	if err != nil || start < 1 || end > 65535 || start > end {
+		return 0, 0, fmt.Errorf("invalid port or port range %q", v)
+	}
+	return
+}
@@ -0,0 +1,81 @@
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeLookup(records map[string][]string) LookupIPFunc {
+	return func(host string) ([]net.IP, error) {
+		addrs, ok := records[host]
+		if !ok {
+			return nil, fmt.Errorf("no such host")
+		}
+		var ips []net.IP
+		for _, addr := range addrs {
+			ips = append(ips, net.ParseIP(addr))
+		}
+		return ips, nil
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy, err := Parse([]byte(`{"rules": [
+		{"connection_types": ["postgres"], "cidrs": ["10.0.0.0/8"], "ports": ["5432"]},
+		{"connection_types": ["tcp"], "hosts": ["*.svc.internal"], "ports": ["8000-9000"]},
+		{"connection_types": ["ssh"]}
+	]}`))
+	assert.NoError(t, err)
+	lookupFn := fakeLookup(map[string][]string{
+		"pg.internal":    {"10.0.10.1"},
+		"pg-mixed.local": {"10.0.10.1", "192.168.0.1"},
+	})
+
+	for _, tt := range []struct {
+		msg      string
+		connType string
+		address  string
+		denied   bool
+	}{
+		{msg: "it must allow ip in cidr", connType: "postgres", address: "10.1.2.3:5432"},
+		{msg: "it must allow hostname resolving to cidr", connType: "postgres", address: "pg.internal:5432"},
+		{msg: "it must deny hostname with an address outside of cidr", connType: "postgres", address: "pg-mixed.local:5432", denied: true},
+		{msg: "it must deny unresolvable hostname", connType: "postgres", address: "unknown.local:5432", denied: true},
+		{msg: "it must deny port not in the rule", connType: "postgres", address: "10.1.2.3:5433", denied: true},
+		{msg: "it must deny connection type without rule", connType: "mysql", address: "10.1.2.3:5432", denied: true},
+		{msg: "it must allow host pattern within port range", connType: "tcp", address: "api.svc.internal:8080"},
+		{msg: "it must deny host pattern outside port range", connType: "tcp", address: "api.svc.internal:9001", denied: true},
+		{msg: "it must deny host not matching pattern", connType: "tcp", address: "metadata.google.internal:8080", denied: true},
+		{msg: "it must allow any address for a rule without host constraints", connType: "ssh", address: "192.168.0.10:22"},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			err := policy.Check(tt.connType, tt.address, lookupFn)
+			if tt.denied {
+				assert.True(t, errors.Is(err, ErrDenied), "expected denied error, got=%v", err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPolicyNilAllowsAll(t *testing.T) {
+	var policy *Policy
+	assert.NoError(t, policy.Check("postgres", "169.254.169.254:80", nil))
+}
+
+func TestParseInvalidPolicy(t *testing.T) {
+	for _, data := range []string{
+		`{"rules": [{"cidrs": ["10.0.0.0/33"]}]}`,
+		`{"rules": [{"ports": ["9000-8000"]}]}`,
+		`{"rules": [{"ports": ["abc"]}]}`,
+		`{"rules": [{"hosts": ["[a-"]}]}`,
+	} {
+		_, err := Parse([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
@@ -122,6 +122,14 @@ func parseResourceOrDie(args []string, method, outputFlag string) *apiResource {
 		if method == "POST" {
 			apir.suffixEndpoint = "/api/serviceaccounts"
 		}
+	case "accesstoken", "accesstokens", "pat":
+		apir.resourceGet = false
+		apir.resourceDelete = true
+		apir.resourceCreate = true
+		apir.suffixEndpoint = path.Join("/api/accesstokens", apir.name)
+		if method == "POST" {
+			apir.suffixEndpoint = "/api/accesstokens"
+		}
 	case "review", "reviews":
 		apir.suffixEndpoint = path.Join("/api/reviews", apir.name)
 	case "plugin", "plugins":
@@ -148,6 +156,22 @@ func parseResourceOrDie(args []string, method, outputFlag string) *apiResource {
 		if method == "POST" {
 			apir.suffixEndpoint = path.Join("/api/policies", apir.resourceType)
 		}
+	case "runbookschedule", "runbookschedules", "schedules":
+		apir.resourceDelete = true
+		apir.resourceCreate = true
+		apir.resourceUpdate = true
+		apir.suffixEndpoint = path.Join("/api/plugins/runbooks/schedules", apir.name)
+		if method == "POST" {
+			apir.suffixEndpoint = "/api/plugins/runbooks/schedules"
+		}
+	case "runbookscheduleruns", "scheduleruns":
+		apir.resourceList = false
+		apir.suffixEndpoint = path.Join("/api/plugins/runbooks/schedules", apir.name, "runs")
+		defer func() {
+			if outputFlag == "" {
+				apir.decodeTo = "list"
+			}
+		}()
 	case "runbooks":
 		// force to decode as object
 		apir.resourceGet = true
@@ -324,7 +348,7 @@ func httpBodyRequest(apir *apiResource, method string, bodyMap map[string]any) (
 	}
 	defer resp.Body.Close()
 	log.Debugf("http response %v", resp.StatusCode)
-	if resp.StatusCode != 200 && resp.StatusCode != 201 && resp.StatusCode != 204 {
+	if resp.StatusCode != 200 && resp.StatusCode != 201 && resp.StatusCode != 202 && resp.StatusCode != 204 {
 		respBody, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("failed performing request, status=%v, body=%v",
 			resp.StatusCode, string(respBody))
@@ -0,0 +1,53 @@
+package admin
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hoophq/hoop/client/cmd/styles"
+	"github.com/spf13/cobra"
+)
+
+var (
+	accessTokenScopesFlag    []string
+	accessTokenExpiresInFlag int
+)
+
+func init() {
+	createAccessTokenCmd.Flags().StringSliceVar(&accessTokenScopesFlag, "scope", nil, "The scopes of the token: sessions:read, connections:manage or exec:<connection>")
+	createAccessTokenCmd.Flags().IntVar(&accessTokenExpiresInFlag, "expires-in-days", 30, "The amount of days the token is valid, up to 365 days")
+	_ = createAccessTokenCmd.MarkFlagRequired("scope")
+}
+
+var createAccessTokenCmd = &cobra.Command{
+	Use:     "accesstoken NAME",
+	Aliases: []string{"accesstokens", "pat"},
+	Short:   "Create a personal access token.",
+	Example: `hoop admin create accesstoken ci-deploy --scope sessions:read --scope exec:pgdemo --expires-in-days 90`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Usage()
+			styles.PrintErrorAndExit("missing resource name")
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		apir := parseResourceOrDie([]string{"accesstokens"}, "POST", outputFlag)
+		resp, err := httpBodyRequest(apir, "POST", map[string]any{
+			"name":            args[0],
+			"scopes":          accessTokenScopesFlag,
+			"expires_in_days": accessTokenExpiresInFlag,
+		})
+		if err != nil {
+			styles.PrintErrorAndExit(err.Error())
+		}
+		if apir.decodeTo == "raw" {
+			jsonData, _ := resp.([]byte)
+			fmt.Println(string(jsonData))
+			return
+		}
+		obj, _ := resp.(map[string]any)
+		fmt.Fprintf(os.Stderr, "personal access token %v created, it expires at %v\n", args[0], obj["expire_at"])
+		fmt.Fprintln(os.Stderr, styles.ClientErrorSimple("store the token in a safe place, it will not be shown again"))
+		fmt.Println(obj["token"])
+	},
+}
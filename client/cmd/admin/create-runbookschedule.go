@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hoophq/hoop/client/cmd/styles"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scheduleConnectionFlag     string
+	scheduleFileFlag           string
+	scheduleCronFlag           string
+	scheduleTimezoneFlag       string
+	scheduleServiceAccountFlag string
+	scheduleParamsFlag         []string
+	scheduleDisableFlag        bool
+	scheduleOverwriteFlag      bool
+)
+
+func init() {
+	createRunbookScheduleCmd.Flags().StringVarP(&scheduleConnectionFlag, "connection", "c", "", "The connection to execute the runbook")
+	createRunbookScheduleCmd.Flags().StringVarP(&scheduleFileFlag, "file", "f", "", "The path of the runbook file in the repository, e.g.: ops/vacuum.runbook.sql")
+	createRunbookScheduleCmd.Flags().StringVar(&scheduleCronFlag, "cron", "", "The cron expression (minute, hour, day of month, month and day of week), e.g.: '0 3 * * 1', '@daily'")
+	createRunbookScheduleCmd.Flags().StringVar(&scheduleTimezoneFlag, "timezone", "UTC", "The IANA timezone the cron expression is evaluated, e.g.: America/Sao_Paulo")
+	createRunbookScheduleCmd.Flags().StringVar(&scheduleServiceAccountFlag, "service-account", "", "The subject of the service account that executes the runbook")
+	createRunbookScheduleCmd.Flags().StringSliceVarP(&scheduleParamsFlag, "param", "p", nil, "The parameters of the runbook in the form of 'key=value'")
+	createRunbookScheduleCmd.Flags().BoolVar(&scheduleDisableFlag, "disable", false, "Create the schedule disabled")
+	createRunbookScheduleCmd.Flags().BoolVar(&scheduleOverwriteFlag, "overwrite", false, "It will update the schedule if it already exists")
+	for _, flagName := range []string{"connection", "file", "cron", "service-account"} {
+		_ = createRunbookScheduleCmd.MarkFlagRequired(flagName)
+	}
+}
+
+var createRunbookScheduleCmd = &cobra.Command{
+	Use:     "runbookschedule NAME",
+	Aliases: []string{"runbookschedules", "schedule"},
+	Short:   "Create a runbook schedule resource.",
+	Example: `hoop admin create runbookschedule weekly-vacuum -c pgdemo -f ops/vacuum.runbook.sql \
+  --cron '0 3 * * 1' --timezone America/Sao_Paulo --service-account automation -p table=users`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Usage()
+			styles.PrintErrorAndExit("missing resource name")
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		resourceName := args[0]
+		actionName := "created"
+		method := "POST"
+		resourceArgs := []string{"runbookschedules"}
+		if scheduleOverwriteFlag {
+			log.Debugf("runbook schedule %v exists, updating", resourceName)
+			actionName = "updated"
+			method = "PUT"
+			resourceArgs = append(resourceArgs, resourceName)
+		}
+		params := map[string]string{}
+		for _, keyVal := range scheduleParamsFlag {
+			key, val, found := strings.Cut(keyVal, "=")
+			if !found || key == "" {
+				styles.PrintErrorAndExit("invalid parameter %q, it must be in the form of 'key=value'", keyVal)
+			}
+			params[key] = val
+		}
+		apir := parseResourceOrDie(resourceArgs, method, outputFlag)
+		resp, err := httpBodyRequest(apir, method, map[string]any{
+			"name":            resourceName,
+			"connection_name": scheduleConnectionFlag,
+			"runbook_file":    scheduleFileFlag,
+			"parameters":      params,
+			"cron_expression": scheduleCronFlag,
+			"timezone":        scheduleTimezoneFlag,
+			"service_account": scheduleServiceAccountFlag,
+			"enabled":         !scheduleDisableFlag,
+		})
+		if err != nil {
+			styles.PrintErrorAndExit(err.Error())
+		}
+		if apir.decodeTo == "raw" {
+			jsonData, _ := resp.([]byte)
+			fmt.Println(string(jsonData))
+			return
+		}
+		fmt.Printf("runbook schedule %v %v\n", resourceName, actionName)
+	},
+}
@@ -11,6 +11,8 @@ func init() {
 	createCmd.AddCommand(createPluginCmd)
 	createCmd.AddCommand(createUserCmd)
 	createCmd.AddCommand(createSvcAccountCmd)
+	createCmd.AddCommand(createRunbookScheduleCmd)
+	createCmd.AddCommand(createAccessTokenCmd)
 	createCmd.PersistentFlags().StringVarP(&outputFlag, "output", "o", "", "Output format. One off: (json)")
 }
 
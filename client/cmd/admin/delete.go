@@ -9,8 +9,10 @@ import (
 
 var deleteLongDesc = `Delete resource by its name. Available ones:
 
+* accesstoken (by its id, the token is revoked)
 * agent
 * connection
+* runbookschedule
 * users
 `
 
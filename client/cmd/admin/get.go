@@ -21,12 +21,15 @@ func init() {
 
 var getLongDesc = `Display one or many resources. Available ones:
 
+* accesstokens (tabview)
 * agents (tabview)
 * connections (tabview)
 * orgkeys (tabview)
 * plugins (tabview)
 * reviews
 * runbooks
+* runbookschedules (tabview)
+* runbookscheduleruns/NAME (tabview)
 * serviceaccounts (tabview)
 * sessions
 * users (tabview)
@@ -35,7 +38,8 @@ var getLongDesc = `Display one or many resources. Available ones:
 var getExamplesDesc = `
 hoop admin get agents
 hoop admin get connections -o json
-hoop admin get plugins`
+hoop admin get plugins
+hoop admin get runbookscheduleruns/weekly-vacuum`
 
 var getCmd = &cobra.Command{
 	Use:     "get RESOURCE",
@@ -189,6 +193,58 @@ var getCmd = &cobra.Command{
 					fmt.Fprintln(w)
 				}
 			}
+		case "runbookschedule", "runbookschedules", "schedules":
+			fmt.Fprintln(w, "NAME\tCONNECTION\tRUNBOOK\tCRON\tTIMEZONE\tSERVICE ACCOUNT\tENABLED\tLAST RUN\tNEXT RUN\t")
+			printRow := func(m map[string]any) {
+				fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t",
+					m["name"], m["connection_name"], m["runbook_file"], m["cron_expression"], m["timezone"],
+					m["service_account"], m["enabled"], mapGetter("last_run_at", m), mapGetter("next_run_at", m))
+				fmt.Fprintln(w)
+			}
+			switch contents := obj.(type) {
+			case map[string]any:
+				printRow(contents)
+			case []map[string]any:
+				for _, m := range contents {
+					printRow(m)
+				}
+			}
+		case "accesstoken", "accesstokens", "pat":
+			fmt.Fprintln(w, "ID\tNAME\tUSER\tTOKEN\tSCOPES\tEXPIRE AT\tLAST USED\tSTATUS\t")
+			contents, _ := obj.([]map[string]any)
+			for _, m := range contents {
+				status := "active"
+				expireAt, _ := time.Parse(time.RFC3339, fmt.Sprintf("%v", m["expire_at"]))
+				switch {
+				case m["revoked_at"] != nil:
+					status = "revoked"
+				case time.Now().After(expireAt):
+					status = "expired"
+				}
+				scopes, _ := m["scopes"].([]any)
+				fmt.Fprintf(w, "%v\t%v\t%v\t%v...\t%v\t%v\t%v\t%v\t",
+					m["id"], m["name"], m["user"], m["token_prefix"], joinItems(scopes),
+					m["expire_at"], mapGetter("last_used_at", m), status)
+				fmt.Fprintln(w)
+			}
+		case "runbookscheduleruns", "scheduleruns":
+			fmt.Fprintln(w, "SCHEDULED AT\tSTATUS\tEXIT CODE\tSESSION\tDURATION\tERROR\t")
+			contents, _ := obj.([]map[string]any)
+			for _, m := range contents {
+				duration := "-"
+				startedAt, _ := time.Parse(time.RFC3339, fmt.Sprintf("%v", m["started_at"]))
+				finishedAt, err := time.Parse(time.RFC3339, fmt.Sprintf("%v", m["finished_at"]))
+				if err == nil {
+					duration = finishedAt.Sub(startedAt).Round(time.Second).String()
+				}
+				errMsg := mapGetter("error", m)
+				if errMsg = strings.ReplaceAll(errMsg, "\n", " "); len(errMsg) > 60 {
+					errMsg = errMsg[:60] + "..."
+				}
+				fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t",
+					m["scheduled_at"], m["status"], mapGetter("exit_code", m), mapGetter("session_id", m), duration, errMsg)
+				fmt.Fprintln(w)
+			}
 		case "runbooks":
 			switch contents := obj.(type) {
 			case map[string]any:
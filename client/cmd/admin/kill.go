@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/hoophq/hoop/client/cmd/styles"
+	clientconfig "github.com/hoophq/hoop/client/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	killReasonFlag     string
+	killUserFlag       string
+	killConnectionFlag string
+)
+
+func init() {
+	killSessionCmd.Flags().StringVar(&killReasonFlag, "reason", "", "The reason of terminating the session")
+	_ = killSessionCmd.MarkFlagRequired("reason")
+
+	killSessionsCmd.Flags().StringVar(&killReasonFlag, "reason", "", "The reason of terminating the sessions")
+	killSessionsCmd.Flags().StringVar(&killUserFlag, "user", "", "Terminate the open sessions of this user email")
+	killSessionsCmd.Flags().StringVar(&killConnectionFlag, "connection", "", "Terminate the open sessions of this connection")
+	_ = killSessionsCmd.MarkFlagRequired("reason")
+
+	killCmd.AddCommand(killSessionCmd)
+	killCmd.AddCommand(killSessionsCmd)
+}
+
+var killCmd = &cobra.Command{
+	Use:   "kill",
+	Short: "Terminate open sessions",
+}
+
+var killSessionCmd = &cobra.Command{
+	Use:     "session ID",
+	Short:   "Terminate an open session by its id",
+	Example: `hoop admin kill session 1CBC8DB5-FBF8-4293-8E35-59A6EEA40207 --reason 'compromised account'`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Usage()
+			styles.PrintErrorAndExit("missing session id")
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		apir := &apiResource{
+			suffixEndpoint: path.Join("/api/sessions", args[0]),
+			conf:           clientconfig.GetClientConfigOrDie(),
+			decodeTo:       "object",
+		}
+		resp, err := httpBodyRequest(apir, "DELETE", map[string]any{"reason": killReasonFlag})
+		if err != nil {
+			styles.PrintErrorAndExit(err.Error())
+		}
+		// the gateway responds with a message when the stream of the session is served by another instance
+		if obj, _ := resp.(map[string]any); obj != nil {
+			fmt.Printf("session %q flagged as killed, %v\n", args[0], obj["message"])
+			return
+		}
+		fmt.Printf("session %q killed\n", args[0])
+	},
+}
+
+var killSessionsCmd = &cobra.Command{
+	Use:     "sessions",
+	Short:   "Terminate all open sessions of a user or a connection",
+	Example: `hoop admin kill sessions --user john.wick@bad.org --reason 'compromised account'`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if killUserFlag == "" && killConnectionFlag == "" {
+			cmd.Usage()
+			styles.PrintErrorAndExit("missing --user or --connection flag")
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		apir := &apiResource{
+			suffixEndpoint: "/api/sessions/kill",
+			conf:           clientconfig.GetClientConfigOrDie(),
+			decodeTo:       "object",
+		}
+		resp, err := httpBodyRequest(apir, "POST", map[string]any{
+			"user_email": killUserFlag,
+			"connection": killConnectionFlag,
+			"reason":     killReasonFlag,
+		})
+		if err != nil {
+			styles.PrintErrorAndExit(err.Error())
+		}
+		obj, _ := resp.(map[string]any)
+		sessionIDs, _ := obj["session_ids"].([]any)
+		for _, sid := range sessionIDs {
+			fmt.Printf("session %q killed\n", sid)
+		}
+		notConnected, _ := obj["not_connected"].([]any)
+		for _, sid := range notConnected {
+			fmt.Printf("session %q flagged as killed, it's terminated by the gateway instance serving it\n", sid)
+		}
+		fmt.Printf("%v session(s) killed, %v session(s) flagged as killed\n", len(sessionIDs), len(notConnected))
+	},
+}
@@ -0,0 +1,55 @@
+package admin
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/hoophq/hoop/client/cmd/styles"
+	"github.com/spf13/cobra"
+)
+
+var toggleLongDesc = `%s a resource by its name. Available ones:
+
+* runbookschedule
+`
+
+var enableCmd = &cobra.Command{
+	Use:     "enable TYPE/NAME",
+	Short:   "Enable resources by name",
+	Long:    fmt.Sprintf(toggleLongDesc, "Enable"),
+	Example: "hoop admin enable runbookschedule/weekly-vacuum",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			styles.PrintErrorAndExit("missing resource: type/name")
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) { toggleResource(args, "enable") },
+}
+
+var disableCmd = &cobra.Command{
+	Use:     "disable TYPE/NAME",
+	Short:   "Disable resources by name",
+	Long:    fmt.Sprintf(toggleLongDesc, "Disable"),
+	Example: "hoop admin disable runbookschedule/weekly-vacuum",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			styles.PrintErrorAndExit("missing resource: type/name")
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) { toggleResource(args, "disable") },
+}
+
+func toggleResource(args []string, action string) {
+	apir := parseResourceOrDie(args, "PUT", "")
+	switch apir.resourceType {
+	case "runbookschedule", "runbookschedules", "schedules":
+	default:
+		styles.PrintErrorAndExit("%s not implemented for resource %q", action, apir.resourceType)
+	}
+	apir.suffixEndpoint = path.Join(apir.suffixEndpoint, action)
+	apir.decodeTo = "object"
+	if _, err := httpBodyRequest(apir, "PUT", nil); err != nil {
+		styles.PrintErrorAndExit(err.Error())
+	}
+	fmt.Printf("%s %q %sd\n", apir.resourceType, apir.name, action)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/hoophq/hoop/client/cmd/styles"
 	clientconfig "github.com/hoophq/hoop/client/config"
@@ -25,7 +26,7 @@ func init() {
 	viewCmd.Flags().BoolVar(&viewRawFlag, "raw", false, "Display sensitive credentials information")
 
 	_ = createCmd.MarkFlagRequired("api-url")
-	MainCmd.AddCommand(createCmd, viewCmd, clearCmd)
+	MainCmd.AddCommand(createCmd, viewCmd, clearCmd, useContextCmd, getContextsCmd)
 }
 
 var MainCmd = &cobra.Command{
@@ -37,7 +38,7 @@ var MainCmd = &cobra.Command{
 
 var createCmd = &cobra.Command{
 	Use:          "create",
-	Short:        "Creates or override a client hoop configuration file",
+	Short:        "Creates or override the selected profile of the client hoop configuration file",
 	SilenceUsage: false,
 	Run: func(cmd *cobra.Command, args []string) {
 		if grpcURLFlag != "" {
@@ -72,6 +73,7 @@ var viewCmd = &cobra.Command{
 	SilenceUsage: false,
 	Run: func(cmd *cobra.Command, args []string) {
 		c := clientconfig.GetClientConfigOrDie()
+		fmt.Printf("profile=%s\n", c.Profile)
 		fmt.Printf("api_url=%s\n", c.ApiURL)
 		fmt.Printf("grpc_url=%s\n", c.GrpcURL)
 		if viewRawFlag {
@@ -87,12 +89,49 @@ var viewCmd = &cobra.Command{
 
 var clearCmd = &cobra.Command{
 	Use:          "clear",
-	Short:        "Delete the client hoop configuration file if exists",
+	Short:        "Delete the selected profile, the configuration file is removed when there are no profiles left",
 	SilenceUsage: false,
 	Run: func(cmd *cobra.Command, args []string) {
+		profile := clientconfig.SelectedProfile()
 		if err := clientconfig.Remove(); err != nil {
 			styles.PrintErrorAndExit("failed removing configuration file, err=%v", err)
 		}
-		fmt.Println("configuration file removed")
+		fmt.Printf("profile %q removed\n", profile)
+	},
+}
+
+var useContextCmd = &cobra.Command{
+	Use:          "use-context NAME",
+	Short:        "Set the current profile of the configuration file",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: false,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := clientconfig.UseProfile(args[0]); err != nil {
+			styles.PrintErrorAndExit(err.Error())
+		}
+		fmt.Printf("switched to profile %q\n", args[0])
+	},
+}
+
+var getContextsCmd = &cobra.Command{
+	Use:          "get-contexts",
+	Short:        "List the profiles of the configuration file",
+	SilenceUsage: false,
+	Run: func(cmd *cobra.Command, args []string) {
+		profiles, err := clientconfig.ListProfiles()
+		if err != nil {
+			styles.PrintErrorAndExit(err.Error())
+		}
+		w := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', 0)
+		defer w.Flush()
+		fmt.Fprintln(w, "CURRENT\tNAME\tAPI URL\t")
+		for _, p := range profiles {
+			current := ""
+			if p.Current {
+				current = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t", current, p.Name, p.ApiURL)
+			fmt.Fprintln(w)
+		}
 	},
 }
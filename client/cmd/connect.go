@@ -2,9 +2,7 @@ package cmd
 
 import (
 	"context"
-	"crypto/x509"
 	"encoding/base64"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
@@ -19,6 +17,7 @@ import (
 	"github.com/briandowns/spinner"
 	"github.com/hoophq/hoop/client/cmd/styles"
 	clientconfig "github.com/hoophq/hoop/client/config"
+	"github.com/hoophq/hoop/client/nativeconfig"
 	"github.com/hoophq/hoop/client/proxy"
 	"github.com/hoophq/hoop/common/grpc"
 	"github.com/hoophq/hoop/common/log"
@@ -33,8 +32,11 @@ import (
 )
 
 type ConnectFlags struct {
-	proxyPort string
-	duration  string
+	proxyPort    string
+	duration     string
+	background   bool
+	nativeConfig bool
+	breakGlass   string
 }
 
 var connectFlags = ConnectFlags{}
@@ -55,10 +57,20 @@ var (
 			if dur.Seconds() < 60 {
 				return fmt.Errorf("the minimum duration is 60 seconds (60s)")
 			}
+			if connectFlags.background && connectFlags.breakGlass != "" {
+				return fmt.Errorf("--break-glass is not supported with --background")
+			}
+			if connectFlags.background && (len(args) > 1 || len(inputEnvVars) > 0) {
+				return fmt.Errorf("arguments and environment variables are not supported with --background")
+			}
 			return nil
 		},
 		SilenceUsage: false,
 		Run: func(cmd *cobra.Command, args []string) {
+			if connectFlags.background {
+				runConnectBackground(args[0])
+				return
+			}
 			clientEnvVars, err := parseClientEnvVars()
 			if err != nil {
 				fmt.Println(err)
@@ -73,16 +85,21 @@ func init() {
 	connectCmd.Flags().StringVarP(&connectFlags.proxyPort, "port", "p", "", "The port to listen the proxy")
 	connectCmd.Flags().StringSliceVarP(&inputEnvVars, "env", "e", nil, "Input environment variables to send")
 	connectCmd.Flags().StringVarP(&connectFlags.duration, "duration", "d", "30m", "The amount of time that the session will last. Valid time units are 's', 'm', 'h'")
+	connectCmd.Flags().BoolVar(&connectFlags.nativeConfig, "native-config", false, "Write the proxy address to native client configuration files (pgpass, my.cnf, ssh config, etc)")
+	connectCmd.Flags().BoolVar(&connectFlags.background, "background", false, "Keep the connection alive in the background using the local daemon")
+	connectCmd.Flags().StringVar(&connectFlags.breakGlass, "break-glass", "", "Request emergency access without approval, the value is the justification. The access is reviewed afterwards")
 	rootCmd.AddCommand(connectCmd)
 }
 
 type connect struct {
-	proxyPort      string
-	client         pb.ClientTransport
-	connStore      memory.Store
-	clientArgs     []string
-	connectionName string
-	loader         *spinner.Spinner
+	proxyPort       string
+	client          pb.ClientTransport
+	connStore       memory.Store
+	clientArgs      []string
+	connectionName  string
+	profile         string
+	loader          *spinner.Spinner
+	hasNativeConfig bool
 }
 
 func runConnect(args []string, clientEnvVars map[string]string) {
@@ -99,6 +116,9 @@ func runConnect(args []string, clientEnvVars map[string]string) {
 	sendOpenSessionPktFn := func() {
 		spec := newClientArgsSpec(c.clientArgs, clientEnvVars)
 		spec[pb.SpecJitTimeout] = []byte(connectFlags.duration)
+		if connectFlags.breakGlass != "" {
+			spec[pb.SpecBreakGlassJustification] = []byte(connectFlags.breakGlass)
+		}
 		if err := c.client.Send(&pb.Packet{
 			Type: pbagent.SessionOpen,
 			Spec: spec,
@@ -132,7 +152,7 @@ func runConnect(args []string, clientEnvVars map[string]string) {
 			}
 			sshHostKeyEnc := pkt.Spec[pb.SpecClientSSHHostKey]
 			if len(sshHostKeyEnc) > 0 {
-				sshHostKeySigner, err = parseHostKey(sshHostKeyEnc)
+				sshHostKeySigner, err = proxy.ParseSSHHostKey(sshHostKeyEnc)
 			}
 			if sshHostKeySigner == nil || err != nil {
 				log.Warn("unable to parse SSH host key received from server, using random key")
@@ -248,6 +268,9 @@ func runConnect(args []string, clientEnvVars map[string]string) {
 				errMsg := fmt.Errorf(`connection type %q not implemented`, connnectionType.String())
 				c.processGracefulExit(errMsg)
 			}
+			if connectFlags.nativeConfig && c.writeNativeConfig(string(sessionID), connnectionType) {
+				ossig.shutdownFn = func() { loader.Stop(); c.removeNativeConfig() }
+			}
 		case pbclient.SessionOpenApproveOK:
 			loader.Color("green")
 			loader.Suffix = " command approved, running ... "
@@ -358,6 +381,7 @@ func runConnect(args []string, clientEnvVars map[string]string) {
 			if srv, ok := c.connStore.Get(string(sessionID)).(proxy.Closer); ok {
 				srv.Close()
 			}
+			c.removeNativeConfig()
 			if len(pkt.Payload) > 0 {
 				os.Stderr.Write([]byte(styles.ClientError(string(pkt.Payload)) + "\n"))
 			}
@@ -378,6 +402,7 @@ func (c *connect) processGracefulExit(err error) {
 	if c.loader != nil {
 		c.loader.Stop()
 	}
+	c.removeNativeConfig()
 	for _, obj := range c.connStore.List() {
 		switch v := obj.(type) {
 		case *proxy.Terminal:
@@ -406,10 +431,45 @@ func (c *connect) printHeader(sessionID string) {
 	fmt.Println()
 }
 
+// writeNativeConfig writes the address of the local proxy into the configuration files of native clients
+func (c *connect) writeNativeConfig(sessionID string, connType pb.ConnectionType) bool {
+	srv, ok := c.connStore.Get(sessionID).(interface{ Host() proxy.Host })
+	if !ok {
+		return false
+	}
+	usage, err := nativeconfig.Write(nativeconfig.Entry{
+		Profile:        c.profile,
+		ConnectionName: c.connectionName,
+		ConnectionType: connType,
+		Host:           srv.Host().Host,
+		Port:           srv.Host().Port,
+	})
+	if err != nil {
+		log.Warnf("failed writing native client configuration, reason=%v", err)
+		return false
+	}
+	c.hasNativeConfig = len(usage) > 0
+	for _, u := range usage {
+		fmt.Printf("native config: %s\n", u)
+	}
+	return c.hasNativeConfig
+}
+
+func (c *connect) removeNativeConfig() {
+	if !c.hasNativeConfig {
+		return
+	}
+	c.hasNativeConfig = false
+	if err := nativeconfig.Remove(c.profile, c.connectionName); err != nil {
+		log.Warnf("failed removing native client configuration, reason=%v", err)
+	}
+}
+
 func (c *connect) printErrorAndExit(format string, v ...any) {
 	if c.loader != nil {
 		c.loader.Stop()
 	}
+	c.removeNativeConfig()
 	errOutput := styles.ClientError(fmt.Sprintf(format, v...))
 	fmt.Println(errOutput)
 	os.Exit(1)
@@ -421,6 +481,7 @@ func newClientConnect(config *clientconfig.Config, loader *spinner.Spinner, args
 		connStore:      memory.New(),
 		clientArgs:     args[1:],
 		connectionName: args[0],
+		profile:        config.Profile,
 		loader:         loader,
 	}
 	grpcClientOptions := []*grpc.ClientOptions{
@@ -477,26 +538,6 @@ func parseClientEnvVars() (map[string]string, error) {
 	return envVar, nil
 }
 
-func parseHostKey(encHostKey []byte) (ssh.Signer, error) {
-	hostKeyBytes, err := base64.StdEncoding.DecodeString(string(encHostKey))
-	if err != nil {
-		return nil, fmt.Errorf("failed decoding base64 hosts key: %v", err)
-	}
-	block, _ := pem.Decode(hostKeyBytes)
-	if block == nil {
-		return nil, fmt.Errorf("failed decoding host key PEM block")
-	}
-	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed parsing private key to PKCS#8 format: %v", err)
-	}
-	signerKey, err := ssh.NewSignerFromKey(key)
-	if err != nil {
-		return nil, fmt.Errorf("failed parsing private key to ssh signer: %v", err)
-	}
-	return signerKey, nil
-}
-
 type osInterrupt struct {
 	shutdownFn context.CancelFunc
 }
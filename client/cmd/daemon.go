@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hoophq/hoop/client/cmd/styles"
+	clientconfig "github.com/hoophq/hoop/client/config"
+	"github.com/hoophq/hoop/client/daemon"
+	"github.com/hoophq/hoop/common/grpc"
+	"github.com/hoophq/hoop/common/log"
+	pb "github.com/hoophq/hoop/common/proto"
+	"github.com/hoophq/hoop/common/version"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonCmd = &cobra.Command{
+		Use:   "daemon",
+		Short: "Keep multiple connections alive in a background process",
+		Long: `Runs a local daemon that keeps port-forwarded sessions alive, reconnecting when the gateway drops.
+Sessions are managed with 'hoop connect --background', 'hoop ls' and 'hoop disconnect'.`,
+		SilenceUsage: false,
+		Run: func(cmd *cobra.Command, args []string) {
+			socketPath, err := daemon.SocketPath()
+			if err != nil {
+				styles.PrintErrorAndExit(err.Error())
+			}
+			d := daemon.New(daemonConnect)
+			sigc := make(chan os.Signal, 1)
+			signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+			go func() {
+				sigval := <-sigc
+				log.Infof("received signal %v, closing sessions", sigval)
+				d.Shutdown()
+				_ = os.Remove(socketPath)
+				os.Exit(0)
+			}()
+			if err := d.Serve(socketPath); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	lsCmd = &cobra.Command{
+		Use:          "ls",
+		Short:        "List the connections managed by the daemon",
+		SilenceUsage: false,
+		Run: func(cmd *cobra.Command, args []string) {
+			items, err := newDaemonClient().List()
+			if err != nil {
+				styles.PrintErrorAndExit("failed listing sessions, is the daemon running? reason=%v", err)
+			}
+			w := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.TabIndent)
+			defer w.Flush()
+			fmt.Fprintln(w, "NAME\tPROFILE\tTYPE\tADDRESS\tSTATUS\tRECONNECTS\tEXPIRES\tMESSAGE\t")
+			for _, s := range items {
+				address := "-"
+				if s.Port != "" {
+					address = fmt.Sprintf("%s:%s", s.Host, s.Port)
+				}
+				expires := time.Until(s.ExpireAt).Round(time.Second).String()
+				if s.Status == daemon.StatusClosed {
+					expires = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%v\t%s\t%s\t", s.Name, toDash(s.Profile), toDash(s.ConnectionType), address,
+					s.Status, s.Reconnects, expires, toDash(s.Message))
+				fmt.Fprintln(w)
+			}
+		},
+	}
+	disconnectCmd = &cobra.Command{
+		Use:          "disconnect NAME",
+		Short:        "Close a connection of the selected profile managed by the daemon",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: false,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := newDaemonClient().Disconnect(args[0], clientconfig.SelectedProfile()); err != nil {
+				styles.PrintErrorAndExit(err.Error())
+			}
+			fmt.Printf("disconnected %v\n", args[0])
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(daemonCmd, lsCmd, disconnectCmd)
+}
+
+// daemonConnect loads the configuration on each attempt to obtain tokens refreshed by hoop login
+func daemonConnect(connectionName, profile string) (pb.ClientTransport, error) {
+	config, err := clientconfig.GetProfileConfig(profile)
+	if err != nil {
+		return nil, err
+	}
+	clientConfig, err := config.GrpcClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	clientConfig.UserAgent = fmt.Sprintf("hoopcli/%v", version.Get().Version)
+	return grpc.Connect(clientConfig,
+		grpc.WithOption(grpc.OptionConnectionName, connectionName),
+		grpc.WithOption("origin", pb.ConnectionOriginClient),
+		grpc.WithOption("verb", pb.ClientVerbConnect),
+	)
+}
+
+func newDaemonClient() *daemon.Client {
+	socketPath, err := daemon.SocketPath()
+	if err != nil {
+		styles.PrintErrorAndExit(err.Error())
+	}
+	return daemon.NewClient(socketPath)
+}
+
+// runConnectBackground hands the connection to the daemon, starting it if it's not running
+func runConnectBackground(connectionName string) {
+	client := newDaemonClient()
+	if err := client.Start(); err != nil {
+		styles.PrintErrorAndExit(err.Error())
+	}
+	profile := clientconfig.SelectedProfile()
+	_, err := client.Connect(daemon.ConnectRequest{
+		Name:         connectionName,
+		Port:         connectFlags.proxyPort,
+		Duration:     connectFlags.duration,
+		Profile:      profile,
+		NativeConfig: connectFlags.nativeConfig,
+	})
+	if err != nil {
+		styles.PrintErrorAndExit(err.Error())
+	}
+	// wait a few seconds for the session to be ready to display its address
+	for i := 0; i < 20; i++ {
+		items, err := client.List()
+		if err != nil {
+			styles.PrintErrorAndExit(err.Error())
+		}
+		for _, s := range items {
+			if s.Name != connectionName || s.Profile != profile {
+				continue
+			}
+			switch s.Status {
+			case daemon.StatusReady:
+				fmt.Printf("connection: %s | session: %s\n", s.Name, s.SessionID)
+				fmt.Printf("ready to accept connections at %s:%s\n", s.Host, s.Port)
+				return
+			case daemon.StatusClosed:
+				styles.PrintErrorAndExit(s.Message)
+			}
+		}
+		time.Sleep(time.Millisecond * 500)
+	}
+	fmt.Printf("connecting %s in background, check its status with 'hoop ls'\n", connectionName)
+}
+
+func toDash(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
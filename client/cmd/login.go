@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -76,7 +77,7 @@ var loginCmd = &cobra.Command{
 			printErrorAndExit(err.Error())
 		}
 		if saved {
-			fmt.Println("Login succeeded")
+			fmt.Printf("Login succeeded (profile: %s)\n", conf.Profile)
 		} else {
 			// means it's a local gateway (development)
 			// print to stdout
@@ -241,13 +242,29 @@ func performLoginWithBasicAuth(apiURL, tlsCA string) (string, error) {
 	password := string(bytePassword)
 	fmt.Println()
 	log.With("username", username, "is-password-set", len(password) > 0).Debugf("prompt credentials result")
-	return authenticateWithUserAndPassword(apiURL, tlsCA, username, password)
+	accessToken, err := authenticateWithUserAndPassword(apiURL, tlsCA, username, password, "")
+	if err != errMFARequired {
+		return accessToken, err
+	}
+	fmt.Fprintf(os.Stderr, "Enter Authentication Code: ")
+	totpCode, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	totpCode = strings.TrimSpace(totpCode)
+	return authenticateWithUserAndPassword(apiURL, tlsCA, username, password, totpCode)
 }
 
-func authenticateWithUserAndPassword(apiURL, tlsCA, username, password string) (string, error) {
+// errMFARequired is returned when the user has the second factor enabled and the code is missing
+var errMFARequired = errors.New("authentication code required")
+
+func authenticateWithUserAndPassword(apiURL, tlsCA, username, password, totpCode string) (string, error) {
 	c := httpclient.NewHttpClient(tlsCA)
 	url := fmt.Sprintf("%s/api/localauth/login", apiURL)
 	payload := map[string]any{"email": username, "password": password}
+	if totpCode != "" {
+		payload["totp_code"] = totpCode
+	}
 	reqBody, _ := json.Marshal(payload)
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
 	if err != nil {
@@ -260,6 +277,14 @@ func authenticateWithUserAndPassword(apiURL, tlsCA, username, password string) (
 	}
 	defer resp.Body.Close()
 	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusUnauthorized && totpCode == "" {
+		var respErr struct {
+			MFARequired bool `json:"mfa_required"`
+		}
+		if json.Unmarshal(respBody, &respErr) == nil && respErr.MFARequired {
+			return "", errMFARequired
+		}
+	}
 	if resp.StatusCode > 299 {
 		return "", fmt.Errorf("failed performing request, status=%v, body=%v",
 			resp.StatusCode, string(respBody))
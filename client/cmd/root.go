@@ -5,6 +5,8 @@ import (
 
 	"github.com/hoophq/hoop/client/cmd/admin"
 	"github.com/hoophq/hoop/client/cmd/config"
+	"github.com/hoophq/hoop/client/cmd/runbooks"
+	clientconfig "github.com/hoophq/hoop/client/config"
 	"github.com/hoophq/hoop/common/grpc"
 	"github.com/hoophq/hoop/common/log"
 	"github.com/spf13/cobra"
@@ -13,6 +15,7 @@ import (
 var (
 	debugGrpcFlag bool
 	debugFlag     bool
+	profileFlag   string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -29,6 +32,7 @@ https://hoop.dev/docs`,
 		if debugFlag {
 			log.SetDefaultLoggerLevel(log.LevelDebug)
 		}
+		clientconfig.SetProfile(profileFlag)
 	},
 }
 
@@ -44,7 +48,9 @@ func Execute() {
 func init() {
 	rootCmd.PersistentFlags().BoolVar(&debugGrpcFlag, "debug-grpc", grpc.ShouldDebugGrpc(), "Turn on debugging of gRPC (http2) if applicable")
 	rootCmd.PersistentFlags().BoolVar(&debugFlag, "debug", false, "Turn on debugging")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "The configuration profile to use, it overrides the env HOOP_PROFILE and the current profile")
 
 	rootCmd.AddCommand(config.MainCmd)
 	rootCmd.AddCommand(admin.MainCmd)
+	rootCmd.AddCommand(runbooks.MainCmd)
 }
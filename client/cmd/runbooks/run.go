@@ -0,0 +1,109 @@
+package runbooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/hoophq/hoop/client/cmd/styles"
+	clientconfig "github.com/hoophq/hoop/client/config"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/spf13/cobra"
+)
+
+// sessionPollInterval is the interval to check if an execution
+// that is still running in background has finished
+var sessionPollInterval = time.Second * 2
+
+var runCmd = &cobra.Command{
+	Use:     "run FILE",
+	Short:   "Run a runbook against a connection",
+	Example: "hoop runbooks run ops/charge.runbook.sql -c pgdemo -p amount=10 -p wallet_id=6736",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		conf := clientconfig.GetClientConfigOrDie()
+		params := parseParamsOrDie(paramsFlag)
+		loader := spinner.New(spinner.CharSets[11], 70*time.Millisecond,
+			spinner.WithWriter(os.Stderr), spinner.WithHiddenCursor(true))
+		loader.Color("green")
+		loader.Suffix = " running ..."
+		loader.Start()
+		done := make(chan os.Signal, 1)
+		signal.Notify(done, syscall.SIGTERM, syscall.SIGINT)
+		go func() {
+			<-done
+			loader.Stop() // this fixes terminal restore
+			os.Exit(143)
+		}()
+
+		var resp openapi.ExecResponse
+		endpoint := fmt.Sprintf("/api/plugins/runbooks/connections/%s/exec", url.PathEscape(connectionFlag))
+		statusCode, err := httpRequest(conf, "POST", endpoint, openapi.RunbookRequest{
+			FileName:   args[0],
+			Parameters: params,
+		}, &resp)
+		if err != nil {
+			loader.Stop()
+			styles.PrintErrorAndExit(err.Error())
+		}
+		switch {
+		case resp.HasReview:
+			loader.Stop()
+			fmt.Fprintln(os.Stderr, resp.Output)
+			fmt.Fprintf(os.Stderr, "the execution requires approval, session: %v\n", resp.SessionID)
+			os.Exit(0)
+		case statusCode == http.StatusAccepted:
+			loader.Suffix = fmt.Sprintf(" running in background, session: %v ...", resp.SessionID)
+			output, exitCode := waitSession(conf, resp.SessionID)
+			loader.Stop()
+			writeOutputAndExit(output, exitCode)
+		}
+		loader.Stop()
+		writeOutputAndExit(resp.Output, resp.ExitCode)
+	},
+}
+
+// waitSession waits until the session finishes returning its output and exit code
+func waitSession(conf *clientconfig.Config, sessionID string) (string, int) {
+	endpoint := fmt.Sprintf("/api/sessions/%s?event_stream=utf8&expand=event_stream", url.PathEscape(sessionID))
+	for {
+		time.Sleep(sessionPollInterval)
+		var session openapi.Session
+		if _, err := httpRequest(conf, "GET", endpoint, nil, &session); err != nil {
+			styles.PrintErrorAndExit("failed fetching session %v: %v", sessionID, err)
+		}
+		if session.EndSession == nil {
+			continue
+		}
+		var eventStream []string
+		_ = json.Unmarshal(session.EventStream, &eventStream)
+		var output string
+		if len(eventStream) > 0 {
+			output = eventStream[0]
+		}
+		exitCode := -2
+		if session.ExitCode != nil {
+			exitCode = *session.ExitCode
+		}
+		return output, exitCode
+	}
+}
+
+func writeOutputAndExit(output string, exitCode int) {
+	if exitCode == 0 {
+		_, _ = os.Stdout.WriteString(output)
+		os.Exit(0)
+	}
+	_, _ = os.Stderr.WriteString(output)
+	// the gateway uses negative codes when it's unable to obtain the exit code from the agent
+	if exitCode < 0 {
+		os.Exit(254)
+	}
+	os.Exit(exitCode)
+}
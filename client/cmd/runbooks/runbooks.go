@@ -0,0 +1,225 @@
+package runbooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hoophq/hoop/client/cmd/styles"
+	clientconfig "github.com/hoophq/hoop/client/config"
+	"github.com/hoophq/hoop/common/httpclient"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/common/version"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/spf13/cobra"
+)
+
+var (
+	connectionFlag string
+	paramsFlag     []string
+	outputFlag     string
+)
+
+func init() {
+	listCmd.Flags().StringVarP(&connectionFlag, "connection", "c", "", "List only the runbooks available for this connection")
+	listCmd.Flags().StringVarP(&outputFlag, "output", "o", "", "Output format. One of: (json)")
+	showCmd.Flags().StringVarP(&connectionFlag, "connection", "c", "", "The connection to render the runbook (required)")
+	showCmd.Flags().StringSliceVarP(&paramsFlag, "param", "p", nil, "The parameters of the runbook in the form of 'key=value'")
+	runCmd.Flags().StringVarP(&connectionFlag, "connection", "c", "", "The connection to run the runbook (required)")
+	runCmd.Flags().StringSliceVarP(&paramsFlag, "param", "p", nil, "The parameters of the runbook in the form of 'key=value'")
+	_ = showCmd.MarkFlagRequired("connection")
+	_ = runCmd.MarkFlagRequired("connection")
+	MainCmd.AddCommand(listCmd, showCmd, runCmd)
+}
+
+var MainCmd = &cobra.Command{
+	Use:   "runbooks",
+	Short: "List, preview and run runbooks",
+}
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the runbooks and their inputs",
+	Example: "hoop runbooks list -c pgdemo",
+	Run: func(cmd *cobra.Command, args []string) {
+		conf := clientconfig.GetClientConfigOrDie()
+		endpoint := "/api/plugins/runbooks/templates"
+		if connectionFlag != "" {
+			endpoint = fmt.Sprintf("/api/plugins/runbooks/connections/%s/templates", url.PathEscape(connectionFlag))
+		}
+		var runbookList openapi.RunbookList
+		if _, err := httpRequest(conf, "GET", endpoint, nil, &runbookList); err != nil {
+			styles.PrintErrorAndExit(err.Error())
+		}
+		if outputFlag == "json" {
+			data, _ := json.MarshalIndent(runbookList, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.TabIndent)
+		defer w.Flush()
+		fmt.Fprintln(w, "NAME\tCONNECTIONS\tINPUTS\tERROR\t")
+		for _, runbook := range runbookList.Items {
+			connections := strings.Join(runbook.ConnectionList, ", ")
+			if connectionFlag != "" {
+				connections = connectionFlag
+			}
+			errMsg := "-"
+			if runbook.Error != nil {
+				errMsg = *runbook.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n",
+				runbook.Name, orDash(connections), orDash(strings.Join(sortedKeys(runbook.Metadata), ", ")), errMsg)
+		}
+	},
+}
+
+var showCmd = &cobra.Command{
+	Use:     "show FILE",
+	Short:   "Show the inputs of a runbook and its rendered content",
+	Example: "hoop runbooks show ops/charge.runbook.sql -c pgdemo -p amount=10 -p wallet_id=6736",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		conf := clientconfig.GetClientConfigOrDie()
+		fileName := args[0]
+		var runbookList openapi.RunbookList
+		endpoint := fmt.Sprintf("/api/plugins/runbooks/connections/%s/templates", url.PathEscape(connectionFlag))
+		if _, err := httpRequest(conf, "GET", endpoint, nil, &runbookList); err != nil {
+			styles.PrintErrorAndExit(err.Error())
+		}
+		var runbook *openapi.Runbook
+		for _, item := range runbookList.Items {
+			if item.Name == fileName {
+				runbook = item
+				break
+			}
+		}
+		if runbook == nil {
+			styles.PrintErrorAndExit("runbook %v not found for connection %v", fileName, connectionFlag)
+		}
+		if runbook.Error != nil {
+			styles.PrintErrorAndExit("runbook %v is invalid: %v", fileName, *runbook.Error)
+		}
+		fmt.Printf("Name:    %v\n", runbook.Name)
+		fmt.Printf("Ref:     %v\n", runbookList.Ref)
+		fmt.Printf("Commit:  %v\n\n", runbookList.Commit)
+		w := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.TabIndent)
+		fmt.Fprintln(w, "INPUT\tTYPE\tREQUIRED\tDEFAULT\tOPTIONS\tDESCRIPTION\t")
+		for _, key := range sortedKeys(runbook.Metadata) {
+			m, _ := runbook.Metadata[key].(map[string]any)
+			options := "-"
+			if items, ok := m["options"].([]any); ok && len(items) > 0 {
+				options = strings.Trim(fmt.Sprintf("%v", items), "[]")
+			}
+			fmt.Fprintf(w, "%s\t%v\t%v\t%v\t%s\t%v\t\n",
+				key, m["type"], m["required"], orDash(toStr(m["default"])), options, orDash(toStr(m["description"])))
+		}
+		w.Flush()
+
+		var preview openapi.RunbookPreview
+		endpoint = fmt.Sprintf("/api/plugins/runbooks/connections/%s/preview", url.PathEscape(connectionFlag))
+		_, err := httpRequest(conf, "POST", endpoint, openapi.RunbookRequest{
+			FileName:   fileName,
+			RefHash:    runbookList.Commit,
+			Parameters: parseParamsOrDie(paramsFlag),
+		}, &preview)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, styles.ClientErrorSimple(fmt.Sprintf("\nunable to render the runbook: %v", err)))
+			return
+		}
+		if len(preview.EnvVars) > 0 {
+			fmt.Printf("\nEnv Vars: %v\n", strings.Join(preview.EnvVars, ", "))
+		}
+		fmt.Printf("\n%s\n", styles.Fainted.Render("--- rendered content ---"))
+		fmt.Println(preview.Content)
+	},
+}
+
+func parseParamsOrDie(keyValList []string) map[string]string {
+	params := map[string]string{}
+	for _, keyVal := range keyValList {
+		key, val, found := strings.Cut(keyVal, "=")
+		if !found || key == "" {
+			styles.PrintErrorAndExit("invalid parameter %q, it must be in the form of 'key=value'", keyVal)
+		}
+		params[key] = val
+	}
+	return params
+}
+
+// httpRequest performs a request against the api decoding the json response into the
+// value of the argument. It returns the status code of successful requests.
+func httpRequest(conf *clientconfig.Config, method, endpoint string, body, into any) (int, error) {
+	apiURL, err := url.JoinPath(conf.ApiURL, endpoint)
+	if err != nil {
+		return 0, err
+	}
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("failed encoding body, err=%v", err)
+		}
+		reqBody = bytes.NewBuffer(data)
+	}
+	log.Debugf("performing http request at %v %v", method, apiURL)
+	req, err := http.NewRequest(method, apiURL, reqBody)
+	if err != nil {
+		return 0, fmt.Errorf("failed creating http request, err=%v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", conf.Token))
+	if conf.IsApiKey() {
+		req.Header.Set("Api-Key", conf.Token)
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("hoopcli/%s", version.Get().Version))
+	resp, err := httpclient.NewHttpClient(conf.TlsCA()).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	log.Debugf("http response %v", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		var httpErr openapi.HTTPError
+		respBody, _ := io.ReadAll(resp.Body)
+		if err := json.Unmarshal(respBody, &httpErr); err == nil && httpErr.Message != "" {
+			return resp.StatusCode, fmt.Errorf("%s", httpErr.Message)
+		}
+		return resp.StatusCode, fmt.Errorf("failed performing request, status=%v, body=%v",
+			resp.StatusCode, string(respBody))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed decoding response, status=%v, err=%v", resp.StatusCode, err)
+	}
+	return resp.StatusCode, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func toStr(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func orDash(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
@@ -10,7 +10,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/BurntSushi/toml"
 	"github.com/google/uuid"
 	"github.com/hoophq/hoop/client/cmd/styles"
 	"github.com/hoophq/hoop/common/clientconfig"
@@ -24,23 +23,25 @@ var ErrEmpty error = errors.New("unable to locate configuration file")
 const apiLocalhostURL = "http://127.0.0.1:8009"
 
 type Config struct {
-	Token        string `toml:"token"`
-	ApiURL       string `toml:"api_url"`
-	GrpcURL      string `toml:"grpc_url"`
-	TlsCAB64Enc  string `toml:"tls_ca"`
+	Token        string `toml:"token,omitempty"`
+	ApiURL       string `toml:"api_url,omitempty"`
+	GrpcURL      string `toml:"grpc_url,omitempty"`
+	TlsCAB64Enc  string `toml:"tls_ca,omitempty"`
 	Mode         string `toml:"-"`
 	InsecureGRPC bool   `toml:"-"`
+	Profile      string `toml:"-"`
 	filepath     string `toml:"-"`
 }
 
-// NewConfigFile creates a new configuration in the filesystem
+// NewConfigFile creates or overrides the selected profile in the configuration file
 func NewConfigFile(apiURL, grpcURL, token, tlsCA string) (string, error) {
-	filepath, err := clientconfig.NewPath(clientconfig.ClientFile)
+	filepath, f, err := openConfigFile()
 	if err != nil {
 		return "", err
 	}
 	config := &Config{
 		filepath:    filepath,
+		Profile:     f.selectProfile(),
 		Token:       token,
 		ApiURL:      apiURL,
 		GrpcURL:     grpcURL,
@@ -50,20 +51,28 @@ func NewConfigFile(apiURL, grpcURL, token, tlsCA string) (string, error) {
 	return filepath, err
 }
 
-// Remove the configuration file if it exists
+// Remove the selected profile from the configuration file.
+// The file is removed when there are no profiles left.
 func Remove() error {
-	filepath, _ := clientconfig.NewPath(clientconfig.ClientFile)
-	if filepath != "" {
+	filepath, f, err := openConfigFile()
+	if err != nil {
+		return err
+	}
+	f.remove(f.selectProfile())
+	if f.isEmpty() {
 		return os.Remove(filepath)
 	}
-	return nil
+	return writeConfigFile(filepath, f)
 }
 
 // Load builds an client config file in the following order.
 // load the configuration based on environment variables  (HOOP_GRPCURL, HOOP_APIURL & HOOP_TOKEN)
-// load based in the configuration file $HOME/.hoop/client.toml.
+// load the selected profile of the configuration file $HOME/.hoop/config.toml, see SetProfile.
 // load a configuration file if localhost grpc port has connectivity.
-func Load() (*Config, error) {
+func Load() (*Config, error) { return load("") }
+
+// load the configuration of a profile, an empty name loads the selected profile
+func load(profileName string) (*Config, error) {
 	// TODO: if env is set, use it
 	grpcURL := os.Getenv("HOOP_GRPCURL")
 	apiServer := os.Getenv("HOOP_APIURL")
@@ -83,15 +92,16 @@ func Load() (*Config, error) {
 	}
 
 	// fallback to reading the configuration file
-	filepath, err := clientconfig.NewPath(clientconfig.ClientFile)
+	filepath, f, err := openConfigFile()
 	if err != nil {
 		return nil, err
 	}
-	var conf Config
-	if _, err := toml.DecodeFile(filepath, &conf); err != nil {
-		return nil, fmt.Errorf("failed decoding configuration file=%v, err=%v", filepath, err)
+	profile := f.selectProfile()
+	if profileName != "" {
+		profile = profileName
 	}
-
+	conf := f.get(profile)
+	conf.Profile = profile
 	if !conf.isEmpty() {
 		if conf.TlsCAB64Enc == "" {
 			conf.TlsCAB64Enc = base64.StdEncoding.EncodeToString([]byte(tlsCA))
@@ -99,7 +109,7 @@ func Load() (*Config, error) {
 		conf.Mode = clientconfig.ModeConfigFile
 		conf.filepath = filepath
 		conf.InsecureGRPC = hasInsecureScheme(conf.GrpcURL)
-		return &conf, nil
+		return conf, nil
 	}
 
 	// fallback connecting to localhost without tls / authentication
@@ -114,9 +124,10 @@ func Load() (*Config, error) {
 			Mode:         clientconfig.ModeLocal,
 			Token:        accessToken,
 			InsecureGRPC: true,
+			Profile:      profile,
 		}, nil
 	}
-	return &Config{filepath: filepath}, ErrEmpty
+	return &Config{filepath: filepath, Profile: profile}, ErrEmpty
 }
 
 // GrpcClientConfig returns a configuration to connect to the gRPC server
@@ -154,12 +165,17 @@ func (c *Config) Save() (bool, error) {
 		return false, nil
 	}
 	debugTokenClaims(c.Token)
-	confBuffer := bytes.NewBuffer([]byte{})
-	if err := toml.NewEncoder(confBuffer).Encode(c); err != nil {
-		return false, fmt.Errorf("failed saving config to %s, encode-err=%v", c.filepath, err)
+	f, err := readConfigFile(c.filepath)
+	if err != nil {
+		return false, err
+	}
+	profile := c.Profile
+	if profile == "" {
+		profile = DefaultProfile
 	}
-	if err := os.WriteFile(c.filepath, confBuffer.Bytes(), 0600); err != nil {
-		return false, fmt.Errorf("failed saving config to %s, err=%v", c.filepath, err)
+	f.set(profile, c)
+	if err := writeConfigFile(c.filepath, f); err != nil {
+		return false, err
 	}
 	return true, nil
 }
@@ -188,13 +204,17 @@ func GetClientConfigOrDie() *Config {
 	default:
 		styles.PrintErrorAndExit(err.Error())
 	}
-	log.Debugf("loaded clientconfig, mode=%v, grpc-tls=%v, api_url=%v, grpc_url=%v, tokenlength=%v, tlsca=%v",
-		config.Mode, !config.InsecureGRPC, config.ApiURL, config.GrpcURL, len(config.Token), config.TlsCAB64Enc != "")
+	log.Debugf("loaded clientconfig, mode=%v, profile=%v, grpc-tls=%v, api_url=%v, grpc_url=%v, tokenlength=%v, tlsca=%v",
+		config.Mode, config.Profile, !config.InsecureGRPC, config.ApiURL, config.GrpcURL, len(config.Token), config.TlsCAB64Enc != "")
 	return config
 }
 
-func GetClientConfig() (*Config, error) {
-	config, err := Load()
+func GetClientConfig() (*Config, error) { return GetProfileConfig("") }
+
+// GetProfileConfig loads and validates the configuration of a profile,
+// an empty name loads the selected profile.
+func GetProfileConfig(profileName string) (*Config, error) {
+	config, err := load(profileName)
 	switch err {
 	case ErrEmpty, nil:
 		if !config.IsValid() || !config.HasToken() {
@@ -203,8 +223,8 @@ func GetClientConfig() (*Config, error) {
 	default:
 		return nil, err
 	}
-	log.Infof("loaded clientconfig, mode=%v, grpc-tls=%v, api_url=%v, grpc_url=%v, tlsca=%v",
-		config.Mode, !config.InsecureGRPC, config.ApiURL, config.GrpcURL, config.TlsCAB64Enc != "")
+	log.Infof("loaded clientconfig, mode=%v, profile=%v, grpc-tls=%v, api_url=%v, grpc_url=%v, tlsca=%v",
+		config.Mode, config.Profile, !config.InsecureGRPC, config.ApiURL, config.GrpcURL, config.TlsCAB64Enc != "")
 	return config, nil
 }
 
@@ -0,0 +1,169 @@
+package clientconfig
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hoophq/hoop/common/clientconfig"
+)
+
+// DefaultProfile is the profile stored in the top level keys of the configuration file.
+// It keeps configuration files created before profiles were introduced working.
+const DefaultProfile = "default"
+
+// profileFlag is the profile selected by the global flag --profile
+var profileFlag string
+
+// configFile is the representation of the configuration file in the filesystem
+//
+//	current_profile = "staging"
+//	api_url = "https://use.hoop.dev" # default profile
+//
+//	[profiles.staging]
+//	api_url = "https://staging.hoop.dev"
+type configFile struct {
+	CurrentProfile string `toml:"current_profile,omitempty"`
+	Config
+	Profiles map[string]*Config `toml:"profiles,omitempty"`
+}
+
+// SetProfile selects the profile to load, it takes precedence over
+// the environment variable HOOP_PROFILE and the current profile of the configuration file.
+func SetProfile(name string) { profileFlag = name }
+
+// selectProfile returns the profile name in the following order: --profile, HOOP_PROFILE and current_profile
+func (f *configFile) selectProfile() string {
+	for _, name := range []string{profileFlag, os.Getenv("HOOP_PROFILE"), f.CurrentProfile} {
+		if name != "" {
+			return name
+		}
+	}
+	return DefaultProfile
+}
+
+func (f *configFile) get(name string) *Config {
+	if name == DefaultProfile {
+		conf := f.Config
+		return &conf
+	}
+	if conf, ok := f.Profiles[name]; ok && conf != nil {
+		c := *conf
+		return &c
+	}
+	return &Config{}
+}
+
+func (f *configFile) set(name string, conf *Config) {
+	if name == DefaultProfile {
+		f.Config = *conf
+		return
+	}
+	if f.Profiles == nil {
+		f.Profiles = map[string]*Config{}
+	}
+	f.Profiles[name] = conf
+}
+
+func (f *configFile) has(name string) bool {
+	if name == DefaultProfile {
+		return !f.Config.isEmpty()
+	}
+	_, ok := f.Profiles[name]
+	return ok
+}
+
+func (f *configFile) remove(name string) {
+	if name == DefaultProfile {
+		f.Config = Config{}
+		return
+	}
+	delete(f.Profiles, name)
+	if f.CurrentProfile == name {
+		f.CurrentProfile = ""
+	}
+}
+
+func (f *configFile) isEmpty() bool { return f.Config.isEmpty() && len(f.Profiles) == 0 }
+
+// openConfigFile reads the configuration file, creating it if it doesn't exist
+func openConfigFile() (string, *configFile, error) {
+	filepath, err := clientconfig.NewPath(clientconfig.ClientFile)
+	if err != nil {
+		return "", nil, err
+	}
+	f, err := readConfigFile(filepath)
+	return filepath, f, err
+}
+
+func readConfigFile(filepath string) (*configFile, error) {
+	var f configFile
+	if _, err := toml.DecodeFile(filepath, &f); err != nil {
+		return nil, fmt.Errorf("failed decoding configuration file=%v, err=%v", filepath, err)
+	}
+	return &f, nil
+}
+
+func writeConfigFile(filepath string, f *configFile) error {
+	confBuffer := bytes.NewBuffer([]byte{})
+	if err := toml.NewEncoder(confBuffer).Encode(f); err != nil {
+		return fmt.Errorf("failed saving config to %s, encode-err=%v", filepath, err)
+	}
+	if err := os.WriteFile(filepath, confBuffer.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed saving config to %s, err=%v", filepath, err)
+	}
+	return nil
+}
+
+// Profile describes a profile of the configuration file
+type Profile struct {
+	Name    string
+	ApiURL  string
+	Current bool
+}
+
+// ListProfiles returns the profiles of the configuration file sorted by name
+func ListProfiles() ([]Profile, error) {
+	_, f, err := openConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	selected := f.selectProfile()
+	var items []Profile
+	if f.has(DefaultProfile) || selected == DefaultProfile {
+		items = append(items, Profile{Name: DefaultProfile, ApiURL: f.ApiURL, Current: selected == DefaultProfile})
+	}
+	for name, conf := range f.Profiles {
+		items = append(items, Profile{Name: name, ApiURL: conf.ApiURL, Current: selected == name})
+	}
+	slices.SortFunc(items, func(a, b Profile) int { return strings.Compare(a.Name, b.Name) })
+	return items, nil
+}
+
+// UseProfile persists the current profile in the configuration file
+func UseProfile(name string) error {
+	filepath, f, err := openConfigFile()
+	if err != nil {
+		return err
+	}
+	if name != DefaultProfile && !f.has(name) {
+		return fmt.Errorf("profile %q not found in %v", name, filepath)
+	}
+	f.CurrentProfile = name
+	if name == DefaultProfile {
+		f.CurrentProfile = ""
+	}
+	return writeConfigFile(filepath, f)
+}
+
+// SelectedProfile returns the name of the profile that will be loaded
+func SelectedProfile() string {
+	_, f, err := openConfigFile()
+	if err != nil {
+		return (&configFile{}).selectProfile()
+	}
+	return f.selectProfile()
+}
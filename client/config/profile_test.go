@@ -0,0 +1,91 @@
+package clientconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfigFile(t *testing.T, content string) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("HOOP_PROFILE", "")
+	t.Setenv("HOOP_GRPCURL", "")
+	t.Setenv("HOOP_APIURL", "")
+	t.Setenv("HOOP_TOKEN", "")
+	t.Cleanup(func() { SetProfile("") })
+	configPath := filepath.Join(home, ".hoop", "config.toml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(configPath), 0700))
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0600))
+	return configPath
+}
+
+func TestLoadSelectedProfile(t *testing.T) {
+	newTestConfigFile(t, `
+current_profile = "staging"
+api_url = "https://use.hoop.dev"
+grpc_url = "grpcs://use.hoop.dev:8443"
+token = "default-token"
+
+[profiles.staging]
+api_url = "https://staging.hoop.dev"
+grpc_url = "grpcs://staging.hoop.dev:8443"
+token = "staging-token"
+`)
+	conf, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "staging", conf.Profile)
+	assert.Equal(t, "staging-token", conf.Token)
+
+	t.Setenv("HOOP_PROFILE", DefaultProfile)
+	conf, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "default-token", conf.Token)
+
+	// the flag takes precedence over the environment variable
+	SetProfile("staging")
+	conf, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "staging-token", conf.Token)
+
+	SetProfile("unknown")
+	_, err = GetClientConfig()
+	assert.Error(t, err)
+}
+
+func TestSaveProfileToken(t *testing.T) {
+	configPath := newTestConfigFile(t, `
+api_url = "https://use.hoop.dev"
+token = "default-token"
+`)
+	SetProfile("prod")
+	conf, err := Load()
+	assert.Equal(t, ErrEmpty, err)
+	conf.ApiURL = "https://prod.hoop.dev"
+	conf.Token = "prod-token"
+	_, err = conf.Save()
+	require.NoError(t, err)
+
+	f, err := readConfigFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "default-token", f.Token)
+	assert.Equal(t, "prod-token", f.Profiles["prod"].Token)
+
+	require.NoError(t, UseProfile("prod"))
+	SetProfile("")
+	conf, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "prod", conf.Profile)
+	assert.Equal(t, "prod-token", conf.Token)
+	assert.Error(t, UseProfile("unknown"))
+
+	require.NoError(t, Remove())
+	f, err = readConfigFile(configPath)
+	require.NoError(t, err)
+	assert.Empty(t, f.Profiles)
+	assert.Empty(t, f.CurrentProfile)
+	assert.Equal(t, "default-token", f.Token)
+}
@@ -0,0 +1,121 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// Client interacts with the api of a daemon using its unix socket
+type Client struct {
+	socketPath string
+	httpClient *http.Client
+}
+
+func NewClient(socketPath string) *Client {
+	return &Client{
+		socketPath: socketPath,
+		httpClient: &http.Client{
+			Timeout: time.Second * 10,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+// Start spawns the daemon in background if it isn't running yet.
+// The output of the daemon is written to daemon.log in the hoop home dir.
+func (c *Client) Start() error {
+	if _, err := c.List(); err == nil {
+		return nil
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed obtaining executable path, reason=%v", err)
+	}
+	logFile, err := os.OpenFile(filepath.Join(filepath.Dir(c.socketPath), "daemon.log"),
+		os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed opening daemon log file, reason=%v", err)
+	}
+	defer logFile.Close()
+	cmd := exec.Command(executable, "daemon")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed starting daemon, reason=%v", err)
+	}
+	_ = cmd.Process.Release()
+	for i := 0; i < 50; i++ {
+		if _, err = c.List(); err == nil {
+			return nil
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+	return fmt.Errorf("timeout waiting for daemon to start, reason=%v", err)
+}
+
+// List returns the sessions managed by the daemon
+func (c *Client) List() ([]Session, error) {
+	var items []Session
+	return items, c.do("GET", "/sessions", nil, &items)
+}
+
+// Connect requests the daemon to open and keep alive a session to a connection
+func (c *Client) Connect(req ConnectRequest) (*Session, error) {
+	var obj Session
+	return &obj, c.do("POST", "/sessions", req, &obj)
+}
+
+// Disconnect closes a session of a profile managed by the daemon
+func (c *Client) Disconnect(name, profile string) error {
+	path := "/sessions/" + url.PathEscape(name) + "?" + url.Values{"profile": {profile}}.Encode()
+	return c.do("DELETE", path, nil, nil)
+}
+
+func (c *Client) do(method, path string, body, into any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed encoding request body, reason=%v", err)
+		}
+		reqBody = bytes.NewBuffer(data)
+	}
+	req, err := http.NewRequest(method, "http://daemon"+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode > 299 {
+		var errResp map[string]string
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		if msg := errResp["message"]; msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return fmt.Errorf("daemon responded with status %v", resp.StatusCode)
+	}
+	if into == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(into)
+}
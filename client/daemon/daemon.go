@@ -0,0 +1,193 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hoophq/hoop/common/clientconfig"
+	"github.com/hoophq/hoop/common/log"
+	pb "github.com/hoophq/hoop/common/proto"
+)
+
+const socketFile = "daemon.sock"
+
+// ErrAlreadyRunning is returned when another daemon is listening in the same socket
+var ErrAlreadyRunning = errors.New("daemon is already running")
+
+type Status string
+
+const (
+	StatusConnecting      Status = "connecting"
+	StatusWaitingApproval Status = "waiting-approval"
+	StatusReady           Status = "ready"
+	StatusReconnecting    Status = "reconnecting"
+	StatusClosed          Status = "closed"
+)
+
+// ConnectRequest opens a session to a connection kept alive by the daemon
+type ConnectRequest struct {
+	Name     string `json:"name"`
+	Port     string `json:"port"`
+	Duration string `json:"duration"`
+	// Profile is the configuration profile used to connect to the gateway
+	Profile string `json:"profile"`
+	// NativeConfig writes the proxy address to the configuration files of native clients
+	NativeConfig bool `json:"native_config"`
+}
+
+// Session is the state of a session managed by the daemon
+type Session struct {
+	Name           string    `json:"name"`
+	Profile        string    `json:"profile"`
+	ConnectionType string    `json:"connection_type"`
+	Host           string    `json:"host"`
+	Port           string    `json:"port"`
+	Status         Status    `json:"status"`
+	SessionID      string    `json:"session_id"`
+	Reconnects     int       `json:"reconnects"`
+	Message        string    `json:"message"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpireAt       time.Time `json:"expire_at"`
+}
+
+// ConnectFunc opens a new gRPC stream with the gateway of a profile for a connection
+type ConnectFunc func(connectionName, profile string) (pb.ClientTransport, error)
+
+// Daemon keeps multiple port-forwarded sessions alive in a single process
+// and exposes its state through a local unix socket.
+type Daemon struct {
+	connectFn ConnectFunc
+	mu        sync.Mutex
+	// sessions by profile and connection name, see sessionKey
+	sessions map[string]*session
+}
+
+// SocketPath returns the path of the unix socket in the hoop home dir
+func SocketPath() (string, error) {
+	homeDir, err := clientconfig.NewHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, socketFile), nil
+}
+
+func New(connectFn ConnectFunc) *Daemon {
+	return &Daemon{connectFn: connectFn, sessions: map[string]*session{}}
+}
+
+// Serve accepts requests in the unix socket until the listener is closed
+func (d *Daemon) Serve(socketPath string) error {
+	if _, err := NewClient(socketPath).List(); err == nil {
+		return ErrAlreadyRunning
+	}
+	// remove any stale socket left by a daemon that didn't exit gracefully
+	_ = os.Remove(socketPath)
+	lis, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed listening at %v, reason=%v", socketPath, err)
+	}
+	defer lis.Close()
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		return fmt.Errorf("failed changing permissions of %v, reason=%v", socketPath, err)
+	}
+	log.Infof("daemon listening at %v", socketPath)
+	return http.Serve(lis, d.Handler())
+}
+
+// Shutdown closes all sessions managed by the daemon
+func (d *Daemon) Shutdown() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, s := range d.sessions {
+		s.stop()
+		delete(d.sessions, key)
+	}
+}
+
+// Handler returns the http api of the daemon
+func (d *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sessions", d.listSessions)
+	mux.HandleFunc("POST /sessions", d.openSession)
+	mux.HandleFunc("DELETE /sessions/{name}", d.closeSession)
+	return mux
+}
+
+// sessionKey identifies a session, connections with the same name could be opened in distinct profiles
+func sessionKey(profile, name string) string { return profile + "/" + name }
+
+func (d *Daemon) listSessions(w http.ResponseWriter, _ *http.Request) {
+	d.mu.Lock()
+	items := []Session{}
+	for _, s := range d.sessions {
+		items = append(items, s.snapshot())
+	}
+	d.mu.Unlock()
+	slices.SortFunc(items, func(a, b Session) int {
+		return strings.Compare(sessionKey(a.Profile, a.Name), sessionKey(b.Profile, b.Name))
+	})
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (d *Daemon) openSession(w http.ResponseWriter, r *http.Request) {
+	var req ConnectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "failed decoding request body: %v", err)
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "missing connection name")
+		return
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid duration %q", req.Duration)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := sessionKey(req.Profile, req.Name)
+	if s, ok := d.sessions[key]; ok && !s.isClosed() {
+		writeError(w, http.StatusConflict, "connection %v of profile %q is already managed by the daemon", req.Name, req.Profile)
+		return
+	}
+	s := newSession(req, duration, d.connectFn)
+	d.sessions[key] = s
+	go s.run()
+	writeJSON(w, http.StatusCreated, s.snapshot())
+}
+
+func (d *Daemon) closeSession(w http.ResponseWriter, r *http.Request) {
+	name, profile := r.PathValue("name"), r.URL.Query().Get("profile")
+	key := sessionKey(profile, name)
+	d.mu.Lock()
+	s, ok := d.sessions[key]
+	delete(d.sessions, key)
+	d.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "connection %v of profile %q is not managed by the daemon", name, profile)
+		return
+	}
+	s.stop()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, obj any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(obj)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, v ...any) {
+	writeJSON(w, status, map[string]string{"message": fmt.Sprintf(format, v...)})
+}
@@ -0,0 +1,194 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/hoophq/hoop/common/proto"
+	pbclient "github.com/hoophq/hoop/common/proto/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTransport struct {
+	recvC chan *pb.Packet
+	doneC chan struct{}
+}
+
+func newFakeTransport(pkts ...*pb.Packet) *fakeTransport {
+	t := &fakeTransport{recvC: make(chan *pb.Packet, len(pkts)), doneC: make(chan struct{})}
+	for _, pkt := range pkts {
+		t.recvC <- pkt
+	}
+	return t
+}
+
+func (t *fakeTransport) Recv() (*pb.Packet, error) {
+	select {
+	case pkt := <-t.recvC:
+		if pkt == nil {
+			return nil, fmt.Errorf("connection reset by peer")
+		}
+		return pkt, nil
+	case <-t.doneC:
+		return nil, context.Canceled
+	}
+}
+func (t *fakeTransport) Send(*pb.Packet) error          { return nil }
+func (t *fakeTransport) StreamContext() context.Context { return context.Background() }
+func (t *fakeTransport) StartKeepAlive()                {}
+func (t *fakeTransport) Close() (error, error) {
+	select {
+	case <-t.doneC:
+	default:
+		close(t.doneC)
+	}
+	return nil, nil
+}
+
+func freePort(t *testing.T) string {
+	lis, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	return port
+}
+
+func startDaemon(t *testing.T, connectFn ConnectFunc) *Client {
+	socketPath := filepath.Join(t.TempDir(), socketFile)
+	d := New(connectFn)
+	go func() { _ = d.Serve(socketPath) }()
+	t.Cleanup(d.Shutdown)
+	client := NewClient(socketPath)
+	assert.Eventually(t, func() bool { _, err := client.List(); return err == nil }, time.Second*2, time.Millisecond*50)
+	return client
+}
+
+func waitSession(t *testing.T, client *Client, name string, cond func(s Session) bool) Session {
+	var got Session
+	assert.Eventually(t, func() bool {
+		items, err := client.List()
+		if err != nil {
+			return false
+		}
+		for _, s := range items {
+			if s.Name == name {
+				got = s
+				return cond(s)
+			}
+		}
+		return false
+	}, time.Second*3, time.Millisecond*50, "last state=%#v", got)
+	return got
+}
+
+func sessionOpenOK(sid string) *pb.Packet {
+	return &pb.Packet{
+		Type: pbclient.SessionOpenOK,
+		Spec: map[string][]byte{
+			pb.SpecGatewaySessionID: []byte(sid),
+			pb.SpecConnectionType:   []byte(pb.ConnectionTypeTCP),
+		},
+	}
+}
+
+func TestDaemonReconnectWhenGatewayDrops(t *testing.T) {
+	transports := []*fakeTransport{
+		// the nil packet emulates the gateway dropping the stream
+		newFakeTransport(sessionOpenOK("sid-1"), nil),
+		newFakeTransport(sessionOpenOK("sid-2")),
+	}
+	var attempt int
+	client := startDaemon(t, func(connectionName, profile string) (pb.ClientTransport, error) {
+		tr := transports[min(attempt, len(transports)-1)]
+		attempt++
+		return tr, nil
+	})
+
+	port := freePort(t)
+	_, err := client.Connect(ConnectRequest{Name: "tcp-demo", Port: port, Duration: "30m"})
+	require.NoError(t, err)
+
+	s := waitSession(t, client, "tcp-demo", func(s Session) bool { return s.SessionID == "sid-2" && s.Status == StatusReady })
+	assert.Equal(t, port, s.Port)
+	assert.Equal(t, 1, s.Reconnects)
+	assert.Equal(t, "tcp", s.ConnectionType)
+
+	_, err = client.Connect(ConnectRequest{Name: "tcp-demo", Duration: "30m"})
+	assert.ErrorContains(t, err, "already managed by the daemon")
+
+	require.NoError(t, client.Disconnect("tcp-demo", ""))
+	items, err := client.List()
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	assert.ErrorContains(t, client.Disconnect("tcp-demo", ""), "is not managed by the daemon")
+}
+
+func TestDaemonSameConnectionInDistinctProfiles(t *testing.T) {
+	client := startDaemon(t, func(connectionName, profile string) (pb.ClientTransport, error) {
+		return newFakeTransport(sessionOpenOK("sid-" + profile)), nil
+	})
+	for _, profile := range []string{"default", "staging"} {
+		_, err := client.Connect(ConnectRequest{Name: "tcp-demo", Port: freePort(t), Duration: "30m", Profile: profile})
+		require.NoError(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		items, err := client.List()
+		return err == nil && len(items) == 2 &&
+			items[0].Profile == "default" && items[0].Status == StatusReady &&
+			items[1].Profile == "staging" && items[1].Status == StatusReady
+	}, time.Second*3, time.Millisecond*50)
+
+	require.NoError(t, client.Disconnect("tcp-demo", "staging"))
+	items, err := client.List()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "default", items[0].Profile)
+	assert.Equal(t, "sid-default", items[0].SessionID)
+	assert.ErrorContains(t, client.Disconnect("tcp-demo", "staging"), "is not managed by the daemon")
+}
+
+func TestDaemonSessionClosedByGateway(t *testing.T) {
+	client := startDaemon(t, func(connectionName, profile string) (pb.ClientTransport, error) {
+		return newFakeTransport(&pb.Packet{Type: pbclient.SessionClose, Payload: []byte("access revoked")}), nil
+	})
+	_, err := client.Connect(ConnectRequest{Name: "mysql-demo", Duration: "5m"})
+	require.NoError(t, err)
+
+	s := waitSession(t, client, "mysql-demo", func(s Session) bool { return s.Status == StatusClosed })
+	assert.Equal(t, "access revoked", s.Message)
+
+	// a closed session could be opened again
+	_, err = client.Connect(ConnectRequest{Name: "mysql-demo", Duration: "5m"})
+	assert.NoError(t, err)
+}
+
+func TestDaemonInvalidRequest(t *testing.T) {
+	client := startDaemon(t, nil)
+	_, err := client.Connect(ConnectRequest{Duration: "5m"})
+	assert.ErrorContains(t, err, "missing connection name")
+	_, err = client.Connect(ConnectRequest{Name: "pg", Duration: "abc"})
+	assert.ErrorContains(t, err, "invalid duration")
+}
+
+func TestSessionStopDuringBackoff(t *testing.T) {
+	s := newSession(ConnectRequest{Name: "pg-demo"}, time.Hour, func(connectionName, profile string) (pb.ClientTransport, error) {
+		return nil, fmt.Errorf("connection refused")
+	})
+	done := make(chan struct{})
+	go func() { s.run(); close(done) }()
+	assert.Eventually(t, func() bool { return s.snapshot().Reconnects == 1 }, time.Second, time.Millisecond*10)
+
+	// the session must end without waiting the backoff to reconnect
+	s.stop()
+	select {
+	case <-done:
+	case <-time.After(minReconnectBackoff / 2):
+		t.Fatal("expected the session to stop while waiting to reconnect")
+	}
+	assert.Equal(t, 1, s.snapshot().Reconnects)
+}
@@ -0,0 +1,6 @@
+package daemon
+
+import "os/exec"
+
+// detachProcess is a noop on windows
+func detachProcess(_ *exec.Cmd) {}
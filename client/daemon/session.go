@@ -0,0 +1,383 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hoophq/hoop/client/nativeconfig"
+	"github.com/hoophq/hoop/client/proxy"
+	"github.com/hoophq/hoop/common/log"
+	pb "github.com/hoophq/hoop/common/proto"
+	pbagent "github.com/hoophq/hoop/common/proto/agent"
+	pbclient "github.com/hoophq/hoop/common/proto/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = time.Minute * 4
+)
+
+var (
+	errSessionStopped = errors.New("session stopped")
+	// errReconnectBackoff reconnects to the gateway after the backoff
+	errReconnectBackoff = errors.New("reconnect with backoff")
+)
+
+// closeErr ends a session without reconnecting to the gateway
+type closeErr struct{ err error }
+
+func (e *closeErr) Error() string { return e.err.Error() }
+
+type proxyServer interface {
+	proxy.Closer
+	Serve(sessionID string) error
+	PacketWriteClient(connectionID string, pkt *pb.Packet) (int, error)
+}
+
+type session struct {
+	connectFn       ConnectFunc
+	nativeConfig    bool
+	hasNativeConfig bool
+	mu              sync.Mutex
+	state           Session
+	client          pb.ClientTransport
+	srv             proxyServer
+	stopped         bool
+	// stopC is closed when the session is stopped
+	stopC chan struct{}
+}
+
+func newSession(req ConnectRequest, duration time.Duration, connectFn ConnectFunc) *session {
+	now := time.Now().UTC()
+	return &session{
+		connectFn:    connectFn,
+		nativeConfig: req.NativeConfig,
+		stopC:        make(chan struct{}),
+		state: Session{
+			Name:      req.Name,
+			Profile:   req.Profile,
+			Port:      req.Port,
+			Status:    StatusConnecting,
+			CreatedAt: now,
+			ExpireAt:  now.Add(duration),
+		},
+	}
+}
+
+// run keeps the session alive until it's stopped, expired or closed by the gateway.
+// A session that was ready reconnects right away, otherwise it backoff exponentially.
+func (s *session) run() {
+	log := log.With("connection", s.state.Name)
+	wait := minReconnectBackoff
+	var err error
+	for {
+		err = s.connect()
+		if err == nil {
+			wait = minReconnectBackoff
+			continue
+		}
+		if err != errReconnectBackoff {
+			break
+		}
+		if !s.waitReconnect(wait) {
+			err = errSessionStopped
+			break
+		}
+		wait = min(wait*2, maxReconnectBackoff)
+	}
+	if err == errSessionStopped {
+		log.Infof("session stopped")
+		return
+	}
+	log.Infof("session closed, reason=%v", err)
+	s.removeNativeConfig()
+	s.mu.Lock()
+	s.state.Status = StatusClosed
+	s.state.Message = err.Error()
+	s.mu.Unlock()
+}
+
+// connect serves the session until the gateway drops the connection. It returns nil to reconnect right away,
+// errReconnectBackoff to reconnect after the backoff and any other error ends the session.
+func (s *session) connect() error {
+	log := log.With("connection", s.state.Name)
+	if s.isStopped() {
+		return errSessionStopped
+	}
+	remaining := time.Until(s.snapshot().ExpireAt)
+	if remaining <= 0 {
+		return fmt.Errorf("session ended, reached connection duration")
+	}
+	client, err := s.connectFn(s.state.Name, s.state.Profile)
+	if err != nil {
+		log.Warnf("failed connecting to gateway, reason=%v", err)
+		s.setReconnecting(err)
+		return errReconnectBackoff
+	}
+	if !s.setClient(client) {
+		_, _ = client.Close()
+		return errSessionStopped
+	}
+	err = s.serve(client, remaining)
+	wasReady := s.snapshot().Status == StatusReady
+	s.closeProxy()
+	_, _ = client.Close()
+	if s.isStopped() {
+		return errSessionStopped
+	}
+	if _, ok := err.(*closeErr); ok {
+		return err
+	}
+	if err == nil {
+		err = fmt.Errorf("gateway closed the connection")
+	}
+	log.Warnf("session disconnected, reconnecting, reason=%v", err)
+	s.setReconnecting(err)
+	if wasReady {
+		return nil
+	}
+	return errReconnectBackoff
+}
+
+// waitReconnect waits the backoff before reconnecting, limited to the remaining duration of the session.
+// It returns false when the session is stopped while waiting.
+func (s *session) waitReconnect(d time.Duration) bool {
+	timer := time.NewTimer(max(min(d, time.Until(s.snapshot().ExpireAt)), 0))
+	defer timer.Stop()
+	select {
+	case <-s.stopC:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (s *session) serve(client pb.ClientTransport, duration time.Duration) error {
+	openSession := func() error {
+		return client.Send(&pb.Packet{
+			Type: pbagent.SessionOpen,
+			Spec: map[string][]byte{pb.SpecJitTimeout: []byte(duration.Round(time.Second).String())},
+		})
+	}
+	if err := openSession(); err != nil {
+		return fmt.Errorf("failed opening session with gateway, reason=%v", err)
+	}
+	for {
+		pkt, err := client.Recv()
+		if err != nil {
+			if st, ok := status.FromError(err); ok {
+				switch st.Code() {
+				case codes.NotFound, codes.FailedPrecondition, codes.PermissionDenied,
+					codes.Unauthenticated, codes.InvalidArgument:
+					return &closeErr{err}
+				}
+			}
+			return err
+		}
+		if pkt == nil {
+			continue
+		}
+		sid := string(pkt.Spec[pb.SpecGatewaySessionID])
+		switch pb.PacketType(pkt.Type) {
+		case pbclient.SessionOpenWaitingApproval:
+			s.mu.Lock()
+			s.state.Status = StatusWaitingApproval
+			s.state.SessionID = sid
+			s.state.Message = fmt.Sprintf("waiting task to be approved at %v", string(pkt.Payload))
+			s.mu.Unlock()
+		case pbclient.SessionOpenApproveOK:
+			if err := openSession(); err != nil {
+				return fmt.Errorf("failed opening session with gateway, reason=%v", err)
+			}
+		case pbclient.SessionOpenOK:
+			if sid == "" {
+				return fmt.Errorf("internal error, session not found")
+			}
+			connType := pb.ConnectionType(pkt.Spec[pb.SpecConnectionType])
+			if err := s.serveProxy(client, sid, connType, pkt.Spec[pb.SpecClientSSHHostKey]); err != nil {
+				return &closeErr{err}
+			}
+			client.StartKeepAlive()
+			s.writeNativeConfig()
+			log.With("sid", sid, "connection", s.state.Name).Infof("session ready")
+		case pbclient.SessionOpenAgentOffline:
+			return pb.ErrAgentOffline
+		case pbclient.SessionOpenTimeout:
+			return &closeErr{fmt.Errorf("session ended, reached connection duration")}
+		case pbclient.PGConnectionWrite,
+			pbclient.MySQLConnectionWrite,
+			pbclient.MSSQLConnectionWrite,
+			pbclient.MongoDBConnectionWrite,
+			pbclient.TCPConnectionWrite,
+			pbclient.SSHConnectionWrite:
+			srv := s.proxy()
+			if srv == nil {
+				continue
+			}
+			connectionID := string(pkt.Spec[pb.SpecClientConnectionID])
+			if _, err := srv.PacketWriteClient(connectionID, pkt); err != nil {
+				return fmt.Errorf("failed writing to client, reason=%v", err)
+			}
+		case pbclient.TCPConnectionClose:
+			if srv := s.proxy(); srv != nil {
+				srv.CloseTCPConnection(string(pkt.Spec[pb.SpecClientConnectionID]))
+			}
+		case pbclient.SessionClose:
+			msg := "session closed by the gateway"
+			if len(pkt.Payload) > 0 {
+				msg = string(pkt.Payload)
+			}
+			return &closeErr{errors.New(msg)}
+		}
+	}
+}
+
+// serveProxy starts the local proxy server, the port is kept between reconnects
+func (s *session) serveProxy(client pb.ClientTransport, sid string, connType pb.ConnectionType, sshHostKey []byte) error {
+	port := s.snapshot().Port
+	var srv proxyServer
+	var host proxy.Host
+	switch connType {
+	case pb.ConnectionTypePostgres:
+		pg := proxy.NewPGServer(port, client)
+		srv, host = pg, pg.Host()
+	case pb.ConnectionTypeMySQL:
+		mysql := proxy.NewMySQLServer(port, client)
+		srv, host = mysql, mysql.Host()
+	case pb.ConnectionTypeMSSQL:
+		mssql := proxy.NewMSSQLServer(port, client)
+		srv, host = mssql, mssql.Host()
+	case pb.ConnectionTypeMongoDB:
+		mongo := proxy.NewMongoDBServer(port, client)
+		srv, host = mongo, mongo.Host()
+	case pb.ConnectionTypeTCP:
+		tcp := proxy.NewTCPServer(port, client, pbagent.TCPConnectionWrite)
+		srv, host = tcp, tcp.Host()
+	case pb.ConnectionTypeSSH:
+		hostKey, err := proxy.ParseSSHHostKey(sshHostKey)
+		if err != nil {
+			log.Warnf("unable to parse SSH host key received from server, using random key, reason=%v", err)
+		}
+		ssh := proxy.NewSSHServer(port, client, hostKey)
+		srv, host = ssh, ssh.Host()
+	default:
+		return fmt.Errorf("connection type %q is not supported by the daemon", connType)
+	}
+	if err := srv.Serve(sid); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.srv = srv
+	s.state.ConnectionType = connType.String()
+	s.state.Host = host.Host
+	s.state.Port = host.Port
+	s.state.SessionID = sid
+	s.state.Status = StatusReady
+	s.state.Message = ""
+	return nil
+}
+
+func (s *session) snapshot() Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+func (s *session) proxy() proxyServer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.srv
+}
+
+func (s *session) setClient(client pb.ClientTransport) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.client = client
+	return !s.stopped
+}
+
+func (s *session) setReconnecting(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Status = StatusReconnecting
+	s.state.Reconnects++
+	s.state.Message = err.Error()
+}
+
+func (s *session) closeProxy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.srv != nil {
+		_ = s.srv.Close()
+		s.srv = nil
+	}
+}
+
+func (s *session) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+func (s *session) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.Status == StatusClosed
+}
+
+// stop closes the session and prevents it from reconnecting
+func (s *session) stop() {
+	s.mu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stopC)
+	}
+	client := s.client
+	s.mu.Unlock()
+	if client != nil {
+		_, _ = client.Close()
+	}
+	s.closeProxy()
+	s.removeNativeConfig()
+}
+
+// writeNativeConfig writes the address of the local proxy into the configuration files of native clients.
+// The port is kept between reconnects, rewriting the entries is a noop in this case.
+func (s *session) writeNativeConfig() {
+	if !s.nativeConfig || s.isStopped() {
+		return
+	}
+	state := s.snapshot()
+	_, err := nativeconfig.Write(nativeconfig.Entry{
+		Profile:        state.Profile,
+		ConnectionName: state.Name,
+		ConnectionType: pb.ConnectionType(state.ConnectionType),
+		Host:           state.Host,
+		Port:           state.Port,
+	})
+	if err != nil {
+		log.With("connection", state.Name).Warnf("failed writing native client configuration, reason=%v", err)
+		return
+	}
+	s.mu.Lock()
+	s.hasNativeConfig = true
+	s.mu.Unlock()
+}
+
+func (s *session) removeNativeConfig() {
+	s.mu.Lock()
+	hasNativeConfig := s.hasNativeConfig
+	s.hasNativeConfig = false
+	s.mu.Unlock()
+	if !hasNativeConfig {
+		return
+	}
+	if err := nativeconfig.Remove(s.state.Profile, s.state.Name); err != nil {
+		log.With("connection", s.state.Name).Warnf("failed removing native client configuration, reason=%v", err)
+	}
+}
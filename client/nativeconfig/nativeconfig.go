@@ -0,0 +1,278 @@
+// Package nativeconfig manages entries in the configuration files of native clients
+// (psql, mysql, ssh, etc) pointing to the local proxy of a connection.
+//
+// Each entry is written in a block delimited by comment markers, allowing
+// to update or remove it without touching the content managed by the user.
+// The entries of connections in profiles other than the default one are
+// prefixed with the name of the profile. The files are replaced atomically,
+// a client never reads a partially written file.
+//
+// Kubeconfig entries are not managed, the Kubernetes clusters are accessed
+// with tcp connections that don't carry the type of the upstream service.
+package nativeconfig
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	clientconfig "github.com/hoophq/hoop/client/config"
+	commonclientconfig "github.com/hoophq/hoop/common/clientconfig"
+	pb "github.com/hoophq/hoop/common/proto"
+)
+
+const (
+	beginMarker = "# BEGIN hoop %s"
+	endMarker   = "# END hoop %s"
+
+	// the local proxies don't validate the credentials of clients
+	proxyUser     = "noop"
+	proxyPassword = "noop"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Entry is the local proxy address of a connection
+type Entry struct {
+	// Profile is the configuration profile of the connection
+	Profile        string
+	ConnectionName string
+	ConnectionType pb.ConnectionType
+	Host           string
+	Port           string
+}
+
+type configFile struct {
+	path    string
+	content string
+	usage   string
+}
+
+// Write creates or updates the native configuration entries of a connection.
+// It returns how to use each entry, connection types without a native
+// configuration are ignored.
+func Write(e Entry) ([]string, error) {
+	files, err := configFiles(e)
+	if err != nil {
+		return nil, err
+	}
+	var usage []string
+	for _, f := range files {
+		if err := upsertBlock(f.path, entryKey(e.Profile, e.ConnectionName), f.content); err != nil {
+			return nil, fmt.Errorf("failed writing %v, reason=%v", f.path, err)
+		}
+		usage = append(usage, f.usage)
+	}
+	return usage, nil
+}
+
+// Remove deletes the native configuration entries of a connection of a profile from all managed files
+func Remove(profile, connectionName string) error {
+	paths, err := managedPaths()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := removeBlock(path, entryKey(profile, connectionName)); err != nil {
+			return fmt.Errorf("failed removing entry from %v, reason=%v", path, err)
+		}
+	}
+	return nil
+}
+
+func configFiles(e Entry) ([]configFile, error) {
+	paths, err := newPaths()
+	if err != nil {
+		return nil, err
+	}
+	name := "hoop-" + invalidNameChars.ReplaceAllString(entryKey(e.Profile, e.ConnectionName), "-")
+	switch e.ConnectionType {
+	case pb.ConnectionTypePostgres:
+		return []configFile{
+			{
+				path:    paths.pgpass,
+				content: fmt.Sprintf("%s:%s:*:%s:%s", e.Host, e.Port, proxyUser, proxyPassword),
+				usage:   fmt.Sprintf("password file at %v", paths.pgpass),
+			},
+			{
+				path: paths.pgService,
+				content: fmt.Sprintf("[%s]\nhost=%s\nport=%s\nuser=%s\nsslmode=disable",
+					name, e.Host, e.Port, proxyUser),
+				usage: fmt.Sprintf(`psql "service=%s"`, name),
+			},
+		}, nil
+	case pb.ConnectionTypeMySQL:
+		// option groups are selected with --defaults-group-suffix
+		suffix := strings.ReplaceAll(strings.TrimPrefix(name, "hoop"), "-", "_")
+		return []configFile{{
+			path: paths.mycnf,
+			content: fmt.Sprintf("[client%s]\nhost=%s\nport=%s\nuser=%s\npassword=%s\nprotocol=TCP",
+				suffix, e.Host, e.Port, proxyUser, proxyPassword),
+			usage: fmt.Sprintf("mysql --defaults-group-suffix=%s", suffix),
+		}}, nil
+	case pb.ConnectionTypeSSH:
+		return []configFile{{
+			path: paths.sshConfig,
+			content: fmt.Sprintf("Host %s\n  HostName %s\n  Port %s\n  User %s\n  HostKeyAlias %s",
+				name, e.Host, e.Port, proxyUser, name),
+			usage: fmt.Sprintf("ssh %s", name),
+		}}, nil
+	case pb.ConnectionTypeMongoDB:
+		return []configFile{{
+			path: paths.mongoURIs,
+			content: fmt.Sprintf("%s=mongodb://%s:%s@%s:%s/?directConnection=true",
+				name, proxyUser, proxyPassword, e.Host, e.Port),
+			usage: fmt.Sprintf("mongodb uri at %v", paths.mongoURIs),
+		}}, nil
+	}
+	return nil, nil
+}
+
+type nativePaths struct {
+	pgpass    string
+	pgService string
+	mycnf     string
+	sshConfig string
+	mongoURIs string
+}
+
+func newPaths() (*nativePaths, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed obtaining home dir, reason=%v", err)
+	}
+	hoopHomeDir, err := commonclientconfig.NewHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	p := &nativePaths{
+		pgpass:    os.Getenv("PGPASSFILE"),
+		pgService: os.Getenv("PGSERVICEFILE"),
+		mycnf:     filepath.Join(home, ".my.cnf"),
+		sshConfig: filepath.Join(home, ".ssh", "config"),
+		mongoURIs: filepath.Join(hoopHomeDir, "mongodb_uris"),
+	}
+	if p.pgpass == "" {
+		p.pgpass = filepath.Join(home, ".pgpass")
+	}
+	if p.pgService == "" {
+		p.pgService = filepath.Join(home, ".pg_service.conf")
+	}
+	return p, nil
+}
+
+func managedPaths() ([]string, error) {
+	p, err := newPaths()
+	if err != nil {
+		return nil, err
+	}
+	return []string{p.pgpass, p.pgService, p.mycnf, p.sshConfig, p.mongoURIs}, nil
+}
+
+// entryKey identifies the entries of a connection in the managed files, the connections
+// of the default profile keep the name of the connection as entries created before profiles
+func entryKey(profile, connectionName string) string {
+	if profile == "" || profile == clientconfig.DefaultProfile {
+		return connectionName
+	}
+	return profile + "/" + connectionName
+}
+
+// upsertBlock replaces the block of a connection or appends it to the end of the file
+func upsertBlock(path, connectionName, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	block := fmt.Sprintf(beginMarker+"\n%s\n"+endMarker+"\n", connectionName, content, connectionName)
+	newData, found := replaceBlock(data, connectionName, block)
+	if !found {
+		if len(newData) > 0 && !bytes.HasSuffix(newData, []byte("\n")) {
+			newData = append(newData, '\n')
+		}
+		newData = append(newData, block...)
+	}
+	return writeFile(path, newData)
+}
+
+func removeBlock(path, connectionName string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	newData, found := replaceBlock(data, connectionName, "")
+	if !found {
+		return nil
+	}
+	return writeFile(path, newData)
+}
+
+// writeFile replaces the content of the file by renaming a temporary file in the same directory.
+// Symbolic links are followed and the permissions of existing files are kept.
+func writeFile(path string, data []byte) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	// pgpass and my.cnf are ignored by clients when they are readable by other users
+	perm := os.FileMode(0600)
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".hoop-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// replaceBlock replaces the lines between the markers of a connection (inclusive)
+func replaceBlock(data []byte, connectionName, block string) ([]byte, bool) {
+	begin, end := fmt.Sprintf(beginMarker, connectionName), fmt.Sprintf(endMarker, connectionName)
+	var out bytes.Buffer
+	var inBlock, found bool
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case !inBlock && strings.TrimSpace(line) == begin:
+			inBlock, found = true, true
+			out.WriteString(block)
+		case inBlock:
+			if strings.TrimSpace(line) == end {
+				inBlock = false
+			}
+		default:
+			out.WriteString(line + "\n")
+		}
+	}
+	if !found {
+		return data, false
+	}
+	return out.Bytes(), true
+}
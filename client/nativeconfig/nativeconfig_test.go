@@ -0,0 +1,131 @@
+package nativeconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/hoophq/hoop/common/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAndRemove(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PGPASSFILE", "")
+	t.Setenv("PGSERVICEFILE", "")
+
+	sshConfig := filepath.Join(home, ".ssh", "config")
+	require.NoError(t, os.MkdirAll(filepath.Dir(sshConfig), 0700))
+	userContent := "Host bastion\n  HostName 10.0.0.1\n"
+	require.NoError(t, os.WriteFile(sshConfig, []byte(userContent), 0600))
+
+	usage, err := Write(Entry{ConnectionName: "ssh-prod", ConnectionType: pb.ConnectionTypeSSH, Host: "127.0.0.1", Port: "2222"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ssh hoop-ssh-prod"}, usage)
+
+	// writing it again must replace the existing block
+	_, err = Write(Entry{ConnectionName: "ssh-prod", ConnectionType: pb.ConnectionTypeSSH, Host: "127.0.0.1", Port: "2223"})
+	require.NoError(t, err)
+	data, err := os.ReadFile(sshConfig)
+	require.NoError(t, err)
+	assert.Equal(t, userContent+
+		"# BEGIN hoop ssh-prod\n"+
+		"Host hoop-ssh-prod\n  HostName 127.0.0.1\n  Port 2223\n  User noop\n  HostKeyAlias hoop-ssh-prod\n"+
+		"# END hoop ssh-prod\n", string(data))
+
+	require.NoError(t, Remove("", "ssh-prod"))
+	data, err = os.ReadFile(sshConfig)
+	require.NoError(t, err)
+	assert.Equal(t, userContent, string(data))
+}
+
+func TestWritePostgres(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PGPASSFILE", "")
+	t.Setenv("PGSERVICEFILE", "")
+
+	usage, err := Write(Entry{ConnectionName: "pg prod", ConnectionType: pb.ConnectionTypePostgres, Host: "127.0.0.1", Port: "5433"})
+	require.NoError(t, err)
+	assert.Contains(t, usage, `psql "service=hoop-pg-prod"`)
+
+	data, err := os.ReadFile(filepath.Join(home, ".pgpass"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "127.0.0.1:5433:*:noop:noop\n")
+	fi, err := os.Stat(filepath.Join(home, ".pgpass"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	data, err = os.ReadFile(filepath.Join(home, ".pg_service.conf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "[hoop-pg-prod]\nhost=127.0.0.1\nport=5433\n")
+
+	require.NoError(t, Remove("default", "pg prod"))
+	data, err = os.ReadFile(filepath.Join(home, ".pgpass"))
+	require.NoError(t, err)
+	assert.Empty(t, string(data))
+}
+
+func TestWriteDistinctProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	for _, profile := range []string{"default", "staging"} {
+		_, err := Write(Entry{Profile: profile, ConnectionName: "ssh-prod", ConnectionType: pb.ConnectionTypeSSH, Host: "127.0.0.1", Port: "2222"})
+		require.NoError(t, err)
+	}
+	sshConfig := filepath.Join(home, ".ssh", "config")
+	data, err := os.ReadFile(sshConfig)
+	require.NoError(t, err)
+	stagingBlock := "# BEGIN hoop staging/ssh-prod\n" +
+		"Host hoop-staging-ssh-prod\n  HostName 127.0.0.1\n  Port 2222\n  User noop\n  HostKeyAlias hoop-staging-ssh-prod\n" +
+		"# END hoop staging/ssh-prod\n"
+	assert.Equal(t, "# BEGIN hoop ssh-prod\n"+
+		"Host hoop-ssh-prod\n  HostName 127.0.0.1\n  Port 2222\n  User noop\n  HostKeyAlias hoop-ssh-prod\n"+
+		"# END hoop ssh-prod\n"+stagingBlock, string(data))
+
+	// removing the entry of the default profile must keep the entry of the other profile
+	require.NoError(t, Remove("default", "ssh-prod"))
+	data, err = os.ReadFile(sshConfig)
+	require.NoError(t, err)
+	assert.Equal(t, stagingBlock, string(data))
+}
+
+func TestWriteSymlinkedConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// the ssh config managed by a dotfiles repository
+	dotfiles := filepath.Join(home, "dotfiles")
+	require.NoError(t, os.MkdirAll(dotfiles, 0700))
+	target := filepath.Join(dotfiles, "ssh_config")
+	require.NoError(t, os.WriteFile(target, []byte("Host bastion\n"), 0644))
+	sshConfig := filepath.Join(home, ".ssh", "config")
+	require.NoError(t, os.MkdirAll(filepath.Dir(sshConfig), 0700))
+	require.NoError(t, os.Symlink(target, sshConfig))
+
+	_, err := Write(Entry{ConnectionName: "ssh-prod", ConnectionType: pb.ConnectionTypeSSH, Host: "127.0.0.1", Port: "2222"})
+	require.NoError(t, err)
+
+	fi, err := os.Lstat(sshConfig)
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSymlink, fi.Mode().Type(), "it must keep the symbolic link")
+	fi, err = os.Stat(target)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), fi.Mode().Perm(), "it must keep the permissions of the file")
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Host bastion\n# BEGIN hoop ssh-prod\n")
+	entries, err := os.ReadDir(dotfiles)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "it must not leave temporary files")
+}
+
+func TestWriteUnsupportedType(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	usage, err := Write(Entry{ConnectionName: "tcp", ConnectionType: pb.ConnectionTypeTCP, Host: "127.0.0.1", Port: "8999"})
+	assert.NoError(t, err)
+	assert.Empty(t, usage)
+}
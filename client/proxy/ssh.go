@@ -3,6 +3,9 @@ package proxy
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"net"
@@ -224,3 +227,24 @@ func (p *SSHServer) getConnection(connectionID string) (io.WriteCloser, error) {
 }
 
 func (p *SSHServer) Host() Host { return getListenAddr(p.listenAddr) }
+
+// ParseSSHHostKey decodes the base64 PEM (PKCS#8) host key sent by the gateway
+func ParseSSHHostKey(encHostKey []byte) (ssh.Signer, error) {
+	hostKeyBytes, err := base64.StdEncoding.DecodeString(string(encHostKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding base64 hosts key: %v", err)
+	}
+	block, _ := pem.Decode(hostKeyBytes)
+	if block == nil {
+		return nil, fmt.Errorf("failed decoding host key PEM block")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing private key to PKCS#8 format: %v", err)
+	}
+	signerKey, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing private key to ssh signer: %v", err)
+	}
+	return signerKey, nil
+}
@@ -47,6 +47,9 @@ type (
 		TLSCA string
 		// This is used to specify a different DNS name when connecting via TLS
 		TLSServerName string
+		// The client certificate and private key (PEM) presented to the server (mTLS)
+		TLSClientCert string
+		TLSClientKey  string
 	}
 )
 
@@ -168,6 +171,13 @@ func loadTLSCredentials(cc ClientConfig) (credentials.TransportCredentials, erro
 		RootCAs:    certPool,
 		ServerName: cc.TLSServerName,
 	}
+	if cc.TLSClientCert != "" || cc.TLSClientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(cc.TLSClientCert), []byte(cc.TLSClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed loading client certificate key pair: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
 	return credentials.NewTLS(config), nil
 }
 
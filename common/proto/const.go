@@ -25,12 +25,14 @@ const (
 	SpecAgentMSPresidioAnalyzerURL   string = "agent.mspresidio_analyzer_url"
 	SpecAgentMSPresidioAnonymizerURL string = "agent.mspresidio_anonymizer_url"
 	SpecAgentGCPRawCredentialsKey    string = "agent.gcp_credentials"
+	SpecAgentEgressDeniedKey         string = "agent.egress_denied"
 	SpecTCPServerConnectKey          string = "tcp.server_connect"
 	SpecReviewDataKey                string = "review.data"
 	SpecGatewayReviewID              string = "review.id"
 	SpecGatewayJitID                 string = "jit.id"
 	SpecJitStatus                    string = "jit.status"
 	SpecJitTimeout                   string = "jit.timeout"
+	SpecBreakGlassJustification      string = "breakglass.justification"
 
 	DefaultKeepAlive time.Duration = 10 * time.Second
 
@@ -41,6 +41,22 @@ type (
 		ClientVerb     string
 		ClientOrigin   string
 		DLPInfoTypes   []string
+		SessionLimits  *SessionLimits
+	}
+
+	// SessionLimits are thresholds enforced by the agent when proxying
+	// a session, a zero value disables the limit
+	SessionLimits struct {
+		// IdleTimeoutSec closes the session when no data is exchanged in this interval
+		IdleTimeoutSec int64
+		// MaxBytesIn is the maximum amount of bytes sent by the client
+		MaxBytesIn int64
+		// MaxBytesOut is the maximum amount of bytes sent by the upstream server
+		MaxBytesOut int64
+		// MaxResponseRows is the maximum amount of rows of a single query response (postgres only)
+		MaxResponseRows int64
+		// MaxResponseBytes is the maximum amount of bytes of a single response
+		MaxResponseBytes int64
 	}
 
 	// TODO: remove it later, kept for compatibility issues
@@ -58,6 +74,12 @@ func (t PacketType) String() string {
 	return string(t)
 }
 
+// IsEmpty returns true if none of the limits are set
+func (l *SessionLimits) IsEmpty() bool {
+	return l == nil || (l.IdleTimeoutSec <= 0 && l.MaxBytesIn <= 0 && l.MaxBytesOut <= 0 &&
+		l.MaxResponseRows <= 0 && l.MaxResponseBytes <= 0)
+}
+
 // NewConnectionWrapper initializes a new connection wrapper
 func NewConnectionWrapper(conn io.WriteCloser, doneC chan struct{}) *ConnectionWrapper {
 	return &ConnectionWrapper{doneC: doneC, conn: conn}
@@ -0,0 +1,143 @@
+package accesstoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/models"
+	pguserauth "github.com/hoophq/hoop/gateway/pgrest/userauth"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+)
+
+const (
+	TokenPrefix = "hpat_"
+	// tokenPrefixSize is the size of the token kept in plain text to identify it
+	tokenPrefixSize = 12
+
+	ScopeSessionsRead      = "sessions:read"
+	ScopeConnectionsManage = "connections:manage"
+	// ScopeExecPrefix allows executing on the connection defined after the prefix, e.g.: exec:pgdemo.
+	// The wildcard exec:* allows executing on any connection.
+	ScopeExecPrefix = "exec:"
+)
+
+var ErrInvalidToken = errors.New("invalid personal access token")
+
+type (
+	// store persists the personal access tokens and fetches the context of their users
+	store interface {
+		GetPersonalAccessTokenByHash(hash string) (*models.PersonalAccessToken, error)
+		UpdatePersonalAccessTokenLastUsed(id string, lastUsedAt time.Time) error
+		FetchUserContext(subject string) (*pguserauth.Context, error)
+	}
+
+	modelsStore struct{}
+)
+
+func (modelsStore) GetPersonalAccessTokenByHash(hash string) (*models.PersonalAccessToken, error) {
+	return models.GetPersonalAccessTokenByHash(hash)
+}
+
+func (modelsStore) UpdatePersonalAccessTokenLastUsed(id string, lastUsedAt time.Time) error {
+	return models.UpdatePersonalAccessTokenLastUsed(id, lastUsedAt)
+}
+
+func (modelsStore) FetchUserContext(subject string) (*pguserauth.Context, error) {
+	return pguserauth.New().FetchUserContext(subject)
+}
+
+// Scopes are the permissions of a personal access token
+type Scopes []string
+
+func (s Scopes) Has(scope string) bool { return slices.Contains(s, scope) }
+
+// HasExec returns true if the token is allowed to execute on any connection
+func (s Scopes) HasExec() bool {
+	for _, scope := range s {
+		if strings.HasPrefix(scope, ScopeExecPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsExec returns true if the token is allowed to execute on the connection
+func (s Scopes) AllowsExec(connectionName string) bool {
+	return connectionName != "" &&
+		(s.Has(ScopeExecPrefix+"*") || s.Has(ScopeExecPrefix+connectionName))
+}
+
+// IsPersonalAccessToken returns true if the token has the format of a personal access token
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, TokenPrefix)
+}
+
+// Generate returns a new personal access token, its hash and its prefix
+func Generate() (token, hash, prefix string, err error) {
+	secretRandomBytes := make([]byte, 32)
+	if _, err := rand.Read(secretRandomBytes); err != nil {
+		return "", "", "", fmt.Errorf("failed generating token: %v", err)
+	}
+	token = TokenPrefix + base64.RawURLEncoding.EncodeToString(secretRandomBytes)
+	return token, Hash(token), token[:tokenPrefixSize], nil
+}
+
+func Hash(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
+
+// ValidateScopes returns an error if any scope is unknown
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		switch {
+		case scope == ScopeSessionsRead, scope == ScopeConnectionsManage:
+		case strings.HasPrefix(scope, ScopeExecPrefix) && len(scope) > len(ScopeExecPrefix):
+		default:
+			return fmt.Errorf("unknown scope %q, accepted values are: %v, %v, %v<connection>",
+				scope, ScopeSessionsRead, ScopeConnectionsManage, ScopeExecPrefix)
+		}
+	}
+	return nil
+}
+
+// Authenticate validates the token returning the context of its user and the scopes of the token.
+// The token must not be expired or revoked and the user must be active.
+func Authenticate(token string) (*pguserauth.Context, Scopes, error) {
+	return authenticate(modelsStore{}, token)
+}
+
+func authenticate(s store, token string) (*pguserauth.Context, Scopes, error) {
+	pat, err := s.GetPersonalAccessTokenByHash(Hash(token))
+	switch err {
+	case models.ErrNotFound:
+		return nil, nil, ErrInvalidToken
+	case nil:
+	default:
+		return nil, nil, fmt.Errorf("failed fetching personal access token: %v", err)
+	}
+	now := time.Now().UTC()
+	if !pat.IsValid(now) {
+		return nil, nil, ErrInvalidToken
+	}
+	ctx, err := s.FetchUserContext(pat.UserSubject)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed fetching user of personal access token: %v", err)
+	}
+	if ctx.IsEmpty() || ctx.OrgID != pat.OrgID || ctx.UserStatus != string(types.UserStatusActive) {
+		return nil, nil, ErrInvalidToken
+	}
+	if err := s.UpdatePersonalAccessTokenLastUsed(pat.ID, now); err != nil {
+		log.With("token", pat.TokenPrefix).Warnf("failed updating last usage of personal access token, reason=%v", err)
+	}
+	return ctx, Scopes(pat.Scopes), nil
+}
@@ -0,0 +1,104 @@
+package accesstoken
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hoophq/hoop/gateway/models"
+	pguserauth "github.com/hoophq/hoop/gateway/pgrest/userauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	token, hash, prefix, err := Generate()
+	assert.NoError(t, err)
+	assert.True(t, IsPersonalAccessToken(token))
+	assert.Equal(t, Hash(token), hash)
+	assert.Equal(t, token[:tokenPrefixSize], prefix)
+	assert.False(t, IsPersonalAccessToken("xagt-token"))
+}
+
+func TestValidateScopes(t *testing.T) {
+	for _, tt := range []struct {
+		msg     string
+		scopes  []string
+		wantErr bool
+	}{
+		{msg: "it should accept the known scopes", scopes: []string{"sessions:read", "connections:manage", "exec:pgdemo", "exec:*"}},
+		{msg: "it should fail without scopes", wantErr: true},
+		{msg: "it should fail with an unknown scope", scopes: []string{"sessions:write"}, wantErr: true},
+		{msg: "it should fail with an exec scope without connection", scopes: []string{"exec:"}, wantErr: true},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			err := ValidateScopes(tt.scopes)
+			assert.Equal(t, tt.wantErr, err != nil, "err=%v", err)
+		})
+	}
+}
+
+func TestScopesAllowsExec(t *testing.T) {
+	assert.True(t, Scopes{"exec:pgdemo"}.AllowsExec("pgdemo"))
+	assert.False(t, Scopes{"exec:pgdemo"}.AllowsExec("bash"))
+	assert.True(t, Scopes{"exec:*"}.AllowsExec("bash"))
+	assert.False(t, Scopes{"sessions:read"}.AllowsExec("bash"))
+	assert.False(t, Scopes{"exec:*"}.AllowsExec(""))
+}
+
+// fakeStore keeps the personal access tokens by their hash
+type fakeStore struct {
+	tokens   map[string]*models.PersonalAccessToken
+	lastUsed []string
+}
+
+func (s *fakeStore) GetPersonalAccessTokenByHash(hash string) (*models.PersonalAccessToken, error) {
+	if pat, ok := s.tokens[hash]; ok {
+		return pat, nil
+	}
+	return nil, models.ErrNotFound
+}
+
+func (s *fakeStore) UpdatePersonalAccessTokenLastUsed(id string, _ time.Time) error {
+	s.lastUsed = append(s.lastUsed, id)
+	return nil
+}
+
+func (s *fakeStore) FetchUserContext(subject string) (*pguserauth.Context, error) {
+	status := "active"
+	if subject == "inactive" {
+		status = "inactive"
+	}
+	return &pguserauth.Context{OrgID: "org", UserSubject: subject, UserStatus: status}, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	now := time.Now().UTC()
+	revokedAt := now.Add(-time.Hour)
+	store := &fakeStore{tokens: map[string]*models.PersonalAccessToken{
+		Hash("hpat_valid"):    {ID: "1", OrgID: "org", UserSubject: "john", Scopes: []string{"exec:pgdemo"}, ExpireAt: now.Add(time.Hour)},
+		Hash("hpat_expired"):  {ID: "2", OrgID: "org", UserSubject: "john", ExpireAt: now.Add(-time.Hour)},
+		Hash("hpat_revoked"):  {ID: "3", OrgID: "org", UserSubject: "john", ExpireAt: now.Add(time.Hour), RevokedAt: &revokedAt},
+		Hash("hpat_inactive"): {ID: "4", OrgID: "org", UserSubject: "inactive", ExpireAt: now.Add(time.Hour)},
+	}}
+	for _, tt := range []struct {
+		msg        string
+		token      string
+		wantScopes Scopes
+		wantErr    error
+	}{
+		{msg: "it should authenticate a valid token", token: "hpat_valid", wantScopes: Scopes{"exec:pgdemo"}},
+		{msg: "it should fail with an unknown token", token: "hpat_unknown", wantErr: ErrInvalidToken},
+		{msg: "it should fail with an expired token", token: "hpat_expired", wantErr: ErrInvalidToken},
+		{msg: "it should fail with a revoked token", token: "hpat_revoked", wantErr: ErrInvalidToken},
+		{msg: "it should fail when the user is inactive", token: "hpat_inactive", wantErr: ErrInvalidToken},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			ctx, scopes, err := authenticate(store, tt.token)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantScopes, scopes)
+			if tt.wantErr == nil {
+				assert.Equal(t, "john", ctx.UserSubject)
+			}
+		})
+	}
+	assert.Equal(t, []string{"1"}, store.lastUsed)
+}
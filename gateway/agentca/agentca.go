@@ -0,0 +1,234 @@
+package agentca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hoophq/hoop/gateway/models"
+)
+
+const (
+	caValidity = 10 * 365 * 24 * time.Hour
+	// the certificates are valid some time before its creation to tolerate clock drifts
+	notBeforeSkew = 5 * time.Minute
+)
+
+var ErrCertificateRequired = errors.New("agent requires a client certificate")
+
+type (
+	// store persists the authorities of the organizations
+	store interface {
+		GetAgentCertificateAuthority(orgID string) (*models.AgentCertificateAuthority, error)
+		CreateAgentCertificateAuthority(ca *models.AgentCertificateAuthority) error
+	}
+
+	modelsStore struct{}
+)
+
+func (modelsStore) GetAgentCertificateAuthority(orgID string) (*models.AgentCertificateAuthority, error) {
+	return models.GetAgentCertificateAuthority(orgID)
+}
+
+func (modelsStore) CreateAgentCertificateAuthority(ca *models.AgentCertificateAuthority) error {
+	return models.CreateAgentCertificateAuthority(ca)
+}
+
+// CA is the certificate authority of an organization, it issues the
+// client certificates of agents authenticating with mutual TLS
+type CA struct {
+	OrgID   string
+	Cert    *x509.Certificate
+	CertPEM string
+	key     *ecdsa.PrivateKey
+}
+
+// AgentCertificate is a client certificate issued to an agent
+type AgentCertificate struct {
+	CertificatePEM string
+	PrivateKeyPEM  string
+	ExpireAt       time.Time
+}
+
+// GetOrCreate returns the authority of the organization, it's created in the first use
+func GetOrCreate(orgID string) (*CA, error) { return getOrCreate(modelsStore{}, orgID) }
+
+func getOrCreate(store store, orgID string) (*CA, error) {
+	obj, err := store.GetAgentCertificateAuthority(orgID)
+	if err == models.ErrNotFound {
+		obj, err = newAuthority(orgID, time.Now().UTC())
+		if err != nil {
+			return nil, err
+		}
+		if err := store.CreateAgentCertificateAuthority(obj); err != nil {
+			return nil, fmt.Errorf("failed storing certificate authority: %v", err)
+		}
+		// another gateway instance may have created it concurrently
+		obj, err = store.GetAgentCertificateAuthority(orgID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching certificate authority: %v", err)
+	}
+	return parseAuthority(obj)
+}
+
+// IssueAgentCertificate creates a client certificate bound to the agent id
+func (ca *CA) IssueAgentCertificate(agentID string, validity time.Duration) (*AgentCertificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating private key: %v", err)
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   agentID,
+			Organization: []string{ca.OrgID},
+		},
+		NotBefore:   now.Add(-notBeforeSkew),
+		NotAfter:    now.Add(validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating certificate: %v", err)
+	}
+	keyPEM, err := encodePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &AgentCertificate{
+		CertificatePEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		PrivateKeyPEM:  keyPEM,
+		ExpireAt:       template.NotAfter,
+	}, nil
+}
+
+// VerifyAgentCertificate validates the client certificate chain presented by an agent.
+// The certificate must be issued by the authority of the organization to the agent id.
+func VerifyAgentCertificate(orgID, agentID string, chain []*x509.Certificate, now time.Time) error {
+	return verifyAgentCertificate(modelsStore{}, orgID, agentID, chain, now)
+}
+
+func verifyAgentCertificate(store store, orgID, agentID string, chain []*x509.Certificate, now time.Time) error {
+	if len(chain) == 0 {
+		return ErrCertificateRequired
+	}
+	obj, err := store.GetAgentCertificateAuthority(orgID)
+	if err == models.ErrNotFound {
+		return fmt.Errorf("organization does not have a certificate authority")
+	}
+	if err != nil {
+		return fmt.Errorf("failed fetching certificate authority: %v", err)
+	}
+	ca, err := parseAuthority(obj)
+	if err != nil {
+		return err
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	leaf := chain[0]
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("failed verifying client certificate: %v", err)
+	}
+	if leaf.Subject.CommonName != agentID {
+		return fmt.Errorf("client certificate was issued to another agent (%v)", leaf.Subject.CommonName)
+	}
+	return nil
+}
+
+func newAuthority(orgID string, now time.Time) (*models.AgentCertificateAuthority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating private key: %v", err)
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   "Hoop Agent CA",
+			Organization: []string{orgID},
+		},
+		NotBefore:             now.Add(-notBeforeSkew),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating certificate authority: %v", err)
+	}
+	keyPEM, err := encodePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AgentCertificateAuthority{
+		OrgID:       orgID,
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		PrivateKey:  keyPEM,
+		CreatedAt:   now,
+	}, nil
+}
+
+func parseAuthority(obj *models.AgentCertificateAuthority) (*CA, error) {
+	certBlock, _ := pem.Decode([]byte(obj.Certificate))
+	if certBlock == nil {
+		return nil, fmt.Errorf("failed decoding certificate authority")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing certificate authority: %v", err)
+	}
+	keyBlock, _ := pem.Decode([]byte(obj.PrivateKey))
+	if keyBlock == nil {
+		return nil, fmt.Errorf("failed decoding certificate authority private key")
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing certificate authority private key: %v", err)
+	}
+	return &CA{OrgID: obj.OrgID, Cert: cert, CertPEM: obj.Certificate, key: key}, nil
+}
+
+func encodePrivateKey(key *ecdsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed encoding private key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})), nil
+}
+
+func newSerialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed generating serial number: %v", err)
+	}
+	return serial, nil
+}
@@ -0,0 +1,147 @@
+package agentca
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStore keeps the authorities in memory by organization
+type fakeStore map[string]*models.AgentCertificateAuthority
+
+func (s fakeStore) GetAgentCertificateAuthority(orgID string) (*models.AgentCertificateAuthority, error) {
+	if ca, ok := s[orgID]; ok {
+		return ca, nil
+	}
+	return nil, models.ErrNotFound
+}
+
+func (s fakeStore) CreateAgentCertificateAuthority(ca *models.AgentCertificateAuthority) error {
+	if _, ok := s[ca.OrgID]; !ok {
+		s[ca.OrgID] = ca
+	}
+	return nil
+}
+
+func parseCertificate(t *testing.T, certPEM string) *x509.Certificate {
+	block, _ := pem.Decode([]byte(certPEM))
+	if !assert.NotNil(t, block) {
+		t.FailNow()
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.NoError(t, err)
+	return cert
+}
+
+func TestGetOrCreate(t *testing.T) {
+	store := fakeStore{}
+	ca, err := getOrCreate(store, "org-a")
+	assert.NoError(t, err)
+	assert.True(t, ca.Cert.IsCA)
+	assert.Len(t, store, 1)
+
+	// it must reuse the stored authority
+	again, err := getOrCreate(store, "org-a")
+	assert.NoError(t, err)
+	assert.Equal(t, ca.CertPEM, again.CertPEM)
+	assert.Len(t, store, 1)
+}
+
+func TestVerifyAgentCertificate(t *testing.T) {
+	store := fakeStore{}
+	caA, err := getOrCreate(store, "org-a")
+	assert.NoError(t, err)
+	caB, err := getOrCreate(store, "org-b")
+	assert.NoError(t, err)
+
+	issue := func(ca *CA, agentID string) *x509.Certificate {
+		cert, err := ca.IssueAgentCertificate(agentID, 24*time.Hour)
+		assert.NoError(t, err)
+		return parseCertificate(t, cert.CertificatePEM)
+	}
+	now := time.Now().UTC()
+	for _, tt := range []struct {
+		msg     string
+		orgID   string
+		agentID string
+		chain   []*x509.Certificate
+		now     time.Time
+		wantErr string
+	}{
+		{
+			msg:     "it should validate a certificate issued to the agent",
+			orgID:   "org-a",
+			agentID: "agent-1",
+			chain:   []*x509.Certificate{issue(caA, "agent-1")},
+			now:     now,
+		},
+		{
+			msg:     "it should fail without a certificate",
+			orgID:   "org-a",
+			agentID: "agent-1",
+			now:     now,
+			wantErr: ErrCertificateRequired.Error(),
+		},
+		{
+			msg:     "it should fail with a certificate issued to another agent",
+			orgID:   "org-a",
+			agentID: "agent-1",
+			chain:   []*x509.Certificate{issue(caA, "agent-2")},
+			now:     now,
+			wantErr: "client certificate was issued to another agent (agent-2)",
+		},
+		{
+			msg:     "it should fail with a certificate issued by another organization",
+			orgID:   "org-a",
+			agentID: "agent-1",
+			chain:   []*x509.Certificate{issue(caB, "agent-1")},
+			now:     now,
+			wantErr: "failed verifying client certificate",
+		},
+		{
+			msg:     "it should fail with an expired certificate",
+			orgID:   "org-a",
+			agentID: "agent-1",
+			chain:   []*x509.Certificate{issue(caA, "agent-1")},
+			now:     now.Add(48 * time.Hour),
+			wantErr: "failed verifying client certificate",
+		},
+		{
+			msg:     "it should fail when the organization does not have an authority",
+			orgID:   "org-c",
+			agentID: "agent-1",
+			chain:   []*x509.Certificate{issue(caA, "agent-1")},
+			now:     now,
+			wantErr: "organization does not have a certificate authority",
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			err := verifyAgentCertificate(store, tt.orgID, tt.agentID, tt.chain, tt.now)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestIssueAgentCertificate(t *testing.T) {
+	store := fakeStore{}
+	ca, err := getOrCreate(store, "org-a")
+	assert.NoError(t, err)
+	cert, err := ca.IssueAgentCertificate("agent-1", 30*24*time.Hour)
+	assert.NoError(t, err)
+
+	leaf := parseCertificate(t, cert.CertificatePEM)
+	assert.Equal(t, "agent-1", leaf.Subject.CommonName)
+	assert.Equal(t, []string{"org-a"}, leaf.Subject.Organization)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, leaf.ExtKeyUsage)
+	assert.False(t, leaf.IsCA)
+	assert.Equal(t, cert.ExpireAt.Unix(), leaf.NotAfter.Unix())
+	assert.Contains(t, cert.PrivateKeyPEM, "EC PRIVATE KEY")
+}
@@ -0,0 +1,160 @@
+package accesstokenapi
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/accesstoken"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/storagev2"
+)
+
+const (
+	defaultExpiresInDays = 30
+	maxExpiresInDays     = 365
+)
+
+// ListPersonalAccessTokens
+//
+//	@Summary		List Personal Access Tokens
+//	@Description	List the personal access tokens of the user, admin users list the tokens of all users of the organization
+//	@Tags			User Management
+//	@Produce		json
+//	@Success		200	{array}		openapi.PersonalAccessToken
+//	@Failure		500	{object}	openapi.HTTPError
+//	@Router			/accesstokens [get]
+func List(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	userSubject := ctx.UserID
+	if ctx.IsAdminUser() {
+		userSubject = ""
+	}
+	items, err := models.ListPersonalAccessTokens(ctx.GetOrgID(), userSubject)
+	if err != nil {
+		log.Errorf("failed listing personal access tokens, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed listing personal access tokens"})
+		return
+	}
+	result := []openapi.PersonalAccessToken{}
+	for _, t := range items {
+		result = append(result, toOpenApi(t))
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CreatePersonalAccessToken
+//
+//	@Summary		Create Personal Access Token
+//	@Description	Create a personal access token for the user. The token is only returned once, only its hash is stored.
+//	@Tags			User Management
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		openapi.PersonalAccessTokenRequest	true	"The request body resource"
+//	@Success		201				{object}	openapi.PersonalAccessToken
+//	@Failure		400,409,422,500	{object}	openapi.HTTPError
+//	@Router			/accesstokens [post]
+func Create(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	var req openapi.PersonalAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if err := accesstoken.ValidateScopes(req.Scopes); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+		return
+	}
+	if req.ExpiresInDays == 0 {
+		req.ExpiresInDays = defaultExpiresInDays
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxExpiresInDays {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": fmt.Sprintf("expires_in_days must be between 1 and %v", maxExpiresInDays)})
+		return
+	}
+	plainToken, tokenHash, tokenPrefix, err := accesstoken.Generate()
+	if err != nil {
+		log.Errorf("failed generating personal access token, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed generating token"})
+		return
+	}
+	now := time.Now().UTC()
+	t := &models.PersonalAccessToken{
+		OrgID:       ctx.GetOrgID(),
+		ID:          uuid.NewString(),
+		UserSubject: ctx.UserID,
+		Name:        req.Name,
+		TokenHash:   tokenHash,
+		TokenPrefix: tokenPrefix,
+		Scopes:      req.Scopes,
+		ExpireAt:    now.AddDate(0, 0, req.ExpiresInDays),
+		CreatedAt:   now,
+		UserEmail:   ctx.UserEmail,
+	}
+	switch err := models.CreatePersonalAccessToken(t); err {
+	case models.ErrAlreadyExists:
+		c.JSON(http.StatusConflict, gin.H{"message": fmt.Sprintf("personal access token %v already exists", req.Name)})
+	case nil:
+		obj := toOpenApi(t)
+		obj.Token = plainToken
+		c.JSON(http.StatusCreated, obj)
+	default:
+		log.Errorf("failed creating personal access token, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed creating personal access token"})
+	}
+}
+
+// RevokePersonalAccessToken
+//
+//	@Summary		Revoke Personal Access Token
+//	@Description	Revoke a personal access token of the user, admin users are able to revoke tokens of any user
+//	@Tags			User Management
+//	@Param			id	path	string	true	"The id of the resource"
+//	@Success		204
+//	@Failure		404,500	{object}	openapi.HTTPError
+//	@Router			/accesstokens/{id} [delete]
+func Revoke(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	t, err := models.GetPersonalAccessToken(ctx.GetOrgID(), c.Param("id"))
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "resource not found"})
+		return
+	case nil:
+	default:
+		log.Errorf("failed fetching personal access token, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed fetching personal access token"})
+		return
+	}
+	if t.UserSubject != ctx.UserID && !ctx.IsAdminUser() {
+		c.JSON(http.StatusNotFound, gin.H{"message": "resource not found"})
+		return
+	}
+	switch err := models.RevokePersonalAccessToken(t.OrgID, t.ID); err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "resource not found or already revoked"})
+	case nil:
+		c.Writer.WriteHeader(http.StatusNoContent)
+	default:
+		log.Errorf("failed revoking personal access token, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed revoking personal access token"})
+	}
+}
+
+func toOpenApi(t *models.PersonalAccessToken) openapi.PersonalAccessToken {
+	return openapi.PersonalAccessToken{
+		ID:          t.ID,
+		Name:        t.Name,
+		User:        t.UserEmail,
+		TokenPrefix: t.TokenPrefix,
+		Scopes:      t.Scopes,
+		ExpireAt:    t.ExpireAt,
+		LastUsedAt:  t.LastUsedAt,
+		RevokedAt:   t.RevokedAt,
+		CreatedAt:   t.CreatedAt,
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/gin-gonic/gin"
@@ -13,6 +14,7 @@ import (
 	"github.com/hoophq/hoop/common/proto"
 	"github.com/hoophq/hoop/gateway/api/openapi"
 	apivalidation "github.com/hoophq/hoop/gateway/api/validation"
+	"github.com/hoophq/hoop/gateway/models"
 	"github.com/hoophq/hoop/gateway/pgrest"
 	pgagents "github.com/hoophq/hoop/gateway/pgrest/agents"
 	"github.com/hoophq/hoop/gateway/storagev2"
@@ -154,6 +156,17 @@ func List(c *gin.Context) {
 		sentry.CaptureException(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed listing agents"})
 	}
+	authItems, err := models.ListAgentAuth(ctx.GetOrgID())
+	if err != nil {
+		log.Errorf("failed listing agents authentication attributes, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed listing agents"})
+		return
+	}
+	agentAuth := map[string]*models.AgentAuth{}
+	for _, a := range authItems {
+		agentAuth[a.ID] = a
+	}
+	now := time.Now().UTC()
 	result := []openapi.AgentListResponse{}
 	for _, a := range items {
 		switch a.Mode {
@@ -166,6 +179,14 @@ func List(c *gin.Context) {
 			// set to default mode if the entity doesn't contain any value
 			a.Mode = proto.AgentModeStandardType
 		}
+		var mtlsRequired bool
+		var previousKeyExpireAt *time.Time
+		if auth, ok := agentAuth[a.ID]; ok {
+			mtlsRequired = auth.MTLSRequired
+			if auth.PreviousKeyExpireAt != nil && auth.PreviousKeyExpireAt.After(now) {
+				previousKeyExpireAt = auth.PreviousKeyExpireAt
+			}
+		}
 		result = append(result, openapi.AgentListResponse{
 			ID:       a.ID,
 			Token:    "", // don't show the hashed token
@@ -181,6 +202,9 @@ func List(c *gin.Context) {
 			GoVersion:     a.GetMeta("goversion"),
 			Compiler:      a.GetMeta("compiler"),
 			Platform:      a.GetMeta("platform"),
+
+			MTLSRequired:        mtlsRequired,
+			PreviousKeyExpireAt: previousKeyExpireAt,
 		})
 	}
 	c.JSON(http.StatusOK, result)
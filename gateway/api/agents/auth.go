@@ -0,0 +1,214 @@
+package apiagents
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoophq/hoop/common/dsnkeys"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/common/proto"
+	"github.com/hoophq/hoop/gateway/agentca"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/pgrest"
+	pgagents "github.com/hoophq/hoop/gateway/pgrest/agents"
+	"github.com/hoophq/hoop/gateway/storagev2"
+)
+
+const (
+	defaultGracePeriodMinutes = 60
+	maxGracePeriodMinutes     = 7 * 24 * 60
+
+	defaultCertificateValidityDays = 90
+	maxCertificateValidityDays     = 365
+)
+
+// RotateAgentKey
+//
+//	@Summary		Rotate Agent Key
+//	@Description	Generates a new key for the agent. The current key remains valid during the grace period, it allows redeploying the agents without downtime.
+//	@Tags			Agents
+//	@Accept			json
+//	@Produce		json
+//	@Param			nameOrID		path		string							true	"The name or ID of the resource"
+//	@Param			request			body		openapi.AgentKeyRotateRequest	false	"The request body resource"
+//	@Success		200				{object}	openapi.AgentCreateResponse
+//	@Failure		400,404,422,500	{object}	openapi.HTTPError
+//	@Router			/agents/{nameOrID}/rotate [post]
+func RotateKey(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	gracePeriod, ok := ParseGracePeriod(c)
+	if !ok {
+		return
+	}
+	ag, ok := fetchAgent(c, ctx, c.Param("nameOrID"))
+	if !ok {
+		return
+	}
+	if ag.Mode == proto.AgentModeMultiConnectionType {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "organization keys must be rotated with the /orgs/keys/rotate route"})
+		return
+	}
+	secretKey, err := RotateAgentSecretKey(ag, gracePeriod, false)
+	if err != nil {
+		log.Errorf("failed rotating agent key, err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed rotating agent key"})
+		return
+	}
+	if ag.Mode == "" {
+		ag.Mode = proto.AgentModeStandardType
+	}
+	dsn, err := dsnkeys.NewString(ctx.GrpcURL, ag.Name, secretKey, ag.Mode)
+	if err != nil {
+		log.Errorf("failed generating dsn, err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed generating dsn"})
+		return
+	}
+	log.With("org", ag.OrgID, "agent", ag.Name).Infof("agent key rotated by %v, grace-period=%v", ctx.UserEmail, gracePeriod)
+	c.JSON(http.StatusOK, openapi.AgentCreateResponse{Token: dsn})
+}
+
+// CreateAgentCertificate
+//
+//	@Summary		Create Agent Certificate
+//	@Description	Issues a client certificate for the agent signed by the certificate authority of the organization. The agent presents it when connecting to the gateway with TLS.
+//	@Tags			Agents
+//	@Accept			json
+//	@Produce		json
+//	@Param			nameOrID		path		string							true	"The name or ID of the resource"
+//	@Param			request			body		openapi.AgentCertificateRequest	false	"The request body resource"
+//	@Success		201				{object}	openapi.AgentCertificateResponse
+//	@Failure		400,404,422,500	{object}	openapi.HTTPError
+//	@Router			/agents/{nameOrID}/certificate [post]
+func CreateCertificate(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	var req openapi.AgentCertificateRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+	if req.ValidityDays == 0 {
+		req.ValidityDays = defaultCertificateValidityDays
+	}
+	if req.ValidityDays < 0 || req.ValidityDays > maxCertificateValidityDays {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": fmt.Sprintf("validity_days must be between 1 and %v", maxCertificateValidityDays)})
+		return
+	}
+	ag, ok := fetchAgent(c, ctx, c.Param("nameOrID"))
+	if !ok {
+		return
+	}
+	ca, err := agentca.GetOrCreate(ctx.GetOrgID())
+	if err != nil {
+		log.Errorf("failed obtaining agent certificate authority, err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed obtaining certificate authority"})
+		return
+	}
+	cert, err := ca.IssueAgentCertificate(ag.ID, time.Duration(req.ValidityDays)*24*time.Hour)
+	if err != nil {
+		log.Errorf("failed issuing agent certificate, err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed issuing certificate"})
+		return
+	}
+	log.With("org", ag.OrgID, "agent", ag.Name).Infof("agent certificate issued by %v, expire-at=%v",
+		ctx.UserEmail, cert.ExpireAt.Format(time.RFC3339))
+	c.JSON(http.StatusCreated, openapi.AgentCertificateResponse{
+		Certificate: cert.CertificatePEM,
+		PrivateKey:  cert.PrivateKeyPEM,
+		CA:          ca.CertPEM,
+		ExpireAt:    cert.ExpireAt,
+	})
+}
+
+// UpdateAgentMTLS
+//
+//	@Summary		Update Agent mTLS
+//	@Description	Require or not the agent to present a client certificate issued by the organization when connecting to the gateway
+//	@Tags			Agents
+//	@Accept			json
+//	@Produce		json
+//	@Param			nameOrID	path	string						true	"The name or ID of the resource"
+//	@Param			request		body	openapi.AgentMTLSRequest	true	"The request body resource"
+//	@Success		204
+//	@Failure		400,404,500	{object}	openapi.HTTPError
+//	@Router			/agents/{nameOrID}/mtls [put]
+func UpdateMTLS(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	var req openapi.AgentMTLSRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	ag, ok := fetchAgent(c, ctx, c.Param("nameOrID"))
+	if !ok {
+		return
+	}
+	switch err := models.UpdateAgentMTLSRequired(ag.OrgID, ag.ID, req.Required); err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "not found"})
+	case nil:
+		log.With("org", ag.OrgID, "agent", ag.Name).Infof("agent mtls updated by %v, required=%v", ctx.UserEmail, req.Required)
+		c.Writer.WriteHeader(http.StatusNoContent)
+	default:
+		log.Errorf("failed updating agent mtls, err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed updating agent"})
+	}
+}
+
+// RotateAgentSecretKey generates a new secret key for the agent. The current key remains
+// valid during the grace period. The plain key is only stored for organization keys.
+func RotateAgentSecretKey(ag *pgrest.Agent, gracePeriod time.Duration, storeKey bool) (string, error) {
+	secretKey, secretKeyHash, err := dsnkeys.GenerateSecureRandomKey()
+	if err != nil {
+		return "", err
+	}
+	var previousKeyExpireAt *time.Time
+	if gracePeriod > 0 {
+		expireAt := time.Now().UTC().Add(gracePeriod)
+		previousKeyExpireAt = &expireAt
+	}
+	plainKey := ""
+	if storeKey {
+		plainKey = secretKey
+	}
+	return secretKey, models.RotateAgentKey(ag.OrgID, ag.ID, secretKeyHash, plainKey, previousKeyExpireAt)
+}
+
+// ParseGracePeriod parses the optional grace period of a key rotation request,
+// it writes the response when the request is invalid
+func ParseGracePeriod(c *gin.Context) (time.Duration, bool) {
+	var req openapi.AgentKeyRotateRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return 0, false
+		}
+	}
+	minutes := defaultGracePeriodMinutes
+	if req.GracePeriodMinutes != nil {
+		minutes = *req.GracePeriodMinutes
+	}
+	if minutes < 0 || minutes > maxGracePeriodMinutes {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": fmt.Sprintf("grace_period_minutes must be between 0 and %v", maxGracePeriodMinutes)})
+		return 0, false
+	}
+	return time.Duration(minutes) * time.Minute, true
+}
+
+func fetchAgent(c *gin.Context, ctx pgrest.OrgContext, nameOrID string) (*pgrest.Agent, bool) {
+	ag, err := pgagents.New().FetchOneByNameOrID(ctx, nameOrID)
+	if err != nil {
+		log.Errorf("failed fetching agent, err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed fetching agent"})
+		return nil, false
+	}
+	if ag == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "not found"})
+		return nil, false
+	}
+	return ag, true
+}
@@ -0,0 +1,57 @@
+package apiroutes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/accesstoken"
+)
+
+// accessTokenAuth authenticates a request with a personal access token,
+// the route must be allowed by the scopes of the token and by the groups of its user
+func (r *Router) accessTokenAuth(c *gin.Context, token string) {
+	ctx, scopes, err := accesstoken.Authenticate(token)
+	if err != nil {
+		log.Infof("failed authenticating with personal access token, reason=%v, url-path=%v", err, c.Request.URL.Path)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "unauthorized"})
+		return
+	}
+	routePath := strings.TrimPrefix(c.FullPath(), r.BasePath())
+	if !isScopeAllowed(scopes, c.Request.Method, routePath, c.Param("name")) {
+		log.Debugf("personal access token scopes not allowed to access route, user=%v, path=%v, scopes=%v",
+			ctx.UserEmail, c.Request.URL.Path, scopes)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "the scopes of the token don't allow accessing this resource"})
+		return
+	}
+	roles := rolesFromContext(c)
+	if !isGroupAllowed(ctx.UserGroups, roles...) &&
+		!isPermissionAllowed(ctx.OrgID, ctx.UserGroups, permissionFromContext(c)) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	r.setUserContext(ctx, c)
+}
+
+// isScopeAllowed maps the scopes of a personal access token to the routes of the api.
+// The connection of exec routes without the name parameter is validated by the gRPC gateway.
+func isScopeAllowed(scopes accesstoken.Scopes, method, routePath, connectionName string) bool {
+	isRead := method == http.MethodGet || method == http.MethodHead
+	switch {
+	case routePath == "/userinfo":
+		return isRead
+	case method == http.MethodPost && (routePath == "/sessions" || routePath == "/sessions/:session_id/exec"):
+		return scopes.HasExec()
+	case routePath == "/sessions" || strings.HasPrefix(routePath, "/sessions/"):
+		return isRead && scopes.Has(accesstoken.ScopeSessionsRead)
+	case routePath == "/connections/:name/exec",
+		routePath == "/plugins/runbooks/connections/:name/exec",
+		routePath == "/plugins/runbooks/connections/:name/preview",
+		routePath == "/plugins/runbooks/connections/:name/templates":
+		return scopes.AllowsExec(connectionName)
+	case routePath == "/connections" || strings.HasPrefix(routePath, "/connections/"):
+		return scopes.Has(accesstoken.ScopeConnectionsManage)
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package apiroutes
+
+import (
+	"testing"
+
+	"github.com/hoophq/hoop/gateway/accesstoken"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsScopeAllowed(t *testing.T) {
+	for _, tt := range []struct {
+		msg        string
+		scopes     accesstoken.Scopes
+		method     string
+		routePath  string
+		connection string
+		want       bool
+	}{
+		{msg: "it should allow the user info with any scope", scopes: accesstoken.Scopes{"exec:pgdemo"}, method: "GET", routePath: "/userinfo", want: true},
+		{msg: "it should allow reading sessions", scopes: accesstoken.Scopes{"sessions:read"}, method: "GET", routePath: "/sessions/:session_id", want: true},
+		{msg: "it should deny reviewing sessions with the read scope", scopes: accesstoken.Scopes{"sessions:read"}, method: "PUT", routePath: "/sessions/:session_id/review", want: false},
+		{msg: "it should deny reading sessions without the scope", scopes: accesstoken.Scopes{"exec:pgdemo"}, method: "GET", routePath: "/sessions", want: false},
+		{msg: "it should allow creating sessions with an exec scope", scopes: accesstoken.Scopes{"exec:pgdemo"}, method: "POST", routePath: "/sessions", want: true},
+		{msg: "it should deny creating sessions without an exec scope", scopes: accesstoken.Scopes{"sessions:read"}, method: "POST", routePath: "/sessions", want: false},
+		{msg: "it should allow running runbooks on the connection of the scope", scopes: accesstoken.Scopes{"exec:pgdemo"}, method: "POST", routePath: "/plugins/runbooks/connections/:name/exec", connection: "pgdemo", want: true},
+		{msg: "it should deny running runbooks on other connections", scopes: accesstoken.Scopes{"exec:pgdemo"}, method: "POST", routePath: "/plugins/runbooks/connections/:name/exec", connection: "bash", want: false},
+		{msg: "it should deny exec on connections with the manage scope", scopes: accesstoken.Scopes{"connections:manage"}, method: "POST", routePath: "/connections/:name/exec", connection: "bash", want: false},
+		{msg: "it should allow managing connections", scopes: accesstoken.Scopes{"connections:manage"}, method: "PUT", routePath: "/connections/:nameOrID", want: true},
+		{msg: "it should deny managing connections without the scope", scopes: accesstoken.Scopes{"exec:*"}, method: "GET", routePath: "/connections", want: false},
+		{msg: "it should deny routes without a scope", scopes: accesstoken.Scopes{"sessions:read", "connections:manage", "exec:*"}, method: "POST", routePath: "/accesstokens", want: false},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			assert.Equal(t, tt.want, isScopeAllowed(tt.scopes, tt.method, tt.routePath, tt.connection))
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/hoophq/hoop/common/apiutils"
 	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/accesstoken"
 	"github.com/hoophq/hoop/gateway/pgrest"
 	pgorgs "github.com/hoophq/hoop/gateway/pgrest/orgs"
 	pguserauth "github.com/hoophq/hoop/gateway/pgrest/userauth"
@@ -51,6 +52,12 @@ func (r *Router) AuthMiddleware(c *gin.Context) {
 		return
 	}
 
+	// personal access token authentication
+	if token, _ := parseToken(c); accesstoken.IsPersonalAccessToken(token) {
+		r.accessTokenAuth(c, token)
+		return
+	}
+
 	// jwt key authentication
 	subject, err := r.validateAccessToken(c)
 	if err != nil {
@@ -106,7 +113,8 @@ func (r *Router) AuthMiddleware(c *gin.Context) {
 
 	// validate routes based on permissions from the user groups of a registered user
 	roles := rolesFromContext(c)
-	if !isGroupAllowed(ctx.UserGroups, roles...) {
+	if !isGroupAllowed(ctx.UserGroups, roles...) &&
+		!isPermissionAllowed(ctx.OrgID, ctx.UserGroups, permissionFromContext(c)) {
 		log.Debugf("not allowed to access route, user=%v, path=%v, roles=%v",
 			ctx.UserEmail, c.Request.URL.Path, roles)
 		c.AbortWithStatus(http.StatusForbidden)
@@ -4,11 +4,16 @@ import (
 	"slices"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hoophq/hoop/common/log"
 	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/rbac"
 	"github.com/hoophq/hoop/gateway/storagev2/types"
 )
 
-const roleContextKey string = "hoop-roles"
+const (
+	roleContextKey       string = "hoop-roles"
+	permissionContextKey string = "hoop-permission"
+)
 
 func rolesFromContext(c *gin.Context) []openapi.RoleType {
 	obj, ok := c.Get(roleContextKey)
@@ -19,6 +24,26 @@ func rolesFromContext(c *gin.Context) []openapi.RoleType {
 	return roles
 }
 
+func permissionFromContext(c *gin.Context) rbac.Permission {
+	perm, _ := c.Get(permissionContextKey)
+	p, _ := perm.(rbac.Permission)
+	return p
+}
+
+// isPermissionAllowed validates if the custom roles of a user grant
+// the permission of the route to any resource
+func isPermissionAllowed(orgID string, userGroups []string, perm rbac.Permission) bool {
+	if perm == "" {
+		return false
+	}
+	policy, err := rbac.Load(orgID, userGroups)
+	if err != nil {
+		log.Errorf("failed loading custom roles, reason=%v", err)
+		return false
+	}
+	return policy.Enabled() && policy.AllowedAny(perm)
+}
+
 // isGroupAllowed validates if the groups of a user is allowed to access a route
 func isGroupAllowed(userGroups []string, roleNames ...openapi.RoleType) (valid bool) {
 	if slices.Contains(userGroups, types.GroupAdmin) {
@@ -58,3 +83,13 @@ func ReadOnlyAccessRole(c *gin.Context) {
 	})
 	c.Next()
 }
+
+// PermissionAccess allows users with a custom role granting the permission
+// to access a route restricted by the built-in roles. The scope of the permission
+// must be validated by the handler of the route.
+func PermissionAccess(perm rbac.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(permissionContextKey, perm)
+		c.Next()
+	}
+}
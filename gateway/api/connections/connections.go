@@ -19,6 +19,7 @@ import (
 	"github.com/hoophq/hoop/gateway/models"
 	"github.com/hoophq/hoop/gateway/pgrest"
 	pgplugins "github.com/hoophq/hoop/gateway/pgrest/plugins"
+	"github.com/hoophq/hoop/gateway/rbac"
 	"github.com/hoophq/hoop/gateway/storagev2"
 	"github.com/hoophq/hoop/gateway/storagev2/types"
 	"github.com/hoophq/hoop/gateway/transport/connectionrequests"
@@ -53,6 +54,9 @@ func Post(c *gin.Context) {
 		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
 		return
 	}
+	if !validateManageAccess(c, ctx, rbac.Resource{Name: req.Name, Tags: req.Tags, AgentID: req.AgentId}) {
+		return
+	}
 	existingConn, err := models.GetConnectionByNameOrID(ctx.OrgID, req.Name)
 	if err != nil {
 		log.Errorf("failed fetching existing connection, err=%v", err)
@@ -91,6 +95,10 @@ func Post(c *gin.Context) {
 		AccessSchema:        req.AccessSchema,
 		GuardRailRules:      req.GuardRailRules,
 		JiraIssueTemplateID: sql.NullString{String: req.JiraIssueTemplateID, Valid: true},
+		SessionLimits:       toModelSessionLimits(req.SessionLimits),
+		ReviewPolicy:        toModelReviewPolicy(req.ReviewPolicy),
+		ReviewRules:         toModelReviewRules(req.ReviewRules),
+		BreakGlass:          toModelBreakGlass(req.BreakGlass),
 	})
 	if err != nil {
 		log.Errorf("failed creating connection, err=%v", err)
@@ -161,6 +169,10 @@ func Put(c *gin.Context) {
 		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
 		return
 	}
+	if !validateManageAccess(c, ctx, rbac.ResourceFromConnection(conn),
+		rbac.Resource{Name: conn.Name, Tags: req.Tags, AgentID: req.AgentId}) {
+		return
+	}
 	setConnectionDefaults(&req)
 
 	// immutable fields
@@ -188,6 +200,10 @@ func Put(c *gin.Context) {
 		AccessSchema:        req.AccessSchema,
 		GuardRailRules:      req.GuardRailRules,
 		JiraIssueTemplateID: sql.NullString{String: req.JiraIssueTemplateID, Valid: true},
+		SessionLimits:       toModelSessionLimits(req.SessionLimits),
+		ReviewPolicy:        toModelReviewPolicy(req.ReviewPolicy),
+		ReviewRules:         toModelReviewRules(req.ReviewRules),
+		BreakGlass:          toModelBreakGlass(req.BreakGlass),
 	})
 	if err != nil {
 		switch err.(type) {
@@ -239,7 +255,17 @@ func Delete(c *gin.Context) {
 		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "missing connection name"})
 		return
 	}
-	err := models.DeleteConnection(ctx.OrgID, connName)
+	conn, err := models.GetConnectionByNameOrID(ctx.OrgID, connName)
+	if err != nil {
+		log.Errorf("failed fetching connection, err=%v", err)
+		sentry.CaptureException(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if conn != nil && !validateManageAccess(c, ctx, rbac.ResourceFromConnection(conn)) {
+		return
+	}
+	err = models.DeleteConnection(ctx.OrgID, connName)
 	switch err {
 	case pgrest.ErrNotFound:
 		c.JSON(http.StatusNotFound, gin.H{"message": "not found"})
@@ -289,7 +315,7 @@ func List(c *gin.Context) {
 	}
 	responseConnList := []openapi.Connection{}
 	for _, conn := range connList {
-		if allowedFn(conn.Name) {
+		if allowedFn(&conn) {
 			var managedBy *string
 			if conn.ManagedBy.Valid {
 				managedBy = &conn.ManagedBy.String
@@ -314,6 +340,10 @@ func List(c *gin.Context) {
 				AccessSchema:        conn.AccessSchema,
 				GuardRailRules:      conn.GuardRailRules,
 				JiraIssueTemplateID: conn.JiraIssueTemplateID.String,
+				SessionLimits:       toOpenAPISessionLimits(conn.SessionLimits),
+				ReviewPolicy:        toOpenAPIReviewPolicy(conn.ReviewPolicy),
+				ReviewRules:         toOpenAPIReviewRules(conn.ReviewRules),
+				BreakGlass:          toOpenAPIBreakGlass(conn.BreakGlass),
 			})
 		}
 
@@ -348,7 +378,7 @@ func Get(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
 		return
 	}
-	if conn == nil || !allowedFn(conn.Name) {
+	if conn == nil || !allowedFn(conn) {
 		c.JSON(http.StatusNotFound, gin.H{"message": "not found"})
 		return
 	}
@@ -377,9 +407,29 @@ func Get(c *gin.Context) {
 		AccessSchema:        conn.AccessSchema,
 		GuardRailRules:      conn.GuardRailRules,
 		JiraIssueTemplateID: conn.JiraIssueTemplateID.String,
+		SessionLimits:       toOpenAPISessionLimits(conn.SessionLimits),
+		ReviewPolicy:        toOpenAPIReviewPolicy(conn.ReviewPolicy),
+		ReviewRules:         toOpenAPIReviewRules(conn.ReviewRules),
+		BreakGlass:          toOpenAPIBreakGlass(conn.BreakGlass),
 	})
 }
 
+// validateManageAccess writes a forbidden response when the custom roles of the user
+// don't grant managing all the resources
+func validateManageAccess(c *gin.Context, ctx pgrest.Context, resources ...rbac.Resource) bool {
+	allowed, err := manageConnectionAllowed(ctx, resources...)
+	if err != nil {
+		log.Errorf("failed validating connection permissions, err=%v", err)
+		sentry.CaptureException(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"message": "user is not allowed to manage this connection"})
+	}
+	return allowed
+}
+
 // FetchByName fetches a connection based in access control rules
 func FetchByName(ctx pgrest.Context, connectionName string) (*models.Connection, error) {
 	// conn, err := pgconnections.New().FetchOneByNameOrID(ctx, connectionName)
@@ -391,7 +441,7 @@ func FetchByName(ctx pgrest.Context, connectionName string) (*models.Connection,
 	if err != nil {
 		return nil, err
 	}
-	if conn == nil || !allowedFn(conn.Name) {
+	if conn == nil || !allowedFn(conn) {
 		return nil, nil
 	}
 	return conn, nil
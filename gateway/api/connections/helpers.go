@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"slices"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/gin-gonic/gin"
@@ -18,33 +19,93 @@ import (
 	"github.com/hoophq/hoop/gateway/models"
 	"github.com/hoophq/hoop/gateway/pgrest"
 	pgplugins "github.com/hoophq/hoop/gateway/pgrest/plugins"
+	"github.com/hoophq/hoop/gateway/rbac"
+	"github.com/hoophq/hoop/gateway/review"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
 	plugintypes "github.com/hoophq/hoop/gateway/transport/plugins/types"
 )
 
+// maxBreakGlassDuration keeps emergency accesses short, longer accesses must be requested with a review
+const maxBreakGlassDuration = 4 * time.Hour
+
 var tagsValRe, _ = regexp.Compile(`^[a-zA-Z0-9_]+(?:[-\.]?[a-zA-Z0-9_]+){0,128}$`)
 
-func accessControlAllowed(ctx pgrest.Context) (func(connName string) bool, error) {
+func accessControlAllowed(ctx pgrest.Context) (func(conn *models.Connection) bool, error) {
+	policy, err := rbac.Load(ctx.GetOrgID(), ctx.GetUserGroups())
+	if err != nil {
+		return nil, err
+	}
+	if policy.Enabled() {
+		return newAccessControlFn(ctx, policy, nil, nil), nil
+	}
+
 	p, err := pgplugins.New().FetchOne(ctx, plugintypes.PluginAccessControlName)
 	if err != nil {
 		return nil, err
 	}
+	var selectors []*models.PolicySelector
+	if p != nil && !ctx.IsAdmin() {
+		selectors, err = models.ListPolicySelectorsByType(ctx.GetOrgID(), models.PolicySelectorAccessControl)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newAccessControlFn(ctx, policy, p, selectors), nil
+}
+
+// newAccessControlFn returns a function that validates if the user is allowed to access a connection
+// based on the custom roles policy or on the access control plugin and its tag selectors
+func newAccessControlFn(ctx pgrest.Context, policy *rbac.Policy, p *types.Plugin, selectors []*models.PolicySelector) func(conn *models.Connection) bool {
+	// users with custom roles are evaluated only by the permissions of their roles
+	if policy.Enabled() {
+		return func(conn *models.Connection) bool {
+			return policy.CanAccess(rbac.ResourceFromConnection(conn))
+		}
+	}
 	if p == nil || ctx.IsAdmin() {
-		return func(_ string) bool { return true }, nil
+		return func(_ *models.Connection) bool { return true }
 	}
 
-	return func(connName string) bool {
+	return func(conn *models.Connection) bool {
+		// groups inherited by the tags of the connection
+		allowedGroups := models.PolicySelectorsConfig(models.MatchPolicySelectors(selectors, conn.Tags))
 		for _, c := range p.Connections {
-			if c.Name == connName {
-				for _, userGroup := range ctx.GetUserGroups() {
-					if allow := slices.Contains(c.Config, userGroup); allow {
-						return allow
-					}
-				}
-				return false
+			if c.Name == conn.Name {
+				allowedGroups = append(allowedGroups, c.Config...)
+				break
+			}
+		}
+		for _, userGroup := range ctx.GetUserGroups() {
+			if slices.Contains(allowedGroups, userGroup) {
+				return true
 			}
 		}
 		return false
-	}, nil
+	}
+}
+
+// manageConnectionAllowed validates if the custom roles of a user grant managing the
+// connection, the access of users without custom roles is validated by the route
+func manageConnectionAllowed(ctx pgrest.Context, resources ...rbac.Resource) (bool, error) {
+	policy, err := rbac.Load(ctx.GetOrgID(), ctx.GetUserGroups())
+	if err != nil {
+		return false, err
+	}
+	for _, res := range resources {
+		if !policy.Allowed(rbac.PermissionManageConnections, res) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isValidTag validates a tag value or a tag in the key=value format
+func isValidTag(re *regexp.Regexp, tag string) bool {
+	key, val, found := strings.Cut(tag, "=")
+	if !found {
+		return re.MatchString(tag)
+	}
+	return re.MatchString(key) && re.MatchString(val)
 }
 
 func setConnectionDefaults(req *openapi.Connection) {
@@ -109,8 +170,30 @@ func validateConnectionRequest(req openapi.Connection) error {
 		errors = append(errors, err.Error())
 	}
 	for _, val := range req.Tags {
-		if !tagsValRe.MatchString(val) {
-			errors = append(errors, "tags: values must contain between 1 and 128 alphanumeric characters, it may include (-), (_) or (.) characters")
+		if !isValidTag(tagsValRe, val) {
+			errors = append(errors, "tags: values must contain between 1 and 128 alphanumeric characters, it may include (-), (_) or (.) characters "+
+				"and be in the key=value format")
+		}
+	}
+	if l := req.SessionLimits; l != nil {
+		if l.IdleTimeoutSec < 0 || l.MaxBytesIn < 0 || l.MaxBytesOut < 0 || l.MaxResponseRows < 0 || l.MaxResponseBytes < 0 {
+			errors = append(errors, "session_limits: values must be greater or equal to zero")
+		}
+		// the rows are only accounted by the agent for the postgres protocol
+		if l.MaxResponseRows > 0 && pb.ToConnectionType(req.Type, req.SubType) != pb.ConnectionTypePostgres {
+			errors = append(errors, "session_limits: max_response_rows is only available for postgres connections")
+		}
+	}
+	if p := req.ReviewPolicy; p != nil {
+		errors = append(errors, validateReviewPolicy(p)...)
+	}
+	errors = append(errors, validateReviewRules(req.ReviewRules)...)
+	if b := req.BreakGlass; b != nil {
+		if b.AccessDurationSec < 60 || b.AccessDurationSec > int(maxBreakGlassDuration.Seconds()) {
+			errors = append(errors, "break_glass: access_duration_sec must be between 60 seconds and 4 hours")
+		}
+		if b.AcknowledgeGroup == "" {
+			errors = append(errors, "break_glass: acknowledge_group is required")
 		}
 	}
 	if len(errors) > 0 {
@@ -119,6 +202,216 @@ func validateConnectionRequest(req openapi.Connection) error {
 	return nil
 }
 
+func toModelSessionLimits(l *openapi.ConnectionSessionLimits) *models.SessionLimits {
+	if l == nil {
+		return nil
+	}
+	return &models.SessionLimits{
+		IdleTimeoutSec:   l.IdleTimeoutSec,
+		MaxBytesIn:       l.MaxBytesIn,
+		MaxBytesOut:      l.MaxBytesOut,
+		MaxResponseRows:  l.MaxResponseRows,
+		MaxResponseBytes: l.MaxResponseBytes,
+	}
+}
+
+func toOpenAPISessionLimits(l *models.SessionLimits) *openapi.ConnectionSessionLimits {
+	if l == nil {
+		return nil
+	}
+	return &openapi.ConnectionSessionLimits{
+		IdleTimeoutSec:   l.IdleTimeoutSec,
+		MaxBytesIn:       l.MaxBytesIn,
+		MaxBytesOut:      l.MaxBytesOut,
+		MaxResponseRows:  l.MaxResponseRows,
+		MaxResponseBytes: l.MaxResponseBytes,
+	}
+}
+
+func validateReviewPolicy(p *openapi.ConnectionReviewPolicy) (errors []string) {
+	if p.ExpireAfterSec < 0 || p.ReminderIntervalSec < 0 || p.EscalateAfterSec < 0 {
+		errors = append(errors, "review_policy: timeouts must be greater or equal to zero")
+	}
+	if p.ReminderIntervalSec > 0 && p.ReminderIntervalSec < 60 {
+		errors = append(errors, "review_policy: reminder_interval_sec must be at least 60 seconds")
+	}
+	if p.EscalateAfterSec > 0 && p.EscalationGroup == "" {
+		errors = append(errors, "review_policy: escalation_group is required when escalate_after_sec is set")
+	}
+	if p.EscalateAfterSec > 0 && p.ExpireAfterSec > 0 && p.EscalateAfterSec >= p.ExpireAfterSec {
+		errors = append(errors, "review_policy: escalate_after_sec must be lower than expire_after_sec")
+	}
+	// a policy without stages uses the groups of the review plugin
+	groups := map[string]bool{}
+	for i, stage := range p.Stages {
+		if len(stage.Groups) == 0 {
+			errors = append(errors, fmt.Sprintf("review_policy: stage %v requires at least one group", i))
+		}
+		for _, g := range stage.Groups {
+			if g.Name == "" {
+				errors = append(errors, fmt.Sprintf("review_policy: stage %v contains a group without name", i))
+				continue
+			}
+			if groups[g.Name] {
+				errors = append(errors, fmt.Sprintf("review_policy: group %q is defined more than once", g.Name))
+			}
+			groups[g.Name] = true
+			if g.MinApprovals < 1 {
+				errors = append(errors, fmt.Sprintf("review_policy: group %q requires at least one approval", g.Name))
+			}
+		}
+	}
+	return
+}
+
+func validateReviewRules(rules []openapi.ConnectionReviewRule) (errors []string) {
+	names := map[string]bool{}
+	for i, r := range rules {
+		if r.Name == "" || len(r.Name) > 128 {
+			errors = append(errors, fmt.Sprintf("review_rules: rule %v must have a name between 1 and 128 characters", i))
+		}
+		if names[r.Name] {
+			errors = append(errors, fmt.Sprintf("review_rules: rule %q is defined more than once", r.Name))
+		}
+		names[r.Name] = true
+		switch types.ReviewRuleAction(r.Action) {
+		case types.ReviewRuleActionRequire, types.ReviewRuleActionSkip:
+		default:
+			errors = append(errors, fmt.Sprintf("review_rules: rule %q has an invalid action %q", r.Name, r.Action))
+		}
+		switch r.QueryType {
+		case "", review.QueryTypeRead, review.QueryTypeWrite:
+		default:
+			errors = append(errors, fmt.Sprintf("review_rules: rule %q has an invalid query type %q", r.Name, r.QueryType))
+		}
+		rule := types.ReviewRule{QueryPattern: r.QueryPattern}
+		if r.TimeWindow != nil {
+			rule.TimeWindow = toReviewRuleTimeWindow(r.TimeWindow)
+		}
+		// the same conditions are evaluated when the rule is matched
+		if err := review.ValidateRule(rule); err != nil {
+			errors = append(errors, fmt.Sprintf("review_rules: rule %q has an %v", r.Name, err))
+		}
+	}
+	return
+}
+
+func toReviewRuleTimeWindow(w *openapi.ConnectionReviewRuleTimeWindow) *types.ReviewRuleTimeWindow {
+	return &types.ReviewRuleTimeWindow{
+		Timezone: w.Timezone,
+		Weekdays: w.Weekdays,
+		Start:    w.Start,
+		End:      w.End,
+		Outside:  w.Outside,
+	}
+}
+
+func toModelReviewRules(rules []openapi.ConnectionReviewRule) []models.ReviewRule {
+	var items []models.ReviewRule
+	for _, r := range rules {
+		rule := models.ReviewRule{
+			Name:         r.Name,
+			Action:       r.Action,
+			QueryType:    r.QueryType,
+			QueryPattern: r.QueryPattern,
+			Users:        r.Users,
+			Groups:       r.Groups,
+		}
+		if w := r.TimeWindow; w != nil {
+			rule.TimeWindow = &models.ReviewRuleTimeWindow{
+				Timezone: w.Timezone,
+				Weekdays: w.Weekdays,
+				Start:    w.Start,
+				End:      w.End,
+				Outside:  w.Outside,
+			}
+		}
+		items = append(items, rule)
+	}
+	return items
+}
+
+func toOpenAPIReviewRules(rules []models.ReviewRule) []openapi.ConnectionReviewRule {
+	var items []openapi.ConnectionReviewRule
+	for _, r := range rules {
+		rule := openapi.ConnectionReviewRule{
+			Name:         r.Name,
+			Action:       r.Action,
+			QueryType:    r.QueryType,
+			QueryPattern: r.QueryPattern,
+			Users:        r.Users,
+			Groups:       r.Groups,
+		}
+		if w := r.TimeWindow; w != nil {
+			rule.TimeWindow = &openapi.ConnectionReviewRuleTimeWindow{
+				Timezone: w.Timezone,
+				Weekdays: w.Weekdays,
+				Start:    w.Start,
+				End:      w.End,
+				Outside:  w.Outside,
+			}
+		}
+		items = append(items, rule)
+	}
+	return items
+}
+
+func toModelBreakGlass(b *openapi.ConnectionBreakGlass) *models.BreakGlass {
+	if b == nil {
+		return nil
+	}
+	return &models.BreakGlass{AccessDurationSec: b.AccessDurationSec, AcknowledgeGroup: b.AcknowledgeGroup}
+}
+
+func toOpenAPIBreakGlass(b *models.BreakGlass) *openapi.ConnectionBreakGlass {
+	if b == nil {
+		return nil
+	}
+	return &openapi.ConnectionBreakGlass{AccessDurationSec: b.AccessDurationSec, AcknowledgeGroup: b.AcknowledgeGroup}
+}
+
+func toModelReviewPolicy(p *openapi.ConnectionReviewPolicy) *models.ReviewPolicy {
+	if p == nil {
+		return nil
+	}
+	policy := &models.ReviewPolicy{
+		DistinctApprovers:   p.DistinctApprovers,
+		ExpireAfterSec:      p.ExpireAfterSec,
+		ReminderIntervalSec: p.ReminderIntervalSec,
+		EscalateAfterSec:    p.EscalateAfterSec,
+		EscalationGroup:     p.EscalationGroup,
+	}
+	for _, s := range p.Stages {
+		stage := models.ReviewPolicyStage{}
+		for _, g := range s.Groups {
+			stage.Groups = append(stage.Groups, models.ReviewPolicyGroup{Name: g.Name, MinApprovals: g.MinApprovals})
+		}
+		policy.Stages = append(policy.Stages, stage)
+	}
+	return policy
+}
+
+func toOpenAPIReviewPolicy(p *models.ReviewPolicy) *openapi.ConnectionReviewPolicy {
+	if p == nil {
+		return nil
+	}
+	policy := &openapi.ConnectionReviewPolicy{
+		DistinctApprovers:   p.DistinctApprovers,
+		ExpireAfterSec:      p.ExpireAfterSec,
+		ReminderIntervalSec: p.ReminderIntervalSec,
+		EscalateAfterSec:    p.EscalateAfterSec,
+		EscalationGroup:     p.EscalationGroup,
+	}
+	for _, s := range p.Stages {
+		stage := openapi.ConnectionReviewPolicyStage{}
+		for _, g := range s.Groups {
+			stage.Groups = append(stage.Groups, openapi.ConnectionReviewPolicyGroup{Name: g.Name, MinApprovals: g.MinApprovals})
+		}
+		policy.Stages = append(policy.Stages, stage)
+	}
+	return policy
+}
+
 var reSanitize, _ = regexp.Compile(`^[a-zA-Z0-9_]+(?:[-\.]?[a-zA-Z0-9_]+){1,128}$`)
 var errInvalidOptionVal = errors.New("option values must contain between 1 and 127 alphanumeric characters, it may include (-), (_) or (.) characters")
 
@@ -139,7 +432,7 @@ func validateListOptions(urlValues url.Values) (o models.ConnectionFilterOption,
 		case "tags":
 			if len(values[0]) > 0 {
 				for _, tagVal := range strings.Split(values[0], ",") {
-					if !reSanitize.MatchString(tagVal) {
+					if !isValidTag(reSanitize, tagVal) {
 						return o, errInvalidOptionVal
 					}
 					o.Tags = append(o.Tags, tagVal)
@@ -14,8 +14,11 @@ import (
 	"github.com/hoophq/hoop/gateway/api/openapi"
 	"github.com/hoophq/hoop/gateway/models"
 	"github.com/hoophq/hoop/gateway/pgrest"
+	pgplugins "github.com/hoophq/hoop/gateway/pgrest/plugins"
+	"github.com/hoophq/hoop/gateway/rbac"
 	"github.com/hoophq/hoop/gateway/storagev2"
 	"github.com/hoophq/hoop/gateway/storagev2/types"
+	plugintypes "github.com/hoophq/hoop/gateway/transport/plugins/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -59,7 +62,10 @@ func TestAccessControlAllowed(t *testing.T) {
 		msg                string
 		allow              bool
 		wantConnectionName string
+		connectionTags     []string
 		groups             []string
+		roles              []*models.Role
+		selectors          []*models.PolicySelector
 		fakeClient         clientFunc
 	}{
 		{
@@ -104,15 +110,66 @@ func TestAccessControlAllowed(t *testing.T) {
 			}),
 			groups: []string{""},
 		},
+		{
+			msg:                "it should allow access to groups inherited by tag selectors",
+			allow:              true,
+			wantConnectionName: "pgprod",
+			connectionTags:     []string{"env=prod", "team=payments"},
+			fakeClient: createTestServer([]*pgrest.PluginConnection{
+				{ConnectionConfig: []string{"sre"}, Connection: pgrest.Connection{Name: "bash"}},
+			}),
+			selectors: []*models.PolicySelector{
+				{Selector: "env=prod,team=payments", Config: []string{"payments"}},
+			},
+			groups: []string{"payments"},
+		},
+		{
+			msg:                "it should deny access when the tags does not match all tags of the selector",
+			allow:              false,
+			wantConnectionName: "pgprod",
+			connectionTags:     []string{"env=prod"},
+			fakeClient: createTestServer([]*pgrest.PluginConnection{
+				{ConnectionConfig: []string{"sre"}, Connection: pgrest.Connection{Name: "bash"}},
+			}),
+			selectors: []*models.PolicySelector{
+				{Selector: "env=prod,team=payments", Config: []string{"payments"}},
+			},
+			groups: []string{"payments"},
+		},
+		{
+			msg:                "it should allow access by custom roles ignoring the plugin groups",
+			allow:              true,
+			wantConnectionName: "bash",
+			fakeClient: createTestServer([]*pgrest.PluginConnection{
+				{ConnectionConfig: []string{"sre"}, Connection: pgrest.Connection{Name: "bash"}},
+			}),
+			roles: []*models.Role{{Groups: []string{"support"}, Permissions: []models.RolePermission{
+				{Actions: []string{"exec"}, Scope: models.RoleScope{Connections: []string{"bash"}}},
+			}}},
+			groups: []string{"support"},
+		},
+		{
+			msg:                "it should deny access by custom roles ignoring the plugin groups",
+			allow:              false,
+			wantConnectionName: "bash",
+			fakeClient: createTestServer([]*pgrest.PluginConnection{
+				{ConnectionConfig: []string{"sre"}, Connection: pgrest.Connection{Name: "bash"}},
+			}),
+			roles: []*models.Role{{Groups: []string{"sre"}, Permissions: []models.RolePermission{
+				{Actions: []string{"view_sessions"}},
+			}}},
+			groups: []string{"sre"},
+		},
 	} {
 		t.Run(tt.msg, func(t *testing.T) {
 			pgrest.WithHttpClient(tt.fakeClient)
 			ctx := storagev2.NewOrganizationContext("").WithUserInfo("", "", "", "", tt.groups)
-			allowed, err := accessControlAllowed(ctx)
+			p, err := pgplugins.New().FetchOne(ctx, plugintypes.PluginAccessControlName)
 			if err != nil {
 				t.Fatalf("did not expect error, got %v", err)
 			}
-			got := allowed(tt.wantConnectionName)
+			allowed := newAccessControlFn(ctx, rbac.NewPolicy(tt.groups, tt.roles), p, tt.selectors)
+			got := allowed(&models.Connection{Name: tt.wantConnectionName, Tags: tt.connectionTags})
 			if got != tt.allow {
 				t.Errorf("expected %v, got %v", tt.allow, got)
 			}
@@ -120,6 +177,49 @@ func TestAccessControlAllowed(t *testing.T) {
 	}
 }
 
+func TestIsValidTag(t *testing.T) {
+	for _, tt := range []struct {
+		tag  string
+		want bool
+	}{
+		{tag: "prod", want: true},
+		{tag: "env=prod", want: true},
+		{tag: "team=payments.api", want: true},
+		{tag: "env=", want: false},
+		{tag: "=prod", want: false},
+		{tag: "env=prod=1", want: false},
+		{tag: "env prod", want: false},
+	} {
+		t.Run(tt.tag, func(t *testing.T) {
+			assert.Equal(t, tt.want, isValidTag(tagsValRe, tt.tag))
+		})
+	}
+}
+
+func TestResolveEffectivePolicy(t *testing.T) {
+	selectors := []*models.PolicySelector{
+		{ID: "1", Name: "prod-reviewers", Type: models.PolicySelectorReview, Selector: "env=prod", Config: []string{"sre", "dba"}},
+		{ID: "2", Name: "payments-reviewers", Type: models.PolicySelectorReview, Selector: "env=prod,team=payments", Config: []string{"payments"}},
+		{ID: "3", Name: "staging-reviewers", Type: models.PolicySelectorReview, Selector: "env=staging", Config: []string{"qa"}},
+		{ID: "4", Name: "prod-access", Type: models.PolicySelectorAccessControl, Selector: "env=prod", Config: []string{"engineering"}},
+	}
+	got := resolveEffectivePolicy(models.PolicySelectorReview, []string{"dba"},
+		[]string{"env=prod", "team=payments"}, selectors)
+	assert.Equal(t, []string{"dba"}, got.Direct)
+	assert.Equal(t, []openapi.EffectivePolicySelector{
+		{ID: "1", Name: "prod-reviewers", Selector: "env=prod", Config: []string{"sre", "dba"}},
+		{ID: "2", Name: "payments-reviewers", Selector: "env=prod,team=payments", Config: []string{"payments"}},
+	}, got.Selectors)
+	assert.Equal(t, []string{"dba", "sre", "payments"}, got.Effective)
+
+	got = resolveEffectivePolicy(models.PolicySelectorGuardRails, nil, []string{"env=prod"}, selectors)
+	assert.Equal(t, openapi.EffectivePolicy{
+		Direct:    []string{},
+		Selectors: []openapi.EffectivePolicySelector{},
+		Effective: []string{},
+	}, got)
+}
+
 func TestConnectionFilterOptions(t *testing.T) {
 	for _, tt := range []struct {
 		msg     string
@@ -881,3 +981,43 @@ func TestCleanMongoOutput(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateConnectionSessionLimits(t *testing.T) {
+	for _, tt := range []struct {
+		msg     string
+		req     openapi.Connection
+		wantErr string
+	}{
+		{
+			msg: "it must accept max response rows for postgres connections",
+			req: openapi.Connection{Name: "pgdemo", Type: "database", SubType: "postgres",
+				SessionLimits: &openapi.ConnectionSessionLimits{MaxResponseRows: 1000}},
+		},
+		{
+			msg: "it must accept other limits for mysql connections",
+			req: openapi.Connection{Name: "mysqldemo", Type: "database", SubType: "mysql",
+				SessionLimits: &openapi.ConnectionSessionLimits{MaxResponseBytes: 1000}},
+		},
+		{
+			msg: "it must reject max response rows for mysql connections",
+			req: openapi.Connection{Name: "mysqldemo", Type: "database", SubType: "mysql",
+				SessionLimits: &openapi.ConnectionSessionLimits{MaxResponseRows: 1000}},
+			wantErr: "session_limits: max_response_rows is only available for postgres connections",
+		},
+		{
+			msg: "it must reject max response rows for mongodb connections",
+			req: openapi.Connection{Name: "mongodemo", Type: "database", SubType: "mongodb",
+				SessionLimits: &openapi.ConnectionSessionLimits{MaxResponseRows: 1000}},
+			wantErr: "session_limits: max_response_rows is only available for postgres connections",
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			err := validateConnectionRequest(tt.req)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package apiconnections
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/models"
+	pgplugins "github.com/hoophq/hoop/gateway/pgrest/plugins"
+	"github.com/hoophq/hoop/gateway/storagev2"
+	plugintypes "github.com/hoophq/hoop/gateway/transport/plugins/types"
+)
+
+// GetPolicies returns the effective policies of a connection
+//
+//	@Summary		Get Connection Policies
+//	@Description	Get the access control, review and guard rail policies of a connection, assigned directly or inherited by policy selectors matching its tags.
+//	@Tags			Connections
+//	@Produce		json
+//	@Param			nameOrID	path		string	true	"Name or UUID of the connection"
+//	@Success		200			{object}	openapi.ConnectionEffectivePolicies
+//	@Failure		404,500		{object}	openapi.HTTPError
+//	@Router			/connections/{nameOrID}/policies [get]
+func GetPolicies(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	conn, err := models.GetConnectionByNameOrID(ctx.OrgID, c.Param("nameOrID"))
+	if err != nil {
+		log.Errorf("failed fetching connection, err=%v", err)
+		sentry.CaptureException(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if conn == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "not found"})
+		return
+	}
+	accessControl, err := pgplugins.New().FetchOne(ctx, plugintypes.PluginAccessControlName)
+	if err != nil {
+		log.Errorf("failed fetching access control plugin, err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	var accessControlGroups []string
+	if accessControl != nil {
+		for _, pc := range accessControl.Connections {
+			if pc.Name == conn.Name {
+				accessControlGroups = pc.Config
+				break
+			}
+		}
+	}
+	selectors, err := models.ListPolicySelectors(ctx.OrgID)
+	if err != nil {
+		log.Errorf("failed listing policy selectors, err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &openapi.ConnectionEffectivePolicies{
+		ConnectionName:       conn.Name,
+		Tags:                 conn.Tags,
+		AccessControlEnabled: accessControl != nil,
+		AccessControl:        resolveEffectivePolicy(models.PolicySelectorAccessControl, accessControlGroups, conn.Tags, selectors),
+		Review:               resolveEffectivePolicy(models.PolicySelectorReview, conn.Reviewers, conn.Tags, selectors),
+		GuardRails:           resolveEffectivePolicy(models.PolicySelectorGuardRails, conn.GuardRailRules, conn.Tags, selectors),
+	})
+}
+
+// resolveEffectivePolicy merges the configuration assigned directly to a connection
+// with the configuration of the selectors of the policy type matching its tags
+func resolveEffectivePolicy(policyType string, direct, tags []string, selectors []*models.PolicySelector) openapi.EffectivePolicy {
+	policy := openapi.EffectivePolicy{
+		Direct:    []string{},
+		Selectors: []openapi.EffectivePolicySelector{},
+		Effective: []string{},
+	}
+	policy.Direct = append(policy.Direct, direct...)
+	var typeSelectors []*models.PolicySelector
+	for _, s := range selectors {
+		if s.Type == policyType {
+			typeSelectors = append(typeSelectors, s)
+		}
+	}
+	matched := models.MatchPolicySelectors(typeSelectors, tags)
+	for _, s := range matched {
+		policy.Selectors = append(policy.Selectors, openapi.EffectivePolicySelector{
+			ID:       s.ID,
+			Name:     s.Name,
+			Selector: s.Selector,
+			Config:   s.Config,
+		})
+	}
+	for _, val := range slices.Concat(direct, models.PolicySelectorsConfig(matched)) {
+		if !slices.Contains(policy.Effective, val) {
+			policy.Effective = append(policy.Effective, val)
+		}
+	}
+	return policy
+}
@@ -2,6 +2,7 @@ package localauthapi
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hoophq/hoop/common/log"
@@ -9,36 +10,172 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	// maxFailedAttempts is the amount of consecutive failed logins before locking the user
+	maxFailedAttempts = 5
+	// the lockout starts with the base duration and doubles on every new failure
+	baseLockoutDuration = time.Minute
+	maxLockoutDuration  = time.Hour
+)
+
+// dummyPasswordHash is compared when the user doesn't exist, it keeps
+// the response time similar to an existing user with a wrong password
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("hoop-dummy-password"), bcrypt.DefaultCost)
+
+type (
+	// store persists the users and their local auth state
+	store interface {
+		GetUserByEmail(email string) (*models.User, error)
+		GetUserBySubjectAndOrg(subject, orgID string) (*models.User, error)
+		GetUserLocalAuth(orgID, userID string) (*models.UserLocalAuth, error)
+		UpdateUserTOTP(orgID, userID string, secret *string, enabled bool, lastCounter int64) error
+		ClaimUserTOTPCounter(userID string, counter int64) (bool, error)
+		IncrementUserLoginFailures(orgID, userID string) (int, error)
+		UpdateUserLockout(userID string, lockedUntil time.Time) error
+		ResetUserLoginFailures(userID string) error
+		CreatePasswordResetToken(t *models.PasswordResetToken) error
+		CreateSelfServicePasswordResetToken(t *models.PasswordResetToken, createdAfter time.Time, maxPending int) (bool, error)
+		ConsumePasswordResetToken(tokenHash, hashedPassword string, now time.Time) (*models.PasswordResetToken, error)
+	}
+
+	modelsStore struct{}
+)
+
+func (modelsStore) GetUserByEmail(email string) (*models.User, error) {
+	return models.GetUserByEmail(email)
+}
+
+func (modelsStore) GetUserBySubjectAndOrg(subject, orgID string) (*models.User, error) {
+	return models.GetUserBySubjectAndOrg(subject, orgID)
+}
+
+func (modelsStore) GetUserLocalAuth(orgID, userID string) (*models.UserLocalAuth, error) {
+	return models.GetUserLocalAuth(orgID, userID)
+}
+
+func (modelsStore) UpdateUserTOTP(orgID, userID string, secret *string, enabled bool, lastCounter int64) error {
+	return models.UpdateUserTOTP(orgID, userID, secret, enabled, lastCounter)
+}
+
+func (modelsStore) ClaimUserTOTPCounter(userID string, counter int64) (bool, error) {
+	return models.ClaimUserTOTPCounter(userID, counter)
+}
+
+func (modelsStore) IncrementUserLoginFailures(orgID, userID string) (int, error) {
+	return models.IncrementUserLoginFailures(orgID, userID)
+}
+
+func (modelsStore) UpdateUserLockout(userID string, lockedUntil time.Time) error {
+	return models.UpdateUserLockout(userID, lockedUntil)
+}
+
+func (modelsStore) ResetUserLoginFailures(userID string) error {
+	return models.ResetUserLoginFailures(userID)
+}
+
+func (modelsStore) CreatePasswordResetToken(t *models.PasswordResetToken) error {
+	return models.CreatePasswordResetToken(t)
+}
+
+func (modelsStore) CreateSelfServicePasswordResetToken(t *models.PasswordResetToken, createdAfter time.Time, maxPending int) (bool, error) {
+	return models.CreateSelfServicePasswordResetToken(t, createdAfter, maxPending)
+}
+
+func (modelsStore) ConsumePasswordResetToken(tokenHash, hashedPassword string, now time.Time) (*models.PasswordResetToken, error) {
+	return models.ConsumePasswordResetToken(tokenHash, hashedPassword, now)
+}
+
 type User struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
 	Name     string `json:"name"`
+	// TOTPCode is required when the user has enabled the second factor
+	TOTPCode string `json:"totp_code"`
 }
 
-func Login(c *gin.Context) {
+func Login(c *gin.Context) { login(c, modelsStore{}) }
+
+func login(c *gin.Context, store store) {
 	var user User
 	if err := c.BindJSON(&user); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
 	}
 
-	dbUser, err := models.GetUserByEmail(user.Email)
+	// all authentication failures return the same response
+	// to avoid leaking which users exist or are locked
+	invalidCredentials := func() {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "invalid credentials"})
+	}
+
+	dbUser, err := store.GetUserByEmail(user.Email)
 	if err != nil {
 		log.Errorf("failed fetching user by email %s, reason=%v", user.Email, err)
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "invalid credentials"})
+		invalidCredentials()
 		return
 	}
-	if dbUser == nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "user not found"})
+	if dbUser == nil || dbUser.HashedPassword == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(user.Password))
+		log.Infof("failed local auth login, user %s not found", user.Email)
+		invalidCredentials()
+		return
+	}
+
+	now := time.Now().UTC()
+	authState, err := store.GetUserLocalAuth(dbUser.OrgID, dbUser.ID)
+	if err != nil {
+		log.Errorf("failed fetching local auth state of user %s, reason=%v", user.Email, err)
+		invalidCredentials()
 		return
 	}
+	if authState.IsLocked(now) {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(user.Password))
+		log.Infof("failed local auth login, user %s is locked until %v", user.Email, authState.LockedUntil.Format(time.RFC3339))
+		invalidCredentials()
+		return
+	}
+
 	err = bcrypt.CompareHashAndPassword([]byte(dbUser.HashedPassword), []byte(user.Password))
 	if err != nil {
-		log.Errorf("failed comparing password for user %s, reason=%v", user.Email, err)
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "invalid credentials"})
+		log.Infof("failed comparing password for user %s, reason=%v", user.Email, err)
+		recordLoginFailure(store, dbUser, now)
+		invalidCredentials()
 		return
 	}
 
+	if authState.TOTPEnabled {
+		// the password is valid at this point, the client must prompt for the second factor
+		if user.TOTPCode == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "totp code required", "mfa_required": true})
+			return
+		}
+		counter, ok := validateTOTP(ptrStr(authState.TOTPSecret), user.TOTPCode, now, authState.TOTPLastCounter)
+		if !ok {
+			log.Infof("failed validating totp code for user %s", user.Email)
+			recordLoginFailure(store, dbUser, now)
+			invalidCredentials()
+			return
+		}
+		claimed, err := store.ClaimUserTOTPCounter(dbUser.ID, counter)
+		if err != nil {
+			log.Errorf("failed updating totp counter of user %s, reason=%v", user.Email, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to generate token"})
+			return
+		}
+		// the code was used by a concurrent login
+		if !claimed {
+			log.Infof("failed validating totp code for user %s, the code was already used", user.Email)
+			recordLoginFailure(store, dbUser, now)
+			invalidCredentials()
+			return
+		}
+	}
+	if authState.FailedAttempts > 0 {
+		if err := store.ResetUserLoginFailures(dbUser.ID); err != nil {
+			log.Warnf("failed resetting failed login attempts of user %s, reason=%v", user.Email, err)
+		}
+	}
+
 	tokenString, err := generateNewAccessToken(dbUser.ID, dbUser.Email)
 	if err != nil {
 		log.Errorf("failed signing token for %s, reason=%v", user.Email, err)
@@ -51,3 +188,44 @@ func Login(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
+
+// recordLoginFailure increments the failed attempts of the user
+// and locks it when the maximum amount of attempts is reached
+func recordLoginFailure(store store, u *models.User, now time.Time) {
+	attempts, err := store.IncrementUserLoginFailures(u.OrgID, u.ID)
+	if err != nil {
+		log.Errorf("failed recording failed login attempt of user %s, reason=%v", u.Email, err)
+		return
+	}
+	lockout := lockoutDuration(attempts)
+	if lockout == 0 {
+		return
+	}
+	log.Infof("locking user %s after %v failed login attempts, duration=%v", u.Email, attempts, lockout)
+	if err := store.UpdateUserLockout(u.ID, now.Add(lockout)); err != nil {
+		log.Errorf("failed locking user %s, reason=%v", u.Email, err)
+	}
+}
+
+// lockoutDuration returns how long the user is locked after the amount of failed attempts,
+// the duration doubles on every failure after reaching the maximum amount of attempts
+func lockoutDuration(attempts int) time.Duration {
+	if attempts < maxFailedAttempts {
+		return 0
+	}
+	lockout := baseLockoutDuration
+	for i := maxFailedAttempts; i < attempts; i++ {
+		lockout *= 2
+		if lockout >= maxLockoutDuration {
+			return maxLockoutDuration
+		}
+	}
+	return lockout
+}
+
+func ptrStr(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
@@ -0,0 +1,231 @@
+package localauthapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeLocalAuthStore keeps the users, their local auth state and reset tokens in memory
+type fakeLocalAuthStore struct {
+	users  map[string]*models.User
+	states map[string]*models.UserLocalAuth
+	tokens []*models.PasswordResetToken
+}
+
+func newFakeStore(users ...*models.User) *fakeLocalAuthStore {
+	s := &fakeLocalAuthStore{users: map[string]*models.User{}, states: map[string]*models.UserLocalAuth{}}
+	for _, u := range users {
+		s.users[u.Email] = u
+	}
+	return s
+}
+
+func (s *fakeLocalAuthStore) GetUserByEmail(email string) (*models.User, error) {
+	return s.users[email], nil
+}
+
+func (s *fakeLocalAuthStore) GetUserBySubjectAndOrg(subject, orgID string) (*models.User, error) {
+	for _, u := range s.users {
+		if u.Subject == subject && u.OrgID == orgID {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeLocalAuthStore) GetUserLocalAuth(orgID, userID string) (*models.UserLocalAuth, error) {
+	if st, ok := s.states[userID]; ok {
+		item := *st
+		return &item, nil
+	}
+	return &models.UserLocalAuth{OrgID: orgID, UserID: userID}, nil
+}
+
+func (s *fakeLocalAuthStore) UpdateUserTOTP(orgID, userID string, secret *string, enabled bool, lastCounter int64) error {
+	st, _ := s.GetUserLocalAuth(orgID, userID)
+	st.TOTPSecret, st.TOTPEnabled, st.TOTPLastCounter = secret, enabled, lastCounter
+	s.states[userID] = st
+	return nil
+}
+
+func (s *fakeLocalAuthStore) ClaimUserTOTPCounter(userID string, counter int64) (bool, error) {
+	st, ok := s.states[userID]
+	if !ok || !st.TOTPEnabled || st.TOTPLastCounter >= counter {
+		return false, nil
+	}
+	st.TOTPLastCounter = counter
+	return true, nil
+}
+
+func (s *fakeLocalAuthStore) IncrementUserLoginFailures(orgID, userID string) (int, error) {
+	st, _ := s.GetUserLocalAuth(orgID, userID)
+	st.FailedAttempts++
+	s.states[userID] = st
+	return st.FailedAttempts, nil
+}
+
+func (s *fakeLocalAuthStore) UpdateUserLockout(userID string, lockedUntil time.Time) error {
+	s.states[userID].LockedUntil = &lockedUntil
+	return nil
+}
+
+func (s *fakeLocalAuthStore) ResetUserLoginFailures(userID string) error {
+	if st, ok := s.states[userID]; ok {
+		st.FailedAttempts, st.LockedUntil = 0, nil
+	}
+	return nil
+}
+
+func (s *fakeLocalAuthStore) CreatePasswordResetToken(t *models.PasswordResetToken) error {
+	s.tokens = append(s.tokens, t)
+	return nil
+}
+
+func (s *fakeLocalAuthStore) CreateSelfServicePasswordResetToken(t *models.PasswordResetToken, createdAfter time.Time, maxPending int) (bool, error) {
+	var pending int
+	for _, item := range s.tokens {
+		if item.UserID != t.UserID {
+			continue
+		}
+		if item.CreatedAt.After(createdAfter) {
+			return false, nil
+		}
+		if item.UsedAt == nil && item.ExpireAt.After(t.CreatedAt) {
+			pending++
+		}
+	}
+	if pending >= maxPending {
+		return false, nil
+	}
+	s.tokens = append(s.tokens, t)
+	return true, nil
+}
+
+func (s *fakeLocalAuthStore) ConsumePasswordResetToken(tokenHash, _ string, now time.Time) (*models.PasswordResetToken, error) {
+	for _, t := range s.tokens {
+		if t.TokenHash == tokenHash && t.UsedAt == nil && t.ExpireAt.After(now) {
+			t.UsedAt = &now
+			return t, nil
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+func newLocalUser(t *testing.T, email, password string) *models.User {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	assert.NoError(t, err)
+	return &models.User{ID: "user-" + email, OrgID: "org-id", Subject: "local|user-" + email, Email: email, HashedPassword: string(hashed)}
+}
+
+func doLogin(store store, req User) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(req)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/localauth/login", bytes.NewReader(body))
+	login(c, store)
+	return w
+}
+
+func TestLoginUniformErrors(t *testing.T) {
+	store := newFakeStore(newLocalUser(t, "john.wick@bad.org", "secret-password"))
+
+	unknownUser := doLogin(store, User{Email: "unknown@bad.org", Password: "secret-password"})
+	wrongPassword := doLogin(store, User{Email: "john.wick@bad.org", Password: "wrong-password"})
+
+	assert.Equal(t, http.StatusUnauthorized, unknownUser.Code)
+	assert.Equal(t, http.StatusUnauthorized, wrongPassword.Code)
+	assert.JSONEq(t, `{"message":"invalid credentials"}`, unknownUser.Body.String())
+	assert.Equal(t, unknownUser.Body.String(), wrongPassword.Body.String())
+}
+
+func TestLoginLockout(t *testing.T) {
+	user := newLocalUser(t, "john.wick@bad.org", "secret-password")
+	store := newFakeStore(user)
+
+	for i := 0; i < maxFailedAttempts; i++ {
+		w := doLogin(store, User{Email: user.Email, Password: "wrong-password"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	state := store.states[user.ID]
+	assert.Equal(t, maxFailedAttempts, state.FailedAttempts)
+	if assert.NotNil(t, state.LockedUntil) {
+		assert.WithinDuration(t, time.Now().UTC().Add(baseLockoutDuration), *state.LockedUntil, 5*time.Second)
+	}
+
+	// a valid password must not authenticate a locked user
+	w := doLogin(store, User{Email: user.Email, Password: "secret-password"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"message":"invalid credentials"}`, w.Body.String())
+	assert.Empty(t, w.Header().Get("Token"))
+
+	// when the lockout expires the failures are cleared on a successful login
+	expired := time.Now().UTC().Add(-time.Second)
+	state.LockedUntil = &expired
+	w = doLogin(store, User{Email: user.Email, Password: "secret-password"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Token"))
+	assert.Equal(t, 0, state.FailedAttempts)
+	assert.Nil(t, state.LockedUntil)
+}
+
+func TestLoginWithTOTP(t *testing.T) {
+	user := newLocalUser(t, "john.wick@bad.org", "secret-password")
+	store := newFakeStore(user)
+	secret := rfcSecret
+	store.states[user.ID] = &models.UserLocalAuth{OrgID: user.OrgID, UserID: user.ID, TOTPSecret: &secret, TOTPEnabled: true}
+	validCode, err := totpCode(secret, time.Now().Unix()/totpPeriod)
+	assert.NoError(t, err)
+
+	t.Run("it should require the code after validating the password", func(t *testing.T) {
+		w := doLogin(store, User{Email: user.Email, Password: "secret-password"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.JSONEq(t, `{"message":"totp code required","mfa_required":true}`, w.Body.String())
+	})
+	t.Run("it should not ask for the code when the password is wrong", func(t *testing.T) {
+		w := doLogin(store, User{Email: user.Email, Password: "wrong-password"})
+		assert.JSONEq(t, `{"message":"invalid credentials"}`, w.Body.String())
+	})
+	t.Run("it should fail with a wrong code", func(t *testing.T) {
+		w := doLogin(store, User{Email: user.Email, Password: "secret-password", TOTPCode: "000000"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.JSONEq(t, `{"message":"invalid credentials"}`, w.Body.String())
+	})
+	t.Run("it should authenticate with a valid code and clear the failures", func(t *testing.T) {
+		assert.Equal(t, 2, store.states[user.ID].FailedAttempts)
+		w := doLogin(store, User{Email: user.Email, Password: "secret-password", TOTPCode: validCode})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Token"))
+		assert.Equal(t, 0, store.states[user.ID].FailedAttempts)
+	})
+	t.Run("it should reject a replayed code", func(t *testing.T) {
+		w := doLogin(store, User{Email: user.Email, Password: "secret-password", TOTPCode: validCode})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestLockoutDuration(t *testing.T) {
+	for _, tt := range []struct {
+		msg      string
+		attempts int
+		want     time.Duration
+	}{
+		{msg: "it should not lock before the maximum attempts", attempts: maxFailedAttempts - 1, want: 0},
+		{msg: "it should lock with the base duration", attempts: maxFailedAttempts, want: baseLockoutDuration},
+		{msg: "it should double the duration on the next failure", attempts: maxFailedAttempts + 1, want: 2 * baseLockoutDuration},
+		{msg: "it should double the duration again", attempts: maxFailedAttempts + 3, want: 8 * baseLockoutDuration},
+		{msg: "it should limit the duration", attempts: maxFailedAttempts + 20, want: maxLockoutDuration},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			assert.Equal(t, tt.want, lockoutDuration(tt.attempts))
+		})
+	}
+}
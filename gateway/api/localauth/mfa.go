@@ -0,0 +1,155 @@
+package localauthapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/storagev2"
+)
+
+// EnrollTOTP
+//
+//	@Summary		Enroll TOTP
+//	@Description	Generates a new TOTP secret for the authenticated local auth user. The second factor is only required after verifying a code of the secret.
+//	@Tags			Authentication
+//	@Produce		json
+//	@Success		201			{object}	openapi.LocalAuthTOTPEnrollment
+//	@Failure		409,422,500	{object}	openapi.HTTPError
+//	@Router			/localauth/totp/enroll [post]
+func EnrollTOTP(c *gin.Context) { enrollTOTP(c, modelsStore{}) }
+
+func enrollTOTP(c *gin.Context, store store) {
+	dbUser, authState, ok := getLocalAuthUser(c, store)
+	if !ok {
+		return
+	}
+	if authState.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"message": "totp is already enabled, disable it before enrolling a new secret"})
+		return
+	}
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		log.Errorf("failed generating totp secret, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed generating totp secret"})
+		return
+	}
+	if err := store.UpdateUserTOTP(dbUser.OrgID, dbUser.ID, &secret, false, 0); err != nil {
+		log.Errorf("failed saving totp secret, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed saving totp secret"})
+		return
+	}
+	c.JSON(http.StatusCreated, openapi.LocalAuthTOTPEnrollment{
+		Secret: secret,
+		URL:    totpURL(secret, dbUser.Email),
+	})
+}
+
+// VerifyTOTP
+//
+//	@Summary		Verify TOTP
+//	@Description	Enables the second factor of the authenticated local auth user by verifying a code of the enrolled secret
+//	@Tags			Authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body	openapi.LocalAuthTOTPRequest	true	"The request body resource"
+//	@Success		204
+//	@Failure		400,409,422,500	{object}	openapi.HTTPError
+//	@Router			/localauth/totp/verify [post]
+func VerifyTOTP(c *gin.Context) { verifyTOTP(c, modelsStore{}) }
+
+func verifyTOTP(c *gin.Context, store store) {
+	var req openapi.LocalAuthTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	dbUser, authState, ok := getLocalAuthUser(c, store)
+	if !ok {
+		return
+	}
+	if authState.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"message": "totp is already enabled"})
+		return
+	}
+	if authState.TOTPSecret == nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "totp is not enrolled"})
+		return
+	}
+	counter, valid := validateTOTP(*authState.TOTPSecret, req.Code, time.Now().UTC(), authState.TOTPLastCounter)
+	if !valid {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "invalid totp code"})
+		return
+	}
+	if err := store.UpdateUserTOTP(dbUser.OrgID, dbUser.ID, authState.TOTPSecret, true, counter); err != nil {
+		log.Errorf("failed enabling totp, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed enabling totp"})
+		return
+	}
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// DisableTOTP
+//
+//	@Summary		Disable TOTP
+//	@Description	Disables the second factor of the authenticated local auth user, a valid code is required
+//	@Tags			Authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body	openapi.LocalAuthTOTPRequest	true	"The request body resource"
+//	@Success		204
+//	@Failure		400,422,500	{object}	openapi.HTTPError
+//	@Router			/localauth/totp/disable [post]
+func DisableTOTP(c *gin.Context) { disableTOTP(c, modelsStore{}) }
+
+func disableTOTP(c *gin.Context, store store) {
+	var req openapi.LocalAuthTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	dbUser, authState, ok := getLocalAuthUser(c, store)
+	if !ok {
+		return
+	}
+	if !authState.TOTPEnabled {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "totp is not enabled"})
+		return
+	}
+	if _, valid := validateTOTP(ptrStr(authState.TOTPSecret), req.Code, time.Now().UTC(), authState.TOTPLastCounter); !valid {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "invalid totp code"})
+		return
+	}
+	if err := store.UpdateUserTOTP(dbUser.OrgID, dbUser.ID, nil, false, 0); err != nil {
+		log.Errorf("failed disabling totp, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed disabling totp"})
+		return
+	}
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// getLocalAuthUser returns the authenticated user and its local auth state,
+// it writes the response when the user doesn't authenticate with a password
+func getLocalAuthUser(c *gin.Context, store store) (*models.User, *models.UserLocalAuth, bool) {
+	ctx := storagev2.ParseContext(c)
+	dbUser, err := store.GetUserBySubjectAndOrg(ctx.UserID, ctx.GetOrgID())
+	if err != nil {
+		log.Errorf("failed fetching user %s, reason=%v", ctx.UserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed fetching user"})
+		return nil, nil, false
+	}
+	if dbUser == nil || dbUser.HashedPassword == "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "the user does not authenticate with local auth"})
+		return nil, nil, false
+	}
+	authState, err := store.GetUserLocalAuth(dbUser.OrgID, dbUser.ID)
+	if err != nil {
+		log.Errorf("failed fetching local auth state of user %s, reason=%v", ctx.UserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed fetching local auth state"})
+		return nil, nil, false
+	}
+	return dbUser, authState, true
+}
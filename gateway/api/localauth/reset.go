@@ -0,0 +1,239 @@
+package localauthapi
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/appconfig"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/storagev2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	selfServiceResetTokenTTL = 30 * time.Minute
+	adminResetTokenTTL       = 24 * time.Hour
+	minPasswordLength        = 8
+
+	// a user is able to request a new self-service token after the interval,
+	// as long as it has less than the maximum amount of valid tokens
+	selfServiceResetInterval = 5 * time.Minute
+	maxPendingResetTokens    = 3
+)
+
+// PasswordResetEvent is a self-service password reset token that must be delivered to the user
+type PasswordResetEvent struct {
+	OrgID     string
+	UserEmail string
+	SlackID   string
+	Token     string
+	ExpireAt  time.Time
+}
+
+// PasswordResetNotifier delivers the self-service password reset tokens to the users, e.g.: slack
+type PasswordResetNotifier interface {
+	OnPasswordResetEvent(ev PasswordResetEvent)
+}
+
+// PasswordResetNotifiers are the notifiers of self-service password reset tokens,
+// the tokens of users without a notifier must be issued by an admin user
+var PasswordResetNotifiers []PasswordResetNotifier
+
+// RequestPasswordReset
+//
+//	@Summary		Request Password Reset
+//	@Description	Issues a password reset token delivered to the user by the configured notifiers. The response is the same whether the user exists or not.
+//	@Description	A new token is issued at most every 5 minutes and only when the user has less than 3 valid tokens.
+//	@Tags			Authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body	openapi.PasswordResetRequest	true	"The request body resource"
+//	@Success		202
+//	@Failure		400	{object}	openapi.HTTPError
+//	@Router			/localauth/password-reset [post]
+func RequestPasswordReset(c *gin.Context) {
+	var req openapi.PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	// the token is issued in background to keep the response the same for any email
+	if isLocalAuthMethod() {
+		go requestPasswordReset(modelsStore{}, req.Email, PasswordResetNotifiers)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the user exists, a password reset token will be delivered"})
+}
+
+func requestPasswordReset(store store, email string, notifiers []PasswordResetNotifier) {
+	dbUser, err := store.GetUserByEmail(email)
+	if err != nil {
+		log.Errorf("failed fetching user by email %s, reason=%v", email, err)
+		return
+	}
+	if dbUser == nil || dbUser.HashedPassword == "" {
+		log.Infof("password reset requested for unknown local auth user %s", email)
+		return
+	}
+	t, plainToken, err := newResetToken(dbUser, dbUser.Subject, selfServiceResetTokenTTL)
+	if err != nil {
+		log.Errorf("failed issuing password reset token for user %s, reason=%v", email, err)
+		return
+	}
+	created, err := store.CreateSelfServicePasswordResetToken(t, t.CreatedAt.Add(-selfServiceResetInterval), maxPendingResetTokens)
+	if err != nil {
+		log.Errorf("failed issuing password reset token for user %s, reason=%v", email, err)
+		return
+	}
+	if !created {
+		log.Infof("password reset of user %s throttled, a token was requested in the last %v or the user has %v valid tokens",
+			email, selfServiceResetInterval, maxPendingResetTokens)
+		return
+	}
+	log.Infof("issued self-service password reset token for user %s, notifiers=%v", email, len(notifiers))
+	ev := PasswordResetEvent{
+		OrgID:     dbUser.OrgID,
+		UserEmail: dbUser.Email,
+		SlackID:   dbUser.SlackID,
+		Token:     plainToken,
+		ExpireAt:  t.ExpireAt,
+	}
+	for _, n := range notifiers {
+		n.OnPasswordResetEvent(ev)
+	}
+}
+
+// ConfirmPasswordReset
+//
+//	@Summary		Confirm Password Reset
+//	@Description	Sets a new password of the user with a password reset token. The token can be used only once and clears the lockout of the user.
+//	@Tags			Authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			request		body	openapi.PasswordResetConfirmRequest	true	"The request body resource"
+//	@Success		204
+//	@Failure		400,422,500	{object}	openapi.HTTPError
+//	@Router			/localauth/password-reset/confirm [post]
+func ConfirmPasswordReset(c *gin.Context) { confirmPasswordReset(c, modelsStore{}) }
+
+func confirmPasswordReset(c *gin.Context, store store) {
+	var req openapi.PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if len(req.Password) < minPasswordLength {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": fmt.Sprintf("the password must have at least %v characters", minPasswordLength)})
+		return
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Errorf("failed hashing password, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to hash password"})
+		return
+	}
+	t, err := store.ConsumePasswordResetToken(hashResetToken(req.Token), string(hashedPassword), time.Now().UTC())
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "invalid or expired password reset token"})
+	case nil:
+		log.Infof("password reset confirmed for user %s, token created by %s", t.UserID, t.CreatedBy)
+		c.Writer.WriteHeader(http.StatusNoContent)
+	default:
+		log.Errorf("failed resetting password, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed resetting password"})
+	}
+}
+
+// CreatePasswordResetToken
+//
+//	@Summary		Create Password Reset Token
+//	@Description	Issues a password reset token of a local auth user. The token is only returned once and must be delivered to the user by the admin.
+//	@Tags			User Management
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		string								true	"The subject identifier of the user"
+//	@Param			request			body		openapi.AdminPasswordResetRequest	false	"The request body resource"
+//	@Success		201				{object}	openapi.PasswordResetToken
+//	@Failure		400,404,422,500	{object}	openapi.HTTPError
+//	@Router			/users/{id}/password-reset [post]
+func CreatePasswordResetToken(c *gin.Context) { createPasswordResetToken(c, modelsStore{}) }
+
+func createPasswordResetToken(c *gin.Context, store store) {
+	ctx := storagev2.ParseContext(c)
+	var req openapi.AdminPasswordResetRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+	if !isLocalAuthMethod() {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "password reset is only available with local auth"})
+		return
+	}
+	dbUser, err := store.GetUserBySubjectAndOrg(c.Param("id"), ctx.GetOrgID())
+	if err != nil {
+		log.Errorf("failed fetching user %s, reason=%v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed fetching user"})
+		return
+	}
+	if dbUser == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("user %s not found", c.Param("id"))})
+		return
+	}
+	if req.DisableMFA {
+		if err := store.UpdateUserTOTP(dbUser.OrgID, dbUser.ID, nil, false, 0); err != nil {
+			log.Errorf("failed disabling totp of user %s, reason=%v", dbUser.Email, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed disabling totp"})
+			return
+		}
+		log.Infof("totp of user %s disabled by %s", dbUser.Email, ctx.UserEmail)
+	}
+	t, plainToken, err := newResetToken(dbUser, ctx.UserID, adminResetTokenTTL)
+	if err == nil {
+		err = store.CreatePasswordResetToken(t)
+	}
+	if err != nil {
+		log.Errorf("failed issuing password reset token for user %s, reason=%v", dbUser.Email, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed issuing password reset token"})
+		return
+	}
+	log.Infof("issued password reset token for user %s by %s", dbUser.Email, ctx.UserEmail)
+	c.JSON(http.StatusCreated, openapi.PasswordResetToken{Token: plainToken, ExpireAt: t.ExpireAt})
+}
+
+// newResetToken returns a new reset token of the user with the hash of the plain token
+func newResetToken(u *models.User, createdBy string, ttl time.Duration) (*models.PasswordResetToken, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed generating random token: %v", err)
+	}
+	plainToken := base64.RawURLEncoding.EncodeToString(secret)
+	now := time.Now().UTC()
+	t := &models.PasswordResetToken{
+		OrgID:     u.OrgID,
+		ID:        uuid.NewString(),
+		UserID:    u.ID,
+		TokenHash: hashResetToken(plainToken),
+		CreatedBy: createdBy,
+		ExpireAt:  now.Add(ttl),
+		CreatedAt: now,
+	}
+	return t, plainToken, nil
+}
+
+func isLocalAuthMethod() bool { return appconfig.Get().AuthMethod() == "local" }
+
+func hashResetToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
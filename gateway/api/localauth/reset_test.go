@@ -0,0 +1,144 @@
+package localauthapi
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeResetNotifier struct{ events []PasswordResetEvent }
+
+func (n *fakeResetNotifier) OnPasswordResetEvent(ev PasswordResetEvent) {
+	n.events = append(n.events, ev)
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	user := newLocalUser(t, "john.wick@bad.org", "secret-password")
+	user.SlackID = "U0123"
+	store := newFakeStore(user)
+	notifier := &fakeResetNotifier{}
+	notifiers := []PasswordResetNotifier{notifier}
+
+	requestPasswordReset(store, "unknown@bad.org", notifiers)
+	assert.Empty(t, store.tokens)
+	assert.Empty(t, notifier.events)
+
+	requestPasswordReset(store, user.Email, notifiers)
+	if assert.Len(t, store.tokens, 1) && assert.Len(t, notifier.events, 1) {
+		ev := notifier.events[0]
+		assert.Equal(t, user.SlackID, ev.SlackID)
+		assert.Equal(t, hashResetToken(ev.Token), store.tokens[0].TokenHash)
+		assert.NotEqual(t, ev.Token, store.tokens[0].TokenHash)
+		assert.Equal(t, user.Subject, store.tokens[0].CreatedBy)
+		assert.WithinDuration(t, time.Now().UTC().Add(selfServiceResetTokenTTL), ev.ExpireAt, 5*time.Second)
+	}
+}
+
+func TestRequestPasswordResetThrottle(t *testing.T) {
+	user := newLocalUser(t, "john.wick@bad.org", "secret-password")
+	now := time.Now().UTC()
+	for _, tt := range []struct {
+		msg         string
+		tokens      []*models.PasswordResetToken
+		wantCreated bool
+	}{
+		{
+			msg: "it must issue a token when the last one was requested before the interval",
+			tokens: []*models.PasswordResetToken{
+				{UserID: user.ID, CreatedAt: now.Add(-selfServiceResetInterval - time.Minute), ExpireAt: now.Add(time.Minute)},
+			},
+			wantCreated: true,
+		},
+		{
+			msg: "it must not issue a token when one was requested in the interval",
+			tokens: []*models.PasswordResetToken{
+				{UserID: user.ID, CreatedAt: now.Add(-time.Minute), ExpireAt: now.Add(time.Minute)},
+			},
+		},
+		{
+			msg: "it must not issue a token when the user has the maximum amount of valid tokens",
+			tokens: []*models.PasswordResetToken{
+				{UserID: user.ID, CreatedAt: now.Add(-time.Hour), ExpireAt: now.Add(time.Hour)},
+				{UserID: user.ID, CreatedAt: now.Add(-time.Hour), ExpireAt: now.Add(time.Hour)},
+				{UserID: user.ID, CreatedAt: now.Add(-time.Hour), ExpireAt: now.Add(time.Hour)},
+			},
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			store := newFakeStore(user)
+			store.tokens = tt.tokens
+			notifier := &fakeResetNotifier{}
+			requestPasswordReset(store, user.Email, []PasswordResetNotifier{notifier})
+			if !tt.wantCreated {
+				assert.Len(t, store.tokens, len(tt.tokens))
+				assert.Empty(t, notifier.events)
+				return
+			}
+			assert.Len(t, store.tokens, len(tt.tokens)+1)
+			assert.Len(t, notifier.events, 1)
+		})
+	}
+}
+
+func TestRequestPasswordResetUniformResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/localauth/password-reset",
+		bytes.NewBufferString(`{"email":"unknown@bad.org"}`))
+	RequestPasswordReset(c)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"message":"if the user exists, a password reset token will be delivered"}`, w.Body.String())
+}
+
+func TestConfirmPasswordReset(t *testing.T) {
+	for _, tt := range []struct {
+		msg      string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			msg:      "it should reset the password with a valid token",
+			body:     `{"token":"valid-token","password":"my-new-password"}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			msg:      "it should fail with an invalid token",
+			body:     `{"token":"invalid-token","password":"my-new-password"}`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"message":"invalid or expired password reset token"}`,
+		},
+		{
+			msg:      "it should fail with a short password",
+			body:     `{"token":"valid-token","password":"short"}`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"message":"the password must have at least 8 characters"}`,
+		},
+		{
+			msg:      "it should fail without the token",
+			body:     `{"password":"my-new-password"}`,
+			wantCode: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			store := newFakeStore()
+			store.tokens = []*models.PasswordResetToken{{UserID: "user-id", CreatedBy: "admin",
+				TokenHash: hashResetToken("valid-token"), ExpireAt: time.Now().UTC().Add(time.Minute)}}
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/localauth/password-reset/confirm",
+				bytes.NewBufferString(tt.body))
+			confirmPasswordReset(c, store)
+			assert.Equal(t, tt.wantCode, c.Writer.Status())
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
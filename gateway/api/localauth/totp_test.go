@@ -0,0 +1,77 @@
+package localauthapi
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the secret of the test vectors of RFC 6238
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	for _, tt := range []struct {
+		msg      string
+		unixTime int64
+		want     string
+	}{
+		{msg: "it should match the rfc vector at 59", unixTime: 59, want: "287082"},
+		{msg: "it should match the rfc vector at 1111111109", unixTime: 1111111109, want: "081804"},
+		{msg: "it should match the rfc vector at 1234567890", unixTime: 1234567890, want: "005924"},
+		{msg: "it should match the rfc vector at 2000000000", unixTime: 2000000000, want: "279037"},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			got, err := totpCode(rfcSecret, tt.unixTime/totpPeriod)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	current := now.Unix() / totpPeriod
+	codeAt := func(counter int64) string {
+		code, _ := totpCode(rfcSecret, counter)
+		return code
+	}
+	for _, tt := range []struct {
+		msg         string
+		code        string
+		lastCounter int64
+		wantCounter int64
+		wantValid   bool
+	}{
+		{msg: "it should accept the code of the current period", code: codeAt(current), wantCounter: current, wantValid: true},
+		{msg: "it should accept the code of the previous period", code: codeAt(current - 1), wantCounter: current - 1, wantValid: true},
+		{msg: "it should accept the code of the next period", code: codeAt(current + 1), wantCounter: current + 1, wantValid: true},
+		{msg: "it should accept codes with surrounding spaces", code: " " + codeAt(current) + " ", wantCounter: current, wantValid: true},
+		{msg: "it should reject codes out of the allowed skew", code: codeAt(current - 2)},
+		{msg: "it should reject a code already used", code: codeAt(current), lastCounter: current},
+		{msg: "it should reject codes older than the last used code", code: codeAt(current - 1), lastCounter: current},
+		{msg: "it should reject a wrong code", code: "000000"},
+		{msg: "it should reject codes with a wrong size", code: "12345"},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			counter, valid := validateTOTP(rfcSecret, tt.code, now, tt.lastCounter)
+			assert.Equal(t, tt.wantValid, valid)
+			assert.Equal(t, tt.wantCounter, counter)
+		})
+	}
+}
+
+func TestTOTPURL(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	got := totpURL(secret, "john.wick@bad.org")
+	assert.True(t, strings.HasPrefix(got, "otpauth://totp/Hoop:john.wick@bad.org?"), got)
+	assert.Contains(t, got, "secret="+secret)
+	assert.Contains(t, got, "issuer=Hoop")
+	assert.Contains(t, got, "digits=6")
+	assert.Contains(t, got, "period=30")
+}
@@ -66,6 +66,17 @@ func (h *handler) Login(c *gin.Context) {
 		return
 	}
 
+	if h.idpProv.SAML != nil {
+		url, err := h.idpProv.SAML.AuthnRequestURL(stateUID)
+		if err != nil {
+			log.Errorf("failed generating saml authentication request, reason=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed generating saml authentication request"})
+			return
+		}
+		c.JSON(http.StatusOK, openapi.Login{URL: url})
+		return
+	}
+
 	var params = []oauth2.AuthCodeOption{}
 	if h.idpProv.Audience != "" {
 		params = append(params, oauth2.SetAuthURLParam("audience", h.idpProv.Audience))
@@ -99,17 +110,8 @@ func (h *handler) LoginCallback(c *gin.Context) {
 	stateUUID := c.Query("state")
 	code := c.Query("code")
 
-	log.With("state", stateUUID).Infof("starting callback")
-	login, err := pglogin.New().FetchOne(stateUUID)
-	if err != nil || login == nil {
-		log.With("state", stateUUID).
-			Warnf("login record is empty or returned with error, err=%v, isempty=%v", err, login == nil)
-		statusCode := http.StatusBadRequest
-		if err != nil {
-			sentry.CaptureException(err)
-			statusCode = http.StatusInternalServerError
-		}
-		c.JSON(statusCode, gin.H{"message": "failed to retrieve login state internally"})
+	login := fetchLoginState(c, stateUUID)
+	if login == nil {
 		return
 	}
 	// TODO: we should redirect to an ui that will render errors properly
@@ -117,7 +119,6 @@ func (h *handler) LoginCallback(c *gin.Context) {
 
 	// update the login state when this method returns
 	defer updateLoginState(login)
-	log.With("state", stateUUID).Debugf("login record found")
 	token, uinfo, err := h.verifyIDToken(code)
 	if err != nil {
 		login.Outcome = fmt.Sprintf("failed verifying id token, reason=%v", err)
@@ -133,6 +134,76 @@ func (h *handler) LoginCallback(c *gin.Context) {
 		return
 	}
 	uinfo.Subject = subject
+	c.Redirect(http.StatusTemporaryRedirect, h.signin(c, login, uinfo, token.AccessToken))
+}
+
+// SAMLCallback
+//
+//	@Summary		SAML Assertion Consumer Service
+//	@Description	Validates the signed SAML response of the identity provider and redirects to the url of the login with an access token issued by the gateway
+//	@Tags			Authentication
+//	@Accept			x-www-form-urlencoded
+//	@Param			SAMLResponse	formData	string	true	"The base64 encoded SAML response"
+//	@Param			RelayState		formData	string	true	"The state of the login"
+//	@Success		302
+//	@Failure		400,404,500		{object}	openapi.HTTPError
+//	@Router			/saml/acs [post]
+func (h *handler) SAMLCallback(c *gin.Context) {
+	if h.idpProv.SAML == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "saml authentication is not enabled"})
+		return
+	}
+	login := fetchLoginState(c, c.PostForm("RelayState"))
+	if login == nil {
+		return
+	}
+	redirectErrorURL := login.Redirect + "?error=unexpected_error"
+	defer updateLoginState(login)
+	uinfo, err := h.idpProv.SAML.ParseResponse(c.PostForm("SAMLResponse"), login.ID)
+	if err != nil {
+		login.Outcome = err.Error()
+		log.Warn(login.Outcome)
+		c.Redirect(http.StatusFound, redirectErrorURL)
+		return
+	}
+	accessToken, err := h.idpProv.NewAccessTokenHS256Alg(uinfo.Subject, uinfo.Email)
+	if err != nil {
+		login.Outcome = fmt.Sprintf("failed issuing access token, reason=%v", err)
+		log.Error(login.Outcome)
+		c.Redirect(http.StatusFound, redirectErrorURL)
+		return
+	}
+	// the response is posted by the browser, the redirect must change the method to GET
+	c.Redirect(http.StatusFound, h.signin(c, login, *uinfo, accessToken))
+}
+
+// SAMLMetadata
+//
+//	@Summary		SAML Service Provider Metadata
+//	@Description	The metadata of the gateway as a SAML service provider, it's used to configure the application in the identity provider
+//	@Tags			Authentication
+//	@Produce		xml
+//	@Success		200	{string}	string
+//	@Failure		404	{object}	openapi.HTTPError
+//	@Router			/saml/metadata [get]
+func (h *handler) SAMLMetadata(c *gin.Context) {
+	if h.idpProv.SAML == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "saml authentication is not enabled"})
+		return
+	}
+	metadata, err := h.idpProv.SAML.Metadata()
+	if err != nil {
+		log.Errorf("failed generating saml metadata, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed generating saml metadata"})
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// signin syncs the user authenticated by the identity provider and
+// returns the url to redirect with the access token or with the error
+func (h *handler) signin(c *gin.Context, login *pgrest.Login, uinfo idp.ProviderUserInfo, accessToken string) string {
+	redirectErrorURL := login.Redirect + "?error=unexpected_error"
 	// get the user by its email to get the actual subject of that user. This is necessary
 	// due to the user subject when it's created inside hoop is changed after that user
 	// logs in with the IDP. The email should always come from the IDP as a design of how
@@ -142,13 +213,13 @@ func (h *handler) LoginCallback(c *gin.Context) {
 		login.Outcome = fmt.Sprintf("failed fetching user by email=%s, reason=%v", uinfo.Email, err)
 		log.Error(login.Outcome)
 		sentry.CaptureException(err)
-		c.Redirect(http.StatusTemporaryRedirect, redirectErrorURL)
-		return
+		return redirectErrorURL
 	}
 
 	// if the user doesn't exist in the database, we should use the subject from the IDP
 	// to allow the user to login. This user will be a new user and will be created at
 	// the end of this method.
+	var subject string
 	if dbUser == nil {
 		subject = uinfo.Subject
 	} else {
@@ -160,10 +231,9 @@ func (h *handler) LoginCallback(c *gin.Context) {
 		login.Outcome = fmt.Sprintf("failed fetching user subject=%s, email=%s, reason=%v", uinfo.Subject, uinfo.Email, err)
 		log.Error(login.Outcome)
 		sentry.CaptureException(err)
-		c.Redirect(http.StatusTemporaryRedirect, redirectErrorURL)
-		return
+		return redirectErrorURL
 	}
-	redirectSuccessURL := login.Redirect + "?token=" + token.AccessToken
+	redirectSuccessURL := login.Redirect + "?token=" + accessToken
 
 	userAgent := apiutils.NormalizeUserAgent(c.Request.Header.Values)
 	log.With("sub", uinfo.Subject, "email", uinfo.Email, "profile", uinfo.Profile,
@@ -175,8 +245,7 @@ func (h *handler) LoginCallback(c *gin.Context) {
 		isNewUser := false
 		if ctx.UserStatus == string(types.UserStatusInactive) {
 			log.With("multitenant", true).Warnf("user %s is inactive. They need to be edited to active before trying to signin", uinfo.Email)
-			c.Redirect(http.StatusTemporaryRedirect, redirectErrorURL)
-			return
+			return redirectErrorURL
 		}
 		if ctx.UserStatus != string(types.UserStatusActive) {
 			isNewUser, err = registerMultiTenantUser(uinfo, login.SlackID)
@@ -185,22 +254,19 @@ func (h *handler) LoginCallback(c *gin.Context) {
 					uinfo.Subject, uinfo.Email, err)
 				log.With("multitenant", true).Error(login.Outcome)
 				sentry.CaptureException(err)
-				c.Redirect(http.StatusTemporaryRedirect, redirectErrorURL)
-				return
+				return redirectErrorURL
 			}
 		}
 
 		h.analyticsTrack(isNewUser, userAgent, ctx)
 		login.Outcome = "success"
-		c.Redirect(http.StatusTemporaryRedirect, redirectSuccessURL)
-		return
+		return redirectSuccessURL
 	}
 
 	if !ctx.IsEmpty() && ctx.UserStatus == string(types.UserStatusInactive) {
 		login.Outcome = fmt.Sprintf("user is inactive subject=%s, email=%s", uinfo.Subject, uinfo.Email)
 		log.With("org", ctx.OrgID).Warn(login.Outcome)
-		c.Redirect(http.StatusTemporaryRedirect, redirectErrorURL)
-		return
+		return redirectErrorURL
 	}
 
 	if len(login.SlackID) > 0 {
@@ -213,15 +279,14 @@ func (h *handler) LoginCallback(c *gin.Context) {
 		if err != errUserInactive {
 			sentry.CaptureException(err)
 		}
-		c.Redirect(http.StatusTemporaryRedirect, redirectErrorURL)
-		return
+		return redirectErrorURL
 	}
 
 	h.analyticsTrack(isNewUser, userAgent, ctx)
 
 	// TODO: add analytics (identify / track)
 	login.Outcome = "success"
-	c.Redirect(http.StatusTemporaryRedirect, redirectSuccessURL)
+	return redirectSuccessURL
 }
 
 func registerMultiTenantUser(uinfo idp.ProviderUserInfo, slackID string) (isNewUser bool, err error) {
@@ -469,6 +534,26 @@ func (h *handler) verifyIDToken(code string) (token *oauth2.Token, uinfo idp.Pro
 	return
 }
 
+// fetchLoginState consumes the login of the state, it writes the error response when it's not found.
+// A state is used by a single callback, replaying the response of the identity provider is rejected.
+func fetchLoginState(c *gin.Context, stateUUID string) *pgrest.Login {
+	log.With("state", stateUUID).Infof("starting callback")
+	state, err := models.ConsumeLoginState(stateUUID)
+	if err != nil {
+		log.With("state", stateUUID).
+			Warnf("login record is empty, already used or returned with error, err=%v", err)
+		statusCode := http.StatusBadRequest
+		if err != models.ErrNotFound {
+			sentry.CaptureException(err)
+			statusCode = http.StatusInternalServerError
+		}
+		c.JSON(statusCode, gin.H{"message": "failed to retrieve login state internally"})
+		return nil
+	}
+	log.With("state", stateUUID).Debugf("login record found")
+	return &pgrest.Login{ID: state.ID, Redirect: state.Redirect, SlackID: state.SlackID.String}
+}
+
 func updateLoginState(l *pgrest.Login) {
 	loginState := &types.Login{ID: l.ID, Redirect: l.Redirect, Outcome: l.Outcome, SlackID: l.SlackID}
 	if err := pglogin.New().Upsert(loginState); err != nil {
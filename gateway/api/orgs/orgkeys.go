@@ -113,6 +113,52 @@ func RevokeAgentKey(c *gin.Context) {
 	c.Writer.WriteHeader(204)
 }
 
+// RotateOrgKey
+//
+//	@Summary		Rotate Org Key
+//	@Description	Generates a new organization key. The current key remains valid during the grace period, it allows redeploying the agents without downtime.
+//	@Tags			Organization Management
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		openapi.AgentKeyRotateRequest	false	"The request body resource"
+//	@Success		200				{object}	openapi.OrgKeyResponse
+//	@Failure		400,404,422,500	{object}	openapi.HTTPError
+//	@Router			/orgs/keys/rotate [post]
+func RotateAgentKey(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	gracePeriod, ok := apiagents.ParseGracePeriod(c)
+	if !ok {
+		return
+	}
+	ag, err := pgagents.New().FetchOneByNameOrID(ctx, agentKeyDefaultName)
+	if err != nil {
+		log.Errorf("failed fetching organization token, err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if ag == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "organization token not found"})
+		return
+	}
+	secretKey, err := apiagents.RotateAgentSecretKey(ag, gracePeriod, true)
+	if err != nil {
+		log.Errorf("failed rotating organization token, err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed rotating organization token"})
+		return
+	}
+	dsn, err := dsnkeys.New(ctx.GrpcURL, agentKeyDefaultName, secretKey)
+	if err != nil {
+		log.Errorf("failed generating agent key, err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed generating dsn"})
+		return
+	}
+	log.With("org", ag.OrgID).Infof("organization key rotated by %v, grace-period=%v", ctx.UserEmail, gracePeriod)
+	c.JSON(http.StatusOK, openapi.OrgKeyResponse{
+		ID:  ag.ID,
+		Key: dsn,
+	})
+}
+
 func ProvisionOrgAgentKey(ctx pgrest.OrgContext, grpcURL string) (agentID, dsn string, err error) {
 	ag, err := pgagents.New().FetchOneByNameOrID(ctx, agentKeyDefaultName)
 	if err != nil {
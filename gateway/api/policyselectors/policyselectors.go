@@ -0,0 +1,217 @@
+package apipolicyselectors
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/storagev2"
+)
+
+// CreatePolicySelector
+//
+//	@Summary		Create Policy Selector
+//	@Description	Assign an access control, review or guard rail policy to all connections matching the tags of the selector
+//	@Tags			Policy Selectors
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		openapi.PolicySelectorRequest	true	"The request body resource"
+//	@Success		201				{object}	openapi.PolicySelector
+//	@Failure		400,409,422,500	{object}	openapi.HTTPError
+//	@Router			/policyselectors [post]
+func Post(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	req := parseRequestPayload(c, ctx.GetOrgID())
+	if req == nil {
+		return
+	}
+	selector := &models.PolicySelector{
+		OrgID:     ctx.GetOrgID(),
+		ID:        uuid.NewString(),
+		Name:      req.Name,
+		Type:      req.Type,
+		Selector:  req.Selector,
+		Config:    req.Config,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+	err := models.CreatePolicySelector(selector)
+	switch err {
+	case models.ErrAlreadyExists:
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+	case nil:
+		c.JSON(http.StatusCreated, toOpenAPI(selector))
+	default:
+		log.Errorf("failed creating policy selector, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+// UpdatePolicySelector
+//
+//	@Summary		Update Policy Selector
+//	@Description	Update a policy selector, the connections matching the selector inherit the changes on their next session.
+//	@Tags			Policy Selectors
+//	@Accept			json
+//	@Produce		json
+//	@Param			id					path		string							true	"The unique identifier of the resource"
+//	@Param			request				body		openapi.PolicySelectorRequest	true	"The request body resource"
+//	@Success		200					{object}	openapi.PolicySelector
+//	@Failure		400,404,409,422,500	{object}	openapi.HTTPError
+//	@Router			/policyselectors/{id} [put]
+func Put(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	req := parseRequestPayload(c, ctx.GetOrgID())
+	if req == nil {
+		return
+	}
+	selector := &models.PolicySelector{
+		OrgID:     ctx.GetOrgID(),
+		ID:        c.Param("id"),
+		Name:      req.Name,
+		Type:      req.Type,
+		Selector:  req.Selector,
+		Config:    req.Config,
+		UpdatedAt: time.Now().UTC(),
+	}
+	err := models.UpdatePolicySelector(selector)
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case models.ErrAlreadyExists:
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+	case nil:
+		c.JSON(http.StatusOK, toOpenAPI(selector))
+	default:
+		log.Errorf("failed updating policy selector, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+// ListPolicySelectors
+//
+//	@Summary		List Policy Selectors
+//	@Description	List the policy selectors of the organization
+//	@Tags			Policy Selectors
+//	@Produce		json
+//	@Success		200	{array}		openapi.PolicySelector
+//	@Failure		500	{object}	openapi.HTTPError
+//	@Router			/policyselectors [get]
+func List(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	items, err := models.ListPolicySelectors(ctx.GetOrgID())
+	if err != nil {
+		log.Errorf("failed listing policy selectors, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	selectors := []openapi.PolicySelector{}
+	for _, s := range items {
+		selectors = append(selectors, *toOpenAPI(s))
+	}
+	c.JSON(http.StatusOK, selectors)
+}
+
+// GetPolicySelector
+//
+//	@Summary		Get Policy Selector
+//	@Description	Get a policy selector
+//	@Tags			Policy Selectors
+//	@Produce		json
+//	@Param			id		path		string	true	"The unique identifier of the resource"
+//	@Success		200		{object}	openapi.PolicySelector
+//	@Failure		404,500	{object}	openapi.HTTPError
+//	@Router			/policyselectors/{id} [get]
+func Get(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	selector, err := models.GetPolicySelector(ctx.GetOrgID(), c.Param("id"))
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "resource not found"})
+	case nil:
+		c.JSON(http.StatusOK, toOpenAPI(selector))
+	default:
+		log.Errorf("failed fetching policy selector, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+// DeletePolicySelector
+//
+//	@Summary		Delete Policy Selector
+//	@Description	Delete a policy selector, the connections matching the selector lose the inherited policy.
+//	@Tags			Policy Selectors
+//	@Produce		json
+//	@Param			id	path	string	true	"The unique identifier of the resource"
+//	@Success		204
+//	@Failure		404,500	{object}	openapi.HTTPError
+//	@Router			/policyselectors/{id} [delete]
+func Delete(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	err := models.DeletePolicySelector(ctx.GetOrgID(), c.Param("id"))
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "resource not found"})
+	case nil:
+		c.Writer.WriteHeader(http.StatusNoContent)
+	default:
+		log.Errorf("failed removing policy selector, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+func parseRequestPayload(c *gin.Context, orgID string) *openapi.PolicySelectorRequest {
+	req := openapi.PolicySelectorRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("failed parsing request payload, err=%v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return nil
+	}
+	if err := validateRequest(orgID, &req); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+		return nil
+	}
+	return &req
+}
+
+func validateRequest(orgID string, req *openapi.PolicySelectorRequest) error {
+	if !slices.Contains(models.PolicySelectorTypes, req.Type) {
+		return fmt.Errorf("invalid type %q, accepted values are %v", req.Type, models.PolicySelectorTypes)
+	}
+	if _, err := models.ParseTagSelector(req.Selector); err != nil {
+		return err
+	}
+	if len(req.Config) == 0 {
+		return fmt.Errorf("config must contain at least one value")
+	}
+	if req.Type != models.PolicySelectorGuardRails {
+		return nil
+	}
+	for _, ruleID := range req.Config {
+		if _, err := models.GetGuardRailRules(orgID, ruleID); err != nil {
+			if err == models.ErrNotFound {
+				return fmt.Errorf("guard rail rule %q not found", ruleID)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func toOpenAPI(s *models.PolicySelector) *openapi.PolicySelector {
+	return &openapi.PolicySelector{
+		ID:        s.ID,
+		Name:      s.Name,
+		Type:      s.Type,
+		Selector:  s.Selector,
+		Config:    s.Config,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+	}
+}
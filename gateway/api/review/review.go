@@ -1,15 +1,20 @@
 package reviewapi
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/gin-gonic/gin"
 	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/api/openapi"
 	"github.com/hoophq/hoop/gateway/pgrest"
 	pgreview "github.com/hoophq/hoop/gateway/pgrest/review"
 	"github.com/hoophq/hoop/gateway/review"
 	"github.com/hoophq/hoop/gateway/storagev2"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
 )
 
 type handler struct {
@@ -88,3 +93,254 @@ func (h *handler) Put(c *gin.Context) { h.legacy.Put(c) }
 //	@Failure		404,500	{object}	openapi.HTTPError
 //	@Router			/sessions/{session_id}/review [put]
 func (h *handler) ReviewBySession(c *gin.Context) { h.legacy.Put(c) }
+
+// ListReviewComments
+//
+//	@Summary		List Review Comments
+//	@Description	List the comments of a review, including the feedback of change requests. The owner of the review, the members of its groups and admins are able to list them.
+//	@Tags			Reviews
+//	@Param			id	path	string	true	"Resource identifier of the review"
+//	@Produce		json
+//	@Success		200			{array}		openapi.ReviewComment
+//	@Failure		403,404,500	{object}	openapi.HTTPError
+//	@Router			/reviews/{id}/comments [get]
+func (h *handler) ListComments(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	comments, err := h.legacy.Service.Comments(ctx, c.Param("id"))
+	if err != nil {
+		handleError(c, err, false)
+		return
+	}
+	items := []openapi.ReviewComment{}
+	for _, comment := range comments {
+		items = append(items, toOpenApiComment(&comment))
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// CreateReviewComment
+//
+//	@Summary		Create Review Comment
+//	@Description	Post a comment in the thread of a review. The owner of the review, the members of its groups and admins are able to comment.
+//	@Tags			Reviews
+//	@Param			id	path	string	true	"Resource identifier of the review"
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		openapi.ReviewCommentRequest	true	"The request body resource"
+//	@Success		201				{object}	openapi.ReviewComment
+//	@Failure		400,403,404,500	{object}	openapi.HTTPError
+//	@Router			/reviews/{id}/comments [post]
+func (h *handler) CreateComment(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	var req openapi.ReviewCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "missing content of the comment"})
+		return
+	}
+	comment, err := h.legacy.Service.Comment(ctx, c.Param("id"), req.Content, review.CommentSourceAPI)
+	if err != nil {
+		handleError(c, err, false)
+		return
+	}
+	c.JSON(http.StatusCreated, toOpenApiComment(comment))
+}
+
+// ListReviewRevisions
+//
+//	@Summary		List Review Revisions
+//	@Description	List the inputs submitted for a review and the reviews each one received. The last item is the current revision. The owner of the review, the members of its groups and admins are able to list them.
+//	@Tags			Reviews
+//	@Param			id	path	string	true	"Resource identifier of the review"
+//	@Produce		json
+//	@Success		200			{array}		openapi.ReviewRevision
+//	@Failure		403,404,500	{object}	openapi.HTTPError
+//	@Router			/reviews/{id}/revisions [get]
+func (h *handler) ListRevisions(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	revisions, err := h.legacy.Service.Revisions(ctx, c.Param("id"))
+	if err != nil {
+		handleError(c, err, false)
+		return
+	}
+	items := []openapi.ReviewRevision{}
+	for _, r := range revisions {
+		items = append(items, openapi.ReviewRevision{
+			Revision:     r.Revision,
+			Input:        r.Input,
+			ReviewGroups: toOpenApiReviewGroups(r.ReviewGroups),
+			SubmittedAt:  r.SubmittedAt,
+		})
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// ResubmitReview
+//
+//	@Summary		Resubmit Review
+//	@Description	Resubmit a review with changes requested using an edited input. It creates a new revision which must be reviewed again by all groups.
+//	@Tags			Reviews
+//	@Param			id	path	string	true	"Resource identifier of the review"
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		openapi.ReviewResubmitRequest	true	"The request body resource"
+//	@Success		200				{object}	openapi.Review
+//	@Failure		400,403,404,500	{object}	openapi.HTTPError
+//	@Router			/reviews/{id}/revisions [post]
+func (h *handler) Resubmit(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	var req openapi.ReviewResubmitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Input) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "missing input of the review"})
+		return
+	}
+	rev, err := h.legacy.Service.Resubmit(ctx, c.Param("id"), req.Input)
+	if err != nil {
+		handleError(c, err, false)
+		return
+	}
+	c.JSON(http.StatusOK, pgreview.ToJson(*rev))
+}
+
+// ExtendReview
+//
+//	@Summary		Extend Review
+//	@Description	Request an extension of an active jit review. The extension is a new review which must be approved by the same groups, the access duration is added to the extended review when it's approved. Only the owner of the review is able to request an extension.
+//	@Tags			Reviews
+//	@Param			id	path	string	true	"Resource identifier of the review"
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		openapi.ReviewAccessDurationRequest	true	"The request body resource"
+//	@Success		201				{object}	openapi.Review
+//	@Failure		400,403,404,500	{object}	openapi.HTTPError
+//	@Router			/reviews/{id}/extend [post]
+func (h *handler) Extend(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	duration, ok := parseAccessDuration(c)
+	if !ok {
+		return
+	}
+	rev, err := h.legacy.Service.RequestExtension(ctx, c.Param("id"), duration)
+	if err != nil {
+		handleError(c, err, false)
+		return
+	}
+	c.JSON(http.StatusCreated, pgreview.ToJson(*rev))
+}
+
+// ShortenReview
+//
+//	@Summary		Shorten Review
+//	@Description	Reduce the access duration of an active jit review, counting from the time it was approved. The sessions opened with the review are terminated when the new duration is reached.
+//	@Tags			Reviews
+//	@Param			id	path	string	true	"Resource identifier of the review"
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		openapi.ReviewAccessDurationRequest	true	"The request body resource"
+//	@Success		200				{object}	openapi.Review
+//	@Failure		400,404,500		{object}	openapi.HTTPError
+//	@Router			/reviews/{id}/shorten [post]
+func (h *handler) Shorten(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	duration, ok := parseAccessDuration(c)
+	if !ok {
+		return
+	}
+	rev, err := h.legacy.Service.Shorten(ctx, c.Param("id"), duration)
+	if err != nil {
+		handleError(c, err, false)
+		return
+	}
+	c.JSON(http.StatusOK, pgreview.ToJson(*rev))
+}
+
+func parseAccessDuration(c *gin.Context) (time.Duration, bool) {
+	var req openapi.ReviewAccessDurationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return 0, false
+	}
+	if req.AccessDurationSec <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "access_duration_sec must be greater than zero"})
+		return 0, false
+	}
+	return time.Duration(req.AccessDurationSec) * time.Second, true
+}
+
+func handleError(c *gin.Context, err error, notFound bool) {
+	switch {
+	case notFound, err == review.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "review not found"})
+	case err == review.ErrNotEligible, err == review.ErrNotOwner:
+		c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+	case errors.Is(err, review.ErrWrongState), err == review.ErrJitExpired, err == review.ErrJitMaxDuration:
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	default:
+		log.Errorf("failed processing review, err=%v", err)
+		sentry.CaptureException(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+func toOpenApiComment(c *types.ReviewComment) openapi.ReviewComment {
+	return openapi.ReviewComment{
+		ID:       c.Id,
+		Revision: c.Revision,
+		Owner: openapi.ReviewOwner{
+			ID:      c.Owner.Id,
+			Name:    c.Owner.Name,
+			Email:   c.Owner.Email,
+			SlackID: c.Owner.SlackID,
+		},
+		Content:          c.Content,
+		ChangesRequested: c.ChangesRequested,
+		Source:           c.Source,
+		CreatedAt:        c.CreatedAt,
+	}
+}
+
+func toOpenApiReviewGroups(groups []types.ReviewGroup) []openapi.ReviewGroup {
+	items := []openapi.ReviewGroup{}
+	for _, g := range groups {
+		var reviewedBy *openapi.ReviewOwner
+		if g.ReviewedBy != nil {
+			reviewedBy = &openapi.ReviewOwner{
+				ID:      g.ReviewedBy.Id,
+				Name:    g.ReviewedBy.Name,
+				Email:   g.ReviewedBy.Email,
+				SlackID: g.ReviewedBy.SlackID,
+			}
+		}
+		approvals := []openapi.ReviewApproval{}
+		for _, a := range g.Approvals {
+			approvals = append(approvals, openapi.ReviewApproval{
+				ReviewedBy: openapi.ReviewOwner{
+					ID:      a.Id,
+					Name:    a.Name,
+					Email:   a.Email,
+					SlackID: a.SlackID,
+				},
+				Status:     openapi.ReviewRequestStatusType(a.Status),
+				ReviewDate: a.ReviewDate,
+			})
+		}
+		items = append(items, openapi.ReviewGroup{
+			ID:           g.Id,
+			Group:        g.Group,
+			Status:       openapi.ReviewRequestStatusType(g.Status),
+			ReviewedBy:   reviewedBy,
+			ReviewDate:   g.ReviewDate,
+			Stage:        g.Stage,
+			MinApprovals: g.MinApprovals,
+			Approvals:    approvals,
+		})
+	}
+	return items
+}
@@ -0,0 +1,215 @@
+package apiroles
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/rbac"
+	"github.com/hoophq/hoop/gateway/storagev2"
+)
+
+// CreateRole
+//
+//	@Summary		Create Role
+//	@Description	Create a custom role with permissions scoped to connections, tags or agents
+//	@Tags			Roles
+//	@Accept			json
+//	@Produce		json
+//	@Param			request		body		openapi.RoleRequest	true	"The request body resource"
+//	@Success		201			{object}	openapi.Role
+//	@Failure		400,409,500	{object}	openapi.HTTPError
+//	@Router			/roles [post]
+func Post(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	req := parseRequestPayload(c)
+	if req == nil {
+		return
+	}
+	role := toModel(req)
+	role.ID = uuid.NewString()
+	role.OrgID = ctx.GetOrgID()
+	role.CreatedAt = time.Now().UTC()
+	role.UpdatedAt = role.CreatedAt
+	err := models.CreateRole(role)
+	switch err {
+	case models.ErrAlreadyExists:
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+	case nil:
+		c.JSON(http.StatusCreated, toOpenAPI(role))
+	default:
+		log.Errorf("failed creating role, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+// UpdateRole
+//
+//	@Summary		Update Role
+//	@Description	Update a custom role
+//	@Tags			Roles
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		string				true	"The unique identifier of the resource"
+//	@Param			request			body		openapi.RoleRequest	true	"The request body resource"
+//	@Success		200				{object}	openapi.Role
+//	@Failure		400,404,409,500	{object}	openapi.HTTPError
+//	@Router			/roles/{id} [put]
+func Put(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	req := parseRequestPayload(c)
+	if req == nil {
+		return
+	}
+	role := toModel(req)
+	role.ID = c.Param("id")
+	role.OrgID = ctx.GetOrgID()
+	role.UpdatedAt = time.Now().UTC()
+	err := models.UpdateRole(role)
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case models.ErrAlreadyExists:
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+	case nil:
+		c.JSON(http.StatusOK, toOpenAPI(role))
+	default:
+		log.Errorf("failed updating role, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+// ListRoles
+//
+//	@Summary		List Roles
+//	@Description	List the custom roles of the organization
+//	@Tags			Roles
+//	@Produce		json
+//	@Success		200	{array}		openapi.Role
+//	@Failure		500	{object}	openapi.HTTPError
+//	@Router			/roles [get]
+func List(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	roleList, err := models.ListRoles(ctx.GetOrgID())
+	if err != nil {
+		log.Errorf("failed listing roles, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	roles := []openapi.Role{}
+	for _, role := range roleList {
+		roles = append(roles, *toOpenAPI(role))
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// GetRole
+//
+//	@Summary		Get Role
+//	@Description	Get a custom role
+//	@Tags			Roles
+//	@Produce		json
+//	@Param			id		path		string	true	"The unique identifier of the resource"
+//	@Success		200		{object}	openapi.Role
+//	@Failure		404,500	{object}	openapi.HTTPError
+//	@Router			/roles/{id} [get]
+func Get(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	role, err := models.GetRole(ctx.GetOrgID(), c.Param("id"))
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "resource not found"})
+	case nil:
+		c.JSON(http.StatusOK, toOpenAPI(role))
+	default:
+		log.Errorf("failed fetching role, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+// DeleteRole
+//
+//	@Summary		Delete Role
+//	@Description	Delete a custom role, the users of the assigned groups lose its permissions.
+//	@Tags			Roles
+//	@Produce		json
+//	@Param			id	path	string	true	"The unique identifier of the resource"
+//	@Success		204
+//	@Failure		404,500	{object}	openapi.HTTPError
+//	@Router			/roles/{id} [delete]
+func Delete(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	err := models.DeleteRole(ctx.GetOrgID(), c.Param("id"))
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "resource not found"})
+	case nil:
+		c.Writer.WriteHeader(http.StatusNoContent)
+	default:
+		log.Errorf("failed removing role, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+func parseRequestPayload(c *gin.Context) *openapi.RoleRequest {
+	req := openapi.RoleRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("failed parsing request payload, err=%v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return nil
+	}
+	if err := rbac.ValidatePermissions(toModel(&req).Permissions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return nil
+	}
+	return &req
+}
+
+func toModel(req *openapi.RoleRequest) *models.Role {
+	role := &models.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Groups:      req.Groups,
+	}
+	if role.Groups == nil {
+		role.Groups = []string{}
+	}
+	for _, p := range req.Permissions {
+		role.Permissions = append(role.Permissions, models.RolePermission{
+			Actions: p.Actions,
+			Scope: models.RoleScope{
+				Connections: p.Scope.Connections,
+				Tags:        p.Scope.Tags,
+				Agents:      p.Scope.Agents,
+			},
+		})
+	}
+	return role
+}
+
+func toOpenAPI(role *models.Role) *openapi.Role {
+	obj := &openapi.Role{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: []openapi.RolePermission{},
+		Groups:      role.Groups,
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+	for _, p := range role.Permissions {
+		obj.Permissions = append(obj.Permissions, openapi.RolePermission{
+			Actions: p.Actions,
+			Scope: openapi.RoleScope{
+				Connections: p.Scope.Connections,
+				Tags:        p.Scope.Tags,
+				Agents:      p.Scope.Agents,
+			},
+		})
+	}
+	return obj
+}
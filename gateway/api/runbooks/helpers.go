@@ -3,6 +3,7 @@ package apirunbooks
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -12,10 +13,41 @@ import (
 	"github.com/hoophq/hoop/gateway/storagev2/types"
 )
 
-const maxTemplateSize = 1000000 // 1MB
+const (
+	maxTemplateSize  = 1000000 // 1MB
+	maskedInputValue = "********"
+)
+
+// connectionConfig is the runbooks plugin configuration of a connection, the items could be:
+//
+//   - ref=<branch|tag> - serve the runbooks from a branch or a tag
+//   - commit=<sha> - pin the runbooks to a commit
+//   - <path-prefix> - the first item without a key restricts the runbooks to the ones under this path
+type connectionConfig struct {
+	pathPrefix string
+	ref        string
+	commit     string
+}
+
+func parseConnectionConfig(config []string) connectionConfig {
+	var cc connectionConfig
+	var hasPathPrefix bool
+	for _, item := range config {
+		switch {
+		case strings.HasPrefix(item, "ref="):
+			cc.ref = strings.TrimPrefix(item, "ref=")
+		case strings.HasPrefix(item, "commit="):
+			cc.commit = strings.TrimPrefix(item, "commit=")
+		case !hasPathPrefix:
+			cc.pathPrefix = item
+			hasPathPrefix = true
+		}
+	}
+	return cc
+}
 
-func fetchRunbookFile(config *templates.RunbookConfig, req openapi.RunbookRequest) (*openapi.Runbook, error) {
-	c, err := templates.FetchRepo(config)
+func fetchRunbookFile(orgID string, config *templates.RunbookConfig, req openapi.RunbookRequest) (*openapi.Runbook, error) {
+	c, err := templates.FetchRepoCached(orgID, config)
 	if err != nil {
 		return nil, err
 	}
@@ -45,81 +77,127 @@ func fetchRunbookFile(config *templates.RunbookConfig, req openapi.RunbookReques
 			}
 			return &openapi.Runbook{
 				Name:       f.Name,
+				Metadata:   t.Attributes(),
 				InputFile:  parsedTemplate.Bytes(),
 				EnvVars:    t.EnvVars(),
-				CommitHash: c.Hash.String()}, nil
+				CommitHash: c.Hash.String(),
+				CommitRef:  c.Ref}, nil
 		}
 	}
 	return nil, fmt.Errorf("runbook %v not found for %v", req.FileName, c.Hash.String())
 }
 
-func listRunbookFiles(pluginConnectionList []*types.PluginConnection, config *templates.RunbookConfig) (*openapi.RunbookList, error) {
-	commit, err := templates.FetchRepo(config)
-	if err != nil {
-		return nil, err
+// runbookRevision is the git revision the runbooks of a connection are served from
+type runbookRevision struct {
+	ref    string
+	commit string
+}
+
+// listRunbookFiles lists the runbooks of all connections. The connections could pin the runbooks
+// to distinct revisions, the runbooks of each revision are listed from its own commit with the
+// connections using it. The attributes of the list are the ones of the first revision listed.
+// The fetchFn fetches the commit of a revision, e.g.: templates.FetchRepoCached
+func listRunbookFiles(orgID string, pluginConnectionList []*types.PluginConnection, config *templates.RunbookConfig,
+	fetchFn func(orgID string, config *templates.RunbookConfig) (*templates.Revision, error)) (*openapi.RunbookList, error) {
+	// the default revision lists all runbooks, even when there are no connections
+	revisions := []runbookRevision{{}}
+	connectionsByRevision := map[runbookRevision][]*types.PluginConnection{}
+	for _, conn := range pluginConnectionList {
+		cc := parseConnectionConfig(conn.Config)
+		rev := runbookRevision{ref: cc.ref, commit: cc.commit}
+		if _, ok := connectionsByRevision[rev]; !ok && rev != (runbookRevision{}) {
+			revisions = append(revisions, rev)
+		}
+		connectionsByRevision[rev] = append(connectionsByRevision[rev], conn)
 	}
-	runbookList := &openapi.RunbookList{
-		Commit:        commit.Hash.String(),
-		CommitAuthor:  commit.Author.String(),
-		CommitMessage: commit.Message,
-		Items:         []*openapi.Runbook{},
+	if len(pluginConnectionList) > 0 && len(connectionsByRevision[runbookRevision{}]) == 0 {
+		revisions = revisions[1:]
 	}
+	var runbookList *openapi.RunbookList
+	for _, rev := range revisions {
+		commit, err := fetchFn(orgID, config.WithRevision(rev.ref, rev.commit))
+		if err != nil {
+			return nil, err
+		}
+		if runbookList == nil {
+			runbookList = &openapi.RunbookList{
+				Ref:           commit.Ref,
+				Commit:        commit.Hash.String(),
+				CommitAuthor:  commit.Author.String(),
+				CommitMessage: commit.Message,
+				Items:         []*openapi.Runbook{},
+			}
+		}
+		items, err := listRunbookFilesFromCommit(commit, connectionsByRevision[rev], rev == runbookRevision{})
+		if err != nil {
+			return nil, err
+		}
+		runbookList.Items = append(runbookList.Items, items...)
+	}
+	return runbookList, nil
+}
+
+// listRunbookFilesFromCommit lists the runbooks of a commit available to the connections,
+// the runbooks without connections are listed only when includeAll is set.
+func listRunbookFilesFromCommit(commit *templates.Revision, connections []*types.PluginConnection, includeAll bool) ([]*openapi.Runbook, error) {
+	items := []*openapi.Runbook{}
 	ctree, _ := commit.Tree()
 	if ctree == nil {
-		return runbookList, nil
+		return items, nil
 	}
-	return runbookList, ctree.Files().ForEach(func(f *object.File) error {
+	return items, ctree.Files().ForEach(func(f *object.File) error {
 		if !templates.IsRunbookFile(f.Name) {
 			return nil
 		}
+		var connectionList []string
+		for _, conn := range connections {
+			pathPrefix := parseConnectionConfig(conn.Config).pathPrefix
+			if strings.HasPrefix(f.Name, pathPrefix) {
+				connectionList = append(connectionList, conn.Name)
+			}
+		}
+		if len(connectionList) == 0 && !includeAll {
+			return nil
+		}
 		runbook := &openapi.Runbook{
 			Name:           f.Name,
 			Metadata:       map[string]any{},
 			ConnectionList: []string{},
 			Error:          nil,
+			CommitHash:     commit.Hash.String(),
+			CommitRef:      commit.Ref,
 		}
 		blobData, err := templates.ReadBlob(f)
 		if err != nil {
 			runbook.Error = toPtrStr(err)
-			runbookList.Items = append(runbookList.Items, runbook)
+			items = append(items, runbook)
 			return nil
 		}
 		if len(blobData) > maxTemplateSize {
 			runbook.Error = toPtrStr(fmt.Errorf("max template size [%v KB] reached", maxTemplateSize/1000))
-			runbookList.Items = append(runbookList.Items, runbook)
+			items = append(items, runbook)
 			return nil
 		}
 		t, err := templates.Parse(string(blobData))
 		if err != nil {
 			runbook.Error = toPtrStr(fmt.Errorf("template parse error: %v", err))
-			runbookList.Items = append(runbookList.Items, runbook)
+			items = append(items, runbook)
 			return nil
 		}
-
-		var connectionList []string
-		for _, conn := range pluginConnectionList {
-			if len(conn.Config) == 0 {
-				connectionList = append(connectionList, conn.Name)
-				continue
-			}
-			pathPrefix := conn.Config[0]
-			if pathPrefix != "" && strings.HasPrefix(f.Name, pathPrefix) {
-				connectionList = append(connectionList, conn.Name)
-			}
-		}
 		runbook.ConnectionList = connectionList
 		runbook.Metadata = t.Attributes()
-		runbookList.Items = append(runbookList.Items, runbook)
+		items = append(items, runbook)
 		return nil
 	})
 }
 
-func listRunbookFilesByPathPrefix(pathPrefix string, config *templates.RunbookConfig) (*openapi.RunbookList, error) {
-	commit, err := templates.FetchRepo(config)
+func listRunbookFilesByPathPrefix(orgID, pathPrefix string, config *templates.RunbookConfig) (*openapi.RunbookList, error) {
+	commit, err := templates.FetchRepoCached(orgID, config)
 	if err != nil {
 		return nil, err
 	}
 	runbookList := &openapi.RunbookList{
+		Ref:           commit.Ref,
 		Commit:        commit.Hash.String(),
 		CommitAuthor:  commit.Author.String(),
 		CommitMessage: commit.Message,
@@ -141,6 +219,8 @@ func listRunbookFilesByPathPrefix(pathPrefix string, config *templates.RunbookCo
 			Metadata:       map[string]any{},
 			ConnectionList: nil,
 			Error:          nil,
+			CommitHash:     commit.Hash.String(),
+			CommitRef:      commit.Ref,
 		}
 		blobData, err := templates.ReadBlob(f)
 		if err != nil {
@@ -166,6 +246,36 @@ func listRunbookFilesByPathPrefix(pathPrefix string, config *templates.RunbookCo
 	})
 }
 
+// maskRunbookSecrets masks the values of the inputs with the password type
+// or the ones exposed as environment variables from the rendered content
+func maskRunbookSecrets(content string, attributes map[string]any, params map[string]string) string {
+	var secrets []string
+	for key, obj := range attributes {
+		metadata, _ := obj.(map[string]any)
+		if metadata == nil {
+			continue
+		}
+		inputType, _ := metadata["type"].(string)
+		asEnv, _ := metadata["asenv"].(string)
+		if inputType != "password" && asEnv == "" {
+			continue
+		}
+		val := params[key]
+		if val == "" {
+			val, _ = metadata["default"].(string)
+		}
+		if val != "" {
+			secrets = append(secrets, val)
+		}
+	}
+	// replace the longest values first to not leave parts of a secret containing another one
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	for _, val := range secrets {
+		content = strings.ReplaceAll(content, val, maskedInputValue)
+	}
+	return content
+}
+
 func toPtrStr(v any) *string {
 	if v == nil || fmt.Sprintf("%v", v) == "" {
 		return nil
@@ -0,0 +1,171 @@
+package apirunbooks
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/hoophq/hoop/gateway/api/runbooks/templates"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConnectionConfig(t *testing.T) {
+	for _, tt := range []struct {
+		msg    string
+		config []string
+		want   connectionConfig
+	}{
+		{
+			msg:  "it should return an empty config",
+			want: connectionConfig{},
+		},
+		{
+			msg:    "it should parse the path prefix",
+			config: []string{"ops/"},
+			want:   connectionConfig{pathPrefix: "ops/"},
+		},
+		{
+			msg:    "it should parse the ref and the commit",
+			config: []string{"ops/", "ref=release", "commit=20320ebbf9fc612256b67dc9e899bbd6e4745c77"},
+			want:   connectionConfig{pathPrefix: "ops/", ref: "release", commit: "20320ebbf9fc612256b67dc9e899bbd6e4745c77"},
+		},
+		{
+			msg:    "it should parse the ref without path prefix",
+			config: []string{"ref=refs/tags/v1.0.0"},
+			want:   connectionConfig{ref: "refs/tags/v1.0.0"},
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseConnectionConfig(tt.config))
+		})
+	}
+}
+
+func TestMaskRunbookSecrets(t *testing.T) {
+	attributes := map[string]any{
+		"customer_id": map[string]any{"type": "number"},
+		"password":    map[string]any{"type": "password"},
+		"token":       map[string]any{"type": "text", "asenv": "API_TOKEN"},
+		"api_key":     map[string]any{"type": "password", "default": "default-key"},
+	}
+	for _, tt := range []struct {
+		msg     string
+		content string
+		params  map[string]string
+		want    string
+	}{
+		{
+			msg:     "it should not mask inputs that aren't secrets",
+			content: "SELECT * FROM customers WHERE id = 10",
+			params:  map[string]string{"customer_id": "10"},
+			want:    "SELECT * FROM customers WHERE id = 10",
+		},
+		{
+			msg:     "it should mask password and asenv inputs",
+			content: "curl -u admin:s3cret -H 'token: tk-123' /customers/10",
+			params:  map[string]string{"customer_id": "10", "password": "s3cret", "token": "tk-123"},
+			want:    "curl -u admin:******** -H 'token: ********' /customers/10",
+		},
+		{
+			msg:     "it should mask the default value of a secret input",
+			content: "key=default-key",
+			params:  map[string]string{},
+			want:    "key=********",
+		},
+		{
+			msg:     "it should mask the longest secrets first",
+			content: "pwd=abc, token=abcdef",
+			params:  map[string]string{"password": "abc", "token": "abcdef"},
+			want:    "pwd=********, token=********",
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			assert.Equal(t, tt.want, maskRunbookSecrets(tt.content, attributes, tt.params))
+		})
+	}
+}
+
+// newTestRepository creates a repository with a commit for each list of files
+func newTestRepository(t *testing.T, commits ...[]string) []*templates.Revision {
+	fs := memfs.New()
+	r, err := git.Init(memory.NewStorage(), fs)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	var revisions []*templates.Revision
+	for i, files := range commits {
+		for _, name := range files {
+			require.NoError(t, util.WriteFile(fs, name, []byte("SELECT 1"), 0644))
+			_, err = wt.Add(name)
+			require.NoError(t, err)
+		}
+		hash, err := wt.Commit(fmt.Sprintf("commit %v", i), &git.CommitOptions{
+			Author: &object.Signature{Name: "hoop", Email: "hoop@hoop.dev", When: time.Now()},
+		})
+		require.NoError(t, err)
+		commit, err := r.CommitObject(hash)
+		require.NoError(t, err)
+		revisions = append(revisions, &templates.Revision{Ref: "refs/heads/main", Commit: commit})
+	}
+	return revisions
+}
+
+func TestListRunbookFiles(t *testing.T) {
+	revisions := newTestRepository(t,
+		[]string{"ops/a.runbook.sql"},
+		[]string{"ops/b.runbook.sql", "dev/c.runbook.sql"},
+	)
+	oldCommit, headCommit := revisions[0], revisions[1]
+	fetchFn := func(_ string, config *templates.RunbookConfig) (*templates.Revision, error) {
+		if config.Commit == oldCommit.Hash.String() {
+			return oldCommit, nil
+		}
+		return headCommit, nil
+	}
+	config := &templates.RunbookConfig{GitURL: "https://github.com/acme/runbooks"}
+
+	t.Run("it should list the runbooks of each connection from its pinned commit", func(t *testing.T) {
+		runbookList, err := listRunbookFiles("org", []*types.PluginConnection{
+			{Name: "pgprod", Config: []string{"ops/", "commit=" + oldCommit.Hash.String()}},
+			{Name: "pgdev", Config: []string{}},
+		}, config, fetchFn)
+		require.NoError(t, err)
+		assert.Equal(t, headCommit.Hash.String(), runbookList.Commit)
+
+		var got []string
+		for _, runbook := range runbookList.Items {
+			got = append(got, fmt.Sprintf("%s:%s:%v", runbook.CommitHash[:7], runbook.Name, runbook.ConnectionList))
+		}
+		assert.Equal(t, []string{
+			headCommit.Hash.String()[:7] + ":dev/c.runbook.sql:[pgdev]",
+			headCommit.Hash.String()[:7] + ":ops/a.runbook.sql:[pgdev]",
+			headCommit.Hash.String()[:7] + ":ops/b.runbook.sql:[pgdev]",
+			oldCommit.Hash.String()[:7] + ":ops/a.runbook.sql:[pgprod]",
+		}, got)
+	})
+
+	t.Run("it should use the pinned commit when all connections are pinned", func(t *testing.T) {
+		runbookList, err := listRunbookFiles("org", []*types.PluginConnection{
+			{Name: "pgprod", Config: []string{"commit=" + oldCommit.Hash.String()}},
+		}, config, fetchFn)
+		require.NoError(t, err)
+		assert.Equal(t, oldCommit.Hash.String(), runbookList.Commit)
+		require.Len(t, runbookList.Items, 1)
+		assert.Equal(t, "ops/a.runbook.sql", runbookList.Items[0].Name)
+		assert.Equal(t, []string{"pgprod"}, runbookList.Items[0].ConnectionList)
+	})
+
+	t.Run("it should list all runbooks of the default revision without connections", func(t *testing.T) {
+		runbookList, err := listRunbookFiles("org", nil, config, fetchFn)
+		require.NoError(t, err)
+		assert.Equal(t, headCommit.Hash.String(), runbookList.Commit)
+		assert.Len(t, runbookList.Items, 3)
+	})
+}
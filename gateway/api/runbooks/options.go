@@ -0,0 +1,176 @@
+package apirunbooks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hoophq/hoop/common/log"
+	pb "github.com/hoophq/hoop/common/proto"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/clientexec"
+	"github.com/hoophq/hoop/gateway/review"
+)
+
+const (
+	optionsQueryTimeout = time.Second * 20
+	// optionsCacheTTL is the maximum age of the options resolved for a user
+	optionsCacheTTL = time.Minute
+)
+
+// optionsQueryFunc runs a query against a connection returning its raw output
+type optionsQueryFunc func(opts *clientexec.Options, query string) (string, error)
+
+// runOptionsQuery runs the query of the options through the gateway
+func runOptionsQuery(opts *clientexec.Options, query string) (string, error) {
+	client, err := clientexec.New(opts)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	// buffered to not block the goroutine when the query times out
+	respCh := make(chan *clientexec.Response, 1)
+	go func() { respCh <- client.Run([]byte(query), nil) }()
+	timeoutCtx, cancelFn := context.WithTimeout(context.Background(), optionsQueryTimeout)
+	defer cancelFn()
+	select {
+	case outcome := <-respCh:
+		if outcome.ExitCode != 0 {
+			return "", fmt.Errorf("query failed: %v", outcome.Output)
+		}
+		return outcome.Output, nil
+	case <-timeoutCtx.Done():
+		return "", fmt.Errorf("query timed out (%v)", optionsQueryTimeout)
+	}
+}
+
+type optionsSource struct {
+	connection string
+	query      string
+}
+
+type optionsCacheKey struct {
+	orgID  string
+	userID string
+	source optionsSource
+}
+
+type cachedOptions struct {
+	options   []string
+	fetchedAt time.Time
+}
+
+// optionsCache keeps the options resolved by each user, it prevents running
+// the queries of the runbooks every time they are listed
+var optionsCache = struct {
+	mu    sync.Mutex
+	items map[optionsCacheKey]*cachedOptions
+}{items: map[optionsCacheKey]*cachedOptions{}}
+
+func getCachedOptions(key optionsCacheKey) ([]string, bool) {
+	optionsCache.mu.Lock()
+	defer optionsCache.mu.Unlock()
+	item, ok := optionsCache.items[key]
+	if !ok || time.Since(item.fetchedAt) > optionsCacheTTL {
+		return nil, false
+	}
+	return item.options, true
+}
+
+func setCachedOptions(key optionsCacheKey, options []string) {
+	optionsCache.mu.Lock()
+	defer optionsCache.mu.Unlock()
+	now := time.Now().UTC()
+	for k, item := range optionsCache.items {
+		if now.Sub(item.fetchedAt) > optionsCacheTTL {
+			delete(optionsCache.items, k)
+		}
+	}
+	optionsCache.items[key] = &cachedOptions{options: options, fetchedAt: now}
+}
+
+// resolveDynamicOptions populates the options of the attributes defined with the
+// optionsfrom function. Each distinct query is executed once with the credentials
+// of the user and the options are cached for a short period, a failure is reported
+// in the attribute as options_error.
+//
+// The queries come from the runbooks repository, only read only queries are executed
+// and they go through the same plugins (audit, review, dlp, guardrails) of any execution.
+func resolveDynamicOptions(runbookList *openapi.RunbookList, orgID, userID, accessToken, userAgent string, queryFn optionsQueryFunc) {
+	sources := map[optionsSource][]map[string]any{}
+	for _, runbook := range runbookList.Items {
+		for _, obj := range runbook.Metadata {
+			metadata, _ := obj.(map[string]any)
+			if metadata == nil {
+				continue
+			}
+			from, _ := metadata["optionsfrom"].(map[string]any)
+			if from == nil {
+				continue
+			}
+			src := optionsSource{fmt.Sprintf("%v", from["connection"]), fmt.Sprintf("%v", from["query"])}
+			sources[src] = append(sources[src], metadata)
+		}
+	}
+	var wg sync.WaitGroup
+	for src, attrs := range sources {
+		wg.Add(1)
+		go func(src optionsSource, attrs []map[string]any) {
+			defer wg.Done()
+			options, err := resolveOptions(optionsCacheKey{orgID, userID, src}, accessToken, userAgent, queryFn)
+			if err != nil {
+				log.With("connection", src.connection).Warnf("failed resolving runbook options, reason=%v", err)
+			}
+			for _, metadata := range attrs {
+				if err != nil {
+					metadata["options_error"] = err.Error()
+					continue
+				}
+				metadata["options"] = options
+			}
+		}(src, attrs)
+	}
+	wg.Wait()
+}
+
+func resolveOptions(key optionsCacheKey, accessToken, userAgent string, queryFn optionsQueryFunc) ([]string, error) {
+	if options, ok := getCachedOptions(key); ok {
+		return options, nil
+	}
+	if review.QueryType(key.source.query) != review.QueryTypeRead {
+		return nil, fmt.Errorf("the optionsfrom query must be read only")
+	}
+	output, err := queryFn(&clientexec.Options{
+		OrgID:          key.orgID,
+		ConnectionName: key.source.connection,
+		BearerToken:    accessToken,
+		UserAgent:      userAgent,
+		Verb:           pb.ClientVerbExec,
+	}, key.source.query)
+	if err != nil {
+		return nil, err
+	}
+	options := parseOptionsOutput(output)
+	setCachedOptions(key, options)
+	return options, nil
+}
+
+// parseOptionsOutput parses the output of a query, the first line is the header
+// and the first column of each row is an option
+func parseOptionsOutput(output string) []string {
+	options := []string{}
+	for i, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if i == 0 || line == "" {
+			continue
+		}
+		// the summary of rows, e.g.: (3 rows)
+		if strings.HasPrefix(line, "(") {
+			break
+		}
+		options = append(options, strings.TrimSpace(strings.Split(line, "\t")[0]))
+	}
+	return options
+}
@@ -0,0 +1,92 @@
+package apirunbooks
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/clientexec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOptionsOutput(t *testing.T) {
+	for _, tt := range []struct {
+		msg    string
+		output string
+		want   []string
+	}{
+		{
+			msg:  "it should return empty options",
+			want: []string{},
+		},
+		{
+			msg:    "it should skip the header and the rows summary",
+			output: "name\nbasic\npro\n\nenterprise\n(3 rows)\n",
+			want:   []string{"basic", "pro", "enterprise"},
+		},
+		{
+			msg:    "it should use the first column of each row",
+			output: "name\tdescription\nbasic\tthe basic plan\npro\tthe pro plan",
+			want:   []string{"basic", "pro"},
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseOptionsOutput(tt.output))
+		})
+	}
+}
+
+func TestResolveDynamicOptions(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	optionsCache.items = map[optionsCacheKey]*cachedOptions{}
+	queryFn := func(opts *clientexec.Options, query string) (string, error) {
+		mu.Lock()
+		queries = append(queries, opts.ConnectionName+":"+query)
+		mu.Unlock()
+		if opts.ConnectionName == "broken" {
+			return "", fmt.Errorf("connection not found")
+		}
+		return "name\nbasic\npro\n(2 rows)", nil
+	}
+	newAttr := func(conn string) map[string]any {
+		return map[string]any{"type": "select", "optionsfrom": map[string]any{"connection": conn, "query": "SELECT name FROM plans"}}
+	}
+	runbookList := &openapi.RunbookList{Items: []*openapi.Runbook{
+		{Name: "a.runbook.sql", Metadata: map[string]any{"plan": newAttr("pgdemo"), "name": map[string]any{"type": "text"}}},
+		{Name: "b.runbook.sql", Metadata: map[string]any{"plan": newAttr("pgdemo"), "other": newAttr("broken")}},
+	}}
+	resolveDynamicOptions(runbookList, "org", "user", "token", "test", queryFn)
+
+	assert.ElementsMatch(t, []string{"pgdemo:SELECT name FROM plans", "broken:SELECT name FROM plans"}, queries)
+	for _, runbook := range runbookList.Items {
+		assert.Equal(t, []string{"basic", "pro"}, runbook.Metadata["plan"].(map[string]any)["options"])
+	}
+	assert.Nil(t, runbookList.Items[0].Metadata["name"].(map[string]any)["options"])
+	assert.Equal(t, "connection not found", runbookList.Items[1].Metadata["other"].(map[string]any)["options_error"])
+
+	t.Run("it should serve the cached options of the user", func(t *testing.T) {
+		queries = nil
+		runbookList := &openapi.RunbookList{Items: []*openapi.Runbook{
+			{Name: "a.runbook.sql", Metadata: map[string]any{"plan": newAttr("pgdemo")}},
+		}}
+		resolveDynamicOptions(runbookList, "org", "user", "token", "test", queryFn)
+		assert.Empty(t, queries)
+		assert.Equal(t, []string{"basic", "pro"}, runbookList.Items[0].Metadata["plan"].(map[string]any)["options"])
+
+		resolveDynamicOptions(runbookList, "org", "other-user", "token", "test", queryFn)
+		assert.Equal(t, []string{"pgdemo:SELECT name FROM plans"}, queries)
+	})
+
+	t.Run("it should not execute queries that are not read only", func(t *testing.T) {
+		queries = nil
+		runbookList := &openapi.RunbookList{Items: []*openapi.Runbook{
+			{Name: "a.runbook.sql", Metadata: map[string]any{"plan": map[string]any{
+				"type": "select", "optionsfrom": map[string]any{"connection": "pgdemo", "query": "DELETE FROM plans RETURNING name"}}}},
+		}}
+		resolveDynamicOptions(runbookList, "org", "user", "token", "test", queryFn)
+		assert.Empty(t, queries)
+		assert.Equal(t, "the optionsfrom query must be read only", runbookList.Items[0].Metadata["plan"].(map[string]any)["options_error"])
+	})
+}
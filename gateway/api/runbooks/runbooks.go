@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -61,12 +62,13 @@ func List(c *gin.Context) {
 		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
 		return
 	}
-	runbookList, err := listRunbookFiles(p.Connections, config)
+	runbookList, err := listRunbookFiles(ctx.GetOrgID(), p.Connections, config, templates.FetchRepoCached)
 	if err != nil {
 		log.Infof("failed listing runbooks, err=%v", err)
 		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": fmt.Sprintf("failed listing runbooks, reason=%v", err)})
 		return
 	}
+	resolveDynamicOptions(runbookList, ctx.GetOrgID(), ctx.GetUserID(), getAccessToken(c), apiutils.NormalizeUserAgent(c.Request.Header.Values), runOptionsQuery)
 	c.PureJSON(http.StatusOK, runbookList)
 }
 
@@ -104,12 +106,10 @@ func ListByConnection(c *gin.Context) {
 		return
 	}
 	hasConnection := false
-	var pathPrefix string
+	var connConfig connectionConfig
 	for _, conn := range p.Connections {
 		if conn.Name == connectionName {
-			if len(conn.Config) > 0 {
-				pathPrefix = conn.Config[0]
-			}
+			connConfig = parseConnectionConfig(conn.Config)
 			hasConnection = true
 			break
 		}
@@ -118,12 +118,14 @@ func ListByConnection(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"message": "runbooks plugin does not have this connection"})
 		return
 	}
-	runbookList, err := listRunbookFilesByPathPrefix(pathPrefix, config)
+	config = config.WithRevision(connConfig.ref, connConfig.commit)
+	runbookList, err := listRunbookFilesByPathPrefix(ctx.GetOrgID(), connConfig.pathPrefix, config)
 	if err != nil {
 		log.Infof("failed listing runbooks, err=%v", err)
 		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": fmt.Sprintf("failed listing runbooks, reason=%v", err)})
 		return
 	}
+	resolveDynamicOptions(runbookList, ctx.GetOrgID(), ctx.GetUserID(), getAccessToken(c), apiutils.NormalizeUserAgent(c.Request.Header.Values), runOptionsQuery)
 	c.JSON(http.StatusOK, runbookList)
 }
 
@@ -166,26 +168,12 @@ func RunExec(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("runbook file %v not found", req.FileName)})
 		return
 	}
-	runbook, err := fetchRunbookFile(config, req)
+	runbook, err := prepareRunbook(ctx.GetOrgID(), config, req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
 	}
 
-	for key, val := range req.EnvVars {
-		// don't replace environment variables from runbook
-		if _, ok := runbook.EnvVars[key]; ok {
-			continue
-		}
-		runbook.EnvVars[key] = val
-	}
-
-	runbookParamsJson, _ := json.Marshal(req.Parameters)
-	sessionLabels := types.SessionLabels{
-		"runbookFile":       req.FileName,
-		"runbookParameters": string(runbookParamsJson),
-	}
-
 	sessionID := uuid.NewString()
 	apiroutes.SetSidSpanAttr(c, sessionID)
 	userAgent := apiutils.NormalizeUserAgent(c.Request.Header.Values)
@@ -207,28 +195,7 @@ func RunExec(c *gin.Context) {
 		return
 	}
 
-	newSession := models.Session{
-		ID:                   sessionID,
-		OrgID:                ctx.GetOrgID(),
-		Connection:           connectionName,
-		ConnectionType:       string(proto.ConnectionTypeCustom),
-		ConnectionSubtype:    connection.SubType.String,
-		Verb:                 proto.ClientVerbExec,
-		Labels:               sessionLabels,
-		Metadata:             req.Metadata,
-		IntegrationsMetadata: nil,
-		Metrics:              nil,
-		BlobInput:            models.BlobInputType(runbook.InputFile),
-		UserID:               ctx.UserID,
-		UserName:             ctx.UserName,
-		UserEmail:            ctx.UserEmail,
-		Status:               string(openapi.SessionStatusOpen),
-		ExitCode:             nil,
-		CreatedAt:            time.Now().UTC(),
-		EndSession:           nil,
-	}
-
-	if err := models.UpsertSession(newSession); err != nil {
+	if err := createRunbookSession(ctx, connection, sessionID, runbook, req, nil); err != nil {
 		log.Errorf("failed persisting session, err=%v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "The session couldn't be created"})
 		return
@@ -264,6 +231,112 @@ func RunExec(c *gin.Context) {
 	}
 }
 
+// PreviewRunbook
+//
+//	@Summary		Runbook Preview
+//	@Description	Render a Runbook with the given parameters without executing it.
+//	@Description	The values of inputs with the password type or exposed as environment variables are masked.
+//	@Tags			Runbooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			name			path		string					true	"The name of the connection"
+//	@Param			request			body		openapi.RunbookRequest	true	"The request body resource"
+//	@Success		200				{object}	openapi.RunbookPreview
+//	@Failure		400,404,422,500	{object}	openapi.HTTPError
+//	@Router			/plugins/runbooks/connections/{name}/preview [post]
+func Preview(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	var req openapi.RunbookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	connection, err := getConnection(ctx, c, c.Param("name"))
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	config, pathPrefix, err := getRunbookConfig(ctx, c, connection)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if pathPrefix != "" && !strings.HasPrefix(req.FileName, pathPrefix) {
+		c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("runbook file %v not found", req.FileName)})
+		return
+	}
+	runbook, err := prepareRunbook(ctx.GetOrgID(), config, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	envVars := []string{}
+	for key := range runbook.EnvVars {
+		envVars = append(envVars, key)
+	}
+	sort.Strings(envVars)
+	c.JSON(http.StatusOK, openapi.RunbookPreview{
+		FileName: runbook.Name,
+		Ref:      runbook.CommitRef,
+		Commit:   runbook.CommitHash,
+		Content:  maskRunbookSecrets(string(runbook.InputFile), runbook.Metadata, req.Parameters),
+		EnvVars:  envVars,
+	})
+}
+
+// prepareRunbook renders the runbook file of the request with its parameters
+// and the environment variables of the request
+func prepareRunbook(orgID string, config *templates.RunbookConfig, req openapi.RunbookRequest) (*openapi.Runbook, error) {
+	runbook, err := fetchRunbookFile(orgID, config, req)
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range req.EnvVars {
+		// don't replace environment variables from runbook
+		if _, ok := runbook.EnvVars[key]; ok {
+			continue
+		}
+		runbook.EnvVars[key] = val
+	}
+	return runbook, nil
+}
+
+// createRunbookSession persists the session of a runbook execution,
+// the labels identify the runbook file, parameters and the git revision
+func createRunbookSession(ctx *storagev2.Context, connection *models.Connection, sessionID string,
+	runbook *openapi.Runbook, req openapi.RunbookRequest, extraLabels types.SessionLabels) error {
+	runbookParamsJson, _ := json.Marshal(req.Parameters)
+	sessionLabels := types.SessionLabels{
+		"runbookFile":       req.FileName,
+		"runbookParameters": string(runbookParamsJson),
+		"runbookRef":        runbook.CommitRef,
+		"runbookCommit":     runbook.CommitHash,
+	}
+	for key, val := range extraLabels {
+		sessionLabels[key] = val
+	}
+	return models.UpsertSession(models.Session{
+		ID:                   sessionID,
+		OrgID:                ctx.GetOrgID(),
+		Connection:           connection.Name,
+		ConnectionType:       string(proto.ConnectionTypeCustom),
+		ConnectionSubtype:    connection.SubType.String,
+		Verb:                 proto.ClientVerbExec,
+		Labels:               sessionLabels,
+		Metadata:             req.Metadata,
+		IntegrationsMetadata: nil,
+		Metrics:              nil,
+		BlobInput:            models.BlobInputType(runbook.InputFile),
+		UserID:               ctx.UserID,
+		UserName:             ctx.UserName,
+		UserEmail:            ctx.UserEmail,
+		Status:               string(openapi.SessionStatusOpen),
+		ExitCode:             nil,
+		CreatedAt:            time.Now().UTC(),
+		EndSession:           nil,
+	})
+}
+
 func getConnection(ctx pgrest.Context, c *gin.Context, connectionName string) (*models.Connection, error) {
 	conn, err := apiconnections.FetchByName(ctx, connectionName)
 	if err != nil {
@@ -288,20 +361,28 @@ func getRunbookConfig(ctx pgrest.Context, c *gin.Context, connection *models.Con
 		c.JSON(http.StatusNotFound, gin.H{"message": "plugin not found"})
 		return nil, "", fmt.Errorf("plugin not found")
 	}
-	var repoPrefix string
+	runbookConfig, pathPrefix, err := connectionRunbookConfig(p, connection)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+		return nil, pathPrefix, err
+	}
+	return runbookConfig, pathPrefix, nil
+}
+
+// connectionRunbookConfig returns the repository configuration of the runbooks plugin
+// with the revision and the path prefix configured for the connection
+func connectionRunbookConfig(p *types.Plugin, connection *models.Connection) (*templates.RunbookConfig, string, error) {
+	var connConfig connectionConfig
 	hasConnection := false
 	for _, conn := range p.Connections {
 		if conn.ConnectionID == connection.ID {
-			if len(conn.Config) > 0 {
-				repoPrefix = conn.Config[0]
-			}
+			connConfig = parseConnectionConfig(conn.Config)
 			hasConnection = true
 			break
 		}
 	}
 	if !hasConnection {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "plugin is not enabled for this connection"})
-		return nil, repoPrefix, fmt.Errorf("plugin is not enabled for this connection")
+		return nil, connConfig.pathPrefix, fmt.Errorf("plugin is not enabled for this connection")
 	}
 	var configEnvVars map[string]string
 	if p.Config != nil {
@@ -309,8 +390,7 @@ func getRunbookConfig(ctx pgrest.Context, c *gin.Context, connection *models.Con
 	}
 	runbookConfig, err := templates.NewRunbookConfig(configEnvVars)
 	if err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
-		return nil, repoPrefix, err
+		return nil, connConfig.pathPrefix, err
 	}
-	return runbookConfig, repoPrefix, nil
+	return runbookConfig.WithRevision(connConfig.ref, connConfig.commit), connConfig.pathPrefix, nil
 }
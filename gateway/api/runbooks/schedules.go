@@ -0,0 +1,450 @@
+package apirunbooks
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/common/proto"
+	apiconnections "github.com/hoophq/hoop/gateway/api/connections"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/api/runbooks/templates"
+	apivalidation "github.com/hoophq/hoop/gateway/api/validation"
+	"github.com/hoophq/hoop/gateway/clientexec"
+	"github.com/hoophq/hoop/gateway/models"
+	pgplugins "github.com/hoophq/hoop/gateway/pgrest/plugins"
+	pguserauth "github.com/hoophq/hoop/gateway/pgrest/userauth"
+	"github.com/hoophq/hoop/gateway/runbookschedule"
+	"github.com/hoophq/hoop/gateway/storagev2"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+	plugintypes "github.com/hoophq/hoop/gateway/transport/plugins/types"
+)
+
+const (
+	defaultScheduleRunsLimit = 100
+	maxScheduleRunsLimit     = 1000
+)
+
+// ListRunbookSchedules
+//
+//	@Summary		List Runbook Schedules
+//	@Description	List the schedules executing runbooks periodically
+//	@Tags			Runbooks
+//	@Produce		json
+//	@Success		200	{array}		openapi.RunbookSchedule
+//	@Failure		500	{object}	openapi.HTTPError
+//	@Router			/plugins/runbooks/schedules [get]
+func ListSchedules(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	items, err := models.ListRunbookSchedules(ctx.GetOrgID())
+	if err != nil {
+		log.Errorf("failed listing runbook schedules, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed listing runbook schedules"})
+		return
+	}
+	resp := []*openapi.RunbookSchedule{}
+	for _, s := range items {
+		resp = append(resp, toOpenApiSchedule(s))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetRunbookSchedule
+//
+//	@Summary		Get Runbook Schedule
+//	@Description	Get a runbook schedule by its name
+//	@Tags			Runbooks
+//	@Produce		json
+//	@Param			name	path		string	true	"The name of the schedule"
+//	@Success		200		{object}	openapi.RunbookSchedule
+//	@Failure		404,500	{object}	openapi.HTTPError
+//	@Router			/plugins/runbooks/schedules/{name} [get]
+func GetSchedule(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	s, err := models.GetRunbookScheduleByName(ctx.GetOrgID(), c.Param("name"))
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "runbook schedule not found"})
+	case nil:
+		c.JSON(http.StatusOK, toOpenApiSchedule(s))
+	default:
+		log.Errorf("failed fetching runbook schedule, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed fetching runbook schedule"})
+	}
+}
+
+// CreateRunbookSchedule
+//
+//	@Summary		Create Runbook Schedule
+//	@Description	Create a schedule that executes a runbook with a cron expression on behalf of a service account.
+//	@Description	Failed executions are notified through the webhooks and Slack plugins.
+//	@Tags			Runbooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		openapi.RunbookScheduleRequest	true	"The request body resource"
+//	@Success		201				{object}	openapi.RunbookSchedule
+//	@Failure		400,409,422,500	{object}	openapi.HTTPError
+//	@Router			/plugins/runbooks/schedules [post]
+func CreateSchedule(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	req := parseScheduleRequest(c, ctx)
+	if req == nil {
+		return
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+	nextRunAt, err := scheduleNextRunAt(enabled, req.CronExpression, req.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	now := time.Now().UTC()
+	s := &models.RunbookSchedule{
+		OrgID:                 ctx.GetOrgID(),
+		ID:                    uuid.NewString(),
+		Name:                  req.Name,
+		ConnectionName:        req.ConnectionName,
+		RunbookFile:           req.RunbookFile,
+		Parameters:            req.Parameters,
+		CronExpression:        req.CronExpression,
+		Timezone:              req.Timezone,
+		Enabled:               enabled,
+		NextRunAt:             nextRunAt,
+		ServiceAccountSubject: req.ServiceAccount,
+		CreatedAt:             now,
+		UpdatedAt:             now,
+	}
+	err = models.CreateRunbookSchedule(s)
+	switch err {
+	case models.ErrAlreadyExists:
+		c.JSON(http.StatusConflict, gin.H{"message": "runbook schedule already exists"})
+	case models.ErrServiceAccountNotFound:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+	case nil:
+		c.JSON(http.StatusCreated, toOpenApiSchedule(s))
+	default:
+		log.Errorf("failed creating runbook schedule, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed creating runbook schedule"})
+	}
+}
+
+// UpdateRunbookSchedule
+//
+//	@Summary		Update Runbook Schedule
+//	@Description	Update a runbook schedule, the enabled state is kept when it's omitted
+//	@Tags			Runbooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			name			path		string							true	"The name of the schedule"
+//	@Param			request			body		openapi.RunbookScheduleRequest	true	"The request body resource"
+//	@Success		200				{object}	openapi.RunbookSchedule
+//	@Failure		400,404,422,500	{object}	openapi.HTTPError
+//	@Router			/plugins/runbooks/schedules/{name} [put]
+func UpdateSchedule(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	req := parseScheduleRequest(c, ctx)
+	if req == nil {
+		return
+	}
+	s, err := models.GetRunbookScheduleByName(ctx.GetOrgID(), c.Param("name"))
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "runbook schedule not found"})
+		return
+	case nil:
+	default:
+		log.Errorf("failed fetching runbook schedule, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed fetching runbook schedule"})
+		return
+	}
+	if req.Name != s.Name {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "the name of the schedule can't be changed"})
+		return
+	}
+	if req.Enabled != nil {
+		s.Enabled = *req.Enabled
+	}
+	s.NextRunAt, err = scheduleNextRunAt(s.Enabled, req.CronExpression, req.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	s.ConnectionName = req.ConnectionName
+	s.RunbookFile = req.RunbookFile
+	s.Parameters = req.Parameters
+	s.CronExpression = req.CronExpression
+	s.Timezone = req.Timezone
+	s.ServiceAccountSubject = req.ServiceAccount
+	s.UpdatedAt = time.Now().UTC()
+	err = models.UpdateRunbookSchedule(s)
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "runbook schedule not found"})
+	case models.ErrServiceAccountNotFound:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+	case nil:
+		c.JSON(http.StatusOK, toOpenApiSchedule(s))
+	default:
+		log.Errorf("failed updating runbook schedule, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed updating runbook schedule"})
+	}
+}
+
+// EnableRunbookSchedule
+//
+//	@Summary		Enable Runbook Schedule
+//	@Description	Enable the executions of a runbook schedule, the next run is calculated from the current time
+//	@Tags			Runbooks
+//	@Produce		json
+//	@Param			name		path		string	true	"The name of the schedule"
+//	@Success		200			{object}	openapi.RunbookSchedule
+//	@Failure		404,422,500	{object}	openapi.HTTPError
+//	@Router			/plugins/runbooks/schedules/{name}/enable [put]
+func EnableSchedule(c *gin.Context) { toggleSchedule(c, true) }
+
+// DisableRunbookSchedule
+//
+//	@Summary		Disable Runbook Schedule
+//	@Description	Disable the executions of a runbook schedule
+//	@Tags			Runbooks
+//	@Produce		json
+//	@Param			name		path		string	true	"The name of the schedule"
+//	@Success		200			{object}	openapi.RunbookSchedule
+//	@Failure		404,422,500	{object}	openapi.HTTPError
+//	@Router			/plugins/runbooks/schedules/{name}/disable [put]
+func DisableSchedule(c *gin.Context) { toggleSchedule(c, false) }
+
+func toggleSchedule(c *gin.Context, enabled bool) {
+	ctx := storagev2.ParseContext(c)
+	s, err := models.GetRunbookScheduleByName(ctx.GetOrgID(), c.Param("name"))
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "runbook schedule not found"})
+		return
+	case nil:
+	default:
+		log.Errorf("failed fetching runbook schedule, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed fetching runbook schedule"})
+		return
+	}
+	nextRunAt, err := scheduleNextRunAt(enabled, s.CronExpression, s.Timezone)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+		return
+	}
+	if err := models.UpdateRunbookScheduleEnabled(ctx.GetOrgID(), s.Name, enabled, nextRunAt); err != nil {
+		log.Errorf("failed updating runbook schedule, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed updating runbook schedule"})
+		return
+	}
+	s.Enabled = enabled
+	s.NextRunAt = nextRunAt
+	c.JSON(http.StatusOK, toOpenApiSchedule(s))
+}
+
+// DeleteRunbookSchedule
+//
+//	@Summary		Delete Runbook Schedule
+//	@Description	Delete a runbook schedule and its run history
+//	@Tags			Runbooks
+//	@Param			name	path	string	true	"The name of the schedule"
+//	@Success		204
+//	@Failure		404,500	{object}	openapi.HTTPError
+//	@Router			/plugins/runbooks/schedules/{name} [delete]
+func DeleteSchedule(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	err := models.DeleteRunbookSchedule(ctx.GetOrgID(), c.Param("name"))
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "runbook schedule not found"})
+	case nil:
+		c.Writer.WriteHeader(http.StatusNoContent)
+	default:
+		log.Errorf("failed removing runbook schedule, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed removing runbook schedule"})
+	}
+}
+
+// ListRunbookScheduleRuns
+//
+//	@Summary		List Runbook Schedule Runs
+//	@Description	List the latest executions of a runbook schedule
+//	@Tags			Runbooks
+//	@Produce		json
+//	@Param			name		path		string	true	"The name of the schedule"
+//	@Param			limit		query		int		false	"The maximum number of runs (default 100, max 1000)"
+//	@Success		200			{array}		openapi.RunbookScheduleRun
+//	@Failure		400,404,500	{object}	openapi.HTTPError
+//	@Router			/plugins/runbooks/schedules/{name}/runs [get]
+func ListScheduleRuns(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	limit := defaultScheduleRunsLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxScheduleRunsLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("limit must be a number between 1 and %v", maxScheduleRunsLimit)})
+			return
+		}
+	}
+	s, err := models.GetRunbookScheduleByName(ctx.GetOrgID(), c.Param("name"))
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "runbook schedule not found"})
+		return
+	case nil:
+	default:
+		log.Errorf("failed fetching runbook schedule, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed fetching runbook schedule"})
+		return
+	}
+	runs, err := models.ListRunbookScheduleRuns(ctx.GetOrgID(), s.ID, limit)
+	if err != nil {
+		log.Errorf("failed listing runbook schedule runs, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed listing runbook schedule runs"})
+		return
+	}
+	resp := []openapi.RunbookScheduleRun{}
+	for _, r := range runs {
+		resp = append(resp, openapi.RunbookScheduleRun{
+			ID:          r.ID,
+			SessionID:   r.SessionID,
+			Status:      r.Status,
+			ExitCode:    r.ExitCode,
+			Error:       r.Error,
+			ScheduledAt: r.ScheduledAt,
+			StartedAt:   r.StartedAt,
+			FinishedAt:  r.FinishedAt,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ExecSchedule executes the runbook of the schedule on behalf of its service account
+// with the same flow of the runbook exec api. It blocks until the execution finishes.
+func ExecSchedule(sched *models.RunbookSchedule) (*clientexec.Response, error) {
+	userCtx, err := pguserauth.New().FetchUserContext(sched.ServiceAccountSubject)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching service account: %v", err)
+	}
+	if userCtx.IsEmpty() {
+		return nil, models.ErrServiceAccountNotFound
+	}
+	if userCtx.UserStatus != string(types.UserStatusActive) {
+		return nil, fmt.Errorf("service account %v is not active", sched.ServiceAccountSubject)
+	}
+	ctx := storagev2.NewContext(userCtx.UserUUID, userCtx.OrgID).
+		WithUserInfo(userCtx.UserName, userCtx.UserEmail, userCtx.UserStatus, "", userCtx.UserGroups)
+	connection, err := apiconnections.FetchByName(ctx, sched.ConnectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching connection: %v", err)
+	}
+	if connection == nil {
+		return nil, fmt.Errorf("connection %v not found", sched.ConnectionName)
+	}
+	p, err := pgplugins.New().FetchOne(ctx, plugintypes.PluginRunbooksName)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching runbooks plugin: %v", err)
+	}
+	if p == nil {
+		return nil, fmt.Errorf("runbooks plugin not found")
+	}
+	config, pathPrefix, err := connectionRunbookConfig(p, connection)
+	if err != nil {
+		return nil, err
+	}
+	if pathPrefix != "" && !strings.HasPrefix(sched.RunbookFile, pathPrefix) {
+		return nil, fmt.Errorf("runbook file %v not found", sched.RunbookFile)
+	}
+	req := openapi.RunbookRequest{FileName: sched.RunbookFile, Parameters: sched.Parameters}
+	runbook, err := prepareRunbook(ctx.GetOrgID(), config, req)
+	if err != nil {
+		return nil, err
+	}
+	sessionID := uuid.NewString()
+	client, err := clientexec.New(&clientexec.Options{
+		OrgID:                 ctx.GetOrgID(),
+		SessionID:             sessionID,
+		ConnectionName:        connection.Name,
+		UserAgent:             "runbook.scheduler",
+		Origin:                proto.ConnectionOriginClientAPIRunbooks,
+		ServiceAccountSubject: sched.ServiceAccountSubject,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	err = createRunbookSession(ctx, connection, sessionID, runbook, req, types.SessionLabels{
+		"runbookScheduleID":   sched.ID,
+		"runbookScheduleName": sched.Name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed persisting session: %v", err)
+	}
+	log.With("sid", sessionID, "schedule", sched.Name).Infof("runbook exec, commit=%s, name=%s, connection=%s",
+		runbook.CommitHash[:8], sched.RunbookFile, connection.Name)
+	return client.Run(runbook.InputFile, runbook.EnvVars), nil
+}
+
+func parseScheduleRequest(c *gin.Context, ctx *storagev2.Context) *openapi.RunbookScheduleRequest {
+	var req openapi.RunbookScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return nil
+	}
+	if err := apivalidation.ValidateResourceName(req.Name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return nil
+	}
+	if !templates.IsRunbookFile(req.RunbookFile) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("runbook_file: %v is not a runbook file", req.RunbookFile)})
+		return nil
+	}
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	conn, err := apiconnections.FetchByName(ctx, req.ConnectionName)
+	if err != nil {
+		log.Errorf("failed fetching connection, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed fetching connection"})
+		return nil
+	}
+	if conn == nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": fmt.Sprintf("connection %v not found", req.ConnectionName)})
+		return nil
+	}
+	return &req
+}
+
+// scheduleNextRunAt validates the cron expression and returns its next activation,
+// disabled schedules don't have a next run
+func scheduleNextRunAt(enabled bool, cronExpr, timezone string) (*time.Time, error) {
+	nextRunAt, err := runbookschedule.NextRun(cronExpr, timezone, time.Now().UTC())
+	if err != nil || !enabled {
+		return nil, err
+	}
+	return &nextRunAt, nil
+}
+
+func toOpenApiSchedule(s *models.RunbookSchedule) *openapi.RunbookSchedule {
+	return &openapi.RunbookSchedule{
+		ID:                 s.ID,
+		Name:               s.Name,
+		ConnectionName:     s.ConnectionName,
+		RunbookFile:        s.RunbookFile,
+		Parameters:         s.Parameters,
+		CronExpression:     s.CronExpression,
+		Timezone:           s.Timezone,
+		ServiceAccount:     s.ServiceAccountSubject,
+		ServiceAccountName: s.ServiceAccountName,
+		Enabled:            s.Enabled,
+		NextRunAt:          s.NextRunAt,
+		LastRunAt:          s.LastRunAt,
+		CreatedAt:          s.CreatedAt,
+		UpdatedAt:          s.UpdatedAt,
+	}
+}
@@ -0,0 +1,125 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hoophq/hoop/common/log"
+)
+
+// defaultRefreshInterval is the maximum age of a cached repository,
+// after that it's refreshed in background while the cached commit is still served
+const defaultRefreshInterval = 5 * time.Minute
+
+// cacheKey identifies the revision of the runbooks repository of an organization
+type cacheKey struct {
+	orgID  string
+	ref    string
+	commit string
+}
+
+type cachedRepository struct {
+	gitURL     string
+	revision   *Revision
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+// repositoryCache keeps the last fetched commit of each revision of the runbooks repository of the organizations
+type repositoryCache struct {
+	mu              sync.Mutex
+	items           map[cacheKey]*cachedRepository
+	refreshInterval time.Duration
+	fetchFn         func(*RunbookConfig) (*Revision, error)
+}
+
+var repoCache = newRepositoryCache(defaultRefreshInterval, FetchRepo)
+
+func newRepositoryCache(refreshInterval time.Duration, fetchFn func(*RunbookConfig) (*Revision, error)) *repositoryCache {
+	return &repositoryCache{
+		items:           map[cacheKey]*cachedRepository{},
+		refreshInterval: refreshInterval,
+		fetchFn:         fetchFn,
+	}
+}
+
+// FetchRepoCached returns the cached revision of the runbooks repository of the organization.
+// The repository is fetched when it's not cached or when the git url has changed,
+// a stale commit triggers a refresh in background and it's served until the refresh succeeds.
+// Pinned commits are immutable and never refreshed.
+func FetchRepoCached(orgID string, config *RunbookConfig) (*Revision, error) {
+	return repoCache.get(orgID, config)
+}
+
+// RefreshRepos fetches the cached revisions of the organization that are changed by the pushed ref
+// and replaces their cached commit. In case of failure the existing cached commits are kept.
+func RefreshRepos(orgID string, config *RunbookConfig, pushedRef string) error {
+	return repoCache.refreshPushedRef(orgID, config, pushedRef)
+}
+
+func (c *repositoryCache) get(orgID string, config *RunbookConfig) (*Revision, error) {
+	key := cacheKey{orgID: orgID, ref: config.Ref, commit: config.Commit}
+	c.mu.Lock()
+	item, ok := c.items[key]
+	if !ok || item.gitURL != config.GitURL {
+		c.mu.Unlock()
+		return c.refresh(key, config)
+	}
+	if key.commit == "" && time.Since(item.fetchedAt) > c.refreshInterval && !item.refreshing {
+		item.refreshing = true
+		go func() {
+			if _, err := c.refresh(key, config); err != nil {
+				log.With("org", orgID).Warnf("failed refreshing runbooks repository, serving commit %v, reason=%v",
+					item.revision.Hash.String(), err)
+			}
+		}()
+	}
+	revision := item.revision
+	c.mu.Unlock()
+	return revision, nil
+}
+
+func (c *repositoryCache) refreshPushedRef(orgID string, config *RunbookConfig, pushedRef string) error {
+	var keys []cacheKey
+	c.mu.Lock()
+	for key := range c.items {
+		if key.orgID == orgID && key.commit == "" && key.ref != "" && refMatches(key.ref, pushedRef) {
+			keys = append(keys, key)
+		}
+	}
+	c.mu.Unlock()
+	// the default branch is always refreshed to have it ready in the next request
+	if refMatches("", pushedRef) {
+		keys = append(keys, cacheKey{orgID: orgID})
+	}
+	var errs []string
+	for _, key := range keys {
+		if _, err := c.refresh(key, config.WithRevision(key.ref, "")); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (c *repositoryCache) refresh(key cacheKey, config *RunbookConfig) (*Revision, error) {
+	revision, err := c.fetchFn(config)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		if item, ok := c.items[key]; ok {
+			item.refreshing = false
+		}
+		return nil, err
+	}
+	c.items[key] = &cachedRepository{
+		gitURL:    config.GitURL,
+		revision:  revision,
+		fetchedAt: time.Now().UTC(),
+	}
+	return revision, nil
+}
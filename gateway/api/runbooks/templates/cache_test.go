@@ -0,0 +1,167 @@
+package templates
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRemote struct {
+	mu      sync.Mutex
+	calls   chan *RunbookConfig
+	commits map[string]string
+	err     error
+}
+
+func newFakeRemote(commits map[string]string) *fakeRemote {
+	return &fakeRemote{calls: make(chan *RunbookConfig, 10), commits: commits}
+}
+
+func (r *fakeRemote) set(ref, hash string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commits[ref] = hash
+	r.err = err
+}
+
+func (r *fakeRemote) fetch(config *RunbookConfig) (*Revision, error) {
+	defer func() { r.calls <- config }()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	hash := r.commits[config.Ref]
+	if config.Commit != "" {
+		hash = config.Commit
+	}
+	return &Revision{Ref: config.Ref, Commit: &object.Commit{Hash: plumbing.NewHash(hash)}}, nil
+}
+
+const (
+	commitA = "a000000000000000000000000000000000000000"
+	commitB = "b000000000000000000000000000000000000000"
+	commitC = "c000000000000000000000000000000000000000"
+)
+
+func TestRepositoryCache(t *testing.T) {
+	config := &RunbookConfig{GitURL: "https://github.com/acme/runbooks"}
+
+	t.Run("it should serve the cached commit without fetching the remote", func(t *testing.T) {
+		remote := newFakeRemote(map[string]string{"": commitA})
+		cache := newRepositoryCache(time.Hour, remote.fetch)
+		for i := 0; i < 3; i++ {
+			rev, err := cache.get("org", config)
+			require.NoError(t, err)
+			assert.Equal(t, commitA, rev.Hash.String())
+		}
+		assert.Len(t, remote.calls, 1)
+	})
+
+	t.Run("it should fetch the remote when the git url changes", func(t *testing.T) {
+		remote := newFakeRemote(map[string]string{"": commitA})
+		cache := newRepositoryCache(time.Hour, remote.fetch)
+		_, err := cache.get("org", config)
+		require.NoError(t, err)
+		remote.set("", commitB, nil)
+		rev, err := cache.get("org", &RunbookConfig{GitURL: "https://github.com/acme/other"})
+		require.NoError(t, err)
+		assert.Equal(t, commitB, rev.Hash.String())
+		assert.Len(t, remote.calls, 2)
+	})
+
+	t.Run("it should cache each revision separately", func(t *testing.T) {
+		remote := newFakeRemote(map[string]string{"": commitA, "release": commitB})
+		cache := newRepositoryCache(time.Hour, remote.fetch)
+		rev, err := cache.get("org", config)
+		require.NoError(t, err)
+		assert.Equal(t, commitA, rev.Hash.String())
+		rev, err = cache.get("org", config.WithRevision("release", ""))
+		require.NoError(t, err)
+		assert.Equal(t, commitB, rev.Hash.String())
+		rev, err = cache.get("org", config.WithRevision("release", commitC))
+		require.NoError(t, err)
+		assert.Equal(t, commitC, rev.Hash.String())
+		assert.Len(t, remote.calls, 3)
+	})
+
+	t.Run("it should serve the stale commit and refresh it in background", func(t *testing.T) {
+		remote := newFakeRemote(map[string]string{"": commitA})
+		cache := newRepositoryCache(0, remote.fetch)
+		_, err := cache.get("org", config)
+		require.NoError(t, err)
+		<-remote.calls
+
+		remote.set("", commitB, nil)
+		rev, err := cache.get("org", config)
+		require.NoError(t, err)
+		assert.Equal(t, commitA, rev.Hash.String())
+		assert.Eventually(t, func() bool {
+			cache.mu.Lock()
+			defer cache.mu.Unlock()
+			return cache.items[cacheKey{orgID: "org"}].revision.Hash.String() == commitB
+		}, time.Second*5, time.Millisecond*10)
+	})
+
+	t.Run("it should not refresh pinned commits", func(t *testing.T) {
+		remote := newFakeRemote(map[string]string{})
+		cache := newRepositoryCache(0, remote.fetch)
+		for i := 0; i < 3; i++ {
+			rev, err := cache.get("org", config.WithRevision("", commitC))
+			require.NoError(t, err)
+			assert.Equal(t, commitC, rev.Hash.String())
+		}
+		assert.Len(t, remote.calls, 1)
+	})
+
+	t.Run("it should keep serving the cached commit when the refresh fails", func(t *testing.T) {
+		remote := newFakeRemote(map[string]string{"": commitA})
+		cache := newRepositoryCache(time.Hour, remote.fetch)
+		_, err := cache.get("org", config)
+		require.NoError(t, err)
+
+		remote.set("", "", fmt.Errorf("remote unavailable"))
+		assert.Error(t, cache.refreshPushedRef("org", config, "refs/heads/main"))
+		rev, err := cache.get("org", config)
+		require.NoError(t, err)
+		assert.Equal(t, commitA, rev.Hash.String())
+	})
+
+	t.Run("it should return the error when there is no cached commit", func(t *testing.T) {
+		remote := newFakeRemote(map[string]string{})
+		remote.set("", "", fmt.Errorf("remote unavailable"))
+		cache := newRepositoryCache(time.Hour, remote.fetch)
+		_, err := cache.get("org", config)
+		assert.EqualError(t, err, "remote unavailable")
+	})
+
+	t.Run("it should refresh the revisions tracking the pushed ref", func(t *testing.T) {
+		remote := newFakeRemote(map[string]string{"": commitA, "release": commitA, "v1": commitA})
+		cache := newRepositoryCache(time.Hour, remote.fetch)
+		for _, ref := range []string{"", "release", "v1"} {
+			_, err := cache.get("org", config.WithRevision(ref, ""))
+			require.NoError(t, err)
+		}
+		_, err := cache.get("org", config.WithRevision("release", commitC))
+		require.NoError(t, err)
+
+		remote.set("release", commitB, nil)
+		require.NoError(t, cache.refreshPushedRef("org", config, "refs/heads/release"))
+		for ref, want := range map[string]string{"": commitA, "release": commitB, "v1": commitA} {
+			rev, err := cache.get("org", config.WithRevision(ref, ""))
+			require.NoError(t, err)
+			assert.Equal(t, want, rev.Hash.String(), "ref=%v", ref)
+		}
+		rev, err := cache.get("org", config.WithRevision("release", commitC))
+		require.NoError(t, err)
+		assert.Equal(t, commitC, rev.Hash.String())
+		// 4 initial fetches and the refresh of the release branch
+		assert.Len(t, remote.calls, 5)
+	})
+}
@@ -17,6 +17,18 @@ import (
 type RunbookConfig struct {
 	GitURL string
 	Auth   transport.AuthMethod
+	// Ref is the branch or tag to serve the runbooks from, it defaults to main or master
+	Ref string
+	// Commit pins the runbooks to a specific commit sha
+	Commit string
+}
+
+// WithRevision returns a copy of the configuration serving the runbooks from the ref and commit
+func (c *RunbookConfig) WithRevision(ref, commit string) *RunbookConfig {
+	newConfig := *c
+	newConfig.Ref = ref
+	newConfig.Commit = commit
+	return &newConfig
 }
 
 var sshKeyScanKnownHostsContent string
@@ -118,7 +130,7 @@ func NewRunbookConfig(envVars map[string]string) (*RunbookConfig, error) {
 		// It uses a custom callback function instead of relying in the known hosts
 		// file from the filesystem.
 		auth.HostKeyCallback = trustedHostKeyCallback(knownHosts)
-		return &RunbookConfig{gitURL, auth}, nil
+		return &RunbookConfig{GitURL: gitURL, Auth: auth}, nil
 	case gitPasswordEnc != "":
 		gitPassword, err := base64.StdEncoding.DecodeString(gitPasswordEnc)
 		if err != nil {
@@ -2,6 +2,7 @@ package templates
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/go-git/go-git/v5"
@@ -11,19 +12,38 @@ import (
 	"github.com/go-git/go-git/v5/storage/memory"
 )
 
-func FetchRepo(rbConfig *RunbookConfig) (*object.Commit, error) {
+// pinnedRefName is the local reference of commits fetched by their sha
+const pinnedRefName = "refs/hoop/pinned"
+
+// Revision is the commit the runbooks are served from
+type Revision struct {
+	// Ref is the full name of the resolved reference, e.g.: refs/heads/main, refs/tags/v1.0.0.
+	// Pinned commits keep the configured ref as it is, it's empty when there's no configured ref
+	Ref string
+	*object.Commit
+}
+
+// FetchRepo fetches the commit of the configured revision, the precedence is:
+// the pinned commit, the configured branch or tag and the main or master branch.
+func FetchRepo(rbConfig *RunbookConfig) (*Revision, error) {
 	r, err := git.Init(memory.NewStorage(), memfs.New())
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = r.CreateRemote(&config.RemoteConfig{
+	remote, err := r.CreateRemote(&config.RemoteConfig{
 		Name: "origin",
 		URLs: []string{rbConfig.GitURL},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed creating remote, err=%v", err)
 	}
+	switch {
+	case rbConfig.Commit != "":
+		return fetchCommit(r, rbConfig)
+	case rbConfig.Ref != "":
+		return fetchRef(r, remote, rbConfig)
+	}
 	err = r.Fetch(&git.FetchOptions{
 		RemoteURL:  rbConfig.GitURL,
 		Auth:       rbConfig.Auth,
@@ -58,7 +78,107 @@ func FetchRepo(rbConfig *RunbookConfig) (*object.Commit, error) {
 		return nil
 	})
 	if resRef != nil {
-		return r.CommitObject(resRef.Hash())
+		commit, err := r.CommitObject(resRef.Hash())
+		if err != nil {
+			return nil, err
+		}
+		branchName := strings.TrimPrefix(resRef.Name().String(), "refs/remotes/origin/")
+		return &Revision{Ref: "refs/heads/" + branchName, Commit: commit}, nil
 	}
 	return nil, fmt.Errorf("master or main ref not found. refs=%v", refList)
 }
+
+// fetchRef resolves the configured branch or tag against the remote references
+// and fetches only the resolved reference
+func fetchRef(r *git.Repository, remote *git.Remote, rbConfig *RunbookConfig) (*Revision, error) {
+	remoteRefs, err := remote.List(&git.ListOptions{Auth: rbConfig.Auth})
+	if err != nil {
+		return nil, fmt.Errorf("failed listing remote references of %v, err=%v", rbConfig.GitURL, err)
+	}
+	refName := resolveRefName(rbConfig.Ref, remoteRefs)
+	if refName == "" {
+		return nil, fmt.Errorf("git reference %q not found in %v", rbConfig.Ref, rbConfig.GitURL)
+	}
+	err = r.Fetch(&git.FetchOptions{
+		RemoteURL:  rbConfig.GitURL,
+		Auth:       rbConfig.Auth,
+		RemoteName: "origin",
+		Tags:       git.NoTags,
+		Depth:      1,
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", refName, refName))},
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, fmt.Errorf("failed pulling %v from repo %v, err=%v", refName, rbConfig.GitURL, err)
+	}
+	ref, err := r.Reference(refName, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting reference %v, err=%v", refName, err)
+	}
+	commit, err := r.CommitObject(ref.Hash())
+	if err == plumbing.ErrObjectNotFound {
+		// annotated tags point to a tag object
+		var tag *object.Tag
+		if tag, err = r.TagObject(ref.Hash()); err == nil {
+			commit, err = tag.Commit()
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed getting commit of %v, err=%v", refName, err)
+	}
+	return &Revision{Ref: refName.String(), Commit: commit}, nil
+}
+
+// fetchCommit fetches the pinned commit by its sha,
+// it requires the remote to allow fetching reachable commits (e.g.: GitHub and GitLab)
+func fetchCommit(r *git.Repository, rbConfig *RunbookConfig) (*Revision, error) {
+	if !plumbing.IsHash(rbConfig.Commit) {
+		return nil, fmt.Errorf("invalid commit sha %q, it must be the full sha of the commit", rbConfig.Commit)
+	}
+	err := r.Fetch(&git.FetchOptions{
+		RemoteURL:  rbConfig.GitURL,
+		Auth:       rbConfig.Auth,
+		RemoteName: "origin",
+		Tags:       git.NoTags,
+		Depth:      1,
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("%s:%s", rbConfig.Commit, pinnedRefName))},
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, fmt.Errorf("failed pulling commit %v from repo %v, err=%v", rbConfig.Commit, rbConfig.GitURL, err)
+	}
+	commit, err := r.CommitObject(plumbing.NewHash(rbConfig.Commit))
+	if err != nil {
+		return nil, fmt.Errorf("failed getting commit %v, err=%v", rbConfig.Commit, err)
+	}
+	// the ref is informative for pinned commits, it's not resolved against the remote
+	return &Revision{Ref: rbConfig.Ref, Commit: commit}, nil
+}
+
+// resolveRefName returns the full name of the remote reference matching the ref,
+// short names are resolved as branches first and then as tags
+func resolveRefName(ref string, remoteRefs []*plumbing.Reference) plumbing.ReferenceName {
+	candidates := []plumbing.ReferenceName{plumbing.ReferenceName(ref)}
+	if !strings.HasPrefix(ref, "refs/") {
+		candidates = []plumbing.ReferenceName{plumbing.NewBranchReferenceName(ref), plumbing.NewTagReferenceName(ref)}
+	}
+	for _, candidate := range candidates {
+		for _, remoteRef := range remoteRefs {
+			if remoteRef.Name() == candidate {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// refMatches reports if a pushed reference changes the revision of the configured ref,
+// an empty ref matches the main and master branches.
+func refMatches(configuredRef, pushedRef string) bool {
+	switch {
+	case configuredRef == "":
+		return pushedRef == "refs/heads/main" || pushedRef == "refs/heads/master"
+	case strings.HasPrefix(configuredRef, "refs/"):
+		return configuredRef == pushedRef
+	}
+	return pushedRef == plumbing.NewBranchReferenceName(configuredRef).String() ||
+		pushedRef == plumbing.NewTagReferenceName(configuredRef).String()
+}
@@ -0,0 +1,53 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveRefName(t *testing.T) {
+	hash := plumbing.NewHash(commitA)
+	remoteRefs := []*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/main", hash),
+		plumbing.NewHashReference("refs/heads/release", hash),
+		plumbing.NewHashReference("refs/tags/release", hash),
+		plumbing.NewHashReference("refs/tags/v1.0.0", hash),
+	}
+	for _, tt := range []struct {
+		ref  string
+		want plumbing.ReferenceName
+	}{
+		{ref: "main", want: "refs/heads/main"},
+		{ref: "v1.0.0", want: "refs/tags/v1.0.0"},
+		{ref: "release", want: "refs/heads/release"},
+		{ref: "refs/tags/release", want: "refs/tags/release"},
+		{ref: "unknown", want: ""},
+		{ref: "refs/heads/v1.0.0", want: ""},
+	} {
+		t.Run(tt.ref, func(t *testing.T) {
+			assert.Equal(t, tt.want, resolveRefName(tt.ref, remoteRefs))
+		})
+	}
+}
+
+func TestRefMatches(t *testing.T) {
+	for _, tt := range []struct {
+		configuredRef string
+		pushedRef     string
+		want          bool
+	}{
+		{configuredRef: "", pushedRef: "refs/heads/main", want: true},
+		{configuredRef: "", pushedRef: "refs/heads/master", want: true},
+		{configuredRef: "", pushedRef: "refs/heads/feature", want: false},
+		{configuredRef: "release", pushedRef: "refs/heads/release", want: true},
+		{configuredRef: "v1.0.0", pushedRef: "refs/tags/v1.0.0", want: true},
+		{configuredRef: "refs/tags/v1.0.0", pushedRef: "refs/heads/v1.0.0", want: false},
+		{configuredRef: "release", pushedRef: "refs/heads/main", want: false},
+	} {
+		t.Run(tt.configuredRef+"="+tt.pushedRef, func(t *testing.T) {
+			assert.Equal(t, tt.want, refMatches(tt.configuredRef, tt.pushedRef))
+		})
+	}
+}
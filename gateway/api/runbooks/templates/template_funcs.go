@@ -49,6 +49,8 @@ func defaultStaticTemplateFuncs() ttemplate.FuncMap {
 			}
 			return v[len(v)-1]
 		},
+		// the options are resolved by the api running the query against the connection
+		"optionsfrom": func(_, _, s string) string { return s },
 		"description": func(_, s string) string { return s },
 		"type":        func(_, s string) string { return s },
 		"squote":      func(s string) string { return fmt.Sprintf(`'%s'`, s) },
@@ -41,6 +41,9 @@ func (t *template) Execute(wr io.Writer, inputs map[string]string) error {
 	if len(missingKeys) > 0 {
 		return fmt.Errorf("the following inputs are missing %v", missingKeys)
 	}
+	if err := validateInputs(t.attributes, inputs); err != nil {
+		return err
+	}
 	return t.textTmpl.Execute(wr, execInputs)
 }
 
@@ -81,7 +84,7 @@ func parseSpecFromTemplate(node parse.Node, into map[string]any) error {
 		}
 		inputKey := strings.TrimSpace(findings[0])
 		inputKey = inputKey[3:] // remove prefix {{.
-		into[inputKey] = parseNode(node.String())
+		into[inputKey] = parseNode(node.(*parse.ActionNode))
 	}
 	if ln, ok := node.(*parse.ListNode); ok {
 		for _, n := range ln.Nodes {
@@ -93,28 +96,26 @@ func parseSpecFromTemplate(node parse.Node, into map[string]any) error {
 	return nil
 }
 
-// parseNode parse a node structure
+// parseNode parse the pipeline of an action node
 // {{ .mykey | myfn "arg" | myfn02 | myfn03 }}
-func parseNode(node string) map[string]any {
+func parseNode(node *parse.ActionNode) map[string]any {
 	specs := map[string]any{
 		"type":        "text",
 		"required":    false,
 		"description": "",
 	}
-
-	// strip start/end template
-	node = node[2:]
-	node = node[:len(node)-2]
-	parts := strings.Split(node, " | ")
-	for _, section := range parts {
-		sectionsParts := strings.Split(section, " ")
-		fnName := sectionsParts[0]
-		fnVal := ""
-		if len(sectionsParts) > 1 {
-			fnVal = strings.TrimSpace(
-				strings.Join(sectionsParts[1:], " "))
-			fnVal = strings.ReplaceAll(fnVal, `"`, "")
+	for _, cmd := range node.Pipe.Cmds {
+		ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+		if !ok {
+			continue
 		}
+		var fnArgs []string
+		for _, arg := range cmd.Args[1:] {
+			if strNode, ok := arg.(*parse.StringNode); ok {
+				fnArgs = append(fnArgs, strNode.Text)
+			}
+		}
+		fnName, fnVal := ident.Ident, strings.Join(fnArgs, " ")
 		switch fnName {
 		case "type":
 			for _, key := range defaultInputTypes {
@@ -128,7 +129,11 @@ func parseNode(node string) map[string]any {
 		case "description", "default", "placeholder":
 			specs[fnName] = fnVal
 		case "options":
-			specs[fnName] = strings.Split(fnVal, " ")
+			specs[fnName] = fnArgs
+		case "optionsfrom":
+			if len(fnArgs) == 2 {
+				specs[fnName] = map[string]any{"connection": fnArgs[0], "query": fnArgs[1]}
+			}
 		case "asenv":
 			specs[fnName] = fnVal
 		}
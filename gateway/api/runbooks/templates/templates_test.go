@@ -136,6 +136,26 @@ func TestTemplateParseAttributes(t *testing.T) {
 				},
 			},
 		},
+		{
+			msg: "it should match options with spaces and the optionsfrom attribute",
+			tmpl: `hero = {{ .hero | options "Peter Parker" "Tony Stark" }}, plan = {{ .plan
+											| optionsfrom "pgdemo" "SELECT name FROM plans"
+											| type "select" }}`,
+			wantAttrs: map[string]any{
+				"hero": map[string]any{
+					"description": "",
+					"required":    false,
+					"type":        "text",
+					"options":     []string{"Peter Parker", "Tony Stark"},
+				},
+				"plan": map[string]any{
+					"description": "",
+					"required":    false,
+					"type":        "select",
+					"optionsfrom": map[string]any{"connection": "pgdemo", "query": "SELECT name FROM plans"},
+				},
+			},
+		},
 	} {
 		t.Run(tt.msg, func(t *testing.T) {
 			tmpl, err := Parse(tt.tmpl)
@@ -218,6 +238,18 @@ func TestTemplateParseHelperFuncs(t *testing.T) {
 			inputs:  map[string]string{"wallet_id": "abc1234567890"},
 			execErr: fmt.Errorf("pattern didn't match:^[0-9]+"),
 		},
+		{
+			msg:      "it should render the template when using optionsfrom function",
+			tmpl:     `plan = {{ .plan | optionsfrom "pgdemo" "SELECT name FROM plans" | type "select" }}`,
+			wantTmpl: `plan = pro`,
+			inputs:   map[string]string{"plan": "pro"},
+		},
+		{
+			msg:     "it should fail when the input is not one of the options",
+			tmpl:    `type = {{ .type | options "house" "car" "boat" }}`,
+			inputs:  map[string]string{"type": "plane"},
+			execErr: fmt.Errorf(`invalid input "type": it must be one of [house car boat]`),
+		},
 		{
 			msg:     "it should return error if required attribute is empty",
 			tmpl:    `SELECT id, firstname, lastname FROM customers WHERE id = {{ .id | required "id is required" }}`,
@@ -244,6 +276,44 @@ func TestTemplateParseHelperFuncs(t *testing.T) {
 	}
 }
 
+func TestTemplateValidateInputTypes(t *testing.T) {
+	for _, tt := range []struct {
+		msg       string
+		inputType string
+		val       string
+		wantErr   bool
+	}{
+		{msg: "it should accept an integer", inputType: "number", val: "42"},
+		{msg: "it should accept a decimal number", inputType: "number", val: "-10.4"},
+		{msg: "it should reject an invalid number", inputType: "number", val: "10,4", wantErr: true},
+		{msg: "it should accept a date", inputType: "date", val: "2024-02-29"},
+		{msg: "it should reject an invalid date", inputType: "date", val: "2023-02-29", wantErr: true},
+		{msg: "it should reject a date in another format", inputType: "date", val: "02/10/2024", wantErr: true},
+		{msg: "it should accept a time without seconds", inputType: "time", val: "09:30"},
+		{msg: "it should accept a time with seconds", inputType: "time", val: "23:59:59"},
+		{msg: "it should reject an invalid time", inputType: "time", val: "25:00", wantErr: true},
+		{msg: "it should accept an email", inputType: "email", val: "john.wick@bad.org"},
+		{msg: "it should reject an email with name", inputType: "email", val: "John <john.wick@bad.org>", wantErr: true},
+		{msg: "it should reject an invalid email", inputType: "email", val: "john.wick", wantErr: true},
+		{msg: "it should accept an url", inputType: "url", val: "https://api.foo.tld/v1"},
+		{msg: "it should reject a relative url", inputType: "url", val: "/v1/users", wantErr: true},
+		{msg: "it should accept a phone number", inputType: "tel", val: "+55 (11) 99999-0000"},
+		{msg: "it should reject an invalid phone number", inputType: "tel", val: "call me", wantErr: true},
+		{msg: "it should accept any value for text types", inputType: "text", val: "10,4"},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			tmpl, err := Parse(fmt.Sprintf(`val = {{ .val | type %q }}`, tt.inputType))
+			if err != nil {
+				t.Fatalf("parse error=%v", err)
+			}
+			err = tmpl.Execute(bytes.NewBufferString(""), map[string]string{"val": tt.val})
+			if tt.wantErr != (err != nil) {
+				t.Errorf("validation error mismatch, want-err=%v, got=%v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestIsRunbookFile(t *testing.T) {
 	for _, tt := range []struct {
 		msg        string
@@ -0,0 +1,77 @@
+package templates
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var regexpTelInput = regexp.MustCompile(`^\+?[0-9][0-9 ().-]{2,}$`)
+
+// validateInputs enforces the type and the static options of each attribute
+// parsed from the template. Empty values are validated by the required function.
+// The options resolved from optionsfrom are advisory, they are only used to
+// populate the inputs in the clients and the submitted values are not checked against them.
+func validateInputs(attributes map[string]any, inputs map[string]string) error {
+	inputKeys := make([]string, 0, len(inputs))
+	for key := range inputs {
+		inputKeys = append(inputKeys, key)
+	}
+	sort.Strings(inputKeys)
+	for _, key := range inputKeys {
+		val := inputs[key]
+		metadata, _ := attributes[key].(map[string]any)
+		if metadata == nil || val == "" {
+			continue
+		}
+		inputType, _ := metadata["type"].(string)
+		if err := validateInputType(inputType, val); err != nil {
+			return fmt.Errorf("invalid input %q: %v", key, err)
+		}
+		if options, _ := metadata["options"].([]string); len(options) > 0 && !slices.Contains(options, val) {
+			return fmt.Errorf("invalid input %q: it must be one of %v", key, options)
+		}
+	}
+	return nil
+}
+
+func validateInputType(inputType, val string) error {
+	switch inputType {
+	case "number":
+		if _, err := strconv.ParseFloat(val, 64); err != nil {
+			return fmt.Errorf("it must be a number")
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, val); err != nil {
+			return fmt.Errorf("it must be a date in the format YYYY-MM-DD")
+		}
+	case "time":
+		_, err := time.Parse("15:04", val)
+		if err != nil {
+			_, err = time.Parse(time.TimeOnly, val)
+		}
+		if err != nil {
+			return fmt.Errorf("it must be a time in the format HH:MM or HH:MM:SS")
+		}
+	case "email":
+		addr, err := mail.ParseAddress(val)
+		if err != nil || addr.Address != val {
+			return fmt.Errorf("it must be an email address")
+		}
+	case "url":
+		u, err := url.ParseRequestURI(val)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("it must be an absolute url")
+		}
+	case "tel":
+		if !regexpTelInput.MatchString(val) {
+			return fmt.Errorf("it must be a phone number")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	gotemplate "text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+var workflowStepNameRe = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// Workflow is a manifest describing ordered runbook executions across connections.
+//
+//	description: Create a database user and notify the team
+//	steps:
+//	  - name: create_user
+//	    connection: pgdemo
+//	    runbook: ops/create-user.runbook.sql
+//	    parameters:
+//	      username: "{{ .inputs.username }}"
+//	  - name: notify
+//	    connection: bash
+//	    runbook: ops/notify.runbook.sh
+//	    continue_on_error: true
+//	    parameters:
+//	      message: "user created: {{ .steps.create_user.output }}"
+//
+// The parameters of each step are templates rendered with the inputs of the workflow
+// and the output (trimmed) and exit code of the previous steps.
+type Workflow struct {
+	Description string         `yaml:"description" json:"description"`
+	Steps       []WorkflowStep `yaml:"steps" json:"steps"`
+}
+
+type WorkflowStep struct {
+	// Name identifies the step, the outputs of the step are available as .steps.<name>
+	Name string `yaml:"name" json:"name"`
+	// Connection is the name of the connection to execute the runbook
+	Connection string `yaml:"connection" json:"connection"`
+	// Runbook is the path of the runbook file in the repository
+	Runbook string `yaml:"runbook" json:"runbook"`
+	// Parameters are the inputs of the runbook, the values are templates
+	Parameters map[string]string `yaml:"parameters" json:"parameters"`
+	// ContinueOnError executes the next steps when this step fails
+	ContinueOnError bool `yaml:"continue_on_error" json:"continue_on_error"`
+
+	parameterTemplates map[string]*gotemplate.Template
+}
+
+// IsWorkflowFile reports if the file is a workflow manifest, e.g.: ops/rotate-user.workflow.yaml
+func IsWorkflowFile(filePath string) bool {
+	parts := strings.Split(filePath, "/")
+	fileName := parts[len(parts)-1]
+	return strings.Contains(fileName, ".workflow.") &&
+		(strings.HasSuffix(fileName, ".yaml") || strings.HasSuffix(fileName, ".yml"))
+}
+
+// ParseWorkflow decodes and validates a workflow manifest
+func ParseWorkflow(data []byte) (*Workflow, error) {
+	var wf Workflow
+	if err := yaml.Unmarshal(data, &wf); err != nil {
+		return nil, fmt.Errorf("failed decoding workflow manifest: %v", err)
+	}
+	if len(wf.Steps) == 0 {
+		return nil, fmt.Errorf("workflow manifest must contain at least one step")
+	}
+	stepNames := map[string]struct{}{}
+	for i := range wf.Steps {
+		step := &wf.Steps[i]
+		if !workflowStepNameRe.MatchString(step.Name) {
+			return nil, fmt.Errorf("step #%v: name %q must start with a letter and contain only letters, numbers or underscores", i+1, step.Name)
+		}
+		if _, ok := stepNames[step.Name]; ok {
+			return nil, fmt.Errorf("step %v: name is duplicated", step.Name)
+		}
+		stepNames[step.Name] = struct{}{}
+		if step.Connection == "" {
+			return nil, fmt.Errorf("step %v: missing connection attribute", step.Name)
+		}
+		if !IsRunbookFile(step.Runbook) {
+			return nil, fmt.Errorf("step %v: runbook %q is not a runbook file", step.Name, step.Runbook)
+		}
+		step.parameterTemplates = map[string]*gotemplate.Template{}
+		for key, val := range step.Parameters {
+			t, err := gotemplate.New(key).Option("missingkey=error").Parse(val)
+			if err != nil {
+				return nil, fmt.Errorf("step %v: failed parsing parameter %v: %v", step.Name, key, err)
+			}
+			step.parameterTemplates[key] = t
+		}
+	}
+	return &wf, nil
+}
+
+// RenderParameters renders the parameters of the step with the workflow inputs and the results of the previous steps
+func (s *WorkflowStep) RenderParameters(inputs map[string]string, steps map[string]WorkflowStepOutput) (map[string]string, error) {
+	if inputs == nil {
+		inputs = map[string]string{}
+	}
+	// templates access the attributes in lower case, e.g.: .steps.create_user.exit_code
+	stepsData := map[string]map[string]any{}
+	for name, out := range steps {
+		stepsData[name] = map[string]any{"output": out.Output, "exit_code": out.ExitCode}
+	}
+	data := map[string]any{"inputs": inputs, "steps": stepsData}
+	params := map[string]string{}
+	for key, t := range s.parameterTemplates {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed rendering parameter %v: %v", key, err)
+		}
+		params[key] = buf.String()
+	}
+	return params, nil
+}
+
+// WorkflowStepOutput is the result of a step available to the next ones
+type WorkflowStepOutput struct {
+	Output   string `json:"output"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// NewWorkflowStepOutput trims the output of a step, commands commonly end their output with a line break
+func NewWorkflowStepOutput(output string, exitCode int) WorkflowStepOutput {
+	return WorkflowStepOutput{Output: strings.TrimSpace(output), ExitCode: exitCode}
+}
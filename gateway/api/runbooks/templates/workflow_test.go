@@ -0,0 +1,117 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorkflow(t *testing.T) {
+	for _, tt := range []struct {
+		msg      string
+		manifest string
+		wantErr  string
+	}{
+		{
+			msg: "it should parse a valid manifest",
+			manifest: `
+description: create user
+steps:
+  - name: create_user
+    connection: pgdemo
+    runbook: ops/create-user.runbook.sql
+    parameters:
+      username: "{{ .inputs.username }}"
+  - name: notify
+    connection: bash
+    runbook: ops/notify.runbook.sh
+    continue_on_error: true
+    parameters:
+      message: "{{ .steps.create_user.output }}"`,
+		},
+		{
+			msg:      "it should fail without steps",
+			manifest: `description: empty`,
+			wantErr:  "workflow manifest must contain at least one step",
+		},
+		{
+			msg: "it should fail with invalid step names",
+			manifest: `
+steps:
+  - name: create-user
+    connection: pgdemo
+    runbook: ops/create-user.runbook.sql`,
+			wantErr: `step #1: name "create-user" must start with a letter and contain only letters, numbers or underscores`,
+		},
+		{
+			msg: "it should fail with duplicated step names",
+			manifest: `
+steps:
+  - name: step1
+    connection: pgdemo
+    runbook: ops/create-user.runbook.sql
+  - name: step1
+    connection: pgdemo
+    runbook: ops/create-user.runbook.sql`,
+			wantErr: "step step1: name is duplicated",
+		},
+		{
+			msg: "it should fail when the runbook is not a runbook file",
+			manifest: `
+steps:
+  - name: step1
+    connection: pgdemo
+    runbook: ops/create-user.sql`,
+			wantErr: `step step1: runbook "ops/create-user.sql" is not a runbook file`,
+		},
+		{
+			msg: "it should fail with invalid parameter templates",
+			manifest: `
+steps:
+  - name: step1
+    connection: pgdemo
+    runbook: ops/create-user.runbook.sql
+    parameters:
+      username: "{{ .inputs.username }"`,
+			wantErr: `step step1: failed parsing parameter username: template: username:1: unexpected "}" in operand`,
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			_, err := ParseWorkflow([]byte(tt.manifest))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestWorkflowRenderParameters(t *testing.T) {
+	wf, err := ParseWorkflow([]byte(`
+steps:
+  - name: step1
+    connection: pgdemo
+    runbook: ops/create-user.runbook.sql
+    parameters:
+      username: "{{ .inputs.username }}"
+      previous: "{{ .steps.step0.output }}:{{ .steps.step0.exit_code }}"`))
+	require.NoError(t, err)
+
+	params, err := wf.Steps[0].RenderParameters(
+		map[string]string{"username": "john"},
+		map[string]WorkflowStepOutput{"step0": NewWorkflowStepOutput("CREATE ROLE\n", 0)})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"username": "john", "previous": "CREATE ROLE:0"}, params)
+
+	_, err = wf.Steps[0].RenderParameters(map[string]string{}, nil)
+	assert.Error(t, err)
+}
+
+func TestIsWorkflowFile(t *testing.T) {
+	assert.True(t, IsWorkflowFile("ops/create-user.workflow.yaml"))
+	assert.True(t, IsWorkflowFile("create-user.workflow.yml"))
+	assert.False(t, IsWorkflowFile("ops/create-user.runbook.sql"))
+	assert.False(t, IsWorkflowFile("ops.workflow.yaml/create-user.runbook.sh"))
+}
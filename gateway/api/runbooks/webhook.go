@@ -0,0 +1,146 @@
+package apirunbooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/api/runbooks/templates"
+	"github.com/hoophq/hoop/gateway/pgrest"
+	pgplugins "github.com/hoophq/hoop/gateway/pgrest/plugins"
+	plugintypes "github.com/hoophq/hoop/gateway/transport/plugins/types"
+)
+
+const maxWebhookPayloadSize = 5000000 // 5MB
+
+var errInvalidWebhookSignature = fmt.Errorf("invalid webhook signature")
+
+type pushEvent struct {
+	Ref string `json:"ref"`
+}
+
+// RunbooksWebhook
+//
+//	@Summary		Runbooks Git Webhook
+//	@Description	Refresh the cached runbooks repository of the organization when a push event is received from GitHub or GitLab.
+//	@Description	GitHub requests are validated with the HMAC signature (`X-Hub-Signature-256`) and GitLab requests with the secret token (`X-Gitlab-Token`), both using the `GIT_WEBHOOK_SECRET` of the runbooks plugin configuration.
+//	@Description	A push refreshes the cached runbooks served from the pushed branch or tag, pinned commits are never refreshed.
+//	@Tags			Runbooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			org_id			path	string	true	"The organization id"
+//	@Success		202
+//	@Success		204
+//	@Failure		400,401,404,422,500	{object}	openapi.HTTPError
+//	@Router			/plugins/runbooks/webhooks/{org_id} [post]
+func Webhook(c *gin.Context) {
+	orgID := c.Param("org_id")
+	if _, err := uuid.Parse(orgID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "organization not found"})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookPayloadSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "failed reading request body"})
+		return
+	}
+	p, err := pgplugins.New().FetchOne(pgrest.NewOrgContext(orgID), plugintypes.PluginRunbooksName)
+	if err != nil {
+		log.Errorf("failed retrieving runbook plugin, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed retrieving runbook plugin"})
+		return
+	}
+	var configEnvVars map[string]string
+	if p != nil && p.Config != nil {
+		configEnvVars = p.Config.EnvVars
+	}
+	secret, err := base64.StdEncoding.DecodeString(configEnvVars["GIT_WEBHOOK_SECRET"])
+	if err != nil || len(secret) == 0 {
+		// it doesn't distinguish a missing plugin from a missing secret to unauthenticated callers
+		c.JSON(http.StatusNotFound, gin.H{"message": "runbooks webhook is not configured"})
+		return
+	}
+	if err := verifyWebhookRequest(c.Request.Header, body, secret); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+		return
+	}
+	ref, isPush, err := parsePushEvent(c.Request.Header, body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if !isPush {
+		c.Writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+	config, err := templates.NewRunbookConfig(configEnvVars)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+		return
+	}
+	// git providers expect a fast response, the repository is fetched in background
+	go func() {
+		if err := templates.RefreshRepos(orgID, config, ref); err != nil {
+			log.With("org", orgID).Warnf("failed refreshing runbooks repository from webhook, ref=%v, reason=%v", ref, err)
+			return
+		}
+		log.With("org", orgID).Infof("refreshed runbooks repository from webhook, ref=%v", ref)
+	}()
+	c.Writer.WriteHeader(http.StatusAccepted)
+}
+
+// verifyWebhookRequest validates the HMAC signature of GitHub requests
+// or the secret token of GitLab requests
+func verifyWebhookRequest(header http.Header, body, secret []byte) error {
+	switch {
+	case header.Get("X-Hub-Signature-256") != "":
+		signature, found := strings.CutPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+		if !found {
+			return errInvalidWebhookSignature
+		}
+		got, err := hex.DecodeString(signature)
+		if err != nil {
+			return errInvalidWebhookSignature
+		}
+		mac := hmac.New(sha256.New, secret)
+		_, _ = mac.Write(body)
+		if !hmac.Equal(got, mac.Sum(nil)) {
+			return errInvalidWebhookSignature
+		}
+		return nil
+	case header.Get("X-Gitlab-Token") != "":
+		if subtle.ConstantTimeCompare([]byte(header.Get("X-Gitlab-Token")), secret) != 1 {
+			return errInvalidWebhookSignature
+		}
+		return nil
+	}
+	return fmt.Errorf("missing webhook signature header")
+}
+
+// parsePushEvent returns the git reference of GitHub or GitLab push events,
+// other events (e.g.: ping) are reported as not being a push
+func parsePushEvent(header http.Header, body []byte) (ref string, isPush bool, err error) {
+	githubEvent := header.Get("X-GitHub-Event")
+	gitlabEvent := header.Get("X-Gitlab-Event")
+	if githubEvent != "push" && gitlabEvent != "Push Hook" {
+		return "", false, nil
+	}
+	var ev pushEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return "", false, fmt.Errorf("failed decoding push event payload: %v", err)
+	}
+	if ev.Ref == "" {
+		return "", false, fmt.Errorf("push event payload is missing the ref attribute")
+	}
+	return ev.Ref, true, nil
+}
@@ -0,0 +1,125 @@
+package apirunbooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyWebhookRequest(t *testing.T) {
+	secret := []byte("my-secret")
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	validSignature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	for _, tt := range []struct {
+		msg     string
+		header  http.Header
+		body    []byte
+		wantErr bool
+	}{
+		{
+			msg:    "it should validate the github signature",
+			header: http.Header{"X-Hub-Signature-256": {validSignature}},
+			body:   body,
+		},
+		{
+			msg:     "it should fail when the github payload is tampered",
+			header:  http.Header{"X-Hub-Signature-256": {validSignature}},
+			body:    []byte(`{"ref":"refs/heads/master"}`),
+			wantErr: true,
+		},
+		{
+			msg:     "it should fail with signatures without the algorithm prefix",
+			header:  http.Header{"X-Hub-Signature-256": {validSignature[len("sha256="):]}},
+			body:    body,
+			wantErr: true,
+		},
+		{
+			msg:    "it should validate the gitlab token",
+			header: http.Header{"X-Gitlab-Token": {"my-secret"}},
+			body:   body,
+		},
+		{
+			msg:     "it should fail with a wrong gitlab token",
+			header:  http.Header{"X-Gitlab-Token": {"other-secret"}},
+			body:    body,
+			wantErr: true,
+		},
+		{
+			msg:     "it should fail without signature headers",
+			header:  http.Header{},
+			body:    body,
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			err := verifyWebhookRequest(tt.header, tt.body, secret)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParsePushEvent(t *testing.T) {
+	for _, tt := range []struct {
+		msg      string
+		header   http.Header
+		body     string
+		wantRef  string
+		wantPush bool
+		wantErr  bool
+	}{
+		{
+			msg:      "it should parse github push events",
+			header:   http.Header{"X-Github-Event": {"push"}},
+			body:     `{"ref":"refs/heads/main","after":"6113728f27ae82c7b1a177c8d03f9e96e0adf246"}`,
+			wantRef:  "refs/heads/main",
+			wantPush: true,
+		},
+		{
+			msg:      "it should parse gitlab push events",
+			header:   http.Header{"X-Gitlab-Event": {"Push Hook"}},
+			body:     `{"object_kind":"push","ref":"refs/heads/master"}`,
+			wantRef:  "refs/heads/master",
+			wantPush: true,
+		},
+		{
+			msg:      "it should parse pushes of tags",
+			header:   http.Header{"X-Github-Event": {"push"}},
+			body:     `{"ref":"refs/tags/v1.0.0"}`,
+			wantRef:  "refs/tags/v1.0.0",
+			wantPush: true,
+		},
+		{
+			msg:    "it should ignore other events",
+			header: http.Header{"X-Github-Event": {"ping"}},
+			body:   `{"zen":"Keep it logically awesome."}`,
+		},
+		{
+			msg:     "it should fail with push events without ref",
+			header:  http.Header{"X-Gitlab-Event": {"Push Hook"}},
+			body:    `{}`,
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			ref, isPush, err := parsePushEvent(tt.header, []byte(tt.body))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantRef, ref)
+			assert.Equal(t, tt.wantPush, isPush)
+		})
+	}
+}
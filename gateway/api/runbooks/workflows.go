@@ -0,0 +1,409 @@
+package apirunbooks
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/apiutils"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/common/proto"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/api/runbooks/templates"
+	sessionapi "github.com/hoophq/hoop/gateway/api/session"
+	"github.com/hoophq/hoop/gateway/clientexec"
+	"github.com/hoophq/hoop/gateway/models"
+	pgplugins "github.com/hoophq/hoop/gateway/pgrest/plugins"
+	"github.com/hoophq/hoop/gateway/storagev2"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+	plugintypes "github.com/hoophq/hoop/gateway/transport/plugins/types"
+)
+
+// workflowStepRunner executes the runbook of a step with the rendered parameters
+type workflowStepRunner func(step *templates.WorkflowStep, params map[string]string) *openapi.RunbookWorkflowStepResult
+
+// workflowStep is a step of a workflow with the resources validated before the execution
+type workflowStep struct {
+	connection *models.Connection
+	config     *templates.RunbookConfig
+}
+
+// ListRunbookWorkflows
+//
+//	@Summary		List Runbook Workflows
+//	@Description	List the workflow manifests (`*.workflow.yaml`) of the runbooks repository
+//	@Tags			Runbooks
+//	@Produce		json
+//	@Success		200			{object}	openapi.RunbookWorkflowList
+//	@Failure		404,422,500	{object}	openapi.HTTPError
+//	@Router			/plugins/runbooks/workflows [get]
+func ListWorkflows(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	config := getPluginRunbookConfig(ctx, c)
+	if config == nil {
+		return
+	}
+	commit, err := templates.FetchRepoCached(ctx.GetOrgID(), config)
+	if err != nil {
+		log.Infof("failed listing workflows, err=%v", err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": fmt.Sprintf("failed listing workflows, reason=%v", err)})
+		return
+	}
+	workflowList := &openapi.RunbookWorkflowList{
+		Ref:    commit.Ref,
+		Commit: commit.Hash.String(),
+		Items:  []*openapi.RunbookWorkflow{},
+	}
+	ctree, _ := commit.Tree()
+	if ctree == nil {
+		c.JSON(http.StatusOK, workflowList)
+		return
+	}
+	err = ctree.Files().ForEach(func(f *object.File) error {
+		if !templates.IsWorkflowFile(f.Name) {
+			return nil
+		}
+		item := &openapi.RunbookWorkflow{Name: f.Name, Steps: []openapi.RunbookWorkflowStepSpec{}}
+		workflowList.Items = append(workflowList.Items, item)
+		wf, _, err := readWorkflowFile(f)
+		if err != nil {
+			item.Error = toPtrStr(err)
+			return nil
+		}
+		item.Description = wf.Description
+		for _, step := range wf.Steps {
+			item.Steps = append(item.Steps, openapi.RunbookWorkflowStepSpec{
+				Name:            step.Name,
+				Connection:      step.Connection,
+				Runbook:         step.Runbook,
+				Parameters:      step.Parameters,
+				ContinueOnError: step.ContinueOnError,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": fmt.Sprintf("failed listing workflows, reason=%v", err)})
+		return
+	}
+	c.PureJSON(http.StatusOK, workflowList)
+}
+
+// RunRunbookWorkflow
+//
+//	@Summary		Runbook Workflow Exec
+//	@Description	Execute the steps of a workflow manifest in order, each step executes a runbook in a connection and it's recorded as a session.
+//	@Description	The workflow is recorded as a parent session, the sessions of the steps contain the labels `workflowSessionID` and `workflowStep`.
+//	@Description	When a step fails or requires a review the next steps are skipped, unless the step is configured with `continue_on_error`.
+//	@Tags			Runbooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		openapi.RunbookWorkflowRequest	true	"The request body resource"
+//	@Success		200				{object}	openapi.RunbookWorkflowResponse	"The workflow has finished"
+//	@Success		202				{object}	openapi.RunbookWorkflowResponse	"The workflow is still in progress"
+//	@Failure		400,404,422,500	{object}	openapi.HTTPError
+//	@Router			/plugins/runbooks/workflows/exec [post]
+func RunWorkflow(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	var req openapi.RunbookWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if err := sessionapi.CoerceMetadataFields(req.Metadata); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+		return
+	}
+	config := getPluginRunbookConfig(ctx, c)
+	if config == nil {
+		return
+	}
+	commit, err := templates.FetchRepoCached(ctx.GetOrgID(), config)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+		return
+	}
+	if req.RefHash != "" && req.RefHash != commit.Hash.String() {
+		c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("mismatch git commit, want=%v, have=%v", req.RefHash, commit.Hash.String())})
+		return
+	}
+	var manifest []byte
+	var wf *templates.Workflow
+	if ctree, _ := commit.Tree(); ctree != nil && templates.IsWorkflowFile(req.FileName) {
+		if f := templates.LookupFile(req.FileName, ctree); f != nil {
+			wf, manifest, err = readWorkflowFile(f)
+			if err != nil {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+				return
+			}
+		}
+	}
+	if wf == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("workflow %v not found for %v", req.FileName, commit.Hash.String())})
+		return
+	}
+
+	// validate the resources of all steps before starting the execution
+	steps := map[string]*workflowStep{}
+	for _, step := range wf.Steps {
+		connection, err := getConnection(ctx, c, step.Connection)
+		if err != nil {
+			log.Errorf("workflow step %v: %v", step.Name, err)
+			return
+		}
+		stepConfig, pathPrefix, err := getRunbookConfig(ctx, c, connection)
+		if err != nil {
+			log.Errorf("workflow step %v: %v", step.Name, err)
+			return
+		}
+		if pathPrefix != "" && !strings.HasPrefix(step.Runbook, pathPrefix) {
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("step %v: runbook file %v not found", step.Name, step.Runbook)})
+			return
+		}
+		steps[step.Name] = &workflowStep{connection: connection, config: stepConfig}
+	}
+
+	sessionID := uuid.NewString()
+	// sessions require a connection, the workflow session is attached to the connection of the first step
+	parentSession := models.Session{
+		ID:             sessionID,
+		OrgID:          ctx.GetOrgID(),
+		Connection:     wf.Steps[0].Connection,
+		ConnectionType: string(proto.ConnectionTypeCustom),
+		Verb:           proto.ClientVerbExec,
+		Labels: types.SessionLabels{
+			"workflowFile":       req.FileName,
+			"workflowParameters": toJSONString(req.Parameters),
+			"runbookRef":         commit.Ref,
+			"runbookCommit":      commit.Hash.String(),
+		},
+		Metadata:   req.Metadata,
+		BlobInput:  models.BlobInputType(manifest),
+		UserID:     ctx.UserID,
+		UserName:   ctx.UserName,
+		UserEmail:  ctx.UserEmail,
+		Status:     string(openapi.SessionStatusOpen),
+		CreatedAt:  time.Now().UTC(),
+		EndSession: nil,
+	}
+	if err := models.UpsertSession(parentSession); err != nil {
+		log.Errorf("failed persisting workflow session, err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "The session couldn't be created"})
+		return
+	}
+
+	accessToken := getAccessToken(c)
+	userAgent := apiutils.NormalizeUserAgent(c.Request.Header.Values)
+	if userAgent == "webapp.core" {
+		userAgent = "webapp.runbook.workflow"
+	}
+	log := log.With("sid", sessionID)
+	log.Infof("runbook workflow exec, commit=%s, name=%s, steps=%v", commit.Hash.String()[:8], req.FileName, len(wf.Steps))
+	runStep := func(step *templates.WorkflowStep, params map[string]string) *openapi.RunbookWorkflowStepResult {
+		return runWorkflowStep(ctx, steps[step.Name], step, params, sessionID, accessToken, userAgent, req.Metadata)
+	}
+
+	respCh := make(chan *openapi.RunbookWorkflowResponse, 1)
+	go func() {
+		resp := executeWorkflow(wf, req.Parameters, runStep)
+		resp.SessionID = sessionID
+		finishWorkflowSession(parentSession, resp)
+		log.Infof("runbook workflow exec finished, status=%v", resp.Status)
+		respCh <- resp
+	}()
+
+	timeoutCtx, cancelFn := context.WithTimeout(context.Background(), time.Second*50)
+	defer cancelFn()
+	select {
+	case resp := <-respCh:
+		c.JSON(http.StatusOK, resp)
+	case <-timeoutCtx.Done():
+		log.Infof("runbook workflow exec timeout (50s), it will return async")
+		c.JSON(http.StatusAccepted, &openapi.RunbookWorkflowResponse{
+			SessionID: sessionID,
+			Status:    openapi.RunbookWorkflowStatusRunning,
+			Steps:     []openapi.RunbookWorkflowStepResult{},
+		})
+	}
+}
+
+// executeWorkflow executes the steps in order passing the results of each step to the next ones.
+// A failed step stops the workflow unless it's configured to continue on error,
+// a step waiting for review always stops the workflow.
+func executeWorkflow(wf *templates.Workflow, inputs map[string]string, runStep workflowStepRunner) *openapi.RunbookWorkflowResponse {
+	resp := &openapi.RunbookWorkflowResponse{Status: openapi.RunbookWorkflowStatusSuccess}
+	outputs := map[string]templates.WorkflowStepOutput{}
+	for i := range wf.Steps {
+		step := &wf.Steps[i]
+		result := &openapi.RunbookWorkflowStepResult{
+			Name:       step.Name,
+			Connection: step.Connection,
+			Runbook:    step.Runbook,
+			ExitCode:   -2,
+			Status:     openapi.RunbookWorkflowStatusSkipped,
+		}
+		if resp.Status == openapi.RunbookWorkflowStatusSuccess {
+			params, err := step.RenderParameters(inputs, outputs)
+			if err != nil {
+				result.Status = openapi.RunbookWorkflowStatusFailed
+				result.Output = err.Error()
+			} else {
+				result = runStep(step, params)
+			}
+			outputs[step.Name] = templates.NewWorkflowStepOutput(result.Output, result.ExitCode)
+			switch {
+			case result.Status == openapi.RunbookWorkflowStatusWaitingReview:
+				resp.Status = openapi.RunbookWorkflowStatusWaitingReview
+			case result.Status == openapi.RunbookWorkflowStatusFailed && !step.ContinueOnError:
+				resp.Status = openapi.RunbookWorkflowStatusFailed
+			}
+		}
+		resp.Steps = append(resp.Steps, *result)
+	}
+	return resp
+}
+
+// runWorkflowStep renders the runbook of the step and executes it as a child session of the workflow
+func runWorkflowStep(ctx *storagev2.Context, ws *workflowStep, step *templates.WorkflowStep, params map[string]string,
+	parentSessionID, accessToken, userAgent string, metadata map[string]any) *openapi.RunbookWorkflowStepResult {
+	result := &openapi.RunbookWorkflowStepResult{
+		Name:       step.Name,
+		Connection: step.Connection,
+		Runbook:    step.Runbook,
+		ExitCode:   -2,
+		Status:     openapi.RunbookWorkflowStatusFailed,
+	}
+	req := openapi.RunbookRequest{FileName: step.Runbook, Parameters: params, Metadata: metadata}
+	runbook, err := fetchRunbookFile(ctx.GetOrgID(), ws.config, req)
+	if err != nil {
+		result.Output = err.Error()
+		return result
+	}
+	result.SessionID = uuid.NewString()
+	err = createRunbookSession(ctx, ws.connection, result.SessionID, runbook, req, types.SessionLabels{
+		"workflowSessionID": parentSessionID,
+		"workflowStep":      step.Name,
+	})
+	if err != nil {
+		log.Errorf("failed persisting workflow step session, err=%v", err)
+		result.Output = "The session couldn't be created"
+		return result
+	}
+	client, err := clientexec.New(&clientexec.Options{
+		OrgID:          ctx.GetOrgID(),
+		SessionID:      result.SessionID,
+		ConnectionName: step.Connection,
+		BearerToken:    accessToken,
+		UserAgent:      userAgent,
+		Origin:         proto.ConnectionOriginClientAPIRunbooks,
+	})
+	if err != nil {
+		result.Output = err.Error()
+		return result
+	}
+	defer client.Close()
+	outcome := client.Run(runbook.InputFile, runbook.EnvVars)
+	result.Output = outcome.Output
+	result.ExitCode = outcome.ExitCode
+	result.ExecutionTimeMili = outcome.ExecutionTimeMili
+	switch {
+	case outcome.HasReview:
+		result.Status = openapi.RunbookWorkflowStatusWaitingReview
+	case outcome.OutputStatus == "success":
+		result.Status = openapi.RunbookWorkflowStatusSuccess
+	}
+	return result
+}
+
+// finishWorkflowSession records the result of the steps in the workflow session
+func finishWorkflowSession(parentSession models.Session, resp *openapi.RunbookWorkflowResponse) {
+	var summary []string
+	var stepRefs []map[string]any
+	for _, step := range resp.Steps {
+		summary = append(summary, fmt.Sprintf("step=%v connection=%v status=%v exit_code=%v session=%v",
+			step.Name, step.Connection, step.Status, step.ExitCode, step.SessionID))
+		stepRefs = append(stepRefs, map[string]any{
+			"name":       step.Name,
+			"connection": step.Connection,
+			"session_id": step.SessionID,
+			"status":     step.Status,
+			"exit_code":  step.ExitCode,
+		})
+	}
+	summary = append(summary, fmt.Sprintf("workflow status=%v\n", resp.Status))
+	parentSession.Labels["workflowSteps"] = toJSONString(stepRefs)
+	if err := models.UpsertSession(parentSession); err != nil {
+		log.With("sid", parentSession.ID).Errorf("failed updating workflow session labels, err=%v", err)
+	}
+	exitCode := 0
+	if resp.Status != openapi.RunbookWorkflowStatusSuccess {
+		exitCode = 1
+	}
+	endDate := time.Now().UTC()
+	blobStream := fmt.Sprintf(`[[0, "o", %q]]`, base64.StdEncoding.EncodeToString([]byte(strings.Join(summary, "\n"))))
+	err := models.UpdateSessionEventStream(models.SessionDone{
+		ID:         parentSession.ID,
+		OrgID:      parentSession.OrgID,
+		Metrics:    map[string]any{"event_size": len(blobStream)},
+		BlobStream: json.RawMessage(blobStream),
+		Status:     string(openapi.SessionStatusDone),
+		ExitCode:   &exitCode,
+		EndSession: &endDate,
+	})
+	if err != nil {
+		log.With("sid", parentSession.ID).Errorf("failed updating workflow session, err=%v", err)
+		sentry.CaptureException(err)
+	}
+}
+
+// getPluginRunbookConfig returns the repository configuration of the runbooks plugin,
+// it writes the error response when it's not available
+func getPluginRunbookConfig(ctx *storagev2.Context, c *gin.Context) *templates.RunbookConfig {
+	p, err := pgplugins.New().FetchOne(ctx, plugintypes.PluginRunbooksName)
+	if err != nil {
+		log.Errorf("failed retrieving runbook plugin, reason=%v", err)
+		sentry.CaptureException(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed retrieving runbook plugin"})
+		return nil
+	}
+	if p == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "plugin runbooks not found"})
+		return nil
+	}
+	var configEnvVars map[string]string
+	if p.Config != nil {
+		configEnvVars = p.Config.EnvVars
+	}
+	config, err := templates.NewRunbookConfig(configEnvVars)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+		return nil
+	}
+	return config
+}
+
+// readWorkflowFile returns the parsed workflow and the content of the manifest
+func readWorkflowFile(f *object.File) (*templates.Workflow, []byte, error) {
+	blob, err := templates.ReadBlob(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(blob) > maxTemplateSize {
+		return nil, nil, fmt.Errorf("max template size [%v KB] reached", maxTemplateSize/1000)
+	}
+	wf, err := templates.ParseWorkflow(blob)
+	return wf, blob, err
+}
+
+func toJSONString(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
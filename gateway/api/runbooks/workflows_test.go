@@ -0,0 +1,138 @@
+package apirunbooks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/api/runbooks/templates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWorkflowManifest = `
+steps:
+  - name: create_user
+    connection: pgdemo
+    runbook: ops/create-user.runbook.sql
+    parameters:
+      username: "{{ .inputs.username }}"
+  - name: grant
+    connection: pgdemo
+    runbook: ops/grant.runbook.sql
+    continue_on_error: %s
+    parameters:
+      role: "{{ .steps.create_user.output }}"
+  - name: notify
+    connection: bash
+    runbook: ops/notify.runbook.sh
+    parameters:
+      message: "{{ .steps.grant.exit_code }}"`
+
+type fakeStepRunner struct {
+	results map[string]openapi.RunbookWorkflowStepResult
+	params  map[string]map[string]string
+}
+
+func (r *fakeStepRunner) run(step *templates.WorkflowStep, params map[string]string) *openapi.RunbookWorkflowStepResult {
+	r.params[step.Name] = params
+	result := r.results[step.Name]
+	result.Name = step.Name
+	result.SessionID = "sid-" + step.Name
+	return &result
+}
+
+func TestExecuteWorkflow(t *testing.T) {
+	success := func(output string) openapi.RunbookWorkflowStepResult {
+		return openapi.RunbookWorkflowStepResult{Status: openapi.RunbookWorkflowStatusSuccess, Output: output}
+	}
+	failed := openapi.RunbookWorkflowStepResult{Status: openapi.RunbookWorkflowStatusFailed, ExitCode: 1, Output: "error"}
+	for _, tt := range []struct {
+		msg             string
+		continueOnError string
+		results         map[string]openapi.RunbookWorkflowStepResult
+		wantStatus      openapi.RunbookWorkflowStatus
+		wantStepStatus  []openapi.RunbookWorkflowStatus
+		wantParams      map[string]map[string]string
+	}{
+		{
+			msg:             "it should pass the outputs to the next steps",
+			continueOnError: "false",
+			results: map[string]openapi.RunbookWorkflowStepResult{
+				"create_user": success("john_role\n"),
+				"grant":       success("GRANT"),
+				"notify":      success("sent"),
+			},
+			wantStatus: openapi.RunbookWorkflowStatusSuccess,
+			wantStepStatus: []openapi.RunbookWorkflowStatus{
+				openapi.RunbookWorkflowStatusSuccess, openapi.RunbookWorkflowStatusSuccess, openapi.RunbookWorkflowStatusSuccess},
+			wantParams: map[string]map[string]string{
+				"create_user": {"username": "john"},
+				"grant":       {"role": "john_role"},
+				"notify":      {"message": "0"},
+			},
+		},
+		{
+			msg:             "it should stop when a step fails",
+			continueOnError: "false",
+			results: map[string]openapi.RunbookWorkflowStepResult{
+				"create_user": success("john_role"),
+				"grant":       failed,
+			},
+			wantStatus: openapi.RunbookWorkflowStatusFailed,
+			wantStepStatus: []openapi.RunbookWorkflowStatus{
+				openapi.RunbookWorkflowStatusSuccess, openapi.RunbookWorkflowStatusFailed, openapi.RunbookWorkflowStatusSkipped},
+			wantParams: map[string]map[string]string{
+				"create_user": {"username": "john"},
+				"grant":       {"role": "john_role"},
+			},
+		},
+		{
+			msg:             "it should continue when the failed step allows errors",
+			continueOnError: "true",
+			results: map[string]openapi.RunbookWorkflowStepResult{
+				"create_user": success("john_role"),
+				"grant":       failed,
+				"notify":      success("sent"),
+			},
+			wantStatus: openapi.RunbookWorkflowStatusSuccess,
+			wantStepStatus: []openapi.RunbookWorkflowStatus{
+				openapi.RunbookWorkflowStatusSuccess, openapi.RunbookWorkflowStatusFailed, openapi.RunbookWorkflowStatusSuccess},
+			wantParams: map[string]map[string]string{
+				"create_user": {"username": "john"},
+				"grant":       {"role": "john_role"},
+				"notify":      {"message": "1"},
+			},
+		},
+		{
+			msg:             "it should stop when a step requires a review",
+			continueOnError: "true",
+			results: map[string]openapi.RunbookWorkflowStepResult{
+				"create_user": {Status: openapi.RunbookWorkflowStatusWaitingReview, Output: "http://review"},
+			},
+			wantStatus: openapi.RunbookWorkflowStatusWaitingReview,
+			wantStepStatus: []openapi.RunbookWorkflowStatus{
+				openapi.RunbookWorkflowStatusWaitingReview, openapi.RunbookWorkflowStatusSkipped, openapi.RunbookWorkflowStatusSkipped},
+			wantParams: map[string]map[string]string{
+				"create_user": {"username": "john"},
+			},
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			wf, err := templates.ParseWorkflow([]byte(fmt.Sprintf(testWorkflowManifest, tt.continueOnError)))
+			require.NoError(t, err)
+			runner := &fakeStepRunner{results: tt.results, params: map[string]map[string]string{}}
+			resp := executeWorkflow(wf, map[string]string{"username": "john"}, runner.run)
+			assert.Equal(t, tt.wantStatus, resp.Status)
+			var stepStatus []openapi.RunbookWorkflowStatus
+			for _, step := range resp.Steps {
+				stepStatus = append(stepStatus, step.Status)
+				if step.Status == openapi.RunbookWorkflowStatusSkipped {
+					assert.Empty(t, step.SessionID)
+				}
+			}
+			assert.Equal(t, tt.wantStepStatus, stepStatus)
+			assert.Equal(t, tt.wantParams, runner.params)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package apiscim
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/storagev2"
+)
+
+const tokenPrefix = "scim-"
+
+// AuthMiddleware authenticates the identity provider with the SCIM token of the organization
+func AuthMiddleware(c *gin.Context) {
+	tokenParts := strings.Split(c.GetHeader("authorization"), " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" || !strings.HasPrefix(tokenParts[1], tokenPrefix) {
+		writeError(c, http.StatusUnauthorized, "", "invalid authorization header")
+		return
+	}
+	token, err := models.GetScimTokenByHash(hashToken(tokenParts[1]))
+	switch err {
+	case models.ErrNotFound:
+		writeError(c, http.StatusUnauthorized, "", "invalid token")
+		return
+	case nil:
+	default:
+		log.Errorf("failed fetching scim token, reason=%v", err)
+		writeError(c, http.StatusInternalServerError, "", "failed validating token")
+		return
+	}
+	c.Set(storagev2.ContextKey,
+		storagev2.NewContext("scim", token.OrgID).
+			WithUserInfo("SCIM", "", string(openapi.StatusActive), "", nil))
+	c.Next()
+}
+
+// CreateScimToken
+//
+//	@Summary		Create SCIM Token
+//	@Description	Generate the token used by the identity provider to provision users and groups in the `/scim/v2` endpoints. It replaces any existing token and it's only returned once.
+//	@Tags			SCIM
+//	@Produce		json
+//	@Success		201	{object}	openapi.ScimToken
+//	@Failure		500	{object}	openapi.HTTPError
+//	@Router			/scim/token [post]
+func CreateToken(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	secretRandomBytes := make([]byte, 32)
+	if _, err := rand.Read(secretRandomBytes); err != nil {
+		log.Errorf("failed generating scim token, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed generating token"})
+		return
+	}
+	plainToken := tokenPrefix + base64.RawURLEncoding.EncodeToString(secretRandomBytes)
+	token := &models.ScimToken{
+		OrgID:     ctx.GetOrgID(),
+		TokenHash: hashToken(plainToken),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := models.UpsertScimToken(token); err != nil {
+		log.Errorf("failed persisting scim token, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, &openapi.ScimToken{Token: plainToken, CreatedAt: token.CreatedAt})
+}
+
+// RevokeScimToken
+//
+//	@Summary		Revoke SCIM Token
+//	@Description	Revoke the SCIM token, the identity provider will not be able to provision users and groups until a new token is created.
+//	@Tags			SCIM
+//	@Produce		json
+//	@Success		204
+//	@Failure		404,500	{object}	openapi.HTTPError
+//	@Router			/scim/token [delete]
+func RevokeToken(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	err := models.DeleteScimToken(ctx.GetOrgID())
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "resource not found"})
+	case nil:
+		c.Writer.WriteHeader(http.StatusNoContent)
+	default:
+		log.Errorf("failed revoking scim token, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+func hashToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
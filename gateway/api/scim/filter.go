@@ -0,0 +1,57 @@
+package apiscim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// filter is a SCIM equality expression, e.g.: userName eq "john@acme.com".
+// It's the only filter required by the identity providers to provision resources.
+type filter struct {
+	attribute string
+	value     string
+}
+
+func parseFilter(expr string) (*filter, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(expr, " ", 3)
+	if len(parts) != 3 || !strings.EqualFold(parts[1], "eq") {
+		return nil, fmt.Errorf("unsupported filter %q, only the eq operator is supported", expr)
+	}
+	value := strings.TrimSpace(parts[2])
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	return &filter{attribute: strings.ToLower(parts[0]), value: value}, nil
+}
+
+// match validates if any of the attribute values is equal to the filter value,
+// a nil filter matches all resources
+func (f *filter) match(attributes map[string][]string) bool {
+	if f == nil {
+		return true
+	}
+	for _, val := range attributes[f.attribute] {
+		if strings.EqualFold(val, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// supports returns an error if the attribute is not supported by the resource
+func (f *filter) supports(attributes ...string) error {
+	if f == nil {
+		return nil
+	}
+	for _, attr := range attributes {
+		if strings.ToLower(attr) == f.attribute {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported filter attribute %q, accepted values are %v", f.attribute, attributes)
+}
@@ -0,0 +1,40 @@
+package apiscim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	for _, tt := range []struct {
+		msg     string
+		expr    string
+		want    *filter
+		wantErr bool
+	}{
+		{msg: "it should return nil with empty filters", expr: ""},
+		{
+			msg:  "it should parse quoted values",
+			expr: `userName eq "john@acme.com"`,
+			want: &filter{attribute: "username", value: "john@acme.com"},
+		},
+		{
+			msg:  "it should parse values with spaces",
+			expr: `displayName EQ "SRE Team"`,
+			want: &filter{attribute: "displayname", value: "SRE Team"},
+		},
+		{msg: "it should fail with unsupported operators", expr: `userName co "john"`, wantErr: true},
+		{msg: "it should fail with invalid expressions", expr: `userName`, wantErr: true},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			got, err := parseFilter(tt.expr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
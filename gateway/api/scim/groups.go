@@ -0,0 +1,296 @@
+package apiscim
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/storagev2"
+)
+
+// ListGroups returns the provisioned groups of the organization, it supports
+// filtering by the displayName and id attributes
+func ListGroups(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	f, err := parseFilter(c.Query("filter"))
+	if err == nil {
+		err = f.supports("displayName", "id", "externalId")
+	}
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalidFilter", "%v", err)
+		return
+	}
+	groups, err := models.ListScimGroups(ctx.GetOrgID())
+	if err != nil {
+		log.Errorf("failed listing scim groups, reason=%v", err)
+		writeError(c, http.StatusInternalServerError, "", "failed listing groups")
+		return
+	}
+	members, err := listGroupMembers(ctx.GetOrgID())
+	if err != nil {
+		log.Errorf("failed listing group members, reason=%v", err)
+		writeError(c, http.StatusInternalServerError, "", "failed listing group members")
+		return
+	}
+	resources := []any{}
+	for _, g := range groups {
+		if !f.match(map[string][]string{
+			"displayname": {g.DisplayName},
+			"id":          {g.ID},
+			"externalid":  {g.ExternalID},
+		}) {
+			continue
+		}
+		// members are included to avoid an additional request per group
+		resources = append(resources, toGroup(g, members[g.DisplayName]))
+	}
+	writeJSON(c, http.StatusOK, paginate(c, resources))
+}
+
+func GetGroup(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	group := getGroup(c, ctx.GetOrgID())
+	if group == nil {
+		return
+	}
+	writeGroup(c, http.StatusOK, group)
+}
+
+// CreateGroup provisions a group and adds its members to the user group with the same name
+func CreateGroup(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	var req Group
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalidSyntax", "%v", err)
+		return
+	}
+	if req.DisplayName == "" {
+		writeError(c, http.StatusBadRequest, "invalidValue", "displayName attribute is required")
+		return
+	}
+	memberIDs := appendUnique(nil, memberValues(req.Members)...)
+	if !validateMembers(c, ctx.GetOrgID(), memberIDs) {
+		return
+	}
+	group := &models.ScimGroup{
+		OrgID:       ctx.GetOrgID(),
+		ID:          uuid.NewString(),
+		DisplayName: req.DisplayName,
+		ExternalID:  req.ExternalID,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+	err := models.CreateScimGroup(group)
+	switch err {
+	case models.ErrAlreadyExists:
+		writeError(c, http.StatusConflict, "uniqueness", "group %s already exists", req.DisplayName)
+		return
+	case nil:
+	default:
+		log.Errorf("failed creating scim group, reason=%v", err)
+		writeError(c, http.StatusInternalServerError, "", "failed creating group")
+		return
+	}
+	if err := models.AddUserGroupMembers(group.OrgID, group.DisplayName, memberIDs); err != nil {
+		log.Errorf("failed adding group members, reason=%v", err)
+		writeError(c, http.StatusInternalServerError, "", "failed adding group members")
+		return
+	}
+	writeGroup(c, http.StatusCreated, group)
+}
+
+func ReplaceGroup(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	group := getGroup(c, ctx.GetOrgID())
+	if group == nil {
+		return
+	}
+	var req Group
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalidSyntax", "%v", err)
+		return
+	}
+	if req.DisplayName == "" {
+		writeError(c, http.StatusBadRequest, "invalidValue", "displayName attribute is required")
+		return
+	}
+	group.ExternalID = req.ExternalID
+	updateGroup(c, group, req.DisplayName, appendUnique(nil, memberValues(req.Members)...))
+}
+
+func PatchGroup(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	group := getGroup(c, ctx.GetOrgID())
+	if group == nil {
+		return
+	}
+	var req PatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalidSyntax", "%v", err)
+		return
+	}
+	userGroups, err := models.GetUserGroupsByName(group.OrgID, group.DisplayName)
+	if err != nil {
+		log.Errorf("failed listing group members, reason=%v", err)
+		writeError(c, http.StatusInternalServerError, "", "failed listing group members")
+		return
+	}
+	var memberIDs []string
+	for _, ug := range userGroups {
+		memberIDs = append(memberIDs, ug.UserID)
+	}
+	displayName, memberIDs, err := applyGroupPatch(group.DisplayName, memberIDs, req.Operations)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalidValue", "%v", err)
+		return
+	}
+	updateGroup(c, group, displayName, memberIDs)
+}
+
+// DeleteGroup removes the group and the user group of its members
+func DeleteGroup(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	group := getGroup(c, ctx.GetOrgID())
+	if group == nil {
+		return
+	}
+	if err := models.DeleteScimGroup(group); err != nil {
+		log.Errorf("failed removing scim group %s, reason=%v", group.ID, err)
+		writeError(c, http.StatusInternalServerError, "", "failed removing group")
+		return
+	}
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func updateGroup(c *gin.Context, group *models.ScimGroup, displayName string, memberIDs []string) {
+	if !validateMembers(c, group.OrgID, memberIDs) {
+		return
+	}
+	oldDisplayName := group.DisplayName
+	group.DisplayName = displayName
+	group.UpdatedAt = time.Now().UTC()
+	err := models.UpdateScimGroup(group, oldDisplayName)
+	switch err {
+	case models.ErrNotFound:
+		notFound(c, "group", group.ID)
+		return
+	case models.ErrAlreadyExists:
+		writeError(c, http.StatusConflict, "uniqueness", "group %s already exists", displayName)
+		return
+	case nil:
+	default:
+		log.Errorf("failed updating scim group %s, reason=%v", group.ID, err)
+		writeError(c, http.StatusInternalServerError, "", "failed updating group")
+		return
+	}
+	if err := models.ReplaceUserGroupMembers(group.OrgID, group.DisplayName, memberIDs); err != nil {
+		log.Errorf("failed updating group members, reason=%v", err)
+		writeError(c, http.StatusInternalServerError, "", "failed updating group members")
+		return
+	}
+	writeGroup(c, http.StatusOK, group)
+}
+
+// validateMembers checks if all members are users of the organization,
+// it writes the error response when any of them is not found
+func validateMembers(c *gin.Context, orgID string, memberIDs []string) bool {
+	for _, id := range memberIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			writeError(c, http.StatusBadRequest, "invalidValue", "member %s not found", id)
+			return false
+		}
+		user, err := models.GetUserByIDAndOrg(id, orgID)
+		if err != nil {
+			log.Errorf("failed fetching user %s, reason=%v", id, err)
+			writeError(c, http.StatusInternalServerError, "", "failed fetching member")
+			return false
+		}
+		if user == nil {
+			writeError(c, http.StatusBadRequest, "invalidValue", "member %s not found", id)
+			return false
+		}
+	}
+	return true
+}
+
+// getGroup fetches the group of the id path parameter, it writes the error response when it's not found
+func getGroup(c *gin.Context, orgID string) *models.ScimGroup {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		notFound(c, "group", id)
+		return nil
+	}
+	group, err := models.GetScimGroup(orgID, id)
+	switch err {
+	case models.ErrNotFound:
+		notFound(c, "group", id)
+		return nil
+	case nil:
+		return group
+	default:
+		log.Errorf("failed fetching scim group %s, reason=%v", id, err)
+		writeError(c, http.StatusInternalServerError, "", "failed fetching group")
+		return nil
+	}
+}
+
+func writeGroup(c *gin.Context, status int, group *models.ScimGroup) {
+	members, err := listGroupMembers(group.OrgID)
+	if err != nil {
+		log.Errorf("failed listing group members, reason=%v", err)
+		writeError(c, http.StatusInternalServerError, "", "failed listing group members")
+		return
+	}
+	writeJSON(c, status, toGroup(group, members[group.DisplayName]))
+}
+
+// listGroupMembers returns the users members of each group of the organization
+func listGroupMembers(orgID string) (map[string][]Member, error) {
+	users, err := models.ListUsers(orgID)
+	if err != nil {
+		return nil, err
+	}
+	userGroups, err := models.GetUserGroupsByOrgID(orgID)
+	if err != nil {
+		return nil, err
+	}
+	emails := map[string]string{}
+	for _, u := range users {
+		emails[u.ID] = u.Email
+	}
+	members := map[string][]Member{}
+	for _, ug := range userGroups {
+		email, ok := emails[ug.UserID]
+		if !ok {
+			continue
+		}
+		members[ug.Name] = append(members[ug.Name], Member{Value: ug.UserID, Display: email})
+	}
+	return members, nil
+}
+
+func memberValues(members []Member) (ids []string) {
+	for _, m := range members {
+		ids = append(ids, m.Value)
+	}
+	return
+}
+
+func toGroup(g *models.ScimGroup, members []Member) *Group {
+	members = slices.Clone(members)
+	if members == nil {
+		members = []Member{}
+	}
+	return &Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          g.ID,
+		ExternalID:  g.ExternalID,
+		DisplayName: g.DisplayName,
+		Members:     members,
+		Meta:        &Meta{ResourceType: "Group", Location: "/Groups/" + g.ID},
+	}
+}
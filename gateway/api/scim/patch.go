@@ -0,0 +1,207 @@
+package apiscim
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// valuePathRe matches value filters in patch paths, e.g.: members[value eq "<id>"]
+var valuePathRe = regexp.MustCompile(`^([a-zA-Z.]+)\[(.+)\](?:\.([a-zA-Z]+))?$`)
+
+// applyUserPatch changes the user attributes based on the patch operations.
+// Attributes not stored by the gateway are ignored.
+func applyUserPatch(u *User, ops []PatchOperation) error {
+	for _, op := range ops {
+		opType := strings.ToLower(op.Op)
+		if opType != "add" && opType != "replace" && opType != "remove" {
+			return fmt.Errorf("unsupported patch operation %q", op.Op)
+		}
+		value := op.Value
+		if opType == "remove" {
+			value = nil
+		}
+		if op.Path != "" {
+			if err := setUserAttribute(u, op.Path, value); err != nil {
+				return err
+			}
+			continue
+		}
+		attributes, ok := op.Value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("patch operation %q without path must contain an object value", op.Op)
+		}
+		for attr, val := range attributes {
+			if err := setUserAttribute(u, attr, val); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func setUserAttribute(u *User, path string, value any) error {
+	if u.Name == nil {
+		u.Name = &Name{}
+	}
+	switch attr := strings.ToLower(path); {
+	case attr == "active":
+		active, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		u.Active = &active
+	case attr == "username":
+		u.UserName = toString(value)
+	case attr == "displayname":
+		u.DisplayName = toString(value)
+	case attr == "externalid":
+		u.ExternalID = toString(value)
+	case attr == "name":
+		var name Name
+		if err := decodeValue(value, &name); err != nil {
+			return err
+		}
+		u.Name = &name
+	case attr == "name.formatted":
+		u.Name.Formatted = toString(value)
+	case attr == "name.givenname":
+		u.Name.GivenName = toString(value)
+	case attr == "name.familyname":
+		u.Name.FamilyName = toString(value)
+	case attr == "emails":
+		var emails []Email
+		if err := decodeValue(value, &emails); err != nil {
+			return err
+		}
+		u.Emails = emails
+	case strings.HasPrefix(attr, "emails["):
+		// e.g.: emails[type eq "work"].value, the gateway keeps a single email per user
+		u.Emails = []Email{{Value: toString(value), Primary: true}}
+	}
+	return nil
+}
+
+// applyGroupPatch returns the display name and the members of a group after applying the patch operations
+func applyGroupPatch(displayName string, members []string, ops []PatchOperation) (string, []string, error) {
+	members = slices.Clone(members)
+	for _, op := range ops {
+		opType := strings.ToLower(op.Op)
+		path := strings.ToLower(op.Path)
+		switch {
+		case path == "":
+			attributes, ok := op.Value.(map[string]any)
+			if !ok {
+				return "", nil, fmt.Errorf("patch operation %q without path must contain an object value", op.Op)
+			}
+			for attr, val := range attributes {
+				var err error
+				displayName, members, err = applyGroupPatch(displayName, members,
+					[]PatchOperation{{Op: op.Op, Path: attr, Value: val}})
+				if err != nil {
+					return "", nil, err
+				}
+			}
+		case path == "displayname":
+			if opType == "remove" {
+				return "", nil, fmt.Errorf("displayName attribute is required")
+			}
+			displayName = toString(op.Value)
+		case path == "members":
+			var refs []Member
+			value := op.Value
+			if ref, ok := value.(map[string]any); ok {
+				value = []any{ref}
+			}
+			if value != nil {
+				if err := decodeValue(value, &refs); err != nil {
+					return "", nil, err
+				}
+			}
+			var ids []string
+			for _, ref := range refs {
+				ids = append(ids, ref.Value)
+			}
+			switch opType {
+			case "add":
+				members = appendUnique(members, ids...)
+			case "replace":
+				members = appendUnique(nil, ids...)
+			case "remove":
+				if op.Value == nil {
+					members = nil
+					continue
+				}
+				members = slices.DeleteFunc(members, func(id string) bool { return slices.Contains(ids, id) })
+			default:
+				return "", nil, fmt.Errorf("unsupported patch operation %q", op.Op)
+			}
+		case strings.HasPrefix(path, "members["):
+			if opType != "remove" {
+				return "", nil, fmt.Errorf("unsupported patch operation %q for path %q", op.Op, op.Path)
+			}
+			matches := valuePathRe.FindStringSubmatch(op.Path)
+			if matches == nil {
+				return "", nil, fmt.Errorf("invalid path %q", op.Path)
+			}
+			f, err := parseFilter(matches[2])
+			if err != nil {
+				return "", nil, err
+			}
+			if err := f.supports("value"); err != nil {
+				return "", nil, err
+			}
+			members = slices.DeleteFunc(members, func(id string) bool { return strings.EqualFold(id, f.value) })
+		default:
+			return "", nil, fmt.Errorf("unsupported path %q", op.Path)
+		}
+	}
+	return displayName, members, nil
+}
+
+func appendUnique(items []string, values ...string) []string {
+	for _, val := range values {
+		if val != "" && !slices.Contains(items, val) {
+			items = append(items, val)
+		}
+	}
+	return items
+}
+
+// parseBool parses boolean values, some identity providers send them as strings, e.g.: "False"
+func parseBool(value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(strings.ToLower(v))
+	case nil:
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean value %v", value)
+}
+
+func toString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// decodeValue converts an arbitrary json value into dst
+func decodeValue(value, dst any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("invalid value %s: %v", string(data), err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package apiscim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyUserPatch(t *testing.T) {
+	for _, tt := range []struct {
+		msg        string
+		ops        []PatchOperation
+		wantActive bool
+		wantName   string
+		wantEmail  string
+		wantErr    bool
+	}{
+		{
+			msg:        "it should deactivate the user with a path operation",
+			ops:        []PatchOperation{{Op: "replace", Path: "active", Value: false}},
+			wantActive: false,
+			wantName:   "John",
+			wantEmail:  "john@acme.com",
+		},
+		{
+			msg:        "it should parse boolean values sent as strings",
+			ops:        []PatchOperation{{Op: "Replace", Path: "active", Value: "False"}},
+			wantActive: false,
+			wantName:   "John",
+			wantEmail:  "john@acme.com",
+		},
+		{
+			msg: "it should change multiple attributes without path",
+			ops: []PatchOperation{{Op: "replace", Value: map[string]any{
+				"active":      true,
+				"displayName": "John Doe",
+			}}},
+			wantActive: true,
+			wantName:   "John Doe",
+			wantEmail:  "john@acme.com",
+		},
+		{
+			msg:        "it should change the primary email with value filters",
+			ops:        []PatchOperation{{Op: "replace", Path: `emails[type eq "work"].value`, Value: "john.doe@acme.com"}},
+			wantActive: true,
+			wantName:   "John",
+			wantEmail:  "john.doe@acme.com",
+		},
+		{
+			msg:        "it should ignore unknown attributes",
+			ops:        []PatchOperation{{Op: "add", Path: "title", Value: "engineer"}},
+			wantActive: true,
+			wantName:   "John",
+			wantEmail:  "john@acme.com",
+		},
+		{
+			msg:     "it should fail with unknown operations",
+			ops:     []PatchOperation{{Op: "move", Path: "active", Value: false}},
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			active := true
+			u := &User{
+				UserName: "john@acme.com",
+				Name:     &Name{Formatted: "John"},
+				Emails:   []Email{{Value: "john@acme.com", Primary: true}},
+				Active:   &active,
+			}
+			err := applyUserPatch(u, tt.ops)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantActive, *u.Active)
+			assert.Equal(t, tt.wantName, u.displayName())
+			assert.Equal(t, tt.wantEmail, u.email())
+		})
+	}
+}
+
+func TestApplyGroupPatch(t *testing.T) {
+	for _, tt := range []struct {
+		msg         string
+		ops         []PatchOperation
+		wantName    string
+		wantMembers []string
+		wantErr     bool
+	}{
+		{
+			msg: "it should add members ignoring existing ones",
+			ops: []PatchOperation{{Op: "add", Path: "members", Value: []any{
+				map[string]any{"value": "u1"},
+				map[string]any{"value": "u3"},
+			}}},
+			wantName:    "sre",
+			wantMembers: []string{"u1", "u2", "u3"},
+		},
+		{
+			msg:         "it should remove members with value filters",
+			ops:         []PatchOperation{{Op: "remove", Path: `members[value eq "u1"]`}},
+			wantName:    "sre",
+			wantMembers: []string{"u2"},
+		},
+		{
+			msg: "it should remove members from the value",
+			ops: []PatchOperation{{Op: "remove", Path: "members", Value: []any{
+				map[string]any{"value": "u2"},
+			}}},
+			wantName:    "sre",
+			wantMembers: []string{"u1"},
+		},
+		{
+			msg:         "it should remove all members without value",
+			ops:         []PatchOperation{{Op: "remove", Path: "members"}},
+			wantName:    "sre",
+			wantMembers: nil,
+		},
+		{
+			msg: "it should replace members and the display name without path",
+			ops: []PatchOperation{{Op: "replace", Value: map[string]any{
+				"displayName": "devops",
+				"members":     []any{map[string]any{"value": "u4"}},
+			}}},
+			wantName:    "devops",
+			wantMembers: []string{"u4"},
+		},
+		{
+			msg:     "it should fail with unsupported paths",
+			ops:     []PatchOperation{{Op: "replace", Path: "owners", Value: "u1"}},
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			name, members, err := applyGroupPatch("sre", []string{"u1", "u2"}, tt.ops)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantMembers, members)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package apiscim
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServiceProviderConfig returns the features supported by the SCIM server
+func ServiceProviderConfig(c *gin.Context) {
+	writeJSON(c, http.StatusOK, gin.H{
+		"schemas":        []string{SchemaSPConfig},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": defaultMaxCount},
+		"changePassword": gin.H{"supported": false},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication with the SCIM token of the organization",
+			"primary":     true,
+		}},
+	})
+}
@@ -0,0 +1,261 @@
+package apiscim
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/appconfig"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/storagev2"
+)
+
+// ListUsers returns the users of the organization, it supports filtering
+// by the userName, id and emails.value attributes
+func ListUsers(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	f, err := parseFilter(c.Query("filter"))
+	if err == nil {
+		err = f.supports("userName", "id", "emails.value")
+	}
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalidFilter", "%v", err)
+		return
+	}
+	users, err := models.ListUsers(ctx.GetOrgID())
+	if err != nil {
+		log.Errorf("failed listing users, reason=%v", err)
+		writeError(c, http.StatusInternalServerError, "", "failed listing users")
+		return
+	}
+	groupRefs, err := listUserGroupRefs(ctx.GetOrgID())
+	if err != nil {
+		log.Errorf("failed listing user groups, reason=%v", err)
+		writeError(c, http.StatusInternalServerError, "", "failed listing user groups")
+		return
+	}
+	resources := []any{}
+	for _, u := range users {
+		if !f.match(map[string][]string{
+			"username":     {u.Email},
+			"id":           {u.ID},
+			"emails.value": {u.Email},
+		}) {
+			continue
+		}
+		resources = append(resources, toUser(&u, groupRefs[u.ID]))
+	}
+	writeJSON(c, http.StatusOK, paginate(c, resources))
+}
+
+func GetUser(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	user := getUser(c, ctx.GetOrgID())
+	if user == nil {
+		return
+	}
+	writeUser(c, http.StatusOK, user)
+}
+
+func CreateUser(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	var req User
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalidSyntax", "%v", err)
+		return
+	}
+	email := req.email()
+	if _, err := mail.ParseAddress(email); err != nil {
+		writeError(c, http.StatusBadRequest, "invalidValue", "invalid email %q", email)
+		return
+	}
+	existingUser, err := models.GetUserByEmailAndOrg(email, ctx.GetOrgID())
+	if err != nil {
+		log.Errorf("failed fetching existing user, reason=%v", err)
+		writeError(c, http.StatusInternalServerError, "", "failed fetching existing user")
+		return
+	}
+	if existingUser != nil {
+		writeError(c, http.StatusConflict, "uniqueness", "user already exists with email %s", email)
+		return
+	}
+
+	user := models.User{
+		ID:      uuid.NewString(),
+		OrgID:   ctx.GetOrgID(),
+		Subject: email,
+		Name:    req.displayName(),
+		Email:   email,
+		Status:  string(openapi.StatusInvited),
+	}
+	// local auth users don't login with the identity provider,
+	// they set their password with the local auth flow
+	if appconfig.Get().AuthMethod() == "local" {
+		user.Subject = fmt.Sprintf("local|%v", user.ID)
+		user.Verified = true
+		user.Status = string(openapi.StatusActive)
+	}
+	if req.Active != nil && !*req.Active {
+		user.Status = string(openapi.StatusInactive)
+	}
+	if err := models.CreateUser(user); err != nil {
+		log.Errorf("failed creating user, reason=%v", err)
+		writeError(c, http.StatusInternalServerError, "", "failed creating user")
+		return
+	}
+	log.With("org", ctx.GetOrgID()).Infof("provisioned user %s with scim", user.Email)
+	writeUser(c, http.StatusCreated, &user)
+}
+
+// ReplaceUser updates the user attributes, the groups of the user are managed by the groups resource
+func ReplaceUser(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	user := getUser(c, ctx.GetOrgID())
+	if user == nil {
+		return
+	}
+	var req User
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalidSyntax", "%v", err)
+		return
+	}
+	updateUser(c, user, &req)
+}
+
+func PatchUser(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	user := getUser(c, ctx.GetOrgID())
+	if user == nil {
+		return
+	}
+	var req PatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalidSyntax", "%v", err)
+		return
+	}
+	scimUser := toUser(user, nil)
+	// the display name is kept in the name attribute, it allows patching any of them
+	scimUser.DisplayName = ""
+	if err := applyUserPatch(scimUser, req.Operations); err != nil {
+		writeError(c, http.StatusBadRequest, "invalidValue", "%v", err)
+		return
+	}
+	updateUser(c, user, scimUser)
+}
+
+// DeleteUser removes the user from the organization
+func DeleteUser(c *gin.Context) {
+	ctx := storagev2.ParseContext(c)
+	user := getUser(c, ctx.GetOrgID())
+	if user == nil {
+		return
+	}
+	if err := models.DeleteUser(ctx.GetOrgID(), user.Subject); err != nil {
+		log.Errorf("failed removing user %s, reason=%v", user.ID, err)
+		writeError(c, http.StatusInternalServerError, "", "failed removing user")
+		return
+	}
+	log.With("org", ctx.GetOrgID()).Infof("deprovisioned user %s with scim", user.Email)
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func updateUser(c *gin.Context, user *models.User, req *User) {
+	email := req.email()
+	if _, err := mail.ParseAddress(email); err != nil {
+		writeError(c, http.StatusBadRequest, "invalidValue", "invalid email %q", email)
+		return
+	}
+	user.Name = req.displayName()
+	user.Email = email
+	switch {
+	case req.Active != nil && !*req.Active:
+		user.Status = string(openapi.StatusInactive)
+	case req.Active != nil && user.Status == string(openapi.StatusInactive):
+		user.Status = string(openapi.StatusActive)
+	}
+	userGroups, err := models.GetUserGroupsByUserID(user.ID)
+	if err != nil {
+		log.Errorf("failed getting user groups for user %s, reason=%v", user.ID, err)
+		writeError(c, http.StatusInternalServerError, "", "failed getting user groups")
+		return
+	}
+	if err := models.UpdateUserAndUserGroups(user, userGroups); err != nil {
+		log.Errorf("failed updating user %s, reason=%v", user.ID, err)
+		writeError(c, http.StatusInternalServerError, "", "failed updating user")
+		return
+	}
+	writeUser(c, http.StatusOK, user)
+}
+
+// getUser fetches the user of the id path parameter, it writes the error response when it's not found
+func getUser(c *gin.Context, orgID string) *models.User {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		notFound(c, "user", id)
+		return nil
+	}
+	user, err := models.GetUserByIDAndOrg(id, orgID)
+	if err != nil {
+		log.Errorf("failed fetching user %s, reason=%v", id, err)
+		writeError(c, http.StatusInternalServerError, "", "failed fetching user")
+		return nil
+	}
+	if user == nil {
+		notFound(c, "user", id)
+	}
+	return user
+}
+
+func writeUser(c *gin.Context, status int, user *models.User) {
+	groupRefs, err := listUserGroupRefs(user.OrgID)
+	if err != nil {
+		log.Errorf("failed listing user groups, reason=%v", err)
+		writeError(c, http.StatusInternalServerError, "", "failed listing user groups")
+		return
+	}
+	writeJSON(c, status, toUser(user, groupRefs[user.ID]))
+}
+
+// listUserGroupRefs returns the scim groups of each user of the organization
+func listUserGroupRefs(orgID string) (map[string][]GroupRef, error) {
+	groups, err := models.ListScimGroups(orgID)
+	if err != nil {
+		return nil, err
+	}
+	userGroups, err := models.GetUserGroupsByOrgID(orgID)
+	if err != nil {
+		return nil, err
+	}
+	groupIDs := map[string]string{}
+	for _, g := range groups {
+		groupIDs[g.DisplayName] = g.ID
+	}
+	refs := map[string][]GroupRef{}
+	for _, ug := range userGroups {
+		groupID, ok := groupIDs[ug.Name]
+		if !ok || ug.UserID == "" {
+			continue
+		}
+		refs[ug.UserID] = append(refs[ug.UserID], GroupRef{Value: groupID, Display: ug.Name})
+	}
+	return refs, nil
+}
+
+func toUser(u *models.User, groups []GroupRef) *User {
+	active := u.Status != string(openapi.StatusInactive)
+	return &User{
+		Schemas:     []string{SchemaUser},
+		ID:          u.ID,
+		UserName:    u.Email,
+		Name:        &Name{Formatted: u.Name},
+		DisplayName: u.Name,
+		Emails:      []Email{{Value: u.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Groups:      groups,
+		Meta:        &Meta{ResourceType: "User", Location: "/Users/" + u.ID},
+	}
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/hoophq/hoop/common/log"
 	"github.com/hoophq/hoop/gateway/analytics"
+	accesstokenapi "github.com/hoophq/hoop/gateway/api/accesstoken"
 	apiagents "github.com/hoophq/hoop/gateway/api/agents"
 	"github.com/hoophq/hoop/gateway/api/apiroutes"
 	apiconnections "github.com/hoophq/hoop/gateway/api/connections"
@@ -28,11 +29,14 @@ import (
 	"github.com/hoophq/hoop/gateway/api/openapi"
 	apiorgs "github.com/hoophq/hoop/gateway/api/orgs"
 	apiplugins "github.com/hoophq/hoop/gateway/api/plugins"
+	apipolicyselectors "github.com/hoophq/hoop/gateway/api/policyselectors"
 	apiproxymanager "github.com/hoophq/hoop/gateway/api/proxymanager"
 	apipublicserverinfo "github.com/hoophq/hoop/gateway/api/publicserverinfo"
 	apireports "github.com/hoophq/hoop/gateway/api/reports"
 	reviewapi "github.com/hoophq/hoop/gateway/api/review"
+	apiroles "github.com/hoophq/hoop/gateway/api/roles"
 	apirunbooks "github.com/hoophq/hoop/gateway/api/runbooks"
+	apiscim "github.com/hoophq/hoop/gateway/api/scim"
 	apiserverinfo "github.com/hoophq/hoop/gateway/api/serverinfo"
 	serviceaccountapi "github.com/hoophq/hoop/gateway/api/serviceaccount"
 	sessionapi "github.com/hoophq/hoop/gateway/api/session"
@@ -41,6 +45,7 @@ import (
 	webhooksapi "github.com/hoophq/hoop/gateway/api/webhooks"
 	"github.com/hoophq/hoop/gateway/appconfig"
 	"github.com/hoophq/hoop/gateway/indexer"
+	"github.com/hoophq/hoop/gateway/rbac"
 	"github.com/hoophq/hoop/gateway/review"
 	"github.com/hoophq/hoop/gateway/security/idp"
 )
@@ -93,6 +98,12 @@ type Api struct {
 
 //  @tag.name Guard Rails
 
+//  @tag.name Roles
+
+//  @tag.name Policy Selectors
+
+//  @tag.name SCIM
+
 //	@tag.name Reviews
 
 //  @tag.name Sessions
@@ -175,6 +186,8 @@ func (api *Api) buildRoutes(r *apiroutes.Router) {
 
 	r.GET("/login", loginHandler.Login)
 	r.GET("/callback", loginHandler.LoginCallback)
+	r.POST("/saml/acs", loginHandler.SAMLCallback)
+	r.GET("/saml/metadata", loginHandler.SAMLMetadata)
 
 	r.POST("/localauth/register",
 		api.TrackRequest(analytics.EventSignup),
@@ -182,6 +195,17 @@ func (api *Api) buildRoutes(r *apiroutes.Router) {
 	r.POST("/localauth/login",
 		api.TrackRequest(analytics.EventLogin),
 		localauthapi.Login)
+	r.POST("/localauth/password-reset", localauthapi.RequestPasswordReset)
+	r.POST("/localauth/password-reset/confirm", localauthapi.ConfirmPasswordReset)
+	r.POST("/localauth/totp/enroll",
+		r.AuthMiddleware,
+		localauthapi.EnrollTOTP)
+	r.POST("/localauth/totp/verify",
+		r.AuthMiddleware,
+		localauthapi.VerifyTOTP)
+	r.POST("/localauth/totp/disable",
+		r.AuthMiddleware,
+		localauthapi.DisableTOTP)
 
 	r.POST("/signup",
 		api.TrackRequest(analytics.EventSignup),
@@ -221,6 +245,10 @@ func (api *Api) buildRoutes(r *apiroutes.Router) {
 		r.AuthMiddleware,
 		api.TrackRequest(analytics.EventUpdateUser),
 		userapi.Update)
+	r.POST("/users/:id/password-reset",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		localauthapi.CreatePasswordResetToken)
 
 	r.GET("/serviceaccounts",
 		apiroutes.ReadOnlyAccessRole,
@@ -237,13 +265,29 @@ func (api *Api) buildRoutes(r *apiroutes.Router) {
 		api.TrackRequest(analytics.EventCreateServiceAccount),
 		serviceaccountapi.Update)
 
+	// personal access tokens are managed by any user, including auditors
+	r.GET("/accesstokens",
+		apiroutes.ReadOnlyAccessRole,
+		r.AuthMiddleware,
+		accesstokenapi.List)
+	r.POST("/accesstokens",
+		apiroutes.ReadOnlyAccessRole,
+		r.AuthMiddleware,
+		accesstokenapi.Create)
+	r.DELETE("/accesstokens/:id",
+		apiroutes.ReadOnlyAccessRole,
+		r.AuthMiddleware,
+		accesstokenapi.Revoke)
+
 	r.POST("/connections",
 		apiroutes.AdminOnlyAccessRole,
+		apiroutes.PermissionAccess(rbac.PermissionManageConnections),
 		r.AuthMiddleware,
 		api.TrackRequest(analytics.EventCreateConnection),
 		apiconnections.Post)
 	r.PUT("/connections/:nameOrID",
 		apiroutes.AdminOnlyAccessRole,
+		apiroutes.PermissionAccess(rbac.PermissionManageConnections),
 		r.AuthMiddleware,
 		api.TrackRequest(analytics.EventUpdateConnection),
 		apiconnections.Put)
@@ -261,6 +305,7 @@ func (api *Api) buildRoutes(r *apiroutes.Router) {
 		apiconnections.Get)
 	r.DELETE("/connections/:name",
 		apiroutes.AdminOnlyAccessRole,
+		apiroutes.PermissionAccess(rbac.PermissionManageConnections),
 		r.AuthMiddleware,
 		api.TrackRequest(analytics.EventDeleteConnection),
 		apiconnections.Delete)
@@ -268,6 +313,10 @@ func (api *Api) buildRoutes(r *apiroutes.Router) {
 		apiroutes.ReadOnlyAccessRole,
 		r.AuthMiddleware,
 		apiconnections.ListDatabases)
+	r.GET("/connections/:nameOrID/policies",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apiconnections.GetPolicies)
 	r.GET("/connections/:nameOrID/schemas",
 		apiroutes.ReadOnlyAccessRole,
 		r.AuthMiddleware,
@@ -297,6 +346,31 @@ func (api *Api) buildRoutes(r *apiroutes.Router) {
 		r.AuthMiddleware,
 		api.TrackRequest(analytics.EventUpdateReview),
 		reviewHandler.Put)
+	r.GET("/reviews/:id/comments",
+		r.AuthMiddleware,
+		api.TrackRequest(analytics.EventFetchReviews),
+		reviewHandler.ListComments)
+	r.POST("/reviews/:id/comments",
+		r.AuthMiddleware,
+		api.TrackRequest(analytics.EventUpdateReview),
+		reviewHandler.CreateComment)
+	r.GET("/reviews/:id/revisions",
+		r.AuthMiddleware,
+		api.TrackRequest(analytics.EventFetchReviews),
+		reviewHandler.ListRevisions)
+	r.POST("/reviews/:id/revisions",
+		r.AuthMiddleware,
+		api.TrackRequest(analytics.EventUpdateReview),
+		reviewHandler.Resubmit)
+	r.POST("/reviews/:id/extend",
+		r.AuthMiddleware,
+		api.TrackRequest(analytics.EventUpdateReview),
+		reviewHandler.Extend)
+	r.POST("/reviews/:id/shorten",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		api.TrackRequest(analytics.EventUpdateReview),
+		reviewHandler.Shorten)
 
 	r.POST("/agents",
 		apiroutes.AdminOnlyAccessRole,
@@ -312,6 +386,18 @@ func (api *Api) buildRoutes(r *apiroutes.Router) {
 		r.AuthMiddleware,
 		api.TrackRequest(analytics.EventDeleteAgent),
 		apiagents.Delete)
+	r.POST("/agents/:nameOrID/rotate",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apiagents.RotateKey)
+	r.POST("/agents/:nameOrID/certificate",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apiagents.CreateCertificate)
+	r.PUT("/agents/:nameOrID/mtls",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apiagents.UpdateMTLS)
 
 	r.POST("/orgs/keys",
 		apiroutes.AdminOnlyAccessRole,
@@ -325,6 +411,10 @@ func (api *Api) buildRoutes(r *apiroutes.Router) {
 		apiroutes.AdminOnlyAccessRole,
 		r.AuthMiddleware,
 		apiorgs.RevokeAgentKey)
+	r.POST("/orgs/keys/rotate",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apiorgs.RotateAgentKey)
 
 	r.PUT("/orgs/license",
 		apiroutes.AdminOnlyAccessRole,
@@ -391,6 +481,14 @@ func (api *Api) buildRoutes(r *apiroutes.Router) {
 	r.PATCH("/sessions/:session_id/metadata",
 		r.AuthMiddleware,
 		sessionapi.PatchMetadata)
+	r.DELETE("/sessions/:session_id",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		sessionapi.Kill)
+	r.POST("/sessions/kill",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		sessionapi.KillBulk)
 	r.GET("/sessions",
 		apiroutes.ReadOnlyAccessRole,
 		r.AuthMiddleware,
@@ -428,6 +526,51 @@ func (api *Api) buildRoutes(r *apiroutes.Router) {
 		r.AuthMiddleware,
 		api.TrackRequest(analytics.EventExecRunbook),
 		apirunbooks.RunExec)
+	r.POST("/plugins/runbooks/connections/:name/preview",
+		r.AuthMiddleware,
+		apirunbooks.Preview)
+	r.GET("/plugins/runbooks/workflows",
+		apiroutes.ReadOnlyAccessRole,
+		r.AuthMiddleware,
+		apirunbooks.ListWorkflows)
+	r.POST("/plugins/runbooks/workflows/exec",
+		r.AuthMiddleware,
+		api.TrackRequest(analytics.EventExecRunbook),
+		apirunbooks.RunWorkflow)
+	r.GET("/plugins/runbooks/schedules",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apirunbooks.ListSchedules)
+	r.GET("/plugins/runbooks/schedules/:name",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apirunbooks.GetSchedule)
+	r.POST("/plugins/runbooks/schedules",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apirunbooks.CreateSchedule)
+	r.PUT("/plugins/runbooks/schedules/:name",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apirunbooks.UpdateSchedule)
+	r.PUT("/plugins/runbooks/schedules/:name/enable",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apirunbooks.EnableSchedule)
+	r.PUT("/plugins/runbooks/schedules/:name/disable",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apirunbooks.DisableSchedule)
+	r.DELETE("/plugins/runbooks/schedules/:name",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apirunbooks.DeleteSchedule)
+	r.GET("/plugins/runbooks/schedules/:name/runs",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apirunbooks.ListScheduleRuns)
+	// the webhook is authenticated with the secret of the runbooks plugin
+	r.POST("/plugins/runbooks/webhooks/:org_id", apirunbooks.Webhook)
 
 	r.GET("/webhooks-dashboard",
 		apiroutes.AdminOnlyAccessRole,
@@ -500,4 +643,71 @@ func (api *Api) buildRoutes(r *apiroutes.Router) {
 		r.AuthMiddleware,
 		api.TrackRequest(analytics.EventDeleteGuardRailRules),
 		apiguardrails.Delete)
+
+	r.POST("/roles",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apiroles.Post)
+	r.PUT("/roles/:id",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apiroles.Put)
+	r.GET("/roles",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apiroles.List)
+	r.GET("/roles/:id",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apiroles.Get)
+	r.DELETE("/roles/:id",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apiroles.Delete)
+
+	r.POST("/policyselectors",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apipolicyselectors.Post)
+	r.PUT("/policyselectors/:id",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apipolicyselectors.Put)
+	r.GET("/policyselectors",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apipolicyselectors.List)
+	r.GET("/policyselectors/:id",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apipolicyselectors.Get)
+	r.DELETE("/policyselectors/:id",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apipolicyselectors.Delete)
+
+	r.POST("/scim/token",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apiscim.CreateToken)
+	r.DELETE("/scim/token",
+		apiroutes.AdminOnlyAccessRole,
+		r.AuthMiddleware,
+		apiscim.RevokeToken)
+
+	// SCIM 2.0 endpoints authenticated with the scim token
+	scim := r.Group("/scim/v2", apiscim.AuthMiddleware)
+	scim.GET("/ServiceProviderConfig", apiscim.ServiceProviderConfig)
+	scim.GET("/Users", apiscim.ListUsers)
+	scim.POST("/Users", apiscim.CreateUser)
+	scim.GET("/Users/:id", apiscim.GetUser)
+	scim.PUT("/Users/:id", apiscim.ReplaceUser)
+	scim.PATCH("/Users/:id", apiscim.PatchUser)
+	scim.DELETE("/Users/:id", apiscim.DeleteUser)
+	scim.GET("/Groups", apiscim.ListGroups)
+	scim.POST("/Groups", apiscim.CreateGroup)
+	scim.GET("/Groups/:id", apiscim.GetGroup)
+	scim.PUT("/Groups/:id", apiscim.ReplaceGroup)
+	scim.PATCH("/Groups/:id", apiscim.PatchGroup)
+	scim.DELETE("/Groups/:id", apiscim.DeleteGroup)
 }
@@ -0,0 +1,171 @@
+package sessionapi
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/api/apiroutes"
+	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/storagev2"
+	"github.com/hoophq/hoop/gateway/transport/streamclient"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type (
+	// killStore fetches and flags the sessions and closes their streams in this gateway instance
+	killStore interface {
+		GetSessionByID(orgID, sid string) (*models.Session, error)
+		ListOpenSessionIDs(orgID, userEmail, connectionName string) ([]string, error)
+		FlagSessionKilled(orgID, sid string, killed map[string]any) error
+		DisconnectProxy(orgID, sid string, reason error) bool
+	}
+
+	gatewayKillStore struct{}
+)
+
+func (gatewayKillStore) GetSessionByID(orgID, sid string) (*models.Session, error) {
+	return models.GetSessionByID(orgID, sid)
+}
+
+func (gatewayKillStore) ListOpenSessionIDs(orgID, userEmail, connectionName string) ([]string, error) {
+	return models.ListOpenSessionIDs(orgID, userEmail, connectionName)
+}
+
+func (gatewayKillStore) FlagSessionKilled(orgID, sid string, killed map[string]any) error {
+	return models.FlagSessionKilled(orgID, sid, killed)
+}
+
+func (gatewayKillStore) DisconnectProxy(orgID, sid string, reason error) bool {
+	return streamclient.DisconnectProxy(orgID, sid, reason)
+}
+
+// Kill
+//
+//	@Summary		Kill Session
+//	@Description	Terminates an open session. It closes the client stream and the resources of the agent, the user that killed the session and the reason are recorded in the integrations metadata of the session.
+//	@Description	When the stream of the session is served by another gateway instance, the session is flagged as killed and it returns 202. The instance serving the stream terminates it in the next seconds.
+//	@Tags			Sessions
+//	@Accept			json
+//	@Produce		json
+//	@Param			session_id		path		string						true	"The id of the resource"
+//	@Param			request			body		openapi.SessionKillRequest	true	"The request body resource"
+//	@Success		204
+//	@Success		202				{object}	openapi.HTTPError	"The session was flagged as killed, it's terminated by the gateway instance serving its stream"
+//	@Failure		400,404,422,500	{object}	openapi.HTTPError
+//	@Router			/sessions/{session_id} [delete]
+func Kill(c *gin.Context) { kill(c, gatewayKillStore{}) }
+
+func kill(c *gin.Context, store killStore) {
+	ctx, sessionID := storagev2.ParseContext(c), c.Param("session_id")
+	apiroutes.SetSidSpanAttr(c, sessionID)
+	var req openapi.SessionKillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	sess, err := store.GetSessionByID(ctx.OrgID, sessionID)
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "not found"})
+		return
+	case nil:
+	default:
+		log.Errorf("failed fetching session %v, reason=%v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed fetching session"})
+		return
+	}
+	if sess.Status != string(openapi.SessionStatusOpen) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": fmt.Sprintf("unable to kill a session with status %q", sess.Status)})
+		return
+	}
+	disconnected, err := killSession(ctx, store, sessionID, req.Reason)
+	if err != nil {
+		log.Errorf("failed killing session %v, reason=%v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed killing session"})
+		return
+	}
+	if !disconnected {
+		c.JSON(http.StatusAccepted, gin.H{"message": "the session was flagged as killed, it's terminated by the gateway instance serving its stream"})
+		return
+	}
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// KillBulk
+//
+//	@Summary		Kill Sessions
+//	@Description	Terminates all open sessions of a user or a connection, e.g.: when an account is compromised. At least one filter is required, when both are set the sessions must match both of them.
+//	@Description	The sessions with a stream served by another gateway instance are flagged as killed and listed in `not_connected`, they are terminated by the instance serving them in the next seconds.
+//	@Tags			Sessions
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		openapi.SessionBulkKillRequest	true	"The request body resource"
+//	@Success		200				{object}	openapi.SessionBulkKillResponse
+//	@Failure		400,422,500		{object}	openapi.HTTPError
+//	@Router			/sessions/kill [post]
+func KillBulk(c *gin.Context) { killBulk(c, gatewayKillStore{}) }
+
+func killBulk(c *gin.Context, store killStore) {
+	ctx := storagev2.ParseContext(c)
+	var req openapi.SessionBulkKillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if req.UserEmail == "" && req.Connection == "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "user_email or connection is required"})
+		return
+	}
+	sessionIDs, err := store.ListOpenSessionIDs(ctx.OrgID, req.UserEmail, req.Connection)
+	if err != nil {
+		log.Errorf("failed listing open sessions, reason=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed listing open sessions"})
+		return
+	}
+	resp := openapi.SessionBulkKillResponse{SessionIDs: []string{}, NotConnected: []string{}}
+	for _, sid := range sessionIDs {
+		disconnected, err := killSession(ctx, store, sid, req.Reason)
+		if err != nil {
+			log.Errorf("failed killing session %v, reason=%v", sid, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": fmt.Sprintf("failed killing session %v, killed sessions=%v", sid, resp.SessionIDs)})
+			return
+		}
+		if !disconnected {
+			resp.NotConnected = append(resp.NotConnected, sid)
+			continue
+		}
+		resp.SessionIDs = append(resp.SessionIDs, sid)
+	}
+	log.With("org", ctx.OrgID).Infof("sessions killed by %v, user=%v, connection=%v, total=%v, not-connected=%v",
+		ctx.UserEmail, req.UserEmail, req.Connection, len(resp.SessionIDs), len(resp.NotConnected))
+	c.JSON(http.StatusOK, resp)
+}
+
+// killSession records who killed the session and closes its stream. It returns false when
+// the stream is not connected to this gateway instance, the instance serving it closes the
+// stream when it finds the flag (streamclient.InitKilledSessionsWatcher).
+func killSession(ctx *storagev2.Context, store killStore, sid, reason string) (bool, error) {
+	err := store.FlagSessionKilled(ctx.OrgID, sid, map[string]any{
+		"killed_by":    ctx.UserEmail,
+		"killed_by_id": ctx.UserID,
+		"reason":       reason,
+		"killed_at":    time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed recording killed session: %v", err)
+	}
+	errMsg := status.Errorf(codes.Aborted, "session killed by %v, reason=%v", ctx.UserEmail, reason)
+	if store.DisconnectProxy(ctx.OrgID, sid, errMsg) {
+		log.With("sid", sid).Infof("session killed by %v, reason=%v", ctx.UserEmail, reason)
+		return true, nil
+	}
+	log.With("sid", sid).Infof("session flagged as killed by %v, the stream is served by another gateway instance, reason=%v",
+		ctx.UserEmail, reason)
+	return false, nil
+}
@@ -0,0 +1,201 @@
+package sessionapi
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/storagev2"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKillStore keeps the sessions in memory, the local streams
+// are the ones connected to the gateway instance serving the request
+type fakeKillStore struct {
+	sessions     []*models.Session
+	localStreams map[string]bool
+	killed       map[string]map[string]any
+	disconnected map[string]error
+}
+
+func newFakeKillStore(sessions ...*models.Session) *fakeKillStore {
+	return &fakeKillStore{
+		sessions:     sessions,
+		localStreams: map[string]bool{},
+		killed:       map[string]map[string]any{},
+		disconnected: map[string]error{},
+	}
+}
+
+func (s *fakeKillStore) GetSessionByID(orgID, sid string) (*models.Session, error) {
+	for _, sess := range s.sessions {
+		if sess.ID == sid && sess.OrgID == orgID {
+			return sess, nil
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+func (s *fakeKillStore) ListOpenSessionIDs(orgID, userEmail, connectionName string) ([]string, error) {
+	var items []string
+	for _, sess := range s.sessions {
+		if sess.OrgID != orgID || sess.Status != "open" ||
+			(userEmail != "" && sess.UserEmail != userEmail) ||
+			(connectionName != "" && sess.Connection != connectionName) {
+			continue
+		}
+		items = append(items, sess.ID)
+	}
+	return items, nil
+}
+
+func (s *fakeKillStore) FlagSessionKilled(orgID, sid string, killed map[string]any) error {
+	s.killed[sid] = killed
+	return nil
+}
+
+func (s *fakeKillStore) DisconnectProxy(orgID, sid string, reason error) bool {
+	if !s.localStreams[sid] {
+		return false
+	}
+	s.disconnected[sid] = reason
+	return true
+}
+
+func newKillTestContext(method, path, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	ctx := storagev2.NewContext("admin-id", "org-id").
+		WithUserInfo("Admin", "admin@bad.org", "active", "", []string{"admin"})
+	c.Set(storagev2.ContextKey, ctx)
+	return c, w
+}
+
+func TestKill(t *testing.T) {
+	for _, tt := range []struct {
+		msg       string
+		sessionID string
+		body      string
+		wantCode  int
+		wantBody  string
+	}{
+		{
+			msg:       "it must kill the session with a client stream",
+			sessionID: "sid-local",
+			body:      `{"reason":"compromised account"}`,
+			wantCode:  http.StatusNoContent,
+		},
+		{
+			msg:       "it must flag the session served by another gateway instance",
+			sessionID: "sid-remote",
+			body:      `{"reason":"compromised account"}`,
+			wantCode:  http.StatusAccepted,
+			wantBody:  `{"message":"the session was flagged as killed, it's terminated by the gateway instance serving its stream"}`,
+		},
+		{
+			msg:       "it must return bad request when the reason is missing",
+			sessionID: "sid-local",
+			body:      `{}`,
+			wantCode:  http.StatusBadRequest,
+		},
+		{
+			msg:       "it must return not found when the session belongs to another organization",
+			sessionID: "sid-other-org",
+			body:      `{"reason":"compromised account"}`,
+			wantCode:  http.StatusNotFound,
+			wantBody:  `{"message":"not found"}`,
+		},
+		{
+			msg:       "it must return unprocessable entity when the session has ended",
+			sessionID: "sid-done",
+			body:      `{"reason":"compromised account"}`,
+			wantCode:  http.StatusUnprocessableEntity,
+			wantBody:  `{"message":"unable to kill a session with status \"done\""}`,
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			store := newFakeKillStore(
+				&models.Session{ID: "sid-local", OrgID: "org-id", Status: "open"},
+				&models.Session{ID: "sid-remote", OrgID: "org-id", Status: "open"},
+				&models.Session{ID: "sid-other-org", OrgID: "other-org-id", Status: "open"},
+				&models.Session{ID: "sid-done", OrgID: "org-id", Status: "done"},
+			)
+			store.localStreams["sid-local"] = true
+			c, w := newKillTestContext(http.MethodDelete, "/api/sessions/"+tt.sessionID, tt.body)
+			c.Params = gin.Params{{Key: "session_id", Value: tt.sessionID}}
+			kill(c, store)
+
+			assert.Equal(t, tt.wantCode, c.Writer.Status())
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+			}
+			if tt.wantCode != http.StatusNoContent && tt.wantCode != http.StatusAccepted {
+				assert.Empty(t, store.killed)
+				return
+			}
+			killed := store.killed[tt.sessionID]
+			assert.Equal(t, "admin@bad.org", killed["killed_by"])
+			assert.Equal(t, "admin-id", killed["killed_by_id"])
+			assert.Equal(t, "compromised account", killed["reason"])
+			if tt.wantCode == http.StatusNoContent {
+				assert.ErrorContains(t, store.disconnected[tt.sessionID], "session killed by admin@bad.org, reason=compromised account")
+			}
+		})
+	}
+}
+
+func TestKillBulk(t *testing.T) {
+	for _, tt := range []struct {
+		msg      string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			msg:      "it must kill all open sessions of the user",
+			body:     `{"user_email":"john.wick@bad.org","reason":"compromised account"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"session_ids":["sid-pg"],"not_connected":["sid-mysql"]}`,
+		},
+		{
+			msg:      "it must kill the open sessions of the user in the connection",
+			body:     `{"user_email":"john.wick@bad.org","connection":"pgdemo","reason":"compromised account"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"session_ids":["sid-pg"],"not_connected":[]}`,
+		},
+		{
+			msg:      "it must return an empty list when there are no open sessions",
+			body:     `{"connection":"redis","reason":"compromised account"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"session_ids":[],"not_connected":[]}`,
+		},
+		{
+			msg:      "it must return unprocessable entity without filters",
+			body:     `{"reason":"compromised account"}`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"message":"user_email or connection is required"}`,
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			store := newFakeKillStore(
+				&models.Session{ID: "sid-pg", OrgID: "org-id", Status: "open", UserEmail: "john.wick@bad.org", Connection: "pgdemo"},
+				&models.Session{ID: "sid-mysql", OrgID: "org-id", Status: "open", UserEmail: "john.wick@bad.org", Connection: "mysqldemo"},
+				&models.Session{ID: "sid-done", OrgID: "org-id", Status: "done", UserEmail: "john.wick@bad.org", Connection: "pgdemo"},
+				&models.Session{ID: "sid-other", OrgID: "org-id", Status: "open", UserEmail: "ana@bad.org", Connection: "redis-prod"},
+			)
+			store.localStreams["sid-pg"] = true
+			c, w := newKillTestContext(http.MethodPost, "/api/sessions/kill", tt.body)
+			killBulk(c, store)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.JSONEq(t, tt.wantBody, w.Body.String())
+			assert.NotContains(t, store.disconnected, "sid-mysql")
+			assert.NotContains(t, store.killed, "sid-done")
+			assert.NotContains(t, store.killed, "sid-other")
+		})
+	}
+}
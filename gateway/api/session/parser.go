@@ -75,12 +75,28 @@ func toOpenApiReview(r *types.Review) (newObj *openapi.Review) {
 				SlackID: g.ReviewedBy.SlackID,
 			}
 		}
+		approvals := []openapi.ReviewApproval{}
+		for _, a := range g.Approvals {
+			approvals = append(approvals, openapi.ReviewApproval{
+				ReviewedBy: openapi.ReviewOwner{
+					ID:      a.Id,
+					Name:    a.Name,
+					Email:   a.Email,
+					SlackID: a.SlackID,
+				},
+				Status:     openapi.ReviewRequestStatusType(a.Status),
+				ReviewDate: a.ReviewDate,
+			})
+		}
 		itemGroups = append(itemGroups, openapi.ReviewGroup{
-			ID:         g.Id,
-			Group:      g.Group,
-			Status:     openapi.ReviewRequestStatusType(g.Status),
-			ReviewedBy: reviewOwner,
-			ReviewDate: g.ReviewDate,
+			ID:           g.Id,
+			Group:        g.Group,
+			Status:       openapi.ReviewRequestStatusType(g.Status),
+			ReviewedBy:   reviewOwner,
+			ReviewDate:   g.ReviewDate,
+			Stage:        g.Stage,
+			MinApprovals: g.MinApprovals,
+			Approvals:    approvals,
 		})
 	}
 	newObj = &openapi.Review{
@@ -106,7 +122,18 @@ func toOpenApiReview(r *types.Review) (newObj *openapi.Review) {
 			ID:   r.Connection.Id,
 			Name: r.Connection.Name,
 		},
-		ReviewGroupsData: itemGroups,
+		ReviewGroupsData:  itemGroups,
+		DistinctApprovers: r.DistinctApprovers,
+		CurrentStage:      r.CurrentStage(),
+		RuleName:          r.RuleName,
+		StatusReason:      r.StatusReason,
+		ExpireAt:          r.ExpireAt,
+		EscalateAt:        r.EscalateAt,
+		EscalationGroup:   r.EscalationGroup,
+		EscalatedAt:       r.EscalatedAt,
+		Revision:          r.Revision,
+		RevisedAt:         r.RevisedAt,
+		ExtendsReviewID:   r.ExtendsReviewId,
 	}
 	return
 }
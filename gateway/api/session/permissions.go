@@ -0,0 +1,45 @@
+package sessionapi
+
+import (
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/rbac"
+	"github.com/hoophq/hoop/gateway/storagev2"
+)
+
+// viewConnections returns the connections where the custom roles
+// of the user grant viewing sessions of other users
+func viewConnections(ctx *storagev2.Context) ([]string, error) {
+	policy, err := rbac.Load(ctx.OrgID, ctx.UserGroups)
+	if err != nil || !policy.Enabled() || !policy.AllowedAny(rbac.PermissionViewSessions) {
+		return nil, err
+	}
+	connList, err := models.ListConnections(ctx.OrgID, models.ConnectionFilterOption{})
+	if err != nil {
+		return nil, err
+	}
+	var items []string
+	for _, conn := range connList {
+		if policy.Allowed(rbac.PermissionViewSessions, rbac.ResourceFromConnection(&conn)) {
+			items = append(items, conn.Name)
+		}
+	}
+	return items, nil
+}
+
+// sessionPolicy returns the custom roles of the user and the connection
+// of the session evaluated against them
+func sessionPolicy(ctx *storagev2.Context, s *models.Session) (*rbac.Policy, rbac.Resource, error) {
+	res := rbac.Resource{Name: s.Connection}
+	policy, err := rbac.Load(ctx.OrgID, ctx.UserGroups)
+	if err != nil || !policy.Enabled() {
+		return policy, res, err
+	}
+	conn, err := models.GetConnectionByNameOrID(ctx.OrgID, s.Connection)
+	if err != nil {
+		return nil, res, err
+	}
+	if conn != nil {
+		res = rbac.ResourceFromConnection(conn)
+	}
+	return policy, res, nil
+}
@@ -126,6 +126,17 @@ func RunReviewedExec(c *gin.Context) {
 		return
 	}
 
+	// the input of the review is the approved revision, it differs from the session
+	// input when the owner resubmits it with changes. The session must audit the input executed.
+	if review.Input != string(session.BlobInput) {
+		if err := models.UpdateSessionReviewedInput(ctx.OrgID, session.ID, review.Input, review.Revision); err != nil {
+			log.Errorf("failed updating session input with the review revision, reason=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed updating session input"})
+			return
+		}
+		log.With("sid", session.ID).Infof("session input updated with the review revision %v", review.Revision)
+	}
+
 	userAgent := apiutils.NormalizeUserAgent(c.Request.Header.Values)
 	if userAgent == "webapp.core" {
 		userAgent = "webapp.review.exec"
@@ -149,7 +160,7 @@ func RunReviewedExec(c *gin.Context) {
 	go func() {
 		defer func() { close(respCh); client.Close() }()
 		select {
-		case respCh <- client.Run([]byte(session.BlobInput), review.InputEnvVars, review.InputClientArgs...):
+		case respCh <- client.Run([]byte(review.Input), review.InputEnvVars, review.InputClientArgs...):
 		default:
 		}
 	}()
@@ -31,6 +31,7 @@ import (
 	"github.com/hoophq/hoop/gateway/jira"
 	"github.com/hoophq/hoop/gateway/models"
 	pgreview "github.com/hoophq/hoop/gateway/pgrest/review"
+	"github.com/hoophq/hoop/gateway/rbac"
 	"github.com/hoophq/hoop/gateway/storagev2"
 	"github.com/hoophq/hoop/gateway/storagev2/types"
 )
@@ -324,6 +325,14 @@ func List(c *gin.Context) {
 	// scope listing to the authenticated user
 	if !ctx.IsAuditorOrAdminUser() {
 		option.User = ctx.UserID
+		viewConnList, err := viewConnections(ctx)
+		if err != nil {
+			log.Errorf("failed validating session permissions, err=%v", err)
+			sentry.CaptureException(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed listing sessions (v2)"})
+			return
+		}
+		option.ViewConnections = viewConnList
 	}
 
 	if option.StartDate.Valid && !option.EndDate.Valid {
@@ -373,14 +382,30 @@ func Get(c *gin.Context) {
 		return
 	}
 
+	policy, res, err := sessionPolicy(ctx, session)
+	if err != nil {
+		log.Errorf("failed validating session permissions, err=%v", err)
+		sentry.CaptureException(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed fetching session"})
+		return
+	}
+
 	// if user is not admin or auditor and session is not owned by user, return 404
-	if session.UserID != ctx.UserID && !ctx.IsAuditorOrAdminUser() {
+	// unless a custom role grants viewing sessions of the connection
+	if session.UserID != ctx.UserID && !ctx.IsAuditorOrAdminUser() &&
+		!(policy.Enabled() && policy.Allowed(rbac.PermissionViewSessions, res)) {
 		c.JSON(http.StatusNotFound, gin.H{"message": "not found"})
 		return
 	}
 
 	fileExt := c.Query("extension")
 	if fileExt != "" {
+		if !policy.Allowed(rbac.PermissionDownloadSessions, res) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  http.StatusForbidden,
+				"message": "user is not allowed to download this session."})
+			return
+		}
 		if appconfig.Get().DisableSessionsDownload() {
 			c.JSON(http.StatusForbidden, gin.H{
 				"status":  http.StatusForbidden,
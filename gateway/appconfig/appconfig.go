@@ -206,7 +206,7 @@ func Load() error {
 func Get() Config { return runtimeConfig }
 
 // loadAuthMethod() returns the auth method to use
-// the possible values are: "local" and "idp".
+// the possible values are: "local", "idp" and "saml".
 // If not set, it defaults to "local"
 // it also cross check the IDP_ISSUER, IDP_CLIENT_ID
 // and IDP_CLIENT_SECRET envs to determine if it should
@@ -218,6 +218,11 @@ func loadAuthMethod() (authMethod string, err error) {
 	switch authMethod {
 	case "local":
 		err = validateLocalAuthJwtKey()
+	case "saml":
+		// access tokens of saml users are issued by the gateway
+		if os.Getenv("JWT_SECRET_KEY") == "" || os.Getenv("SAML_IDP_METADATA_URL") == "" {
+			err = fmt.Errorf("when AUTH_METHOD is set as `saml`, you must configure a random string value at the JWT_SECRET_KEY and the SAML_IDP_METADATA_URL environment variables")
+		}
 	case "idp":
 	default:
 		if !hasIdpEnvs() {
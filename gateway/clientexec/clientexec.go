@@ -59,6 +59,9 @@ type Options struct {
 	Origin         string
 	Verb           string
 	UserAgent      string
+	// ServiceAccountSubject executes on behalf of the service account instead of the bearer token,
+	// it's used by executions started by the gateway, e.g.: scheduled runbooks
+	ServiceAccountSubject string
 }
 
 type Response struct {
@@ -123,20 +126,28 @@ func New(opts *Options) (*clientExec, error) {
 		userAgent = opts.UserAgent
 	}
 
+	clientOptions := []*grpc.ClientOptions{
+		grpc.WithOption(grpc.OptionConnectionName, opts.ConnectionName),
+		grpc.WithOption("origin", opts.Origin),
+		grpc.WithOption("verb", opts.Verb),
+		grpc.WithOption("session-id", opts.SessionID),
+		grpc.WithOption("plain-exec-key", PlainExecSecretKey),
+	}
+	bearerToken := opts.BearerToken
+	if opts.ServiceAccountSubject != "" {
+		// the gateway authenticates the service account with the plain exec key
+		bearerToken = PlainExecSecretKey
+		clientOptions = append(clientOptions, grpc.WithOption("service-account-subject", opts.ServiceAccountSubject))
+	}
+
 	tlsCA := appconfig.Get().GatewayTLSCa()
 	client, err := grpc.Connect(grpc.ClientConfig{
 		ServerAddress: grpc.LocalhostAddr,
-		Token:         opts.BearerToken,
+		Token:         bearerToken,
 		UserAgent:     userAgent,
 		Insecure:      tlsCA == "",
 		TLSCA:         tlsCA,
-	},
-		grpc.WithOption(grpc.OptionConnectionName, opts.ConnectionName),
-		grpc.WithOption("origin", opts.Origin),
-		grpc.WithOption("verb", opts.Verb),
-		grpc.WithOption("session-id", opts.SessionID),
-		grpc.WithOption("plain-exec-key", PlainExecSecretKey),
-	)
+	}, clientOptions...)
 	if err != nil {
 		_ = wlog.Close()
 		return nil, err
@@ -12,13 +12,16 @@ import (
 	"github.com/hoophq/hoop/common/version"
 	"github.com/hoophq/hoop/gateway/agentcontroller"
 	"github.com/hoophq/hoop/gateway/api"
+	localauthapi "github.com/hoophq/hoop/gateway/api/localauth"
 	apiorgs "github.com/hoophq/hoop/gateway/api/orgs"
+	apirunbooks "github.com/hoophq/hoop/gateway/api/runbooks"
 	"github.com/hoophq/hoop/gateway/appconfig"
 	"github.com/hoophq/hoop/gateway/indexer"
 	"github.com/hoophq/hoop/gateway/models"
 	"github.com/hoophq/hoop/gateway/pgrest"
 	pgorgs "github.com/hoophq/hoop/gateway/pgrest/orgs"
 	"github.com/hoophq/hoop/gateway/review"
+	"github.com/hoophq/hoop/gateway/runbookschedule"
 	"github.com/hoophq/hoop/gateway/security/idp"
 	"github.com/hoophq/hoop/gateway/transport"
 	"github.com/hoophq/hoop/gateway/webappjs"
@@ -94,10 +97,11 @@ func Run() {
 		ReviewService: reviewService,
 		IDProvider:    idProvider,
 	}
+	pluginReviewService := &review.Service{TransportService: g}
 	// order matters
 	plugintypes.RegisteredPlugins = []plugintypes.Plugin{
 		pluginsreview.New(
-			&review.Service{TransportService: g},
+			pluginReviewService,
 			apiURL,
 		),
 		pluginsaudit.New(),
@@ -106,7 +110,7 @@ func Run() {
 		pluginsrbac.New(),
 		pluginswebhooks.New(),
 		pluginsslack.New(
-			&review.Service{TransportService: g},
+			pluginReviewService,
 			idProvider),
 	}
 	reviewService.TransportService = g
@@ -125,6 +129,35 @@ func Run() {
 	}
 	connectionstatus.InitConciliationProcess()
 	streamclient.InitProxyMemoryCleanup()
+	streamclient.InitKilledSessionsWatcher()
+
+	// plugins notifying reviewers about timeouts and comments of reviews
+	var reviewNotifiers []review.Notifier
+	for _, p := range plugintypes.RegisteredPlugins {
+		if n, ok := p.(review.Notifier); ok {
+			reviewNotifiers = append(reviewNotifiers, n)
+		}
+	}
+	reviewService.Notifiers = reviewNotifiers
+	pluginReviewService.Notifiers = reviewNotifiers
+	reviewService.InitScheduler()
+	review.InitJitSessionsWatcher()
+
+	// plugins notifying the failures of scheduled runbooks
+	scheduleService := runbookschedule.Service{Executor: apirunbooks.ExecSchedule}
+	for _, p := range plugintypes.RegisteredPlugins {
+		if n, ok := p.(runbookschedule.Notifier); ok {
+			scheduleService.Notifiers = append(scheduleService.Notifiers, n)
+		}
+	}
+	scheduleService.InitScheduler()
+
+	// plugins delivering the self-service password reset tokens of local auth users
+	for _, p := range plugintypes.RegisteredPlugins {
+		if n, ok := p.(localauthapi.PasswordResetNotifier); ok {
+			localauthapi.PasswordResetNotifiers = append(localauthapi.PasswordResetNotifiers, n)
+		}
+	}
 
 	if grpc.ShouldDebugGrpc() {
 		log.SetGrpcLogger()
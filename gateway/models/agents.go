@@ -0,0 +1,119 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	tableAgents                      = "private.agents"
+	tableAgentCertificateAuthorities = "private.agent_certificate_authorities"
+)
+
+// AgentAuth are the authentication attributes of an agent
+// that aren't managed by the postgrest api
+type AgentAuth struct {
+	OrgID               string     `gorm:"column:org_id"`
+	ID                  string     `gorm:"column:id"`
+	PreviousKeyHash     *string    `gorm:"column:previous_key_hash"`
+	PreviousKeyExpireAt *time.Time `gorm:"column:previous_key_expire_at"`
+	MTLSRequired        bool       `gorm:"column:mtls_required"`
+}
+
+// AgentCertificateAuthority issues the client certificates used by agents to authenticate with mTLS
+type AgentCertificateAuthority struct {
+	OrgID       string    `gorm:"column:org_id"`
+	Certificate string    `gorm:"column:certificate"`
+	PrivateKey  string    `gorm:"column:private_key"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+}
+
+func GetAgentAuth(agentID string) (*AgentAuth, error) {
+	var item AgentAuth
+	if err := DB.Table(tableAgents).Where("id = ?", agentID).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func ListAgentAuth(orgID string) ([]*AgentAuth, error) {
+	var items []*AgentAuth
+	return items, DB.Table(tableAgents).Where("org_id = ?", orgID).Find(&items).Error
+}
+
+// GetAgentAuthByPreviousKeyHash returns the agent which the previous key
+// is still valid in the grace period of a key rotation
+func GetAgentAuthByPreviousKeyHash(keyHash string, now time.Time) (*AgentAuth, error) {
+	var item AgentAuth
+	if err := DB.Table(tableAgents).
+		Where("previous_key_hash = ? AND previous_key_expire_at > ?", keyHash, now).
+		First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// RotateAgentKey replaces the key of the agent, the current key remains valid
+// until the previous key expiration. A nil expiration invalidates it immediately.
+func RotateAgentKey(orgID, agentID, keyHash, key string, previousKeyExpireAt *time.Time) error {
+	previousKeyHash := gorm.Expr("key_hash")
+	if previousKeyExpireAt == nil {
+		previousKeyHash = gorm.Expr("NULL")
+	}
+	values := map[string]any{
+		"previous_key_hash":      previousKeyHash,
+		"previous_key_expire_at": previousKeyExpireAt,
+		"key_hash":               keyHash,
+		"updated_at":             time.Now().UTC(),
+	}
+	// only organization keys store the plain key
+	if key != "" {
+		values["key"] = key
+	}
+	res := DB.Table(tableAgents).
+		Where("org_id = ? AND id = ?", orgID, agentID).
+		Updates(values)
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return res.Error
+}
+
+func UpdateAgentMTLSRequired(orgID, agentID string, required bool) error {
+	res := DB.Table(tableAgents).
+		Where("org_id = ? AND id = ?", orgID, agentID).
+		Updates(map[string]any{"mtls_required": required, "updated_at": time.Now().UTC()})
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return res.Error
+}
+
+func GetAgentCertificateAuthority(orgID string) (*AgentCertificateAuthority, error) {
+	var item AgentCertificateAuthority
+	if err := DB.Table(tableAgentCertificateAuthorities).Where("org_id = ?", orgID).
+		First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// CreateAgentCertificateAuthority stores the authority of the organization,
+// it's a noop when the organization already has one
+func CreateAgentCertificateAuthority(ca *AgentCertificateAuthority) error {
+	return DB.Table(tableAgentCertificateAuthorities).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(ca).Error
+}
@@ -45,6 +45,10 @@ type Connection struct {
 	Envs                map[string]string `gorm:"column:envs;serializer:json;->"`
 	GuardRailRules      pq.StringArray    `gorm:"column:guardrail_rules;type:text[];->"`
 	JiraIssueTemplateID sql.NullString    `gorm:"column:jira_issue_template_id"`
+	SessionLimits       *SessionLimits    `gorm:"column:session_limits;serializer:json"`
+	ReviewPolicy        *ReviewPolicy     `gorm:"column:review_policy;serializer:json"`
+	ReviewRules         []ReviewRule      `gorm:"column:review_rules;serializer:json"`
+	BreakGlass          *BreakGlass       `gorm:"column:break_glass;serializer:json"`
 
 	// Read Only fields
 	RedactEnabled             bool           `gorm:"column:redact_enabled;->"`
@@ -63,6 +67,60 @@ func (c Connection) AsSecrets() map[string]any {
 	return dst
 }
 
+// SessionLimits are thresholds enforced by the agent when proxying a session
+type SessionLimits struct {
+	IdleTimeoutSec   int64 `json:"idle_timeout_sec"`
+	MaxBytesIn       int64 `json:"max_bytes_in"`
+	MaxBytesOut      int64 `json:"max_bytes_out"`
+	MaxResponseRows  int64 `json:"max_response_rows"`
+	MaxResponseBytes int64 `json:"max_response_bytes"`
+}
+
+// ReviewPolicy defines the ordered stages of approval of reviews
+type ReviewPolicy struct {
+	Stages              []ReviewPolicyStage `json:"stages"`
+	DistinctApprovers   bool                `json:"distinct_approvers"`
+	ExpireAfterSec      int                 `json:"expire_after_sec"`
+	ReminderIntervalSec int                 `json:"reminder_interval_sec"`
+	EscalateAfterSec    int                 `json:"escalate_after_sec"`
+	EscalationGroup     string              `json:"escalation_group"`
+}
+
+type ReviewPolicyStage struct {
+	Groups []ReviewPolicyGroup `json:"groups"`
+}
+
+type ReviewPolicyGroup struct {
+	Name         string `json:"name"`
+	MinApprovals int    `json:"min_approvals"`
+}
+
+// ReviewRule decides if a session of the connection requires a review
+type ReviewRule struct {
+	Name         string                `json:"name"`
+	Action       string                `json:"action"`
+	QueryType    string                `json:"query_type"`
+	QueryPattern string                `json:"query_pattern"`
+	Users        []string              `json:"users"`
+	Groups       []string              `json:"groups"`
+	TimeWindow   *ReviewRuleTimeWindow `json:"time_window"`
+}
+
+type ReviewRuleTimeWindow struct {
+	Timezone string   `json:"timezone"`
+	Weekdays []string `json:"weekdays"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Outside  bool     `json:"outside"`
+}
+
+// BreakGlass allows emergency access to a connection without approval,
+// the access is reviewed afterwards by the acknowledge group
+type BreakGlass struct {
+	AccessDurationSec int    `json:"access_duration_sec"`
+	AcknowledgeGroup  string `json:"acknowledge_group"`
+}
+
 type EnvVars struct {
 	ID    string            `gorm:"column:id"`
 	OrgID string            `gorm:"column:org_id"`
@@ -160,21 +218,35 @@ func DeleteConnection(orgID, name string) error {
 }
 
 func GetConnectionGuardRailRules(orgID, name string) (*ConnectionGuardRailRules, error) {
+	selectorRules, err := connectionSelectorGuardRailRules(orgID, name)
+	if err != nil {
+		return nil, err
+	}
 	var conn ConnectionGuardRailRules
-	err := DB.Model(&ConnectionGuardRailRules{}).Raw(`
+	err = DB.Model(&ConnectionGuardRailRules{}).Raw(`
 	SELECT
 		c.id, c.org_id, c.name,
 		(
 			SELECT json_agg(r.input) FROM private.guardrail_rules r
-			INNER JOIN private.guardrail_rules_connections rc ON rc.connection_id = c.id AND rc.rule_id = r.id
+			WHERE r.org_id = c.org_id AND (
+				r.id::text = ANY(@selector_rules) OR
+				EXISTS (SELECT 1 FROM private.guardrail_rules_connections rc WHERE rc.connection_id = c.id AND rc.rule_id = r.id)
+			)
 		) AS guardrail_input_rules,
 		(
 			SELECT json_agg(r.output) FROM private.guardrail_rules r
-			INNER JOIN private.guardrail_rules_connections rc ON rc.connection_id = c.id AND rc.rule_id = r.id
+			WHERE r.org_id = c.org_id AND (
+				r.id::text = ANY(@selector_rules) OR
+				EXISTS (SELECT 1 FROM private.guardrail_rules_connections rc WHERE rc.connection_id = c.id AND rc.rule_id = r.id)
+			)
 		) AS guardrail_output_rules
 	FROM private.connections c
-	WHERE c.org_id = ? AND c.name = ?
-	`, orgID, name).First(&conn).Error
+	WHERE c.org_id = @org_id AND c.name = @name
+	`, map[string]any{
+		"org_id":         orgID,
+		"name":           name,
+		"selector_rules": selectorRules,
+	}).First(&conn).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -184,6 +256,23 @@ func GetConnectionGuardRailRules(orgID, name string) (*ConnectionGuardRailRules,
 	return &conn, nil
 }
 
+// connectionSelectorGuardRailRules returns the guard rail rules
+// assigned to a connection by policy selectors
+func connectionSelectorGuardRailRules(orgID, name string) (pq.StringArray, error) {
+	selectors, err := ListPolicySelectorsByType(orgID, PolicySelectorGuardRails)
+	if err != nil || len(selectors) == 0 {
+		return pq.StringArray{}, err
+	}
+	var conn Connection
+	err = DB.Raw(`SELECT COALESCE(_tags, ARRAY[]::TEXT[]) AS _tags FROM private.connections WHERE org_id = ? AND name = ?`,
+		orgID, name).Scan(&conn).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching connection tags, reason=%v", err)
+	}
+	rules := pq.StringArray{}
+	return append(rules, PolicySelectorsConfig(MatchPolicySelectors(selectors, conn.Tags))...), nil
+}
+
 func GetConnectionByNameOrID(orgID, nameOrID string) (*Connection, error) {
 	var conn Connection
 	err := DB.Model(&Connection{}).Raw(`
@@ -191,7 +280,7 @@ func GetConnectionByNameOrID(orgID, nameOrID string) (*Connection, error) {
 		c.id, c.org_id, c.name, c.command, c.status, c.type, c.subtype, c.managed_by,
 		c.access_mode_runbooks, c.access_mode_exec, c.access_mode_connect, c.access_schema,
 		c.agent_id, a.name AS agent_name, a.mode AS agent_mode,
-		c.jira_issue_template_id, it.issue_transition_name_on_close,
+		c.jira_issue_template_id, it.issue_transition_name_on_close, c.session_limits, c.review_policy, c.review_rules, c.break_glass,
 		COALESCE(c._tags, ARRAY[]::TEXT[]) AS _tags,
 		( SELECT envs FROM public.env_vars WHERE id = c.id ) AS envs,
 		COALESCE(dlpc.config, ARRAY[]::TEXT[]) AS redact_types,
@@ -250,7 +339,7 @@ func ListConnections(orgID string, opts ConnectionFilterOption) ([]Connection, e
 	SELECT
 		c.id, c.org_id, c.agent_id, c.name, c.command, c.status, c.type, c.subtype, c.managed_by,
 		c.access_mode_runbooks, c.access_mode_exec, c.access_mode_connect, c.access_schema,
-		c.jira_issue_template_id,
+		c.jira_issue_template_id, c.session_limits, c.review_policy, c.review_rules, c.break_glass,
 		COALESCE(c._tags, ARRAY[]::TEXT[]) AS _tags,
 		( SELECT envs FROM public.env_vars WHERE id = c.id ) AS envs,
 		COALESCE(dlpc.config, ARRAY[]::TEXT[]) AS redact_types,
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	tableUserLocalAuth      = "private.user_local_auth"
+	tablePasswordResetToken = "private.password_reset_tokens"
+)
+
+// UserLocalAuth holds the second factor and the failed login attempts
+// of users authenticating with the local auth method
+type UserLocalAuth struct {
+	UserID          string     `gorm:"column:user_id"`
+	OrgID           string     `gorm:"column:org_id"`
+	TOTPSecret      *string    `gorm:"column:totp_secret"`
+	TOTPEnabled     bool       `gorm:"column:totp_enabled"`
+	TOTPLastCounter int64      `gorm:"column:totp_last_counter"`
+	FailedAttempts  int        `gorm:"column:failed_attempts"`
+	LockedUntil     *time.Time `gorm:"column:locked_until"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at"`
+}
+
+func (a *UserLocalAuth) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
+
+// PasswordResetToken allows setting a new password of a local auth user,
+// it's created by the user itself or by an admin user
+type PasswordResetToken struct {
+	OrgID     string     `gorm:"column:org_id"`
+	ID        string     `gorm:"column:id"`
+	UserID    string     `gorm:"column:user_id"`
+	TokenHash string     `gorm:"column:token_hash"`
+	CreatedBy string     `gorm:"column:created_by"`
+	ExpireAt  time.Time  `gorm:"column:expire_at"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+}
+
+// GetUserLocalAuth returns the local auth state of the user,
+// an empty state is returned when the user doesn't have one yet
+func GetUserLocalAuth(orgID, userID string) (*UserLocalAuth, error) {
+	var item UserLocalAuth
+	err := DB.Table(tableUserLocalAuth).Where("user_id = ?", userID).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &UserLocalAuth{OrgID: orgID, UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// UpdateUserTOTP creates or updates the second factor of the user,
+// the failed login attempts and the lockout of the user are kept
+func UpdateUserTOTP(orgID, userID string, secret *string, enabled bool, lastCounter int64) error {
+	return DB.Table(tableUserLocalAuth).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"totp_secret", "totp_enabled", "totp_last_counter", "updated_at"}),
+		}).
+		Create(&UserLocalAuth{
+			UserID:          userID,
+			OrgID:           orgID,
+			TOTPSecret:      secret,
+			TOTPEnabled:     enabled,
+			TOTPLastCounter: lastCounter,
+			UpdatedAt:       time.Now().UTC(),
+		}).Error
+}
+
+// ClaimUserTOTPCounter records the counter of a valid code of the user. It returns false
+// when a code of the same or a later counter was used, e.g.: by a concurrent login.
+func ClaimUserTOTPCounter(userID string, counter int64) (bool, error) {
+	res := DB.Table(tableUserLocalAuth).
+		Where("user_id = ? AND totp_enabled = ? AND totp_last_counter < ?", userID, true, counter).
+		Updates(map[string]any{"totp_last_counter": counter, "updated_at": time.Now().UTC()})
+	return res.RowsAffected == 1, res.Error
+}
+
+// IncrementUserLoginFailures adds a failed login attempt to the user
+// and returns the amount of consecutive failed attempts
+func IncrementUserLoginFailures(orgID, userID string) (int, error) {
+	var attempts int
+	err := DB.Raw(`
+	INSERT INTO private.user_local_auth (user_id, org_id, failed_attempts, updated_at)
+	VALUES (?, ?, 1, NOW())
+	ON CONFLICT (user_id) DO UPDATE
+		SET failed_attempts = user_local_auth.failed_attempts + 1, updated_at = NOW()
+	RETURNING failed_attempts`, userID, orgID).Scan(&attempts).Error
+	return attempts, err
+}
+
+// UpdateUserLockout locks the login of the user until the given time
+func UpdateUserLockout(userID string, lockedUntil time.Time) error {
+	return DB.Table(tableUserLocalAuth).
+		Where("user_id = ?", userID).
+		Updates(map[string]any{"locked_until": lockedUntil, "updated_at": time.Now().UTC()}).Error
+}
+
+// ResetUserLoginFailures clears the failed login attempts and the lockout of the user
+func ResetUserLoginFailures(userID string) error {
+	return DB.Table(tableUserLocalAuth).
+		Where("user_id = ?", userID).
+		Updates(map[string]any{"failed_attempts": 0, "locked_until": nil, "updated_at": time.Now().UTC()}).Error
+}
+
+func CreatePasswordResetToken(t *PasswordResetToken) error {
+	return DB.Table(tablePasswordResetToken).Model(t).Create(t).Error
+}
+
+// CreateSelfServicePasswordResetToken creates the reset token requested by the user itself.
+// It returns false without creating it when the user requested a token after createdAfter
+// or when the user has maxPending valid tokens.
+func CreateSelfServicePasswordResetToken(t *PasswordResetToken, createdAfter time.Time, maxPending int) (bool, error) {
+	var created bool
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// serializes the requests of the user
+		var userID string
+		err := tx.Raw(`SELECT id FROM private.users WHERE id = ? FOR UPDATE`, t.UserID).Scan(&userID).Error
+		if err != nil {
+			return err
+		}
+		var recent, pending int64
+		err = tx.Table(tablePasswordResetToken).
+			Where("user_id = ? AND created_at > ?", t.UserID, createdAfter).
+			Count(&recent).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Table(tablePasswordResetToken).
+			Where("user_id = ? AND used_at IS NULL AND expire_at > ?", t.UserID, t.CreatedAt).
+			Count(&pending).Error
+		if err != nil || recent > 0 || pending >= int64(maxPending) {
+			return err
+		}
+		if err := tx.Table(tablePasswordResetToken).Model(t).Create(t).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// ConsumePasswordResetToken sets the password of the user of a valid reset token.
+// The token and any other pending token of the user are invalidated, and the
+// failed login attempts are cleared. It returns ErrNotFound if the token is invalid or expired.
+func ConsumePasswordResetToken(tokenHash, hashedPassword string, now time.Time) (*PasswordResetToken, error) {
+	var item PasswordResetToken
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Table(tablePasswordResetToken).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL AND expire_at > ?", tokenHash, now).
+			First(&item).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		err = tx.Table(tablePasswordResetToken).
+			Where("user_id = ? AND used_at IS NULL", item.UserID).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Table("private.users").
+			Where("id = ?", item.UserID).
+			Update("hashed_password", hashedPassword).Error
+		if err != nil {
+			return err
+		}
+		return tx.Table(tableUserLocalAuth).
+			Where("user_id = ?", item.UserID).
+			Updates(map[string]any{"failed_attempts": 0, "locked_until": nil, "updated_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
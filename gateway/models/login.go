@@ -0,0 +1,33 @@
+package models
+
+import "database/sql"
+
+// LoginStateProcessing is the outcome of a login state consumed by a callback
+// of the identity provider, it's replaced by the outcome of the login when it finishes.
+const LoginStateProcessing = "processing"
+
+// LoginState is the state of a login started with the identity provider
+type LoginState struct {
+	ID       string         `gorm:"column:id"`
+	Redirect string         `gorm:"column:redirect"`
+	SlackID  sql.NullString `gorm:"column:slack_id"`
+}
+
+// ConsumeLoginState marks a pending login state as processing and returns it.
+// A state is consumed only once, it returns ErrNotFound if the state
+// doesn't exist or it was already used by another callback.
+func ConsumeLoginState(id string) (*LoginState, error) {
+	var items []LoginState
+	err := DB.Raw(`
+	UPDATE private.login
+	SET outcome = ?, updated_at = NOW()
+	WHERE id = ? AND COALESCE(outcome, '') = ''
+	RETURNING id, redirect, slack_id`, LoginStateProcessing, id).Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrNotFound
+	}
+	return &items[0], nil
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+const tablePersonalAccessTokens = "private.personal_access_tokens"
+
+// lastUsedInterval throttles the updates of the last usage of a token
+const lastUsedInterval = time.Minute
+
+// PersonalAccessToken authenticates a user in the api and the gRPC gateway,
+// the scopes restrict what the token is allowed to access
+type PersonalAccessToken struct {
+	OrgID       string         `gorm:"column:org_id"`
+	ID          string         `gorm:"column:id"`
+	UserSubject string         `gorm:"column:user_subject"`
+	Name        string         `gorm:"column:name"`
+	TokenHash   string         `gorm:"column:token_hash"`
+	TokenPrefix string         `gorm:"column:token_prefix"`
+	Scopes      pq.StringArray `gorm:"column:scopes;type:text[]"`
+	ExpireAt    time.Time      `gorm:"column:expire_at"`
+	LastUsedAt  *time.Time     `gorm:"column:last_used_at"`
+	RevokedAt   *time.Time     `gorm:"column:revoked_at"`
+	CreatedAt   time.Time      `gorm:"column:created_at"`
+
+	// read only attribute of the user
+	UserEmail string `gorm:"column:user_email;->"`
+}
+
+func (t *PersonalAccessToken) IsValid(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpireAt)
+}
+
+func personalAccessTokensQuery() *gorm.DB {
+	return DB.Table(tablePersonalAccessTokens + " AS t").
+		Select("t.*, u.email AS user_email").
+		Joins("LEFT JOIN private.users u ON u.subject = t.user_subject")
+}
+
+// ListPersonalAccessTokens returns the tokens of the organization,
+// an empty user subject returns the tokens of all users
+func ListPersonalAccessTokens(orgID, userSubject string) ([]*PersonalAccessToken, error) {
+	var items []*PersonalAccessToken
+	tx := personalAccessTokensQuery().Where("t.org_id = ?", orgID)
+	if userSubject != "" {
+		tx = tx.Where("t.user_subject = ?", userSubject)
+	}
+	return items, tx.Order("t.created_at DESC").Find(&items).Error
+}
+
+func GetPersonalAccessToken(orgID, id string) (*PersonalAccessToken, error) {
+	var item PersonalAccessToken
+	if err := personalAccessTokensQuery().Where("t.org_id = ? AND t.id = ?", orgID, id).
+		First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func GetPersonalAccessTokenByHash(tokenHash string) (*PersonalAccessToken, error) {
+	var item PersonalAccessToken
+	if err := DB.Table(tablePersonalAccessTokens).Where("token_hash = ?", tokenHash).
+		First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func CreatePersonalAccessToken(t *PersonalAccessToken) error {
+	err := DB.Table(tablePersonalAccessTokens).Model(t).Create(t).Error
+	if err == gorm.ErrDuplicatedKey {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// RevokePersonalAccessToken revokes a token that hasn't been revoked yet
+func RevokePersonalAccessToken(orgID, id string) error {
+	res := DB.Table(tablePersonalAccessTokens).
+		Where("org_id = ? AND id = ? AND revoked_at IS NULL", orgID, id).
+		Update("revoked_at", time.Now().UTC())
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return res.Error
+}
+
+// UpdatePersonalAccessTokenLastUsed records the usage of the token,
+// it's updated at most once per minute to avoid a write on every request
+func UpdatePersonalAccessTokenLastUsed(id string, now time.Time) error {
+	return DB.Table(tablePersonalAccessTokens).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-lastUsedInterval)).
+		Update("last_used_at", now).Error
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const tablePolicySelectors = "private.policy_selectors"
+
+// The policy types that could be assigned by selectors,
+// access control and review match the name of their plugins.
+const (
+	PolicySelectorAccessControl = "access_control"
+	PolicySelectorReview        = "review"
+	PolicySelectorGuardRails    = "guardrails"
+)
+
+var PolicySelectorTypes = []string{
+	PolicySelectorAccessControl,
+	PolicySelectorReview,
+	PolicySelectorGuardRails,
+}
+
+// PolicySelector assigns a policy to all connections matching the tags of the selector.
+// The config contains the groups of access control and review policies or
+// the id of the rules of guard rails policies.
+type PolicySelector struct {
+	OrgID     string         `gorm:"column:org_id"`
+	ID        string         `gorm:"column:id"`
+	Name      string         `gorm:"column:name"`
+	Type      string         `gorm:"column:type"`
+	Selector  string         `gorm:"column:selector"`
+	Config    pq.StringArray `gorm:"column:config;type:text[]"`
+	CreatedAt time.Time      `gorm:"column:created_at"`
+	UpdatedAt time.Time      `gorm:"column:updated_at"`
+}
+
+// ParseTagSelector parses a comma separated list of tags, e.g.: env=prod,team=payments.
+// A connection matches the selector when it contains all the tags.
+func ParseTagSelector(selector string) ([]string, error) {
+	var tags []string
+	for _, tag := range strings.Split(selector, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			return nil, fmt.Errorf("selector %q contains an empty tag", selector)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// Matches returns true if the tags contain all the tags of the selector
+func (s *PolicySelector) Matches(tags []string) bool {
+	selectorTags, err := ParseTagSelector(s.Selector)
+	if err != nil {
+		return false
+	}
+	for _, tag := range selectorTags {
+		if !slices.Contains(tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchPolicySelectors returns the selectors matching the tags
+func MatchPolicySelectors(selectors []*PolicySelector, tags []string) (items []*PolicySelector) {
+	for _, s := range selectors {
+		if s.Matches(tags) {
+			items = append(items, s)
+		}
+	}
+	return
+}
+
+// PolicySelectorsConfig returns the config of all selectors without duplicates
+func PolicySelectorsConfig(selectors []*PolicySelector) (config []string) {
+	for _, s := range selectors {
+		for _, val := range s.Config {
+			if !slices.Contains(config, val) {
+				config = append(config, val)
+			}
+		}
+	}
+	return
+}
+
+func ListPolicySelectors(orgID string) ([]*PolicySelector, error) {
+	var items []*PolicySelector
+	return items,
+		DB.Table(tablePolicySelectors).
+			Where("org_id = ?", orgID).Order("name ASC").Find(&items).Error
+}
+
+func ListPolicySelectorsByType(orgID, policyType string) ([]*PolicySelector, error) {
+	var items []*PolicySelector
+	return items,
+		DB.Table(tablePolicySelectors).
+			Where("org_id = ? AND type = ?", orgID, policyType).Order("name ASC").Find(&items).Error
+}
+
+func GetPolicySelector(orgID, id string) (*PolicySelector, error) {
+	var item PolicySelector
+	if err := DB.Table(tablePolicySelectors).Where("org_id = ? AND id = ?", orgID, id).
+		First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func CreatePolicySelector(s *PolicySelector) error {
+	err := DB.Table(tablePolicySelectors).Model(s).Create(s).Error
+	if err == gorm.ErrDuplicatedKey {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func UpdatePolicySelector(s *PolicySelector) error {
+	res := DB.Table(tablePolicySelectors).
+		Model(s).
+		Clauses(clause.Returning{}).
+		Where("org_id = ? AND id = ?", s.OrgID, s.ID).
+		Select("name", "type", "selector", "config", "updated_at").
+		Updates(PolicySelector{
+			Name:      s.Name,
+			Type:      s.Type,
+			Selector:  s.Selector,
+			Config:    s.Config,
+			UpdatedAt: s.UpdatedAt,
+		})
+	if res.Error == gorm.ErrDuplicatedKey {
+		return ErrAlreadyExists
+	}
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return res.Error
+}
+
+func DeletePolicySelector(orgID, id string) error {
+	res := DB.Table(tablePolicySelectors).
+		Where(`org_id = ? AND id = ?`, orgID, id).
+		Delete(&PolicySelector{})
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return res.Error
+}
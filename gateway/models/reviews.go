@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const tableReviews = "private.reviews"
+
+const (
+	reviewStatusPending  = "PENDING"
+	reviewStatusRejected = "REJECTED"
+)
+
+// ClaimReviewExpiration rejects a pending review that reached its expiration. It returns false
+// when the review was reviewed or expired by another gateway instance after it was fetched.
+func ClaimReviewExpiration(orgID, id, reason string) (bool, error) {
+	res := DB.Table(tableReviews).
+		Where("org_id = ? AND id = ? AND status = ?", orgID, id, reviewStatusPending).
+		Updates(map[string]any{"status": reviewStatusRejected, "status_reason": reason})
+	return res.RowsAffected == 1, res.Error
+}
+
+// ClaimReviewEscalation marks a pending review as escalated. It returns false when the review
+// was reviewed or escalated by another gateway instance after it was fetched.
+func ClaimReviewEscalation(orgID, id string, now time.Time) (bool, error) {
+	res := DB.Table(tableReviews).
+		Where("org_id = ? AND id = ? AND status = ? AND escalated_at IS NULL", orgID, id, reviewStatusPending).
+		Updates(map[string]any{"escalated_at": now, "reminded_at": now})
+	return res.RowsAffected == 1, res.Error
+}
+
+// ClaimReviewReminder records the reminder of a pending review not reminded since remindBefore.
+// It returns false when the review was reviewed or reminded by another gateway instance after it was fetched.
+func ClaimReviewReminder(orgID, id string, remindBefore, now time.Time) (bool, error) {
+	res := DB.Table(tableReviews).
+		Where("org_id = ? AND id = ? AND status = ? AND (reminded_at IS NULL OR reminded_at <= ?)",
+			orgID, id, reviewStatusPending, remindBefore).
+		Update("reminded_at", now)
+	return res.RowsAffected == 1, res.Error
+}
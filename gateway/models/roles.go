@@ -0,0 +1,110 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const tableRoles = "private.roles"
+
+// Role is a set of permissions scoped to resources that are granted
+// to the users of the assigned groups
+type Role struct {
+	OrgID       string           `gorm:"column:org_id"`
+	ID          string           `gorm:"column:id"`
+	Name        string           `gorm:"column:name"`
+	Description string           `gorm:"column:description"`
+	Permissions []RolePermission `gorm:"column:permissions;serializer:json"`
+	Groups      pq.StringArray   `gorm:"column:groups;type:text[]"`
+	CreatedAt   time.Time        `gorm:"column:created_at"`
+	UpdatedAt   time.Time        `gorm:"column:updated_at"`
+}
+
+// RolePermission grants a list of actions to the resources matching the scope
+type RolePermission struct {
+	Actions []string  `json:"actions"`
+	Scope   RoleScope `json:"scope"`
+}
+
+// RoleScope matches connections by name, tags or agents.
+// An empty scope matches all connections of the organization.
+type RoleScope struct {
+	Connections []string `json:"connections"`
+	Tags        []string `json:"tags"`
+	Agents      []string `json:"agents"`
+}
+
+func ListRoles(orgID string) ([]*Role, error) {
+	var roles []*Role
+	return roles,
+		DB.Table(tableRoles).
+			Where("org_id = ?", orgID).Order("name ASC").Find(&roles).Error
+}
+
+// ListRolesByGroups returns the roles assigned to any of the groups
+func ListRolesByGroups(orgID string, groups []string) ([]*Role, error) {
+	var roles []*Role
+	if len(groups) == 0 {
+		return roles, nil
+	}
+	return roles,
+		DB.Table(tableRoles).
+			Where("org_id = ? AND groups && ?", orgID, pq.StringArray(groups)).
+			Order("name ASC").Find(&roles).Error
+}
+
+func GetRole(orgID, roleID string) (*Role, error) {
+	var role Role
+	if err := DB.Table(tableRoles).Where("org_id = ? AND id = ?", orgID, roleID).
+		First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func CreateRole(role *Role) error {
+	err := DB.Table(tableRoles).Model(role).Create(role).Error
+	if err == gorm.ErrDuplicatedKey {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func UpdateRole(r *Role) error {
+	res := DB.Table(tableRoles).
+		Model(r).
+		Clauses(clause.Returning{}).
+		Where("org_id = ? AND id = ?", r.OrgID, r.ID).
+		Select("name", "description", "permissions", "groups", "updated_at").
+		Updates(Role{
+			Name:        r.Name,
+			Description: r.Description,
+			Permissions: r.Permissions,
+			Groups:      r.Groups,
+			UpdatedAt:   r.UpdatedAt,
+		})
+	if res.Error == gorm.ErrDuplicatedKey {
+		return ErrAlreadyExists
+	}
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return res.Error
+}
+
+func DeleteRole(orgID, roleID string) error {
+	res := DB.Table(tableRoles).
+		Where(`org_id = ? AND id = ?`, orgID, roleID).
+		Delete(&Role{})
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return res.Error
+}
@@ -0,0 +1,208 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	tableRunbookSchedules    = "private.runbook_schedules"
+	tableRunbookScheduleRuns = "private.runbook_schedule_runs"
+)
+
+const (
+	RunbookScheduleRunStatusRunning       = "running"
+	RunbookScheduleRunStatusSuccess       = "success"
+	RunbookScheduleRunStatusFailed        = "failed"
+	RunbookScheduleRunStatusWaitingReview = "waiting_review"
+)
+
+var ErrServiceAccountNotFound = errors.New("service account not found")
+
+// RunbookSchedule executes a runbook periodically on behalf of a service account
+type RunbookSchedule struct {
+	OrgID            string            `gorm:"column:org_id"`
+	ID               string            `gorm:"column:id"`
+	ServiceAccountID string            `gorm:"column:service_account_id"`
+	Name             string            `gorm:"column:name"`
+	ConnectionName   string            `gorm:"column:connection_name"`
+	RunbookFile      string            `gorm:"column:runbook_file"`
+	Parameters       map[string]string `gorm:"column:parameters;serializer:json"`
+	CronExpression   string            `gorm:"column:cron_expression"`
+	Timezone         string            `gorm:"column:timezone"`
+	Enabled          bool              `gorm:"column:enabled"`
+	NextRunAt        *time.Time        `gorm:"column:next_run_at"`
+	LastRunAt        *time.Time        `gorm:"column:last_run_at"`
+	CreatedAt        time.Time         `gorm:"column:created_at"`
+	UpdatedAt        time.Time         `gorm:"column:updated_at"`
+
+	// read only attributes of the service account
+	ServiceAccountSubject string `gorm:"column:service_account_subject;->"`
+	ServiceAccountName    string `gorm:"column:service_account_name;->"`
+}
+
+// RunbookScheduleRun is an execution of a runbook schedule
+type RunbookScheduleRun struct {
+	OrgID       string     `gorm:"column:org_id"`
+	ID          string     `gorm:"column:id"`
+	ScheduleID  string     `gorm:"column:schedule_id"`
+	SessionID   *string    `gorm:"column:session_id"`
+	Status      string     `gorm:"column:status"`
+	ExitCode    *int       `gorm:"column:exit_code"`
+	Error       *string    `gorm:"column:error"`
+	ScheduledAt time.Time  `gorm:"column:scheduled_at"`
+	StartedAt   time.Time  `gorm:"column:started_at"`
+	FinishedAt  *time.Time `gorm:"column:finished_at"`
+}
+
+func runbookSchedulesQuery() *gorm.DB {
+	return DB.Table(tableRunbookSchedules + " AS s").
+		Select("s.*, sa.subject AS service_account_subject, sa.name AS service_account_name").
+		Joins("INNER JOIN private.service_accounts sa ON sa.id = s.service_account_id")
+}
+
+func ListRunbookSchedules(orgID string) ([]*RunbookSchedule, error) {
+	var items []*RunbookSchedule
+	return items,
+		runbookSchedulesQuery().
+			Where("s.org_id = ?", orgID).Order("s.name ASC").Find(&items).Error
+}
+
+func GetRunbookScheduleByName(orgID, name string) (*RunbookSchedule, error) {
+	var item RunbookSchedule
+	if err := runbookSchedulesQuery().Where("s.org_id = ? AND s.name = ?", orgID, name).
+		First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ListDueRunbookSchedules returns the enabled schedules of all organizations
+// which the next execution has been reached
+func ListDueRunbookSchedules(now time.Time) ([]*RunbookSchedule, error) {
+	var items []*RunbookSchedule
+	return items,
+		runbookSchedulesQuery().
+			Where("s.enabled = TRUE AND s.next_run_at <= ?", now).
+			Order("s.next_run_at ASC").Find(&items).Error
+}
+
+// CreateRunbookSchedule creates the schedule resolving the service account by its subject
+func CreateRunbookSchedule(s *RunbookSchedule) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := setScheduleServiceAccount(tx, s); err != nil {
+			return err
+		}
+		err := tx.Table(tableRunbookSchedules).Model(s).Create(s).Error
+		if err == gorm.ErrDuplicatedKey {
+			return ErrAlreadyExists
+		}
+		return err
+	})
+}
+
+// UpdateRunbookSchedule updates the schedule resolving the service account by its subject
+func UpdateRunbookSchedule(s *RunbookSchedule) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := setScheduleServiceAccount(tx, s); err != nil {
+			return err
+		}
+		res := tx.Table(tableRunbookSchedules).
+			Model(s).
+			Clauses(clause.Returning{}).
+			Where("org_id = ? AND name = ?", s.OrgID, s.Name).
+			Select("service_account_id", "connection_name", "runbook_file", "parameters",
+				"cron_expression", "timezone", "enabled", "next_run_at", "updated_at").
+			Updates(RunbookSchedule{
+				ServiceAccountID: s.ServiceAccountID,
+				ConnectionName:   s.ConnectionName,
+				RunbookFile:      s.RunbookFile,
+				Parameters:       s.Parameters,
+				CronExpression:   s.CronExpression,
+				Timezone:         s.Timezone,
+				Enabled:          s.Enabled,
+				NextRunAt:        s.NextRunAt,
+				UpdatedAt:        s.UpdatedAt,
+			})
+		if res.Error == nil && res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return res.Error
+	})
+}
+
+// UpdateRunbookScheduleEnabled enables or disables the schedule,
+// the next run is recalculated by the caller when it's enabled
+func UpdateRunbookScheduleEnabled(orgID, name string, enabled bool, nextRunAt *time.Time) error {
+	res := DB.Table(tableRunbookSchedules).
+		Where("org_id = ? AND name = ?", orgID, name).
+		Updates(map[string]any{"enabled": enabled, "next_run_at": nextRunAt, "updated_at": time.Now().UTC()})
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return res.Error
+}
+
+// ClaimRunbookSchedule advances the next run of a due schedule. It returns false when
+// the schedule was changed or claimed by another gateway instance, in this case it must not be executed.
+func ClaimRunbookSchedule(s *RunbookSchedule, nextRunAt, now time.Time) (bool, error) {
+	res := DB.Table(tableRunbookSchedules).
+		Where("id = ? AND enabled = TRUE AND next_run_at = ?", s.ID, s.NextRunAt).
+		Updates(map[string]any{"next_run_at": nextRunAt, "last_run_at": now})
+	return res.RowsAffected == 1, res.Error
+}
+
+func DeleteRunbookSchedule(orgID, name string) error {
+	res := DB.Table(tableRunbookSchedules).
+		Where("org_id = ? AND name = ?", orgID, name).
+		Delete(&RunbookSchedule{})
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return res.Error
+}
+
+func CreateRunbookScheduleRun(run *RunbookScheduleRun) error {
+	return DB.Table(tableRunbookScheduleRuns).Model(run).Create(run).Error
+}
+
+func UpdateRunbookScheduleRun(run *RunbookScheduleRun) error {
+	return DB.Table(tableRunbookScheduleRuns).
+		Where("id = ?", run.ID).
+		Select("session_id", "status", "exit_code", "error", "finished_at").
+		Updates(run).Error
+}
+
+// ListRunbookScheduleRuns returns the latest executions of the schedule
+func ListRunbookScheduleRuns(orgID, scheduleID string, limit int) ([]*RunbookScheduleRun, error) {
+	var items []*RunbookScheduleRun
+	return items,
+		DB.Table(tableRunbookScheduleRuns).
+			Where("org_id = ? AND schedule_id = ?", orgID, scheduleID).
+			Order("started_at DESC").Limit(limit).Find(&items).Error
+}
+
+func setScheduleServiceAccount(tx *gorm.DB, s *RunbookSchedule) error {
+	var sa struct {
+		ID   string `gorm:"column:id"`
+		Name string `gorm:"column:name"`
+	}
+	err := tx.Table("private.service_accounts").
+		Where("org_id = ? AND subject = ?", s.OrgID, s.ServiceAccountSubject).
+		First(&sa).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrServiceAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+	s.ServiceAccountID = sa.ID
+	s.ServiceAccountName = sa.Name
+	return nil
+}
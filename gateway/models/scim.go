@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	tableScimTokens = "private.scim_tokens"
+	tableScimGroups = "private.scim_groups"
+)
+
+// ScimToken authenticates the identity provider provisioning users and groups of an organization
+type ScimToken struct {
+	OrgID     string    `gorm:"column:org_id"`
+	TokenHash string    `gorm:"column:token_hash"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+// ScimGroup is a group provisioned by the identity provider,
+// its members are stored as user groups with the same display name
+type ScimGroup struct {
+	OrgID       string    `gorm:"column:org_id"`
+	ID          string    `gorm:"column:id"`
+	DisplayName string    `gorm:"column:display_name"`
+	ExternalID  string    `gorm:"column:external_id"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at"`
+}
+
+// UpsertScimToken replaces the token of the organization
+func UpsertScimToken(t *ScimToken) error {
+	return DB.Table(tableScimTokens).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "org_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"token_hash", "created_at"}),
+		}).
+		Create(t).Error
+}
+
+func GetScimTokenByHash(tokenHash string) (*ScimToken, error) {
+	var t ScimToken
+	if err := DB.Table(tableScimTokens).Where("token_hash = ?", tokenHash).
+		First(&t).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func DeleteScimToken(orgID string) error {
+	res := DB.Table(tableScimTokens).Where("org_id = ?", orgID).Delete(&ScimToken{})
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return res.Error
+}
+
+func ListScimGroups(orgID string) ([]*ScimGroup, error) {
+	var items []*ScimGroup
+	return items,
+		DB.Table(tableScimGroups).
+			Where("org_id = ?", orgID).Order("display_name ASC").Find(&items).Error
+}
+
+func GetScimGroup(orgID, id string) (*ScimGroup, error) {
+	var item ScimGroup
+	if err := DB.Table(tableScimGroups).Where("org_id = ? AND id = ?", orgID, id).
+		First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func CreateScimGroup(g *ScimGroup) error {
+	err := DB.Table(tableScimGroups).Model(g).Create(g).Error
+	if err == gorm.ErrDuplicatedKey {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// UpdateScimGroup updates the group and renames the user groups of its members
+func UpdateScimGroup(g *ScimGroup, oldDisplayName string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Table(tableScimGroups).
+			Model(g).
+			Clauses(clause.Returning{}).
+			Where("org_id = ? AND id = ?", g.OrgID, g.ID).
+			Select("display_name", "external_id", "updated_at").
+			Updates(ScimGroup{
+				DisplayName: g.DisplayName,
+				ExternalID:  g.ExternalID,
+				UpdatedAt:   g.UpdatedAt,
+			})
+		if res.Error == gorm.ErrDuplicatedKey {
+			return ErrAlreadyExists
+		}
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		if oldDisplayName == g.DisplayName {
+			return nil
+		}
+		return tx.Exec(`UPDATE private.user_groups SET name = ? WHERE org_id = ? AND name = ? AND user_id IS NOT NULL`,
+			g.DisplayName, g.OrgID, oldDisplayName).Error
+	})
+}
+
+// DeleteScimGroup removes the group and the memberships of its users
+func DeleteScimGroup(g *ScimGroup) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`DELETE FROM private.user_groups WHERE org_id = ? AND name = ? AND user_id IS NOT NULL`,
+			g.OrgID, g.DisplayName).Error
+		if err != nil {
+			return err
+		}
+		return tx.Table(tableScimGroups).
+			Where("org_id = ? AND id = ?", g.OrgID, g.ID).
+			Delete(&ScimGroup{}).Error
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
@@ -43,15 +44,19 @@ type SessionOption struct {
 	EndDate        sql.NullString
 	Offset         int
 	Limit          int
+	// ViewConnections lists the sessions of these connections
+	// in addition to the ones matching the user
+	ViewConnections pq.StringArray
 }
 
 func NewSessionOption() SessionOption {
 	return SessionOption{
-		User:           "%",
-		ConnectionType: "%",
-		ConnectionName: "%",
-		Limit:          20,
-		Offset:         0,
+		User:            "%",
+		ConnectionType:  "%",
+		ConnectionName:  "%",
+		Limit:           20,
+		Offset:          0,
+		ViewConnections: pq.StringArray{},
 	}
 }
 
@@ -144,7 +149,7 @@ func ListSessions(orgID string, opt SessionOption) (*SessionList, error) {
 		FROM private.sessions s
 		WHERE s.org_id = @org_id AND
 		(
-			COALESCE(s.user_id::text, '') LIKE @user_id AND
+			(COALESCE(s.user_id::text, '') LIKE @user_id OR s.connection = ANY(@view_connections)) AND
 			COALESCE(s.connection::text, '') LIKE @connection AND
 			COALESCE(s.connection_type::text, '')::TEXT LIKE @connection_type AND
 			CASE WHEN (@start_date)::text IS NOT NULL
@@ -152,12 +157,13 @@ func ListSessions(orgID string, opt SessionOption) (*SessionList, error) {
 				ELSE true
 			END
 		)`, map[string]any{
-			"org_id":          orgID,
-			"user_id":         opt.User,
-			"connection":      opt.ConnectionName,
-			"connection_type": opt.ConnectionType,
-			"start_date":      opt.StartDate,
-			"end_date":        opt.EndDate,
+			"org_id":           orgID,
+			"user_id":          opt.User,
+			"view_connections": opt.ViewConnections,
+			"connection":       opt.ConnectionName,
+			"connection_type":  opt.ConnectionType,
+			"start_date":       opt.StartDate,
+			"end_date":         opt.EndDate,
 		}).First(&sessionList.Total).Error
 		if err != nil {
 			return fmt.Errorf("unable to obtain total count of sessions, reason=%v", err)
@@ -172,7 +178,7 @@ func ListSessions(orgID string, opt SessionOption) (*SessionList, error) {
 		FROM private.sessions s
 		WHERE s.org_id = @org_id AND
 		(
-			COALESCE(s.user_id::text, '') LIKE @user_id AND
+			(COALESCE(s.user_id::text, '') LIKE @user_id OR s.connection = ANY(@view_connections)) AND
 			COALESCE(s.connection::text, '') LIKE @connection AND
 			COALESCE(s.connection_type::text, '')::TEXT LIKE @connection_type AND
 			CASE WHEN (@start_date)::text IS NOT NULL
@@ -184,14 +190,15 @@ func ListSessions(orgID string, opt SessionOption) (*SessionList, error) {
 		LIMIT @limit
 		OFFSET @offset
 		`, map[string]any{
-			"org_id":          orgID,
-			"user_id":         opt.User,
-			"connection":      opt.ConnectionName,
-			"connection_type": opt.ConnectionType,
-			"start_date":      opt.StartDate,
-			"end_date":        opt.EndDate,
-			"limit":           opt.Limit,
-			"offset":          opt.Offset,
+			"org_id":           orgID,
+			"user_id":          opt.User,
+			"view_connections": opt.ViewConnections,
+			"connection":       opt.ConnectionName,
+			"connection_type":  opt.ConnectionType,
+			"start_date":       opt.StartDate,
+			"end_date":         opt.EndDate,
+			"limit":            opt.Limit,
+			"offset":           opt.Offset,
 		}).Find(&sessionList.Items).Error
 		if err == nil {
 			sessionList.HasNextPage = len(sessionList.Items) == opt.Limit
@@ -302,6 +309,105 @@ func UpdateSessionIntegrationMetadata(orgID, sid string, metadata map[string]any
 	return res.Error
 }
 
+// FlagSessionBreakGlass records the emergency access of the session in the integrations metadata
+func FlagSessionBreakGlass(orgID, sid string, breakGlass map[string]any) error {
+	data, err := json.Marshal(map[string]any{"break_glass": breakGlass})
+	if err != nil {
+		return err
+	}
+	res := DB.Exec(`
+	UPDATE private.sessions
+	SET integrations_metadata = COALESCE(integrations_metadata, '{}'::JSONB) || ?::JSONB
+	WHERE org_id = ? AND id = ?`, string(data), orgID, sid)
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return res.Error
+}
+
+// UpdateSessionReviewedInput replaces the input of a session with the revision of its review
+// approved to be executed, the number of the revision is recorded in the integrations metadata
+func UpdateSessionReviewedInput(orgID, sid, input string, revision int) error {
+	blobInput, err := json.Marshal([]string{input})
+	if err != nil {
+		return err
+	}
+	metadata, err := json.Marshal(map[string]any{"review_revision": revision})
+	if err != nil {
+		return err
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		blobInputID := tx.Table(tableSessions).
+			Select("blob_input_id").
+			Where("org_id = ? AND id = ?", orgID, sid)
+		res := tx.Table(tableBlobs).
+			Where("org_id = ? AND type = ? AND id = (?)", orgID, "session-input", blobInputID).
+			Updates(map[string]any{"blob_stream": json.RawMessage(blobInput)})
+		if res.Error == nil && res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		if res.Error != nil {
+			return fmt.Errorf("failed updating session blob input, reason=%v", res.Error)
+		}
+		return tx.Exec(`
+		UPDATE private.sessions
+		SET integrations_metadata = COALESCE(integrations_metadata, '{}'::JSONB) || ?::JSONB
+		WHERE org_id = ? AND id = ?`, string(metadata), orgID, sid).Error
+	})
+}
+
+// FlagSessionKilled records who terminated the session in the integrations metadata
+func FlagSessionKilled(orgID, sid string, killed map[string]any) error {
+	data, err := json.Marshal(map[string]any{"killed": killed})
+	if err != nil {
+		return err
+	}
+	res := DB.Exec(`
+	UPDATE private.sessions
+	SET integrations_metadata = COALESCE(integrations_metadata, '{}'::JSONB) || ?::JSONB
+	WHERE org_id = ? AND id = ?`, string(data), orgID, sid)
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return res.Error
+}
+
+// KilledSession is a session flagged as killed through the api
+type KilledSession struct {
+	ID       string `gorm:"column:id"`
+	OrgID    string `gorm:"column:org_id"`
+	KilledBy string `gorm:"column:killed_by"`
+	Reason   string `gorm:"column:reason"`
+}
+
+// ListKilledSessions returns the sessions flagged as killed among the provided ids
+func ListKilledSessions(sids []string) ([]KilledSession, error) {
+	var items []KilledSession
+	err := DB.Raw(`
+	SELECT id, org_id,
+		integrations_metadata->'killed'->>'killed_by' AS killed_by,
+		integrations_metadata->'killed'->>'reason' AS reason
+	FROM private.sessions
+	WHERE id IN (?) AND integrations_metadata->'killed' IS NOT NULL`, sids).
+		Scan(&items).Error
+	return items, err
+}
+
+// ListOpenSessionIDs returns the id of open sessions filtering by the user email
+// and the connection name, empty filters are ignored
+func ListOpenSessionIDs(orgID, userEmail, connectionName string) ([]string, error) {
+	tx := DB.Table(tableSessions).
+		Where("org_id = ? AND status = ?", orgID, "open")
+	if userEmail != "" {
+		tx = tx.Where("user_email = ?", userEmail)
+	}
+	if connectionName != "" {
+		tx = tx.Where("connection = ?", connectionName)
+	}
+	var sessionIDs []string
+	return sessionIDs, tx.Order("created_at ASC").Pluck("id", &sessionIDs).Error
+}
+
 func UpdateSessionMetadata(orgID, userEmail, sid string, metadata map[string]any) error {
 	res := DB.Table(tableSessions).
 		Where("org_id = ? AND id = ? AND user_email = ?", orgID, sid, userEmail).
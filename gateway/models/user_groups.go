@@ -4,6 +4,7 @@ import (
 	"database/sql"
 
 	"github.com/hoophq/hoop/common/log"
+	"gorm.io/gorm"
 )
 
 type UserGroup struct {
@@ -47,3 +48,57 @@ func DeleteUserGroupsByUserID(userID string) error {
 	log.Debugf("deleting user groups for user=%s", userID)
 	return DB.Where("user_id = ?", userID).Delete(&UserGroup{}).Error
 }
+
+// GetUserGroupsByName returns the users members of a group
+func GetUserGroupsByName(orgID, name string) ([]UserGroup, error) {
+	var userGroups []UserGroup
+	if err := DB.Where("org_id = ? AND name = ? AND user_id IS NOT NULL", orgID, name).
+		Find(&userGroups).Error; err != nil {
+		return nil, err
+	}
+	return userGroups, nil
+}
+
+// AddUserGroupMembers adds the users to a group, existing members are ignored
+func AddUserGroupMembers(orgID, name string, userIDs []string) error {
+	log.Debugf("adding members to user group=%s for org=%s", name, orgID)
+	for _, userID := range userIDs {
+		err := DB.Exec(`
+		INSERT INTO private.user_groups (org_id, user_id, name) VALUES (?, ?, ?)
+		ON CONFLICT DO NOTHING`, orgID, userID, name).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveUserGroupMembers removes the users from a group
+func RemoveUserGroupMembers(orgID, name string, userIDs []string) error {
+	log.Debugf("removing members from user group=%s for org=%s", name, orgID)
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return DB.Where("org_id = ? AND name = ? AND user_id IN ?", orgID, name, userIDs).
+		Delete(&UserGroup{}).Error
+}
+
+// ReplaceUserGroupMembers replaces all the users members of a group
+func ReplaceUserGroupMembers(orgID, name string, userIDs []string) error {
+	log.Debugf("replacing members of user group=%s for org=%s", name, orgID)
+	return DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("org_id = ? AND name = ? AND user_id IS NOT NULL", orgID, name).
+			Delete(&UserGroup{}).Error
+		if err != nil {
+			return err
+		}
+		var userGroups []UserGroup
+		for _, userID := range userIDs {
+			userGroups = append(userGroups, UserGroup{OrgID: orgID, UserID: userID, Name: name})
+		}
+		if len(userGroups) == 0 {
+			return nil
+		}
+		return tx.Create(&userGroups).Error
+	})
+}
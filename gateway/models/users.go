@@ -70,6 +70,19 @@ func GetUserBySubjectAndOrg(subject, orgID string) (*User, error) {
 	return user, nil
 }
 
+func GetUserByIDAndOrg(id, orgID string) (*User, error) {
+	log.Debugf("getting user=%s for org=%s", id, orgID)
+	var user *User
+	if err := DB.Where("org_id = ? AND id = ?", orgID, id).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
 func GetUserByEmailAndOrg(email, orgID string) (*User, error) {
 	log.Debugf("getting user=%s for org=%s", email, orgID)
 	var user *User
@@ -52,6 +52,19 @@ func (a *agent) FetchOneByToken(token string) (*pgrest.Agent, error) {
 	return &agent, nil
 }
 
+func (a *agent) FetchOneByID(id string) (*pgrest.Agent, error) {
+	var agent pgrest.Agent
+	if err := pgrest.New("/agents?select=*,orgs(name,license)&id=eq.%v", url.QueryEscape(id)).
+		FetchOne().
+		DecodeInto(&agent); err != nil {
+		if err == pgrest.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &agent, nil
+}
+
 func (a *agent) Upsert(agent *pgrest.Agent) error {
 	status := pgrest.AgentStatusDisconnected
 	if agent.Status != "" {
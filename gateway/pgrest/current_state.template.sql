@@ -275,15 +275,30 @@ CREATE VIEW reviews AS
     SELECT
         id, org_id, session_id, connection_id, connection_name, type, blob_input_id,
         input_env_vars, input_client_args, access_duration_sec, status,
-        owner_id, owner_email, owner_name, owner_slack_id, created_at, revoked_at
+        owner_id, owner_email, owner_name, owner_slack_id, created_at, revoked_at,
+        distinct_approvers, rule_name, status_reason, expire_at, reminder_interval_sec, reminded_at,
+        escalate_at, escalation_group, escalated_at, revision, revised_at, slack_threads,
+        extends_review_id
     FROM private.reviews;
 
 CREATE VIEW review_groups AS
     SELECT
         id, org_id, review_id, group_name, status,
-        owner_id, owner_email, owner_name, owner_slack_id, reviewed_at
+        owner_id, owner_email, owner_name, owner_slack_id, reviewed_at,
+        stage, min_approvals, approvals
     FROM private.review_groups;
 
+CREATE VIEW review_comments AS
+    SELECT
+        id, org_id, review_id, revision,
+        owner_id, owner_email, owner_name, owner_slack_id,
+        content, changes_requested, source, created_at
+    FROM private.review_comments;
+
+CREATE VIEW review_revisions AS
+    SELECT id, org_id, review_id, revision, input, review_groups, submitted_at, created_at
+    FROM private.review_revisions;
+
 CREATE FUNCTION blob_input(reviews) RETURNS SETOF blobs ROWS 1 AS $$
   SELECT * FROM blobs WHERE id = $1.blob_input_id
 $$ stable language sql;
@@ -335,6 +350,8 @@ GRANT SELECT, INSERT, UPDATE ON sessions TO {{ .pgrest_role }};
 GRANT SELECT, INSERT, UPDATE ON blobs TO {{ .pgrest_role }};
 GRANT SELECT, INSERT, UPDATE ON reviews TO {{ .pgrest_role }};
 GRANT SELECT, INSERT, UPDATE ON review_groups TO {{ .pgrest_role }};
+GRANT SELECT, INSERT ON review_comments TO {{ .pgrest_role }};
+GRANT SELECT, INSERT ON review_revisions TO {{ .pgrest_role }};
 GRANT SELECT, INSERT, UPDATE, DELETE ON proxymanager_state TO {{ .pgrest_role }};
 GRANT SELECT, INSERT, UPDATE ON audit TO {{ .pgrest_role }};
 
@@ -1,6 +1,7 @@
 package pgreview
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"time"
@@ -41,6 +42,21 @@ func (r *review) Upsert(rev *types.Review) error {
 		"owner_name":          rev.ReviewOwner.Name,
 		"owner_slack_id":      rev.ReviewOwner.SlackID,
 		"revoked_at":          rev.RevokeAt,
+		"distinct_approvers":  rev.DistinctApprovers,
+		"rule_name":           toStringPtr(rev.RuleName),
+		// timeouts of pending reviews
+		"status_reason":         toStringPtr(rev.StatusReason),
+		"expire_at":             toUTCPtr(rev.ExpireAt),
+		"reminder_interval_sec": int(rev.ReminderInterval.Seconds()),
+		"reminded_at":           toUTCPtr(rev.RemindedAt),
+		"escalate_at":           toUTCPtr(rev.EscalateAt),
+		"escalation_group":      toStringPtr(rev.EscalationGroup),
+		"escalated_at":          toUTCPtr(rev.EscalatedAt),
+		// reviews created before revisions have a single one
+		"revision":   max(rev.Revision, 1),
+		"revised_at": toUTCPtr(rev.RevisedAt),
+		// extensions of jit grants
+		"extends_review_id": toStringPtr(rev.ExtendsReviewId),
 		// required only for migrating resources from xtdb to postgrest
 		"created_at": toStringPtr(createdAt),
 	}).Error()
@@ -67,6 +83,10 @@ func (r *review) Upsert(rev *types.Review) error {
 			"group_name":  revgroup.Group,
 			"status":      revgroup.Status,
 			"reviewed_at": revgroup.ReviewDate,
+			// review policy
+			"stage":         revgroup.Stage,
+			"min_approvals": max(revgroup.MinApprovals, 1),
+			"approvals":     revgroup.Approvals,
 		}
 		var reviewedBy types.ReviewOwner
 		if revgroup.ReviewedBy != nil {
@@ -135,7 +155,7 @@ func (r *review) FetchOneBySid(ctx pgrest.OrgContext, sid string) (*types.Review
 
 func (r *review) FetchJit(ctx pgrest.OrgContext, ownerUserID, connectionID string) (*types.Review, error) {
 	var rev Review
-	err := pgrest.New("/reviews?org_id=eq.%s&type=eq.jit&status=eq.APPROVED&owner_id=eq.%s&connection_id=eq.%s&select=*,review_groups(*)&order=created_at.desc&limit=1",
+	err := pgrest.New("/reviews?org_id=eq.%s&type=eq.jit&status=eq.APPROVED&extends_review_id=is.null&owner_id=eq.%s&connection_id=eq.%s&select=*,review_groups(*)&order=created_at.desc&limit=1",
 		ctx.GetOrgID(),
 		url.QueryEscape(ownerUserID),
 		url.QueryEscape(connectionID),
@@ -149,6 +169,26 @@ func (r *review) FetchJit(ctx pgrest.OrgContext, ownerUserID, connectionID strin
 	return parseReview(rev), nil
 }
 
+// FetchPendingWithTimeouts returns the pending reviews of all organizations
+// containing any timeout set by a review policy
+func (r *review) FetchPendingWithTimeouts() ([]types.Review, error) {
+	var items []Review
+	err := pgrest.New("/reviews?status=eq.PENDING&or=(expire_at.not.is.null,escalate_at.not.is.null,reminder_interval_sec.gt.0)&select=*,review_groups(*)").
+		List().
+		DecodeInto(&items)
+	if err != nil {
+		if err == pgrest.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var result []types.Review
+	for _, r := range items {
+		result = append(result, *parseReview(r))
+	}
+	return result, nil
+}
+
 func ToJson(rev types.Review) *types.ReviewJSON {
 	return &types.ReviewJSON{
 		Id:        rev.Id,
@@ -169,6 +209,17 @@ func ToJson(rev types.Review) *types.ReviewJSON {
 			Id:   rev.Connection.Id,
 			Name: rev.Connection.Name,
 		},
+		DistinctApprovers: rev.DistinctApprovers,
+		CurrentStage:      rev.CurrentStage(),
+		RuleName:          rev.RuleName,
+		StatusReason:      rev.StatusReason,
+		ExpireAt:          rev.ExpireAt,
+		EscalateAt:        rev.EscalateAt,
+		EscalationGroup:   rev.EscalationGroup,
+		EscalatedAt:       rev.EscalatedAt,
+		Revision:          rev.Revision,
+		RevisedAt:         rev.RevisedAt,
+		ExtendsReviewId:   rev.ExtendsReviewId,
 	}
 }
 
@@ -198,8 +249,21 @@ func parseReview(r Review) *types.Review {
 		},
 		// the connection id is expanded is used to perform a join on xtdb
 		// when the entity exists this field is a map, otherwise is a string containing the xtid
-		ConnectionId:    r.ConnectionID,
-		ReviewGroupsIds: []string{},
+		ConnectionId:      r.ConnectionID,
+		ReviewGroupsIds:   []string{},
+		DistinctApprovers: r.DistinctApprovers,
+		RuleName:          toString(r.RuleName),
+		StatusReason:      toString(r.StatusReason),
+		ExpireAt:          parseTime(r.ExpireAt),
+		ReminderInterval:  time.Duration(r.ReminderInterval) * time.Second,
+		RemindedAt:        parseTime(r.RemindedAt),
+		EscalateAt:        parseTime(r.EscalateAt),
+		EscalationGroup:   toString(r.EscalationGroup),
+		EscalatedAt:       parseTime(r.EscalatedAt),
+		Revision:          max(r.Revision, 1),
+		RevisedAt:         parseTime(r.RevisedAt),
+		SlackThreads:      r.SlackThreads,
+		ExtendsReviewId:   toString(r.ExtendsReviewID),
 	}
 	for _, rg := range r.ReviewGroups {
 		revGroup := types.ReviewGroup{
@@ -208,6 +272,10 @@ func parseReview(r Review) *types.Review {
 			Status:     types.ReviewStatus(rg.Status),
 			ReviewedBy: nil,
 			ReviewDate: rg.ReviewedAt,
+			// groups created before review policies require a single approval
+			Stage:        rg.Stage,
+			MinApprovals: max(rg.MinApprovals, 1),
+			Approvals:    rg.Approvals,
 		}
 		if rg.OwnerUserID != nil {
 			revGroup.ReviewedBy = &types.ReviewOwner{
@@ -228,3 +296,113 @@ func (r *review) PatchStatus(ctx pgrest.OrgContext, reviewID string, status type
 		Patch(map[string]any{"status": status}).
 		Error()
 }
+
+// PatchSlackThreads updates the slack messages sent to reviewers
+func (r *review) PatchSlackThreads(ctx pgrest.OrgContext, reviewID string, threads []types.ReviewSlackThread) error {
+	return pgrest.New("/reviews?org_id=eq.%s&id=eq.%s", ctx.GetOrgID(), url.QueryEscape(reviewID)).
+		Patch(map[string]any{"slack_threads": threads}).
+		Error()
+}
+
+// FetchOneBySlackThread returns the review containing the slack message of a channel
+func (r *review) FetchOneBySlackThread(ctx pgrest.OrgContext, channel, ts string) (*types.Review, error) {
+	thread, err := json.Marshal([]types.ReviewSlackThread{{Channel: channel, Timestamp: ts}})
+	if err != nil {
+		return nil, err
+	}
+	var rev Review
+	err = pgrest.New("/reviews?org_id=eq.%s&slack_threads=cs.%s&select=*,review_groups(*),blob_input(*)",
+		ctx.GetOrgID(), url.QueryEscape(string(thread))).
+		FetchOne().
+		DecodeInto(&rev)
+	if err != nil {
+		if err == pgrest.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return parseReview(rev), nil
+}
+
+func (r *review) CreateComment(c *types.ReviewComment) error {
+	return pgrest.New("/review_comments").Create(map[string]any{
+		"id":                c.Id,
+		"org_id":            c.OrgId,
+		"review_id":         c.ReviewId,
+		"revision":          c.Revision,
+		"owner_id":          c.Owner.Id,
+		"owner_email":       c.Owner.Email,
+		"owner_name":        toStringPtr(c.Owner.Name),
+		"owner_slack_id":    toStringPtr(c.Owner.SlackID),
+		"content":           c.Content,
+		"changes_requested": c.ChangesRequested,
+		"source":            c.Source,
+		"created_at":        c.CreatedAt.UTC().Format(time.RFC3339),
+	}).Error()
+}
+
+func (r *review) FetchComments(ctx pgrest.OrgContext, reviewID string) ([]types.ReviewComment, error) {
+	var items []ReviewComment
+	err := pgrest.New("/review_comments?org_id=eq.%s&review_id=eq.%s&order=created_at.asc",
+		ctx.GetOrgID(), url.QueryEscape(reviewID)).
+		List().
+		DecodeInto(&items)
+	if err != nil && err != pgrest.ErrNotFound {
+		return nil, err
+	}
+	result := []types.ReviewComment{}
+	for _, c := range items {
+		createdAt, _ := time.ParseInLocation("2006-01-02T15:04:05", c.CreatedAt, time.UTC)
+		result = append(result, types.ReviewComment{
+			Id:       c.ID,
+			OrgId:    c.OrgID,
+			ReviewId: c.ReviewID,
+			Revision: c.Revision,
+			Owner: types.ReviewOwner{
+				Id:      c.OwnerUserID,
+				Email:   c.OwnerEmail,
+				Name:    toString(c.OwnerName),
+				SlackID: toString(c.OwnerSlackID),
+			},
+			Content:          c.Content,
+			ChangesRequested: c.ChangesRequested,
+			Source:           c.Source,
+			CreatedAt:        createdAt,
+		})
+	}
+	return result, nil
+}
+
+// CreateRevision stores an input replaced by a resubmission of the review
+func (r *review) CreateRevision(orgID, reviewID string, rev types.ReviewRevision) error {
+	return pgrest.New("/review_revisions").Create(map[string]any{
+		"org_id":        orgID,
+		"review_id":     reviewID,
+		"revision":      rev.Revision,
+		"input":         toStringPtr(rev.Input),
+		"review_groups": rev.ReviewGroups,
+		"submitted_at":  rev.SubmittedAt.UTC().Format(time.RFC3339),
+	}).Error()
+}
+
+// FetchRevisions returns the inputs replaced by resubmissions of the review
+func (r *review) FetchRevisions(ctx pgrest.OrgContext, reviewID string) ([]types.ReviewRevision, error) {
+	var items []ReviewRevision
+	err := pgrest.New("/review_revisions?org_id=eq.%s&review_id=eq.%s&order=revision.asc",
+		ctx.GetOrgID(), url.QueryEscape(reviewID)).
+		List().
+		DecodeInto(&items)
+	if err != nil && err != pgrest.ErrNotFound {
+		return nil, err
+	}
+	result := []types.ReviewRevision{}
+	for _, rev := range items {
+		result = append(result, types.ReviewRevision{
+			Revision:     rev.Revision,
+			Input:        toString(rev.Input),
+			ReviewGroups: rev.ReviewGroups,
+			SubmittedAt:  *parseTime(&rev.SubmittedAt),
+		})
+	}
+	return result, nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/hoophq/hoop/gateway/pgrest"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
 )
 
 type ReviewGroup struct {
@@ -18,6 +19,10 @@ type ReviewGroup struct {
 	OwnerName    *string `json:"owner_name"`
 	OwnerSlackID *string `json:"owner_slack_id"`
 	ReviewedAt   *string `json:"reviewed_at"`
+
+	Stage        int                    `json:"stage"`
+	MinApprovals int                    `json:"min_approvals"`
+	Approvals    []types.ReviewApproval `json:"approvals"`
 }
 
 type Review struct {
@@ -38,11 +43,50 @@ type Review struct {
 	OwnerSlackID      *string           `json:"owner_slack_id"`
 	CreatedAt         string            `json:"created_at"`
 	RevokedAt         *string           `json:"revoked_at"`
+	DistinctApprovers bool              `json:"distinct_approvers"`
+	RuleName          *string           `json:"rule_name"`
+	StatusReason      *string           `json:"status_reason"`
+	ExpireAt          *string           `json:"expire_at"`
+	ReminderInterval  int               `json:"reminder_interval_sec"`
+	RemindedAt        *string           `json:"reminded_at"`
+	EscalateAt        *string           `json:"escalate_at"`
+	EscalationGroup   *string           `json:"escalation_group"`
+	EscalatedAt       *string           `json:"escalated_at"`
+	Revision          int               `json:"revision"`
+	RevisedAt         *string           `json:"revised_at"`
+	ExtendsReviewID   *string           `json:"extends_review_id"`
+
+	SlackThreads []types.ReviewSlackThread `json:"slack_threads"`
 
 	BlobInput    *pgrest.Blob  `json:"blob_input"`
 	ReviewGroups []ReviewGroup `json:"review_groups"`
 }
 
+type ReviewComment struct {
+	ID               string  `json:"id"`
+	OrgID            string  `json:"org_id"`
+	ReviewID         string  `json:"review_id"`
+	Revision         int     `json:"revision"`
+	OwnerUserID      string  `json:"owner_id"`
+	OwnerEmail       string  `json:"owner_email"`
+	OwnerName        *string `json:"owner_name"`
+	OwnerSlackID     *string `json:"owner_slack_id"`
+	Content          string  `json:"content"`
+	ChangesRequested bool    `json:"changes_requested"`
+	Source           string  `json:"source"`
+	CreatedAt        string  `json:"created_at"`
+}
+
+type ReviewRevision struct {
+	ID           string              `json:"id"`
+	OrgID        string              `json:"org_id"`
+	ReviewID     string              `json:"review_id"`
+	Revision     int                 `json:"revision"`
+	Input        *string             `json:"input"`
+	ReviewGroups []types.ReviewGroup `json:"review_groups"`
+	SubmittedAt  string              `json:"submitted_at"`
+}
+
 // func (r *Review) GetSessionID() string {
 // 	if r.SessionID != nil {
 // 		return *r.SessionID
@@ -55,10 +99,12 @@ func (r *Review) GetCreatedAt() time.Time {
 	return createdAt
 }
 
-func (r *Review) GetRevokedAt() *time.Time {
-	if r.RevokedAt != nil {
-		revokedAt, _ := time.ParseInLocation("2006-01-02T15:04:05", *r.RevokedAt, time.UTC)
-		return &revokedAt
+func (r *Review) GetRevokedAt() *time.Time { return parseTime(r.RevokedAt) }
+
+func parseTime(v *string) *time.Time {
+	if v != nil {
+		t, _ := time.ParseInLocation("2006-01-02T15:04:05", *v, time.UTC)
+		return &t
 	}
 	return nil
 }
@@ -84,6 +130,14 @@ func toString(v *string) string {
 	return ""
 }
 
+func toUTCPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := t.UTC()
+	return &v
+}
+
 func toStringPtr(v string) *string {
 	if v == "" {
 		return nil
@@ -0,0 +1,182 @@
+// Package rbac evaluates the custom roles of an organization.
+//
+// Custom roles are opt-in per user: a user that belongs to a group assigned
+// to at least one role has its access evaluated by the permissions of these roles.
+// Admins and users without custom roles keep the behavior of the built-in roles.
+package rbac
+
+import (
+	"fmt"
+	"slices"
+
+	pb "github.com/hoophq/hoop/common/proto"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+)
+
+type Permission string
+
+const (
+	PermissionConnect           Permission = "connect"
+	PermissionExec              Permission = "exec"
+	PermissionRunbooks          Permission = "runbooks"
+	PermissionViewSessions      Permission = "view_sessions"
+	PermissionDownloadSessions  Permission = "download_sessions"
+	PermissionApproveReviews    Permission = "approve_reviews"
+	PermissionManageConnections Permission = "manage_connections"
+)
+
+// Permissions is the list of all available permissions
+var Permissions = []Permission{
+	PermissionConnect,
+	PermissionExec,
+	PermissionRunbooks,
+	PermissionViewSessions,
+	PermissionDownloadSessions,
+	PermissionApproveReviews,
+	PermissionManageConnections,
+}
+
+// accessPermissions are the permissions that make a connection visible to a user
+var accessPermissions = []Permission{
+	PermissionConnect,
+	PermissionExec,
+	PermissionRunbooks,
+	PermissionManageConnections,
+}
+
+// Resource is a connection evaluated against the scope of the roles
+type Resource struct {
+	Name    string
+	Tags    []string
+	AgentID string
+}
+
+// ResourceFromConnection returns the resource attributes of a connection
+func ResourceFromConnection(conn *models.Connection) Resource {
+	return Resource{Name: conn.Name, Tags: conn.Tags, AgentID: conn.AgentID.String}
+}
+
+// Policy is the set of custom roles assigned to the groups of a user
+type Policy struct {
+	isAdmin bool
+	roles   []*models.Role
+}
+
+// NewPolicy returns a policy of a user containing the roles assigned to its groups
+func NewPolicy(userGroups []string, roles []*models.Role) *Policy {
+	p := &Policy{isAdmin: slices.Contains(userGroups, types.GroupAdmin)}
+	for _, role := range roles {
+		for _, group := range role.Groups {
+			if slices.Contains(userGroups, group) {
+				p.roles = append(p.roles, role)
+				break
+			}
+		}
+	}
+	return p
+}
+
+// Load fetches the custom roles assigned to the groups of a user
+func Load(orgID string, userGroups []string) (*Policy, error) {
+	if slices.Contains(userGroups, types.GroupAdmin) {
+		return NewPolicy(userGroups, nil), nil
+	}
+	roles, err := models.ListRolesByGroups(orgID, userGroups)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching roles: %v", err)
+	}
+	return NewPolicy(userGroups, roles), nil
+}
+
+// Enabled returns true if the access of the user must be evaluated by custom roles
+func (p *Policy) Enabled() bool { return p != nil && !p.isAdmin && len(p.roles) > 0 }
+
+// Allowed validates if the permission is granted to the resource
+func (p *Policy) Allowed(perm Permission, res Resource) bool {
+	if !p.Enabled() {
+		return true
+	}
+	for _, role := range p.roles {
+		for _, rp := range role.Permissions {
+			if slices.Contains(rp.Actions, string(perm)) && scopeMatch(rp.Scope, res) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllowedAny validates if the permission is granted to any resource
+func (p *Policy) AllowedAny(perm Permission) bool {
+	if !p.Enabled() {
+		return true
+	}
+	for _, role := range p.roles {
+		for _, rp := range role.Permissions {
+			if slices.Contains(rp.Actions, string(perm)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CanAccess validates if the resource is visible to the user,
+// it requires any permission that interacts with the connection
+func (p *Policy) CanAccess(res Resource) bool {
+	for _, perm := range accessPermissions {
+		if p.Allowed(perm, res) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientPermission returns the permission required by a client to open a session
+func ClientPermission(verb, origin string) Permission {
+	if origin == pb.ConnectionOriginClientAPIRunbooks {
+		return PermissionRunbooks
+	}
+	switch verb {
+	case pb.ClientVerbExec, pb.ClientVerbPlainExec:
+		return PermissionExec
+	}
+	return PermissionConnect
+}
+
+// ValidatePermissions returns an error if a role contains an unknown permission
+func ValidatePermissions(perms []models.RolePermission) error {
+	if len(perms) == 0 {
+		return fmt.Errorf("at least one permission is required")
+	}
+	for _, rp := range perms {
+		if len(rp.Actions) == 0 {
+			return fmt.Errorf("at least one action is required for each permission")
+		}
+		for _, action := range rp.Actions {
+			if !slices.Contains(Permissions, Permission(action)) {
+				return fmt.Errorf("unknown permission %q, accepted values are %v", action, Permissions)
+			}
+		}
+	}
+	return nil
+}
+
+func scopeMatch(scope models.RoleScope, res Resource) bool {
+	if len(scope.Connections) == 0 && len(scope.Tags) == 0 && len(scope.Agents) == 0 {
+		return true
+	}
+	if slices.Contains(scope.Connections, res.Name) {
+		return true
+	}
+	if res.AgentID != "" && slices.Contains(scope.Agents, res.AgentID) {
+		return true
+	}
+	for _, tag := range res.Tags {
+		if slices.Contains(scope.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
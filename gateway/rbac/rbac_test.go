@@ -0,0 +1,89 @@
+package rbac
+
+import (
+	"testing"
+
+	pb "github.com/hoophq/hoop/common/proto"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyAllowed(t *testing.T) {
+	roles := []*models.Role{
+		{
+			Name:   "dba",
+			Groups: []string{"dba"},
+			Permissions: []models.RolePermission{
+				{Actions: []string{"connect", "exec"}, Scope: models.RoleScope{Tags: []string{"prod"}}},
+				{Actions: []string{"view_sessions"}},
+			},
+		},
+		{
+			Name:   "runbooks",
+			Groups: []string{"support"},
+			Permissions: []models.RolePermission{
+				{Actions: []string{"runbooks"}, Scope: models.RoleScope{
+					Connections: []string{"pgdemo"},
+					Agents:      []string{"agent-01"},
+				}},
+			},
+		},
+	}
+	prodConn := Resource{Name: "pgprod", Tags: []string{"prod", "db"}, AgentID: "agent-02"}
+	demoConn := Resource{Name: "pgdemo", AgentID: "agent-03"}
+	agentConn := Resource{Name: "bash", AgentID: "agent-01"}
+
+	for _, tt := range []struct {
+		msg    string
+		groups []string
+		perm   Permission
+		res    Resource
+		want   bool
+	}{
+		{msg: "it must allow by tag scope", groups: []string{"dba"}, perm: PermissionExec, res: prodConn, want: true},
+		{msg: "it must deny when tag does not match", groups: []string{"dba"}, perm: PermissionExec, res: demoConn, want: false},
+		{msg: "it must deny permission not granted", groups: []string{"dba"}, perm: PermissionRunbooks, res: prodConn, want: false},
+		{msg: "it must allow with empty scope", groups: []string{"dba"}, perm: PermissionViewSessions, res: demoConn, want: true},
+		{msg: "it must allow by connection scope", groups: []string{"support"}, perm: PermissionRunbooks, res: demoConn, want: true},
+		{msg: "it must allow by agent scope", groups: []string{"support"}, perm: PermissionRunbooks, res: agentConn, want: true},
+		{msg: "it must combine roles of all groups", groups: []string{"support", "dba"}, perm: PermissionConnect, res: prodConn, want: true},
+		{msg: "it must allow users without custom roles", groups: []string{"engineering"}, perm: PermissionExec, res: demoConn, want: true},
+		{msg: "it must allow admin users", groups: []string{"dba", types.GroupAdmin}, perm: PermissionRunbooks, res: prodConn, want: true},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			p := NewPolicy(tt.groups, roles)
+			assert.Equal(t, tt.want, p.Allowed(tt.perm, tt.res))
+		})
+	}
+}
+
+func TestPolicyCanAccess(t *testing.T) {
+	roles := []*models.Role{{
+		Groups: []string{"auditors"},
+		Permissions: []models.RolePermission{
+			{Actions: []string{"view_sessions", "download_sessions"}},
+			{Actions: []string{"connect"}, Scope: models.RoleScope{Connections: []string{"pgreplica"}}},
+		},
+	}}
+	p := NewPolicy([]string{"auditors"}, roles)
+	assert.True(t, p.Enabled())
+	assert.True(t, p.CanAccess(Resource{Name: "pgreplica"}))
+	assert.False(t, p.CanAccess(Resource{Name: "pgprod"}))
+	assert.True(t, p.AllowedAny(PermissionDownloadSessions))
+	assert.False(t, p.AllowedAny(PermissionManageConnections))
+}
+
+func TestClientPermission(t *testing.T) {
+	assert.Equal(t, PermissionConnect, ClientPermission(pb.ClientVerbConnect, pb.ConnectionOriginClient))
+	assert.Equal(t, PermissionExec, ClientPermission(pb.ClientVerbExec, pb.ConnectionOriginClient))
+	assert.Equal(t, PermissionExec, ClientPermission(pb.ClientVerbPlainExec, pb.ConnectionOriginClientAPI))
+	assert.Equal(t, PermissionRunbooks, ClientPermission(pb.ClientVerbPlainExec, pb.ConnectionOriginClientAPIRunbooks))
+}
+
+func TestValidatePermissions(t *testing.T) {
+	assert.NoError(t, ValidatePermissions([]models.RolePermission{{Actions: []string{"connect", "approve_reviews"}}}))
+	assert.Error(t, ValidatePermissions(nil))
+	assert.Error(t, ValidatePermissions([]models.RolePermission{{}}))
+	assert.Error(t, ValidatePermissions([]models.RolePermission{{Actions: []string{"delete_everything"}}}))
+}
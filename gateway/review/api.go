@@ -3,6 +3,7 @@ package review
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hoophq/hoop/common/log"
@@ -22,9 +23,17 @@ type (
 	service interface {
 		Review(ctx *storagev2.Context, id string, status types.ReviewStatus) (*types.Review, error)
 		ReviewBySid(ctx *storagev2.Context, sid string, status types.ReviewStatus) (*types.Review, error)
-		Revoke(ctx pgrest.OrgContext, id string) (*types.Review, error)
-		RevokeBySid(ctx pgrest.OrgContext, sid string) (*types.Review, error)
+		Revoke(ctx *storagev2.Context, id string) (*types.Review, error)
+		RevokeBySid(ctx *storagev2.Context, sid string) (*types.Review, error)
 		Persist(ctx pgrest.OrgContext, review *types.Review) error
+		FindBySessionID(ctx pgrest.OrgContext, sid string) (*types.Review, error)
+		Comment(ctx *storagev2.Context, id, content, source string) (*types.ReviewComment, error)
+		Comments(ctx *storagev2.Context, id string) ([]types.ReviewComment, error)
+		RequestChanges(ctx *storagev2.Context, id, feedback, source string) (*types.Review, error)
+		Resubmit(ctx *storagev2.Context, id, input string) (*types.Review, error)
+		Revisions(ctx *storagev2.Context, id string) ([]types.ReviewRevision, error)
+		RequestExtension(ctx *storagev2.Context, id string, duration time.Duration) (*types.Review, error)
+		Shorten(ctx *storagev2.Context, id string, duration time.Duration) (*types.Review, error)
 	}
 )
 
@@ -54,15 +63,27 @@ func (h *Handler) Put(c *gin.Context) {
 		}
 		review, err = h.Service.Review(ctx, id, status)
 	case types.ReviewStatusRevoked:
-		if !ctx.IsAdmin() {
-			c.AbortWithStatus(http.StatusForbidden)
-			return
-		}
 		if isReviewBySid {
 			review, err = h.Service.RevokeBySid(ctx, id)
 			break
 		}
 		review, err = h.Service.Revoke(ctx, id)
+	case types.ReviewStatusChangesRequested:
+		feedback := strings.TrimSpace(req["comment"])
+		if feedback == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "missing comment with the requested changes"})
+			return
+		}
+		if isReviewBySid {
+			if review, err = h.Service.FindBySessionID(ctx, id); err == nil && review == nil {
+				err = ErrNotFound
+			}
+			if err != nil {
+				break
+			}
+			id = review.Id
+		}
+		review, err = h.Service.RequestChanges(ctx, id, feedback, CommentSourceAPI)
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid status"})
 		return
@@ -71,7 +92,9 @@ func (h *Handler) Put(c *gin.Context) {
 	switch err {
 	case ErrNotFound:
 		c.JSON(http.StatusNotFound, gin.H{"message": "not found"})
-	case ErrNotEligible, ErrWrongState:
+	case ErrNotOwner, ErrSelfApproval:
+		c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+	case ErrNotEligible, ErrWrongState, ErrStagePending, ErrAlreadyReviewed, ErrNotOneTime:
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 	case nil:
 		c.JSON(http.StatusOK, sanitizeReview(review))
@@ -148,6 +171,17 @@ func sanitizeReview(review *types.Review) types.ReviewJSON {
 			Id:   connectionToStringFn("xt/id"),
 			Name: connectionToStringFn("connection/name"),
 		},
-		ReviewGroupsData: review.ReviewGroupsData,
+		ReviewGroupsData:  review.ReviewGroupsData,
+		DistinctApprovers: review.DistinctApprovers,
+		CurrentStage:      review.CurrentStage(),
+		RuleName:          review.RuleName,
+		StatusReason:      review.StatusReason,
+		ExpireAt:          review.ExpireAt,
+		EscalateAt:        review.EscalateAt,
+		EscalationGroup:   review.EscalationGroup,
+		EscalatedAt:       review.EscalatedAt,
+		Revision:          review.Revision,
+		RevisedAt:         review.RevisedAt,
+		ExtendsReviewId:   review.ExtendsReviewId,
 	}
 }
@@ -0,0 +1,79 @@
+package review
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoophq/hoop/gateway/storagev2"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeService fails the review operations with err
+type fakeService struct {
+	service
+	err error
+}
+
+func (s *fakeService) Review(*storagev2.Context, string, types.ReviewStatus) (*types.Review, error) {
+	return nil, s.err
+}
+
+func (s *fakeService) RequestChanges(*storagev2.Context, string, string, string) (*types.Review, error) {
+	return nil, s.err
+}
+
+func TestPutErrors(t *testing.T) {
+	for _, tt := range []struct {
+		msg      string
+		body     string
+		err      error
+		wantCode int
+		wantBody string
+	}{
+		{
+			msg:      "it must forbid approving its own review",
+			body:     `{"status":"APPROVED"}`,
+			err:      ErrSelfApproval,
+			wantCode: http.StatusForbidden,
+			wantBody: `{"message":"unable to self approve review"}`,
+		},
+		{
+			msg:      "it must forbid requesting changes of its own review",
+			body:     `{"status":"CHANGES_REQUESTED","comment":"use a limit clause"}`,
+			err:      ErrSelfApproval,
+			wantCode: http.StatusForbidden,
+			wantBody: `{"message":"unable to self approve review"}`,
+		},
+		{
+			msg:      "it must return bad request when the review is in the wrong state",
+			body:     `{"status":"REJECTED"}`,
+			err:      ErrWrongState,
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"message":"review in wrong state"}`,
+		},
+		{
+			msg:      "it must return not found when the review does not exist",
+			body:     `{"status":"APPROVED"}`,
+			err:      ErrNotFound,
+			wantCode: http.StatusNotFound,
+			wantBody: `{"message":"not found"}`,
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPut, "/api/reviews/review-id", bytes.NewBufferString(tt.body))
+			c.Params = gin.Params{{Key: "id", Value: "review-id"}}
+			c.Set(storagev2.ContextKey, storagev2.NewContext("user-id", "org-id"))
+			h := &Handler{Service: &fakeService{err: tt.err}}
+			h.Put(c)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.JSONEq(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package review
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/pgrest"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+)
+
+// BreakGlassInput is the emergency access requested by a user to a connection
+type BreakGlassInput struct {
+	SID           string
+	Owner         types.ReviewOwner
+	Connection    types.ReviewConnection
+	Justification string
+	Policy        *types.BreakGlassPolicy
+}
+
+// OpenBreakGlass grants access to the session without approval. It creates a review
+// that must be acknowledged by the group of the policy, flags the session and
+// notifies the participants of the connection.
+func (s *Service) OpenBreakGlass(ctx pgrest.OrgContext, in BreakGlassInput) (*types.Review, error) {
+	rev := newBreakGlassReview(ctx.GetOrgID(), in, time.Now().UTC())
+	if err := s.Persist(ctx, rev); err != nil {
+		return nil, fmt.Errorf("failed saving break-glass review: %v", err)
+	}
+	err := models.FlagSessionBreakGlass(rev.OrgId, rev.Session, map[string]any{
+		"review_id":     rev.Id,
+		"justification": in.Justification,
+		"revoke_at":     rev.RevokeAt.Format(time.RFC3339),
+	})
+	if err != nil {
+		log.With("sid", rev.Session, "id", rev.Id).Warnf("failed flagging break-glass session, reason=%v", err)
+	}
+	log.With("sid", rev.Session, "id", rev.Id, "user", rev.ReviewOwner.Email, "org", rev.OrgId).
+		Infof("break-glass access granted, duration=%v", rev.AccessDuration)
+	s.notify(Event{Type: EventBreakGlass, Review: rev, Groups: pendingGroups(rev)})
+	return rev, nil
+}
+
+// acknowledgeBreakGlass persists the review of an emergency access, the session
+// is not affected since the access was granted when it was opened
+func (s *Service) acknowledgeBreakGlass(ctx pgrest.OrgContext, rev *types.Review) (*types.Review, error) {
+	if err := s.Persist(ctx, rev); err != nil {
+		return nil, fmt.Errorf("saving review error: %v", err)
+	}
+	if rev.Status != types.ReviewStatusPending {
+		s.notify(Event{Type: EventBreakGlassReviewed, Review: rev})
+	}
+	return rev, nil
+}
+
+func newBreakGlassReview(orgID string, in BreakGlassInput, now time.Time) *types.Review {
+	revokeAt := now.Add(in.Policy.AccessDuration)
+	return &types.Review{
+		Id:              uuid.NewString(),
+		OrgId:           orgID,
+		CreatedAt:       now,
+		Type:            ReviewTypeBreakGlass,
+		Session:         in.SID,
+		Input:           in.Justification,
+		Connection:      in.Connection,
+		ConnectionId:    in.Connection.Id,
+		CreatedBy:       in.Owner.Id,
+		ReviewOwner:     in.Owner,
+		AccessDuration:  in.Policy.AccessDuration,
+		RevokeAt:        &revokeAt,
+		Status:          types.ReviewStatusPending,
+		ReviewGroupsIds: []string{in.Policy.AcknowledgeGroup},
+		ReviewGroupsData: []types.ReviewGroup{{
+			Group:        in.Policy.AcknowledgeGroup,
+			Status:       types.ReviewStatusPending,
+			MinApprovals: 1,
+		}},
+	}
+}
@@ -0,0 +1,31 @@
+package review
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBreakGlassReview(t *testing.T) {
+	now := time.Date(2024, 7, 25, 10, 0, 0, 0, time.UTC)
+	rev := newBreakGlassReview("org", BreakGlassInput{
+		SID:           "sid",
+		Owner:         reviewer("owner"),
+		Connection:    types.ReviewConnection{Id: "conn-id", Name: "pgprod"},
+		Justification: "database is down, INC-1234",
+		Policy:        &types.BreakGlassPolicy{AccessDuration: 30 * time.Minute, AcknowledgeGroup: "security"},
+	}, now)
+
+	assert.NotEmpty(t, rev.Id)
+	assert.Equal(t, ReviewTypeBreakGlass, rev.Type)
+	assert.Equal(t, "sid", rev.Session)
+	assert.Equal(t, "database is down, INC-1234", rev.Input)
+	assert.Equal(t, types.ReviewStatusPending, rev.Status)
+	assert.Equal(t, 30*time.Minute, rev.AccessDuration)
+	assert.Equal(t, now.Add(30*time.Minute), *rev.RevokeAt)
+	assert.Equal(t, []types.ReviewGroup{{Group: "security", Status: types.ReviewStatusPending, MinApprovals: 1}},
+		rev.ReviewGroupsData)
+	assert.Equal(t, []string{"security"}, pendingGroups(rev))
+}
@@ -0,0 +1,221 @@
+package review
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/log"
+	pb "github.com/hoophq/hoop/common/proto"
+	pgreview "github.com/hoophq/hoop/gateway/pgrest/review"
+	"github.com/hoophq/hoop/gateway/storagev2"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+)
+
+const (
+	CommentSourceAPI   = "api"
+	CommentSourceSlack = "slack"
+)
+
+// Comment adds a comment to the current revision of a review.
+// The owner of the review, the members of its groups and admins are able to comment.
+func (s *Service) Comment(ctx *storagev2.Context, reviewID, content, source string) (*types.ReviewComment, error) {
+	rev, err := s.FindOne(ctx, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch review error: %v", err)
+	}
+	if rev == nil {
+		return nil, ErrNotFound
+	}
+	if !canComment(rev, ctx) {
+		return nil, ErrNotEligible
+	}
+	comment, err := createComment(ctx, rev, content, source, false)
+	if err != nil {
+		return nil, err
+	}
+	s.notify(Event{Type: EventCommented, Review: rev, Comment: comment})
+	return comment, nil
+}
+
+// RequestChanges sends the review back to its owner with the feedback of the reviewer.
+// The review is reviewed again by all groups when the owner resubmits an edited input.
+func (s *Service) RequestChanges(ctx *storagev2.Context, reviewID, feedback, source string) (*types.Review, error) {
+	rev, err := s.FindOne(ctx, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch review error: %v", err)
+	}
+	if rev == nil {
+		return nil, ErrNotFound
+	}
+	if rev.Status != types.ReviewStatusPending {
+		return rev, ErrWrongState
+	}
+	if rev.Type != ReviewTypeOneTime {
+		return nil, ErrNotOneTime
+	}
+	if rev.ReviewOwner.Id == ctx.UserID && !ctx.IsAdmin() {
+		return nil, ErrSelfApproval
+	}
+
+	reviewer := types.ReviewOwner{Id: ctx.UserID, Name: ctx.UserName, Email: ctx.UserEmail, SlackID: ctx.SlackID}
+	if err := applyReview(rev, reviewer, ctx.UserGroups, types.ReviewStatusChangesRequested, time.Now()); err != nil {
+		return nil, err
+	}
+	rev.StatusReason = fmt.Sprintf("changes requested by %s: %s", ctx.UserEmail, feedback)
+	// the feedback is stored first, a review is never sent back to its owner without it
+	comment, err := createComment(ctx, rev, feedback, source, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Persist(ctx, rev); err != nil {
+		return nil, fmt.Errorf("saving review error: %v", err)
+	}
+	// release the connection if there's a client waiting
+	if s.TransportService != nil {
+		s.TransportService.ReviewStatusChange(rev)
+	}
+	s.notify(Event{Type: EventChangesRequested, Review: rev, Comment: comment})
+	return rev, nil
+}
+
+// Resubmit replaces the input of a review with changes requested, creating a new revision.
+// The previous input and the reviews it received are kept in the history of the review.
+func (s *Service) Resubmit(ctx *storagev2.Context, reviewID, input string) (*types.Review, error) {
+	rev, err := s.FindOne(ctx, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch review error: %v", err)
+	}
+	if rev == nil {
+		return nil, ErrNotFound
+	}
+	if rev.Status != types.ReviewStatusChangesRequested {
+		return rev, ErrWrongState
+	}
+	if rev.ReviewOwner.Id != ctx.UserID {
+		return nil, ErrNotOwner
+	}
+
+	err = pgreview.New().CreateRevision(rev.OrgId, rev.Id, types.ReviewRevision{
+		Revision:     rev.Revision,
+		Input:        rev.Input,
+		ReviewGroups: rev.ReviewGroupsData,
+		SubmittedAt:  rev.SubmittedAt(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed saving review revision: %v", err)
+	}
+	resubmitReview(rev, input, time.Now().UTC())
+	if err := s.Persist(ctx, rev); err != nil {
+		return nil, fmt.Errorf("saving review error: %v", err)
+	}
+	log.With("id", rev.Id, "sid", rev.Session, "org", rev.OrgId).
+		Infof("review resubmitted, revision=%v, input-length=%v", rev.Revision, len(rev.Input))
+	s.notify(Event{Type: EventResubmitted, Review: rev, Groups: pendingGroups(rev)})
+	return rev, nil
+}
+
+// Comments returns the comments of all revisions of a review,
+// only the users able to comment are able to list them.
+func (s *Service) Comments(ctx *storagev2.Context, reviewID string) ([]types.ReviewComment, error) {
+	rev, err := s.FindOne(ctx, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch review error: %v", err)
+	}
+	if rev == nil {
+		return nil, ErrNotFound
+	}
+	if !canComment(rev, ctx) {
+		return nil, ErrNotEligible
+	}
+	comments, err := pgreview.New().FetchComments(ctx, rev.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching review comments: %v", err)
+	}
+	return comments, nil
+}
+
+// Revisions returns all inputs submitted for a review, the last item is the current revision.
+// Only the users able to comment are able to list them.
+func (s *Service) Revisions(ctx *storagev2.Context, reviewID string) ([]types.ReviewRevision, error) {
+	rev, err := s.FindOne(ctx, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch review error: %v", err)
+	}
+	if rev == nil {
+		return nil, ErrNotFound
+	}
+	if !canComment(rev, ctx) {
+		return nil, ErrNotEligible
+	}
+	revisions, err := pgreview.New().FetchRevisions(ctx, rev.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching review revisions: %v", err)
+	}
+	return append(revisions, types.ReviewRevision{
+		Revision:     rev.Revision,
+		Input:        rev.Input,
+		ReviewGroups: rev.ReviewGroupsData,
+		SubmittedAt:  rev.SubmittedAt(),
+	}), nil
+}
+
+// resubmitReview starts a new revision of the review with the input, the timeouts
+// of the review policy are restarted from the time of the resubmission
+func resubmitReview(rev *types.Review, input string, now time.Time) {
+	submittedAt := rev.SubmittedAt()
+	if rev.ExpireAt != nil {
+		expireAt := now.Add(rev.ExpireAt.Sub(submittedAt))
+		rev.ExpireAt = &expireAt
+	}
+	if rev.EscalateAt != nil {
+		escalateAt := now.Add(rev.EscalateAt.Sub(submittedAt))
+		rev.EscalateAt = &escalateAt
+	}
+	rev.EscalatedAt = nil
+	rev.RemindedAt = nil
+	rev.Input = input
+	rev.Revision = max(rev.Revision, 1) + 1
+	rev.RevisedAt = &now
+	rev.Status = types.ReviewStatusPending
+	rev.StatusReason = ""
+	resetReviewGroups(rev)
+}
+
+func canComment(rev *types.Review, ctx *storagev2.Context) bool {
+	if rev.ReviewOwner.Id == ctx.UserID || ctx.IsAdmin() {
+		return true
+	}
+	if rev.EscalationGroup != "" && pb.IsInList(rev.EscalationGroup, ctx.UserGroups) {
+		return true
+	}
+	for _, g := range rev.ReviewGroupsData {
+		if pb.IsInList(g.Group, ctx.UserGroups) {
+			return true
+		}
+	}
+	return false
+}
+
+func createComment(ctx *storagev2.Context, rev *types.Review, content, source string, changesRequested bool) (*types.ReviewComment, error) {
+	comment := &types.ReviewComment{
+		Id:       uuid.NewString(),
+		OrgId:    rev.OrgId,
+		ReviewId: rev.Id,
+		Revision: max(rev.Revision, 1),
+		Owner: types.ReviewOwner{
+			Id:      ctx.UserID,
+			Name:    ctx.UserName,
+			Email:   ctx.UserEmail,
+			SlackID: ctx.SlackID,
+		},
+		Content:          content,
+		ChangesRequested: changesRequested,
+		Source:           source,
+		CreatedAt:        time.Now().UTC(),
+	}
+	if err := pgreview.New().CreateComment(comment); err != nil {
+		return nil, fmt.Errorf("failed saving review comment: %v", err)
+	}
+	return comment, nil
+}
@@ -0,0 +1,79 @@
+package review
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hoophq/hoop/gateway/storagev2"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyReviewChangesRequested(t *testing.T) {
+	rev := newPolicyReview(false, []types.ReviewPolicyGroup{{Name: "sre", MinApprovals: 2}})
+	require.NoError(t, applyReview(rev, reviewer("u1"), []string{"sre"}, types.ReviewStatusApproved, time.Now()))
+	require.NoError(t, applyReview(rev, reviewer("u2"), []string{"sre"}, types.ReviewStatusChangesRequested, time.Now()))
+	assert.Equal(t, types.ReviewStatusChangesRequested, rev.Status)
+	assert.Equal(t, types.ReviewStatusPending, rev.ReviewGroupsData[0].Status)
+	assert.Len(t, rev.ReviewGroupsData[0].Approvals, 2)
+
+	err := applyReview(rev, reviewer("u3"), []string{"dba"}, types.ReviewStatusChangesRequested, time.Now())
+	assert.Equal(t, ErrNotEligible, err)
+}
+
+func TestResubmitReview(t *testing.T) {
+	createdAt := time.Date(2024, 7, 25, 10, 0, 0, 0, time.UTC)
+	now := createdAt.Add(time.Hour)
+	expireAt, escalateAt := createdAt.Add(24*time.Hour), createdAt.Add(2*time.Hour)
+	rev := newPolicyReview(false, []types.ReviewPolicyGroup{{Name: "sre", MinApprovals: 2}})
+	rev.CreatedAt = createdAt
+	rev.Input = "DELETE FROM customers"
+	rev.ExpireAt, rev.EscalateAt = &expireAt, &escalateAt
+	rev.EscalatedAt, rev.RemindedAt = &now, &now
+	require.NoError(t, applyReview(rev, reviewer("u1"), []string{"sre"}, types.ReviewStatusApproved, now))
+	require.NoError(t, applyReview(rev, reviewer("u2"), []string{"sre"}, types.ReviewStatusChangesRequested, now))
+	rev.StatusReason = "changes requested by u2@hoop.dev: add a where clause"
+
+	resubmitReview(rev, "DELETE FROM customers WHERE id = 10", now)
+	assert.Equal(t, types.ReviewStatusPending, rev.Status)
+	assert.Equal(t, "DELETE FROM customers WHERE id = 10", rev.Input)
+	assert.Equal(t, 2, rev.Revision)
+	assert.Equal(t, now, rev.SubmittedAt())
+	assert.Empty(t, rev.StatusReason)
+	assert.Equal(t, now.Add(24*time.Hour), *rev.ExpireAt)
+	assert.Equal(t, now.Add(2*time.Hour), *rev.EscalateAt)
+	assert.Nil(t, rev.EscalatedAt)
+	assert.Nil(t, rev.RemindedAt)
+	assert.Equal(t, []types.ReviewGroup{{Group: "sre", Status: types.ReviewStatusPending, MinApprovals: 2}}, rev.ReviewGroupsData)
+
+	resubmitReview(rev, "DELETE FROM customers WHERE id = 11", now.Add(time.Hour))
+	assert.Equal(t, 3, rev.Revision)
+	assert.Equal(t, now.Add(25*time.Hour), *rev.ExpireAt)
+}
+
+func TestCanComment(t *testing.T) {
+	rev := newPolicyReview(false, []types.ReviewPolicyGroup{{Name: "sre", MinApprovals: 1}})
+	rev.ReviewOwner = reviewer("owner")
+	rev.EscalationGroup = "security"
+	newCtx := func(userID string, groups ...string) *storagev2.Context {
+		ctx := storagev2.NewContext(userID, "org")
+		ctx.UserGroups = groups
+		return ctx
+	}
+	for _, tt := range []struct {
+		msg  string
+		ctx  *storagev2.Context
+		want bool
+	}{
+		{msg: "it should allow the owner", ctx: newCtx("owner"), want: true},
+		{msg: "it should allow members of review groups", ctx: newCtx("u1", "sre"), want: true},
+		{msg: "it should allow members of the escalation group", ctx: newCtx("u1", "security"), want: true},
+		{msg: "it should allow admins", ctx: newCtx("u1", types.GroupAdmin), want: true},
+		{msg: "it should deny users outside of the review", ctx: newCtx("u1", "dba"), want: false},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			assert.Equal(t, tt.want, canComment(rev, tt.ctx))
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/pgrest"
+	pgreview "github.com/hoophq/hoop/gateway/pgrest/review"
+	"github.com/hoophq/hoop/gateway/storagev2"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+)
+
+const (
+	// MaxJitDuration is the maximum duration of a jit grant, including its extensions
+	MaxJitDuration = time.Hour * 48
+	// jitSessionsInterval is how often the grants of the sessions are fetched,
+	// it applies the changes made by other gateway instances
+	jitSessionsInterval = time.Second * 30
+)
+
+var (
+	ErrJitExpired = errors.New("jit access has expired")
+	ErrJitRevoked = errors.New("jit access has been revoked")
+	// ErrJitMaxDuration is returned when an extension exceeds the maximum duration of a grant
+	ErrJitMaxDuration = fmt.Errorf("jit access must not be greater than %v hours", MaxJitDuration.Hours())
+)
+
+type jitSession struct {
+	orgID    string
+	revokeAt time.Time
+	timer    *time.Timer
+	cancelFn context.CancelCauseFunc
+}
+
+var jitSessions = struct {
+	mu sync.Mutex
+	// review id -> session id
+	items map[string]map[string]*jitSession
+}{items: map[string]map[string]*jitSession{}}
+
+// NewJitContext returns the context of a session opened with a jit grant.
+// It's canceled when the grant reaches the time to be revoked, following
+// the changes of the grant: extensions, shortening and revocation.
+// The sessions are kept in memory, the changes made by other gateway
+// instances are applied by InitJitSessionsWatcher.
+func NewJitContext(parent context.Context, grant *types.Review, sid string) context.Context {
+	ctx, cancelFn := context.WithCancelCause(parent)
+	sess := &jitSession{orgID: grant.OrgId, revokeAt: *grant.RevokeAt, cancelFn: cancelFn}
+	sess.timer = time.AfterFunc(time.Until(*grant.RevokeAt), func() { cancelFn(ErrJitExpired) })
+
+	jitSessions.mu.Lock()
+	defer jitSessions.mu.Unlock()
+	if _, ok := jitSessions.items[grant.Id]; !ok {
+		jitSessions.items[grant.Id] = map[string]*jitSession{}
+	}
+	jitSessions.items[grant.Id][sid] = sess
+	context.AfterFunc(ctx, func() {
+		sess.timer.Stop()
+		jitSessions.mu.Lock()
+		defer jitSessions.mu.Unlock()
+		delete(jitSessions.items[grant.Id], sid)
+		if len(jitSessions.items[grant.Id]) == 0 {
+			delete(jitSessions.items, grant.Id)
+		}
+	})
+	return ctx
+}
+
+// updateJitSessions applies the changes of a grant to the sessions opened with it,
+// revoked grants end their sessions immediately.
+func updateJitSessions(grant *types.Review) {
+	jitSessions.mu.Lock()
+	defer jitSessions.mu.Unlock()
+	sessions := jitSessions.items[grant.Id]
+	for sid, sess := range sessions {
+		switch {
+		case grant.Status == types.ReviewStatusRevoked:
+			sess.cancelFn(ErrJitRevoked)
+		case grant.RevokeAt != nil:
+			sess.revokeAt = *grant.RevokeAt
+			sess.timer.Reset(time.Until(*grant.RevokeAt))
+		}
+		log.With("id", grant.Id, "sid", sid, "status", grant.Status).
+			Infof("jit session updated, revoke-at=%v", grant.RevokeAt)
+	}
+}
+
+// InitJitSessionsWatcher applies the changes of the grants made by other gateway
+// instances to the sessions opened in this instance, e.g.: revoking a grant
+func InitJitSessionsWatcher() {
+	log.Infof("initializing jit sessions watcher, interval=%v", jitSessionsInterval)
+	go func() {
+		for {
+			time.Sleep(jitSessionsInterval)
+			refreshJitSessions(func(orgID, id string) (*types.Review, error) {
+				return pgreview.New().FetchOneByID(pgrest.NewOrgContext(orgID), id)
+			})
+		}
+	}()
+}
+
+// refreshJitSessions fetches the grants of the sessions and updates the ones that have changed
+func refreshJitSessions(fetchFn func(orgID, id string) (*types.Review, error)) {
+	// grant id -> session
+	grants := map[string]jitSession{}
+	jitSessions.mu.Lock()
+	for grantID, sessions := range jitSessions.items {
+		for _, sess := range sessions {
+			grants[grantID] = jitSession{orgID: sess.orgID, revokeAt: sess.revokeAt}
+			break
+		}
+	}
+	jitSessions.mu.Unlock()
+	for grantID, sess := range grants {
+		grant, err := fetchFn(sess.orgID, grantID)
+		if err != nil {
+			log.With("id", grantID, "org", sess.orgID).Warnf("failed fetching jit grant, reason=%v", err)
+			continue
+		}
+		if grant == nil {
+			continue
+		}
+		revoked := grant.Status == types.ReviewStatusRevoked
+		if revoked || (grant.RevokeAt != nil && !grant.RevokeAt.Equal(sess.revokeAt)) {
+			updateJitSessions(grant)
+		}
+	}
+}
+
+// RequestExtension creates a review to extend the duration of an active jit grant.
+// The extension is reviewed by the same groups of the grant.
+func (s *Service) RequestExtension(ctx *storagev2.Context, grantID string, duration time.Duration) (*types.Review, error) {
+	grant, err := s.FindOne(ctx, grantID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch review error: %v", err)
+	}
+	if grant == nil || grant.Type != ReviewTypeJit || grant.ExtendsReviewId != "" {
+		return nil, ErrNotFound
+	}
+	if grant.ReviewOwner.Id != ctx.UserID {
+		return nil, ErrNotOwner
+	}
+	if err := validateActiveGrant(grant, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if grant.AccessDuration+duration > MaxJitDuration {
+		return nil, ErrJitMaxDuration
+	}
+
+	ext := newExtensionReview(grant, duration, time.Now().UTC())
+	if err := s.Persist(ctx, ext); err != nil {
+		return nil, fmt.Errorf("saving review error: %v", err)
+	}
+	log.With("id", ext.Id, "grant", grant.Id, "org", grant.OrgId, "user", ctx.UserEmail).
+		Infof("jit extension requested, duration=%v", duration)
+	s.notify(Event{Type: EventExtensionRequested, Review: ext, Groups: pendingGroups(ext)})
+	return ext, nil
+}
+
+// Shorten reduces the access duration of an active jit grant,
+// the sessions opened with the grant end at the new time.
+func (s *Service) Shorten(ctx *storagev2.Context, grantID string, duration time.Duration) (*types.Review, error) {
+	grant, err := s.FindOne(ctx, grantID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch review error: %v", err)
+	}
+	if grant == nil || grant.Type != ReviewTypeJit || grant.ExtendsReviewId != "" {
+		return nil, ErrNotFound
+	}
+	now := time.Now().UTC()
+	if err := validateActiveGrant(grant, now); err != nil {
+		return nil, err
+	}
+	if duration >= grant.AccessDuration {
+		return nil, fmt.Errorf("%w, the duration must be lower than %v", ErrWrongState, grant.AccessDuration)
+	}
+	shortenGrant(grant, duration, now)
+	grant.StatusReason = fmt.Sprintf("access duration shortened to %v by %s", duration, ctx.UserEmail)
+	if err := s.Persist(ctx, grant); err != nil {
+		return nil, fmt.Errorf("saving review error: %v", err)
+	}
+	updateJitSessions(grant)
+	return grant, nil
+}
+
+// reviewExtension persists the review of an extension, when it's approved
+// the duration of the grant is extended, including the sessions opened with it
+func (s *Service) reviewExtension(ctx *storagev2.Context, ext *types.Review) (*types.Review, error) {
+	var grant *types.Review
+	if ext.Status == types.ReviewStatusApproved {
+		var err error
+		if grant, err = s.extendGrant(ctx, ext); err != nil {
+			return nil, err
+		}
+		ext.RevokeAt = grant.RevokeAt
+		if err := s.Persist(ctx, grant); err != nil {
+			return nil, fmt.Errorf("saving review error: %v", err)
+		}
+	}
+	if err := s.Persist(ctx, ext); err != nil {
+		return nil, fmt.Errorf("saving review error: %v", err)
+	}
+	if grant != nil {
+		log.With("id", ext.Id, "grant", grant.Id, "org", grant.OrgId).
+			Infof("jit extension approved, revoke-at=%v", grant.RevokeAt.Format(time.RFC3339))
+		updateJitSessions(grant)
+	}
+	return ext, nil
+}
+
+// extendGrant applies an approved extension to its grant
+func (s *Service) extendGrant(ctx *storagev2.Context, ext *types.Review) (*types.Review, error) {
+	grant, err := s.FindOne(ctx, ext.ExtendsReviewId)
+	if err != nil {
+		return nil, fmt.Errorf("fetch review error: %v", err)
+	}
+	if grant == nil {
+		return nil, ErrNotFound
+	}
+	if err := validateActiveGrant(grant, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if grant.AccessDuration+ext.AccessDuration > MaxJitDuration {
+		return nil, ErrJitMaxDuration
+	}
+	revokeAt := grant.RevokeAt.Add(ext.AccessDuration)
+	grant.RevokeAt = &revokeAt
+	grant.AccessDuration += ext.AccessDuration
+	return grant, nil
+}
+
+func newExtensionReview(grant *types.Review, duration time.Duration, now time.Time) *types.Review {
+	groups := make([]types.ReviewGroup, len(grant.ReviewGroupsData))
+	for i, g := range grant.ReviewGroupsData {
+		groups[i] = types.ReviewGroup{
+			Group:        g.Group,
+			Status:       types.ReviewStatusPending,
+			Stage:        g.Stage,
+			MinApprovals: g.MinApprovals,
+		}
+	}
+	return &types.Review{
+		Id:        uuid.NewString(),
+		OrgId:     grant.OrgId,
+		CreatedAt: now,
+		Type:      ReviewTypeJit,
+		// extensions are not bound to a session, it keeps the review unique
+		Session:           uuid.NewString(),
+		Connection:        grant.Connection,
+		ReviewOwner:       grant.ReviewOwner,
+		AccessDuration:    duration,
+		Status:            types.ReviewStatusPending,
+		ReviewGroupsData:  groups,
+		DistinctApprovers: grant.DistinctApprovers,
+		ExtendsReviewId:   grant.Id,
+	}
+}
+
+// shortenGrant sets the time to revoke the grant based on the new duration,
+// counting from the time it was approved
+func shortenGrant(grant *types.Review, duration time.Duration, now time.Time) {
+	approvedAt := grant.RevokeAt.Add(-grant.AccessDuration)
+	revokeAt := approvedAt.Add(duration)
+	if revokeAt.Before(now) {
+		revokeAt = now
+	}
+	grant.RevokeAt = &revokeAt
+	grant.AccessDuration = duration
+}
+
+func validateActiveGrant(grant *types.Review, now time.Time) error {
+	if grant.Status != types.ReviewStatusApproved {
+		return ErrWrongState
+	}
+	if grant.RevokeAt == nil || !grant.RevokeAt.After(now) {
+		return ErrJitExpired
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hoophq/hoop/gateway/storagev2"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newJitGrant(id string, revokeAt time.Time) *types.Review {
+	return &types.Review{Id: id, Type: ReviewTypeJit, Status: types.ReviewStatusApproved, RevokeAt: &revokeAt}
+}
+
+func TestJitContextExpires(t *testing.T) {
+	grant := newJitGrant("grant-expire", time.Now().Add(50*time.Millisecond))
+	ctx := NewJitContext(context.Background(), grant, "sid-1")
+	select {
+	case <-ctx.Done():
+		assert.Equal(t, ErrJitExpired, context.Cause(ctx))
+	case <-time.After(time.Second):
+		t.Fatal("expected the context to expire")
+	}
+}
+
+func TestJitContextRevoked(t *testing.T) {
+	grant := newJitGrant("grant-revoke", time.Now().Add(time.Hour))
+	ctx1 := NewJitContext(context.Background(), grant, "sid-1")
+	ctx2 := NewJitContext(context.Background(), grant, "sid-2")
+
+	grant.Status = types.ReviewStatusRevoked
+	updateJitSessions(grant)
+	for _, ctx := range []context.Context{ctx1, ctx2} {
+		<-ctx.Done()
+		assert.Equal(t, ErrJitRevoked, context.Cause(ctx))
+	}
+	// the sessions are removed when their context is done
+	assert.Eventually(t, func() bool {
+		jitSessions.mu.Lock()
+		defer jitSessions.mu.Unlock()
+		_, ok := jitSessions.items[grant.Id]
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestJitContextUpdated(t *testing.T) {
+	grant := newJitGrant("grant-update", time.Now().Add(50*time.Millisecond))
+	ctx := NewJitContext(context.Background(), grant, "sid-1")
+
+	extendedRevokeAt := time.Now().Add(time.Hour)
+	grant.RevokeAt = &extendedRevokeAt
+	updateJitSessions(grant)
+	select {
+	case <-ctx.Done():
+		t.Fatal("expected the context to follow the extension of the grant")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	shortenedRevokeAt := time.Now()
+	grant.RevokeAt = &shortenedRevokeAt
+	updateJitSessions(grant)
+	select {
+	case <-ctx.Done():
+		assert.Equal(t, ErrJitExpired, context.Cause(ctx))
+	case <-time.After(time.Second):
+		t.Fatal("expected the context to expire after shortening the grant")
+	}
+}
+
+func TestShortenGrant(t *testing.T) {
+	approvedAt := time.Date(2024, 7, 25, 10, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		msg          string
+		duration     time.Duration
+		now          time.Time
+		wantRevokeAt time.Time
+	}{
+		{
+			msg:          "it should count the duration from the approval",
+			duration:     2 * time.Hour,
+			now:          approvedAt.Add(time.Hour),
+			wantRevokeAt: approvedAt.Add(2 * time.Hour),
+		},
+		{
+			msg:          "it should revoke immediately when the duration has already passed",
+			duration:     30 * time.Minute,
+			now:          approvedAt.Add(time.Hour),
+			wantRevokeAt: approvedAt.Add(time.Hour),
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			grant := newJitGrant("grant", approvedAt.Add(8*time.Hour))
+			grant.AccessDuration = 8 * time.Hour
+			shortenGrant(grant, tt.duration, tt.now)
+			assert.Equal(t, tt.wantRevokeAt, *grant.RevokeAt)
+			assert.Equal(t, tt.duration, grant.AccessDuration)
+		})
+	}
+}
+
+func TestNewExtensionReview(t *testing.T) {
+	now := time.Date(2024, 7, 25, 10, 0, 0, 0, time.UTC)
+	grant := newJitGrant("grant", now.Add(time.Hour))
+	grant.OrgId = "org"
+	grant.Session = "sid"
+	grant.ReviewOwner = reviewer("owner")
+	grant.DistinctApprovers = true
+	grant.ReviewGroupsData = []types.ReviewGroup{
+		{Id: "g1", Group: "sre", Status: types.ReviewStatusApproved, MinApprovals: 2,
+			Approvals: []types.ReviewApproval{{Status: types.ReviewStatusApproved}}},
+		{Id: "g2", Group: "security", Status: types.ReviewStatusApproved, Stage: 1},
+	}
+
+	ext := newExtensionReview(grant, time.Hour, now)
+	assert.NotEmpty(t, ext.Id)
+	assert.NotEqual(t, grant.Session, ext.Session)
+	assert.Equal(t, grant.Id, ext.ExtendsReviewId)
+	assert.Equal(t, types.ReviewStatusPending, ext.Status)
+	assert.Equal(t, time.Hour, ext.AccessDuration)
+	assert.Nil(t, ext.RevokeAt)
+	assert.True(t, ext.DistinctApprovers)
+	assert.Equal(t, []types.ReviewGroup{
+		{Group: "sre", Status: types.ReviewStatusPending, MinApprovals: 2},
+		{Group: "security", Status: types.ReviewStatusPending, Stage: 1},
+	}, ext.ReviewGroupsData)
+}
+
+func TestValidateActiveGrant(t *testing.T) {
+	now := time.Date(2024, 7, 25, 10, 0, 0, 0, time.UTC)
+	assert.NoError(t, validateActiveGrant(newJitGrant("grant", now.Add(time.Minute)), now))
+	assert.Equal(t, ErrJitExpired, validateActiveGrant(newJitGrant("grant", now), now))
+
+	revoked := newJitGrant("grant", now.Add(time.Minute))
+	revoked.Status = types.ReviewStatusRevoked
+	assert.Equal(t, ErrWrongState, validateActiveGrant(revoked, now))
+}
+
+func TestRefreshJitSessions(t *testing.T) {
+	revoked := newJitGrant("grant-refresh-revoked", time.Now().Add(time.Hour))
+	extended := newJitGrant("grant-refresh-extended", time.Now().Add(50*time.Millisecond))
+	unchanged := newJitGrant("grant-refresh-unchanged", time.Now().Add(time.Hour))
+	failed := newJitGrant("grant-refresh-failed", time.Now().Add(time.Hour))
+	revokedCtx := NewJitContext(context.Background(), revoked, "sid-1")
+	extendedCtx := NewJitContext(context.Background(), extended, "sid-2")
+	unchangedCtx := NewJitContext(context.Background(), unchanged, "sid-3")
+	failedCtx := NewJitContext(context.Background(), failed, "sid-4")
+
+	// the changes made by another gateway instance
+	extendedRevokeAt := time.Now().Add(time.Hour)
+	stored := map[string]*types.Review{
+		revoked.Id:   {Id: revoked.Id, Type: ReviewTypeJit, Status: types.ReviewStatusRevoked, RevokeAt: revoked.RevokeAt},
+		extended.Id:  newJitGrant(extended.Id, extendedRevokeAt),
+		unchanged.Id: unchanged,
+	}
+	refreshJitSessions(func(orgID, id string) (*types.Review, error) {
+		if id == failed.Id {
+			return nil, fmt.Errorf("connection refused")
+		}
+		return stored[id], nil
+	})
+
+	<-revokedCtx.Done()
+	assert.Equal(t, ErrJitRevoked, context.Cause(revokedCtx))
+	select {
+	case <-extendedCtx.Done():
+		t.Fatal("expected the context to follow the extension of the grant")
+	case <-time.After(200 * time.Millisecond):
+	}
+	assert.NoError(t, unchangedCtx.Err())
+	assert.NoError(t, failedCtx.Err())
+}
+
+func TestRevokeExtension(t *testing.T) {
+	ctx := storagev2.NewContext("user-id", "org-id")
+	ext := newJitGrant("ext-id", time.Now().Add(time.Hour))
+	ext.ReviewOwner.Id = "user-id"
+	ext.ExtendsReviewId = "grant-id"
+	_, err := (&Service{}).revoke(ctx, ext)
+	assert.Equal(t, ErrNotFound, err)
+}
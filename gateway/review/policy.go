@@ -0,0 +1,169 @@
+package review
+
+import (
+	"time"
+
+	pb "github.com/hoophq/hoop/common/proto"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+)
+
+// NewReviewGroups creates the groups of a review based on the policy of the connection.
+// Without a policy, each group of the plugin configuration requires one approval.
+func NewReviewGroups(policy *types.ReviewPolicy, pluginGroups []string) []types.ReviewGroup {
+	reviewGroups := []types.ReviewGroup{}
+	if policy == nil || len(policy.Stages) == 0 {
+		for _, group := range pluginGroups {
+			reviewGroups = append(reviewGroups, types.ReviewGroup{
+				Group:        group,
+				Status:       types.ReviewStatusPending,
+				MinApprovals: 1,
+			})
+		}
+		return reviewGroups
+	}
+	for stage, s := range policy.Stages {
+		for _, g := range s.Groups {
+			reviewGroups = append(reviewGroups, types.ReviewGroup{
+				Group:        g.Name,
+				Status:       types.ReviewStatusPending,
+				Stage:        stage,
+				MinApprovals: max(g.MinApprovals, 1),
+			})
+		}
+	}
+	return reviewGroups
+}
+
+// SetReviewPolicy copies the attributes of the policy evaluated
+// after the creation of the review
+func SetReviewPolicy(rev *types.Review, policy *types.ReviewPolicy) {
+	if policy == nil {
+		return
+	}
+	rev.DistinctApprovers = policy.DistinctApprovers
+	if policy.ExpireAfterSec > 0 {
+		expireAt := rev.CreatedAt.Add(time.Duration(policy.ExpireAfterSec) * time.Second)
+		rev.ExpireAt = &expireAt
+	}
+	if policy.ReminderIntervalSec > 0 {
+		rev.ReminderInterval = time.Duration(policy.ReminderIntervalSec) * time.Second
+	}
+	if policy.EscalateAfterSec > 0 && policy.EscalationGroup != "" {
+		escalateAt := rev.CreatedAt.Add(time.Duration(policy.EscalateAfterSec) * time.Second)
+		rev.EscalateAt = &escalateAt
+		rev.EscalationGroup = policy.EscalationGroup
+	}
+}
+
+// applyReview records the decision of a reviewer in the groups of the current stage.
+// A rejection or a change request from any eligible reviewer applies to the whole review,
+// an approval is counted for each pending group of the reviewer, or only for the first one
+// when the review requires distinct approvers. The review is approved when all groups are approved.
+// After the escalation, a member of the escalation group reviews all pending groups at once.
+func applyReview(rev *types.Review, reviewer types.ReviewOwner, userGroups []string, status types.ReviewStatus, now time.Time) error {
+	currentStage := rev.CurrentStage()
+	isEscalationReviewer := rev.EscalatedAt != nil && rev.EscalationGroup != "" &&
+		pb.IsInList(rev.EscalationGroup, userGroups)
+	var eligible []int
+	var hasLaterStage, hasReviewed bool
+	for i, g := range rev.ReviewGroupsData {
+		if isEscalationReviewer {
+			if g.Status == types.ReviewStatusPending {
+				eligible = append(eligible, i)
+			}
+			continue
+		}
+		if !pb.IsInList(g.Group, userGroups) {
+			continue
+		}
+		if hasApprover(g, reviewer.Id) {
+			hasReviewed = true
+			continue
+		}
+		if g.Stage > currentStage {
+			hasLaterStage = true
+			continue
+		}
+		if g.Stage == currentStage && g.Status == types.ReviewStatusPending {
+			eligible = append(eligible, i)
+		}
+	}
+	if rev.DistinctApprovers && hasReviewed {
+		return ErrAlreadyReviewed
+	}
+	switch {
+	case len(eligible) > 0:
+	case hasLaterStage:
+		return ErrStagePending
+	case hasReviewed:
+		return ErrAlreadyReviewed
+	default:
+		return ErrNotEligible
+	}
+	if rev.DistinctApprovers && !isEscalationReviewer {
+		eligible = eligible[:1]
+	}
+
+	reviewDate := now.UTC().Format(time.RFC3339)
+	for _, i := range eligible {
+		g := &rev.ReviewGroupsData[i]
+		g.Approvals = append(g.Approvals, types.ReviewApproval{
+			ReviewOwner: reviewer,
+			Status:      status,
+			ReviewDate:  reviewDate,
+		})
+		g.ReviewedBy = &types.ReviewOwner{Id: reviewer.Id, Name: reviewer.Name, Email: reviewer.Email, SlackID: reviewer.SlackID}
+		g.ReviewDate = &reviewDate
+		switch {
+		case status == types.ReviewStatusRejected:
+			g.Status = types.ReviewStatusRejected
+		case status == types.ReviewStatusChangesRequested:
+			// the group is reviewed again when the input is resubmitted
+		case isEscalationReviewer, countApprovals(*g) >= max(g.MinApprovals, 1):
+			g.Status = types.ReviewStatusApproved
+		}
+	}
+
+	switch status {
+	case types.ReviewStatusRejected, types.ReviewStatusChangesRequested:
+		rev.Status = status
+		return nil
+	}
+	for _, g := range rev.ReviewGroupsData {
+		if g.Status != types.ReviewStatusApproved {
+			return nil
+		}
+	}
+	rev.Status = types.ReviewStatusApproved
+	return nil
+}
+
+func hasApprover(g types.ReviewGroup, userID string) bool {
+	for _, a := range g.Approvals {
+		if a.Id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func countApprovals(g types.ReviewGroup) (count int) {
+	for _, a := range g.Approvals {
+		if a.Status == types.ReviewStatusApproved {
+			count++
+		}
+	}
+	return
+}
+
+// resetReviewGroups discards the reviews of all groups, it's used when
+// the review is resubmitted with a new input
+func resetReviewGroups(rev *types.Review) {
+	for i := range rev.ReviewGroupsData {
+		g := &rev.ReviewGroupsData[i]
+		g.Status = types.ReviewStatusPending
+		g.ReviewedBy = nil
+		g.ReviewDate = nil
+		g.Approvals = nil
+	}
+}
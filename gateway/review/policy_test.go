@@ -0,0 +1,116 @@
+package review
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPolicyReview(distinct bool, stages ...[]types.ReviewPolicyGroup) *types.Review {
+	policy := &types.ReviewPolicy{DistinctApprovers: distinct}
+	for _, groups := range stages {
+		policy.Stages = append(policy.Stages, types.ReviewPolicyStage{Groups: groups})
+	}
+	return &types.Review{
+		Status:            types.ReviewStatusPending,
+		DistinctApprovers: distinct,
+		ReviewGroupsData:  NewReviewGroups(policy, nil),
+	}
+}
+
+func reviewer(id string) types.ReviewOwner { return types.ReviewOwner{Id: id, Email: id + "@hoop.dev"} }
+
+func TestNewReviewGroups(t *testing.T) {
+	groups := NewReviewGroups(nil, []string{"sre", "dba"})
+	assert.Equal(t, []types.ReviewGroup{
+		{Group: "sre", Status: types.ReviewStatusPending, MinApprovals: 1},
+		{Group: "dba", Status: types.ReviewStatusPending, MinApprovals: 1},
+	}, groups)
+
+	groups = NewReviewGroups(&types.ReviewPolicy{Stages: []types.ReviewPolicyStage{
+		{Groups: []types.ReviewPolicyGroup{{Name: "sre", MinApprovals: 2}}},
+		{Groups: []types.ReviewPolicyGroup{{Name: "security"}}},
+	}}, []string{"dba"})
+	assert.Equal(t, []types.ReviewGroup{
+		{Group: "sre", Status: types.ReviewStatusPending, MinApprovals: 2},
+		{Group: "security", Status: types.ReviewStatusPending, Stage: 1, MinApprovals: 1},
+	}, groups)
+}
+
+func TestApplyReviewQuorum(t *testing.T) {
+	now := time.Now()
+	rev := newPolicyReview(false, []types.ReviewPolicyGroup{{Name: "sre", MinApprovals: 2}})
+
+	require.NoError(t, applyReview(rev, reviewer("u1"), []string{"sre"}, types.ReviewStatusApproved, now))
+	assert.Equal(t, types.ReviewStatusPending, rev.Status)
+	assert.Equal(t, types.ReviewStatusPending, rev.ReviewGroupsData[0].Status)
+	assert.Equal(t, "1/2 approvals", rev.ReviewGroupsData[0].ApprovalsProgress())
+
+	err := applyReview(rev, reviewer("u1"), []string{"sre"}, types.ReviewStatusApproved, now)
+	assert.Equal(t, ErrAlreadyReviewed, err)
+
+	require.NoError(t, applyReview(rev, reviewer("u2"), []string{"sre"}, types.ReviewStatusApproved, now))
+	assert.Equal(t, types.ReviewStatusApproved, rev.Status)
+	assert.Equal(t, types.ReviewStatusApproved, rev.ReviewGroupsData[0].Status)
+	assert.Len(t, rev.ReviewGroupsData[0].Approvals, 2)
+}
+
+func TestApplyReviewStages(t *testing.T) {
+	now := time.Now()
+	rev := newPolicyReview(false,
+		[]types.ReviewPolicyGroup{{Name: "sre", MinApprovals: 1}},
+		[]types.ReviewPolicyGroup{{Name: "security", MinApprovals: 1}},
+	)
+	assert.Equal(t, 0, rev.CurrentStage())
+
+	err := applyReview(rev, reviewer("u1"), []string{"security"}, types.ReviewStatusApproved, now)
+	assert.Equal(t, ErrStagePending, err)
+
+	err = applyReview(rev, reviewer("u1"), []string{"dba"}, types.ReviewStatusApproved, now)
+	assert.Equal(t, ErrNotEligible, err)
+
+	require.NoError(t, applyReview(rev, reviewer("u1"), []string{"sre"}, types.ReviewStatusApproved, now))
+	assert.Equal(t, 1, rev.CurrentStage())
+	assert.Equal(t, types.ReviewStatusPending, rev.Status)
+
+	require.NoError(t, applyReview(rev, reviewer("u2"), []string{"security"}, types.ReviewStatusApproved, now))
+	assert.Equal(t, types.ReviewStatusApproved, rev.Status)
+	assert.Equal(t, 1, rev.CurrentStage())
+}
+
+func TestApplyReviewDistinctApprovers(t *testing.T) {
+	now := time.Now()
+	for _, tt := range []struct {
+		msg      string
+		distinct bool
+		want     types.ReviewStatus
+	}{
+		{msg: "it should approve all groups of the reviewer", distinct: false, want: types.ReviewStatusApproved},
+		{msg: "it should approve a single group when approvers must be distinct", distinct: true, want: types.ReviewStatusPending},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			rev := newPolicyReview(tt.distinct, []types.ReviewPolicyGroup{
+				{Name: "sre", MinApprovals: 1},
+				{Name: "dba", MinApprovals: 1},
+			})
+			require.NoError(t, applyReview(rev, reviewer("u1"), []string{"sre", "dba"}, types.ReviewStatusApproved, now))
+			assert.Equal(t, tt.want, rev.Status)
+			if tt.distinct {
+				err := applyReview(rev, reviewer("u1"), []string{"sre", "dba"}, types.ReviewStatusApproved, now)
+				assert.Equal(t, ErrAlreadyReviewed, err)
+			}
+		})
+	}
+}
+
+func TestApplyReviewRejection(t *testing.T) {
+	rev := newPolicyReview(false, []types.ReviewPolicyGroup{{Name: "sre", MinApprovals: 3}})
+	require.NoError(t, applyReview(rev, reviewer("u1"), []string{"sre"}, types.ReviewStatusApproved, time.Now()))
+	require.NoError(t, applyReview(rev, reviewer("u2"), []string{"sre"}, types.ReviewStatusRejected, time.Now()))
+	assert.Equal(t, types.ReviewStatusRejected, rev.Status)
+	assert.Equal(t, types.ReviewStatusRejected, rev.ReviewGroupsData[0].Status)
+	assert.Equal(t, "u2", rev.ReviewGroupsData[0].ReviewedBy.Id)
+}
@@ -0,0 +1,222 @@
+package review
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+)
+
+const (
+	QueryTypeRead  = "read"
+	QueryTypeWrite = "write"
+)
+
+var (
+	readStatements = []string{"SELECT", "SHOW", "EXPLAIN", "DESCRIBE", "DESC", "WITH", "VALUES", "TABLE"}
+	// statements containing any of these keywords are not considered read only,
+	// it includes constructions like SELECT ... INTO, SELECT ... FOR UPDATE and EXPLAIN ANALYZE
+	reWriteKeywords = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|UPSERT|REPLACE|CREATE|DROP|ALTER|TRUNCATE|GRANT|REVOKE|INTO|ANALYZE|CALL|EXEC|EXECUTE|COPY|LOCK)\b`)
+	reLineComments  = regexp.MustCompile(`--[^\n]*`)
+	reBlockComments = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	// identifiers followed by a parenthesis are function calls, keywords or column lists
+	reFunctionCalls = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_$.]*)\s*\(`)
+	// quoted identifiers followed by a parenthesis, e.g.: "pg_terminate_backend"(1)
+	reQuotedCalls = regexp.MustCompile("[\"`\\]]\\s*\\(")
+	// functions and keywords followed by a parenthesis allowed in read only statements,
+	// any other function call could have side effects and the statement is considered a write
+	readOnlyCalls = []string{
+		// keywords
+		"select", "with", "values", "as", "in", "exists", "any", "all", "some", "not", "and", "or",
+		"from", "join", "on", "using", "where", "over", "filter", "within", "partition", "by",
+		"union", "intersect", "except", "distinct", "lateral", "case", "when", "then", "else",
+		"between", "like", "ilike", "is", "having", "limit", "offset", "top", "row", "array", "cast",
+		// types
+		"char", "character", "varchar", "nvarchar", "numeric", "decimal", "float", "timestamp", "time",
+		// aggregate and window functions
+		"count", "count_big", "sum", "avg", "min", "max", "string_agg", "array_agg", "json_agg", "jsonb_agg",
+		"bool_and", "bool_or", "row_number", "rank", "dense_rank", "ntile", "lag", "lead",
+		"first_value", "last_value", "percentile_cont", "percentile_disc",
+		// scalar functions
+		"coalesce", "nullif", "ifnull", "isnull", "if", "greatest", "least", "lower", "upper",
+		"length", "char_length", "len", "substring", "substr", "trim", "ltrim", "rtrim", "concat",
+		"concat_ws", "split_part", "position", "left", "right", "format", "md5", "round", "floor",
+		"ceil", "ceiling", "abs", "now", "date", "date_trunc", "date_part", "extract", "age",
+		"to_char", "to_date", "to_timestamp", "date_format", "datediff", "dateadd", "getdate",
+		"json_build_object", "jsonb_build_object", "unnest", "generate_series",
+	}
+	weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// RuleInput is the information of a session evaluated by review rules
+type RuleInput struct {
+	Input      string
+	UserEmail  string
+	UserGroups []string
+	Now        time.Time
+}
+
+// MatchRule returns the first rule matching all of its conditions, or nil when there's no match.
+func MatchRule(rules []types.ReviewRule, in RuleInput) *types.ReviewRule {
+	for _, rule := range rules {
+		if matchRule(rule, in) {
+			return &rule
+		}
+	}
+	return nil
+}
+
+func matchRule(rule types.ReviewRule, in RuleInput) bool {
+	if rule.QueryType != "" && QueryType(in.Input) != rule.QueryType {
+		return false
+	}
+	// rules that can't be evaluated only match when they require a review
+	failClosed := rule.Action == types.ReviewRuleActionRequire
+	if rule.QueryPattern != "" {
+		re, err := compileQueryPattern(rule.QueryPattern)
+		if err != nil {
+			log.Warnf("failed compiling query pattern of review rule %q, reason=%v", rule.Name, err)
+			return failClosed
+		}
+		if !re.MatchString(in.Input) {
+			return false
+		}
+	}
+	if len(rule.Users) > 0 && !slices.ContainsFunc(rule.Users, func(u string) bool { return strings.EqualFold(u, in.UserEmail) }) {
+		return false
+	}
+	if len(rule.Groups) > 0 && !slices.ContainsFunc(rule.Groups, func(g string) bool { return slices.Contains(in.UserGroups, g) }) {
+		return false
+	}
+	if rule.TimeWindow != nil {
+		inWindow, err := InTimeWindow(rule.TimeWindow, in.Now)
+		if err != nil {
+			log.Warnf("failed evaluating time window of review rule %q, reason=%v", rule.Name, err)
+			return failClosed
+		}
+		if inWindow == rule.TimeWindow.Outside {
+			return false
+		}
+	}
+	return true
+}
+
+// QueryType classifies the statements of an input as read or write.
+// An input is read only when all of its statements are read only,
+// it returns an empty string when the input doesn't contain any statement.
+//
+// The classification is used to skip reviews, any construction that could
+// have side effects is considered a write. The keywords and function calls are
+// verified in the whole input, including comments and string literals.
+func QueryType(input string) string {
+	stripped := reBlockComments.ReplaceAllString(input, " ")
+	stripped = reLineComments.ReplaceAllString(stripped, " ")
+	var hasStatement bool
+	for _, stmt := range strings.Split(stripped, ";") {
+		fields := strings.Fields(stmt)
+		if len(fields) == 0 {
+			continue
+		}
+		hasStatement = true
+		keyword := strings.ToUpper(strings.TrimLeft(fields[0], "("))
+		if !slices.Contains(readStatements, keyword) {
+			return QueryTypeWrite
+		}
+	}
+	if !hasStatement {
+		return ""
+	}
+	if reWriteKeywords.MatchString(input) || hasUnsafeCalls(input) {
+		return QueryTypeWrite
+	}
+	return QueryTypeRead
+}
+
+// hasUnsafeCalls reports if the input calls functions that are not known to be read only
+func hasUnsafeCalls(input string) bool {
+	if reQuotedCalls.MatchString(input) {
+		return true
+	}
+	for _, match := range reFunctionCalls.FindAllStringSubmatch(input, -1) {
+		if !slices.Contains(readOnlyCalls, strings.ToLower(match[1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRule checks if the conditions of a rule can be evaluated
+func ValidateRule(rule types.ReviewRule) error {
+	if _, err := compileQueryPattern(rule.QueryPattern); err != nil {
+		return fmt.Errorf("invalid query pattern: %v", err)
+	}
+	if rule.TimeWindow != nil {
+		if err := ValidateTimeWindow(rule.TimeWindow); err != nil {
+			return fmt.Errorf("invalid time window: %v", err)
+		}
+	}
+	return nil
+}
+
+func compileQueryPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// InTimeWindow reports if the time is inside of the window
+func InTimeWindow(w *types.ReviewRuleTimeWindow, t time.Time) (bool, error) {
+	loc := time.UTC
+	if w.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(w.Timezone); err != nil {
+			return false, err
+		}
+	}
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false, err
+	}
+	t = t.In(loc)
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	day := t.Weekday()
+	var inClock bool
+	switch {
+	case start <= end:
+		inClock = clock >= start && clock < end
+	case clock >= start:
+		inClock = true
+	case clock < end:
+		// the window spans midnight, the time belongs to the window started in the previous day
+		inClock, day = true, (day+6)%7
+	}
+	if !inClock {
+		return false, nil
+	}
+	return len(w.Weekdays) == 0 || slices.Contains(w.Weekdays, weekdays[day]), nil
+}
+
+// ValidateTimeWindow checks if the attributes of a time window are valid
+func ValidateTimeWindow(w *types.ReviewRuleTimeWindow) error {
+	for _, day := range w.Weekdays {
+		if !slices.Contains(weekdays, day) {
+			return fmt.Errorf("invalid weekday %q, accepted values are %v", day, strings.Join(weekdays, ", "))
+		}
+	}
+	_, err := InTimeWindow(w, time.Now())
+	return err
+}
+
+func parseClock(v string) (time.Duration, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected format is HH:MM", v)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,127 @@
+package review
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryType(t *testing.T) {
+	for _, tt := range []struct {
+		input string
+		want  string
+	}{
+		{input: "SELECT * FROM customers", want: QueryTypeRead},
+		{input: "-- list users\nselect id from users; show tables;", want: QueryTypeRead},
+		{input: "WITH c AS (SELECT 1) SELECT * FROM c", want: QueryTypeRead},
+		{input: "EXPLAIN SELECT 1", want: QueryTypeRead},
+		{input: "(SELECT 1) UNION (SELECT 2)", want: QueryTypeRead},
+		{input: "SELECT 1; DELETE FROM customers", want: QueryTypeWrite},
+		{input: "SELECT * INTO backup FROM customers", want: QueryTypeWrite},
+		{input: "SELECT * FROM customers FOR UPDATE", want: QueryTypeWrite},
+		{input: "EXPLAIN ANALYZE DELETE FROM customers", want: QueryTypeWrite},
+		{input: "WITH d AS (DELETE FROM c RETURNING *) SELECT * FROM d", want: QueryTypeWrite},
+		{input: "/* cleanup */ UPDATE customers SET name = ''", want: QueryTypeWrite},
+		{input: "db.customers.find({})", want: QueryTypeWrite},
+		{input: " ;-- nothing ", want: ""},
+		{input: "SELECT count(*), max(price) FROM orders WHERE id IN (1, 2)", want: QueryTypeRead},
+		{input: "SELECT pg_terminate_backend(123)", want: QueryTypeWrite},
+		{input: "select setval('orders_id_seq', 1)", want: QueryTypeWrite},
+		{input: "SELECT lo_unlink(1234)", want: QueryTypeWrite},
+		{input: "SELECT public.my_mutating_fn()", want: QueryTypeWrite},
+		{input: `SELECT "pg_terminate_backend"(123)`, want: QueryTypeWrite},
+		{input: "SELECT '--', pg_terminate_backend(123)", want: QueryTypeWrite},
+	} {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryType(tt.input))
+		})
+	}
+}
+
+func TestMatchRuleFailClosed(t *testing.T) {
+	badWindow := &types.ReviewRuleTimeWindow{Timezone: "Mars/Olympus", Start: "09:00", End: "18:00"}
+	in := RuleInput{Input: "SELECT 1", Now: time.Now()}
+	rule := MatchRule([]types.ReviewRule{{Name: "require", Action: types.ReviewRuleActionRequire, TimeWindow: badWindow}}, in)
+	assert.NotNil(t, rule, "it should match require rules that can't be evaluated")
+	rule = MatchRule([]types.ReviewRule{{Name: "skip", Action: types.ReviewRuleActionSkip, QueryPattern: "("}}, in)
+	assert.Nil(t, rule, "it should not match skip rules that can't be evaluated")
+}
+
+func TestValidateRule(t *testing.T) {
+	assert.NoError(t, ValidateRule(types.ReviewRule{QueryPattern: `^vacuum\b`}))
+	assert.EqualError(t, ValidateRule(types.ReviewRule{QueryPattern: "("}),
+		"invalid query pattern: error parsing regexp: missing closing ): `(?i)(`")
+	assert.ErrorContains(t, ValidateRule(types.ReviewRule{TimeWindow: &types.ReviewRuleTimeWindow{Start: "9h", End: "18:00"}}),
+		"invalid time window")
+}
+
+func TestInTimeWindow(t *testing.T) {
+	// 2024-09-09 is a monday
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2024, time.September, 9, hour, minute, 0, 0, time.UTC)
+	}
+	businessHours := &types.ReviewRuleTimeWindow{Weekdays: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "18:00"}
+	overnight := &types.ReviewRuleTimeWindow{Weekdays: []string{"sun"}, Start: "22:00", End: "06:00"}
+	for _, tt := range []struct {
+		msg    string
+		window *types.ReviewRuleTimeWindow
+		now    time.Time
+		want   bool
+	}{
+		{msg: "it should match inside business hours", window: businessHours, now: monday(9, 0), want: true},
+		{msg: "it should not match at the end of business hours", window: businessHours, now: monday(18, 0), want: false},
+		{msg: "it should not match on weekends", window: businessHours, now: monday(10, 0).AddDate(0, 0, -1), want: false},
+		{msg: "it should match the overnight window started in the previous day", window: overnight, now: monday(5, 59), want: true},
+		{msg: "it should not match the overnight window started in the current day", window: overnight, now: monday(23, 0), want: false},
+		{
+			msg:    "it should convert the time to the timezone of the window",
+			window: &types.ReviewRuleTimeWindow{Timezone: "America/Sao_Paulo", Start: "09:00", End: "18:00"},
+			now:    monday(20, 0),
+			want:   true,
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			got, err := InTimeWindow(tt.window, tt.now)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	assert.Error(t, ValidateTimeWindow(&types.ReviewRuleTimeWindow{Start: "9h", End: "18:00"}))
+	assert.Error(t, ValidateTimeWindow(&types.ReviewRuleTimeWindow{Start: "09:00", End: "18:00", Weekdays: []string{"monday"}}))
+	assert.Error(t, ValidateTimeWindow(&types.ReviewRuleTimeWindow{Timezone: "Mars/Olympus", Start: "09:00", End: "18:00"}))
+}
+
+func TestMatchRule(t *testing.T) {
+	rules := []types.ReviewRule{
+		{Name: "exempt-dba", Action: types.ReviewRuleActionSkip, Users: []string{"DBA@hoop.dev"}},
+		{Name: "after-hours", Action: types.ReviewRuleActionRequire, TimeWindow: &types.ReviewRuleTimeWindow{
+			Start: "09:00", End: "18:00", Outside: true,
+		}},
+		{Name: "read-only", Action: types.ReviewRuleActionSkip, QueryType: QueryTypeRead},
+		{Name: "sre-maintenance", Action: types.ReviewRuleActionSkip, Groups: []string{"sre"}, QueryPattern: `^vacuum\b`},
+	}
+	businessTime := time.Date(2024, time.September, 9, 10, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		msg  string
+		in   RuleInput
+		want string
+	}{
+		{msg: "it should match exempt users", in: RuleInput{Input: "DELETE FROM c", UserEmail: "dba@hoop.dev", Now: businessTime.Add(time.Hour * 12)}, want: "exempt-dba"},
+		{msg: "it should require review outside business hours", in: RuleInput{Input: "SELECT 1", Now: businessTime.Add(time.Hour * 12)}, want: "after-hours"},
+		{msg: "it should skip read only queries", in: RuleInput{Input: "SELECT 1", Now: businessTime}, want: "read-only"},
+		{msg: "it should match groups and query pattern", in: RuleInput{Input: "VACUUM customers", UserGroups: []string{"sre"}, Now: businessTime}, want: "sre-maintenance"},
+		{msg: "it should not match when any condition doesn't match", in: RuleInput{Input: "VACUUM customers", UserGroups: []string{"dev"}, Now: businessTime}},
+		{msg: "it should not match writes", in: RuleInput{Input: "UPDATE c SET a=1", Now: businessTime}},
+		{msg: "it should not skip reads calling functions with side effects", in: RuleInput{Input: "SELECT pg_terminate_backend(1)", Now: businessTime}},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			var got string
+			if rule := MatchRule(rules, tt.in); rule != nil {
+				got = rule.Name
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
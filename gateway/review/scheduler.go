@@ -0,0 +1,158 @@
+package review
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/models"
+	pgreview "github.com/hoophq/hoop/gateway/pgrest/review"
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+)
+
+type EventType string
+
+const (
+	EventReminder  EventType = "reminder"
+	EventEscalated EventType = "escalated"
+	EventExpired   EventType = "expired"
+
+	EventCommented        EventType = "commented"
+	EventChangesRequested EventType = "changes_requested"
+	EventResubmitted      EventType = "resubmitted"
+
+	EventExtensionRequested EventType = "extension_requested"
+
+	EventBreakGlass         EventType = "break_glass"
+	EventBreakGlassReviewed EventType = "break_glass_reviewed"
+)
+
+var schedulerInterval = time.Minute
+
+// Event is a change of a pending review performed by the scheduler or by the users of the thread
+type Event struct {
+	Type   EventType
+	Review *types.Review
+	// Groups are the groups that must be notified about the event
+	Groups []string
+	// Comment is the comment of the thread that generated the event
+	Comment *types.ReviewComment
+}
+
+// Notifier delivers the events of pending reviews to reviewers, e.g.: slack and webhooks
+type Notifier interface {
+	OnReviewEvent(ev Event)
+}
+
+// InitScheduler processes the timeouts of pending reviews in background. It runs in every
+// gateway instance, each event is claimed atomically and it's processed by a single instance.
+func (s *Service) InitScheduler() {
+	log.Infof("initializing review scheduler, interval=%v, notifiers=%v", schedulerInterval, len(s.Notifiers))
+	go func() {
+		for {
+			time.Sleep(schedulerInterval)
+			items, err := pgreview.New().FetchPendingWithTimeouts()
+			if err != nil {
+				log.Warnf("failed fetching pending reviews, reason=%v", err)
+				continue
+			}
+			for _, rev := range items {
+				if err := s.processTimeouts(&rev, time.Now().UTC()); err != nil {
+					log.With("id", rev.Id, "sid", rev.Session, "org", rev.OrgId).
+						Warnf("failed processing review timeouts, reason=%v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *Service) processTimeouts(rev *types.Review, now time.Time) error {
+	ev := nextEvent(rev, now)
+	if ev == nil {
+		return nil
+	}
+	claimed, err := claimEvent(ev, now)
+	if err != nil {
+		return fmt.Errorf("failed saving review: %v", err)
+	}
+	// the review was changed after it was fetched, e.g.: approved by a user
+	// or processed by the scheduler of another gateway instance
+	if !claimed {
+		log.With("id", rev.Id, "sid", rev.Session, "org", rev.OrgId).
+			Debugf("review %v event already processed or the review has changed", ev.Type)
+		return nil
+	}
+	log.With("id", rev.Id, "sid", rev.Session, "org", rev.OrgId, "groups", ev.Groups).
+		Infof("processing review %v event", ev.Type)
+	// release the connection if there's a client waiting
+	if ev.Type == EventExpired && s.TransportService != nil {
+		s.TransportService.ReviewStatusChange(rev)
+	}
+	s.notify(*ev)
+	return nil
+}
+
+// claimEvent persists only the attributes changed by the event when the review is still in
+// the state it was fetched. It returns false when the event must not be processed.
+func claimEvent(ev *Event, now time.Time) (bool, error) {
+	rev := ev.Review
+	switch ev.Type {
+	case EventExpired:
+		return models.ClaimReviewExpiration(rev.OrgId, rev.Id, rev.StatusReason)
+	case EventEscalated:
+		return models.ClaimReviewEscalation(rev.OrgId, rev.Id, now)
+	case EventReminder:
+		return models.ClaimReviewReminder(rev.OrgId, rev.Id, now.Add(-rev.ReminderInterval), now)
+	}
+	return false, fmt.Errorf("unknown scheduler event %v", ev.Type)
+}
+
+func (s *Service) notify(ev Event) {
+	for _, n := range s.Notifiers {
+		n.OnReviewEvent(ev)
+	}
+}
+
+// nextEvent updates the review with the first timeout reached: expiration, escalation or reminder.
+// It returns nil when the review doesn't have any timeout to process.
+func nextEvent(rev *types.Review, now time.Time) *Event {
+	if rev.Status != types.ReviewStatusPending {
+		return nil
+	}
+	if rev.ExpireAt != nil && !now.Before(*rev.ExpireAt) {
+		rev.Status = types.ReviewStatusRejected
+		rev.StatusReason = fmt.Sprintf("review expired after %v without approval",
+			rev.ExpireAt.Sub(rev.SubmittedAt()).Round(time.Second))
+		return &Event{Type: EventExpired, Review: rev, Groups: pendingGroups(rev)}
+	}
+	if rev.EscalateAt != nil && rev.EscalatedAt == nil && !now.Before(*rev.EscalateAt) {
+		rev.EscalatedAt = &now
+		rev.RemindedAt = &now
+		return &Event{Type: EventEscalated, Review: rev, Groups: []string{rev.EscalationGroup}}
+	}
+	if rev.ReminderInterval > 0 {
+		lastNotification := rev.SubmittedAt()
+		if rev.RemindedAt != nil {
+			lastNotification = *rev.RemindedAt
+		}
+		if !now.Before(lastNotification.Add(rev.ReminderInterval)) {
+			rev.RemindedAt = &now
+			return &Event{Type: EventReminder, Review: rev, Groups: pendingGroups(rev)}
+		}
+	}
+	return nil
+}
+
+// pendingGroups returns the groups able to review the current stage
+func pendingGroups(rev *types.Review) (groups []string) {
+	currentStage := rev.CurrentStage()
+	for _, g := range rev.ReviewGroupsData {
+		if g.Stage == currentStage && g.Status == types.ReviewStatusPending {
+			groups = append(groups, g.Group)
+		}
+	}
+	if rev.EscalatedAt != nil && rev.EscalationGroup != "" {
+		groups = append(groups, rev.EscalationGroup)
+	}
+	return
+}
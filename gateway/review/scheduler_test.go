@@ -0,0 +1,86 @@
+package review
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hoophq/hoop/gateway/storagev2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newScheduledReview(createdAt time.Time, policy *types.ReviewPolicy) *types.Review {
+	rev := &types.Review{
+		Status:           types.ReviewStatusPending,
+		CreatedAt:        createdAt,
+		ReviewGroupsData: NewReviewGroups(policy, []string{"sre"}),
+	}
+	SetReviewPolicy(rev, policy)
+	return rev
+}
+
+func TestNextEvent(t *testing.T) {
+	createdAt := time.Date(2024, time.September, 9, 10, 0, 0, 0, time.UTC)
+	policy := &types.ReviewPolicy{
+		ExpireAfterSec:      3600 * 24,
+		ReminderIntervalSec: 3600,
+		EscalateAfterSec:    3600 * 4,
+		EscalationGroup:     "security",
+	}
+	rev := newScheduledReview(createdAt, policy)
+
+	assert.Nil(t, nextEvent(rev, createdAt.Add(time.Minute*59)))
+
+	ev := nextEvent(rev, createdAt.Add(time.Hour))
+	require.NotNil(t, ev)
+	assert.Equal(t, EventReminder, ev.Type)
+	assert.Equal(t, []string{"sre"}, ev.Groups)
+	assert.Nil(t, nextEvent(rev, createdAt.Add(time.Minute*90)), "it should wait the reminder interval")
+
+	ev = nextEvent(rev, createdAt.Add(time.Hour*4))
+	require.NotNil(t, ev)
+	assert.Equal(t, EventEscalated, ev.Type)
+	assert.Equal(t, []string{"security"}, ev.Groups)
+	assert.NotNil(t, rev.EscalatedAt)
+
+	ev = nextEvent(rev, createdAt.Add(time.Hour*5))
+	require.NotNil(t, ev)
+	assert.Equal(t, EventReminder, ev.Type)
+	assert.Equal(t, []string{"sre", "security"}, ev.Groups)
+
+	ev = nextEvent(rev, createdAt.Add(time.Hour*24))
+	require.NotNil(t, ev)
+	assert.Equal(t, EventExpired, ev.Type)
+	assert.Equal(t, types.ReviewStatusRejected, rev.Status)
+	assert.Equal(t, "review expired after 24h0m0s without approval", rev.StatusReason)
+
+	assert.Nil(t, nextEvent(rev, createdAt.Add(time.Hour*25)), "it should ignore reviews that aren't pending")
+}
+
+func TestNextEventWithoutTimeouts(t *testing.T) {
+	createdAt := time.Now().UTC()
+	rev := newScheduledReview(createdAt, nil)
+	assert.Nil(t, nextEvent(rev, createdAt.Add(time.Hour*24*30)))
+}
+
+func TestApplyReviewEscalation(t *testing.T) {
+	createdAt := time.Now().UTC()
+	rev := newScheduledReview(createdAt, &types.ReviewPolicy{
+		Stages: []types.ReviewPolicyStage{
+			{Groups: []types.ReviewPolicyGroup{{Name: "sre", MinApprovals: 2}}},
+			{Groups: []types.ReviewPolicyGroup{{Name: "dba", MinApprovals: 1}}},
+		},
+		EscalateAfterSec: 60,
+		EscalationGroup:  "security",
+	})
+
+	err := applyReview(rev, reviewer("u1"), []string{"security"}, types.ReviewStatusApproved, createdAt)
+	assert.Equal(t, ErrNotEligible, err, "it should not allow the escalation group before the escalation")
+
+	require.NotNil(t, nextEvent(rev, createdAt.Add(time.Minute)))
+	require.NoError(t, applyReview(rev, reviewer("u1"), []string{"security"}, types.ReviewStatusApproved, createdAt))
+	assert.Equal(t, types.ReviewStatusApproved, rev.Status)
+	for _, g := range rev.ReviewGroupsData {
+		assert.Equal(t, types.ReviewStatusApproved, g.Status)
+	}
+}
@@ -6,11 +6,12 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	pb "github.com/hoophq/hoop/common/proto"
 	"github.com/hoophq/hoop/gateway/api/openapi"
+	"github.com/hoophq/hoop/gateway/models"
 	"github.com/hoophq/hoop/gateway/pgrest"
 	pgreview "github.com/hoophq/hoop/gateway/pgrest/review"
 	pgsession "github.com/hoophq/hoop/gateway/pgrest/session"
+	"github.com/hoophq/hoop/gateway/rbac"
 	"github.com/hoophq/hoop/gateway/storagev2"
 	"github.com/hoophq/hoop/gateway/storagev2/types"
 )
@@ -18,6 +19,8 @@ import (
 type (
 	Service struct {
 		TransportService transportService
+		// Notifiers receive the events of reviews, e.g.: timeouts and comments
+		Notifiers []Notifier
 	}
 
 	transportService interface {
@@ -30,11 +33,19 @@ var (
 	ErrWrongState   = errors.New("review in wrong state")
 	ErrNotEligible  = errors.New("not eligible for review")
 	ErrSelfApproval = errors.New("unable to self approve review")
+	// ErrStagePending is returned when the reviewer belongs only to groups of later stages
+	ErrStagePending    = errors.New("previous review stages are pending approval")
+	ErrAlreadyReviewed = errors.New("user has already reviewed")
+	ErrNotOwner        = errors.New("only the owner of the review is able to perform this operation")
+	// ErrNotOneTime is returned when requesting changes of a review without an input
+	ErrNotOneTime = errors.New("changes can be requested only for one time reviews")
 )
 
 const (
 	ReviewTypeJit     = "jit"
 	ReviewTypeOneTime = "onetime"
+	// ReviewTypeBreakGlass is the review of an emergency access, it's acknowledged after the access is granted
+	ReviewTypeBreakGlass = "break_glass"
 )
 
 func (s *Service) FindOne(ctx pgrest.OrgContext, id string) (*types.Review, error) {
@@ -58,23 +69,35 @@ func (s *Service) Persist(ctx pgrest.OrgContext, review *types.Review) error {
 	}
 
 	parsedReview := &types.Review{
-		Id:               review.Id,
-		CreatedAt:        review.CreatedAt,
-		OrgId:            review.OrgId,
-		Type:             review.Type,
-		Session:          review.Session,
-		Connection:       review.Connection,
-		ConnectionId:     review.Connection.Id,
-		CreatedBy:        review.ReviewOwner.Id,
-		ReviewOwner:      review.ReviewOwner,
-		Input:            review.Input,
-		InputEnvVars:     review.InputEnvVars,
-		InputClientArgs:  review.InputClientArgs,
-		AccessDuration:   review.AccessDuration,
-		RevokeAt:         review.RevokeAt,
-		Status:           review.Status,
-		ReviewGroupsIds:  review.ReviewGroupsIds,
-		ReviewGroupsData: review.ReviewGroupsData,
+		Id:                review.Id,
+		CreatedAt:         review.CreatedAt,
+		OrgId:             review.OrgId,
+		Type:              review.Type,
+		Session:           review.Session,
+		Connection:        review.Connection,
+		ConnectionId:      review.Connection.Id,
+		CreatedBy:         review.ReviewOwner.Id,
+		ReviewOwner:       review.ReviewOwner,
+		Input:             review.Input,
+		InputEnvVars:      review.InputEnvVars,
+		InputClientArgs:   review.InputClientArgs,
+		AccessDuration:    review.AccessDuration,
+		RevokeAt:          review.RevokeAt,
+		Status:            review.Status,
+		ReviewGroupsIds:   review.ReviewGroupsIds,
+		ReviewGroupsData:  review.ReviewGroupsData,
+		DistinctApprovers: review.DistinctApprovers,
+		RuleName:          review.RuleName,
+		StatusReason:      review.StatusReason,
+		ExpireAt:          review.ExpireAt,
+		ReminderInterval:  review.ReminderInterval,
+		RemindedAt:        review.RemindedAt,
+		EscalateAt:        review.EscalateAt,
+		EscalationGroup:   review.EscalationGroup,
+		EscalatedAt:       review.EscalatedAt,
+		Revision:          review.Revision,
+		RevisedAt:         review.RevisedAt,
+		ExtendsReviewId:   review.ExtendsReviewId,
 	}
 
 	if err := pgreview.New().Upsert(parsedReview); err != nil {
@@ -83,47 +106,44 @@ func (s *Service) Persist(ctx pgrest.OrgContext, review *types.Review) error {
 	return nil
 }
 
-func (s *Service) RevokeBySid(ctx pgrest.OrgContext, sid string) (*types.Review, error) {
+// RevokeBySid revokes a jit grant using the session id
+func (s *Service) RevokeBySid(ctx *storagev2.Context, sid string) (*types.Review, error) {
 	rev, err := s.FindBySessionID(ctx, sid)
 	if err != nil {
 		return nil, err
 	}
-	// non-jit type reviews cannot be revoked
-	if rev == nil || rev.Type != ReviewTypeJit {
-		return nil, ErrNotFound
-	}
-	// only approved reviews could be revoked
-	if rev.Status != types.ReviewStatusApproved {
-		return nil, ErrWrongState
-	}
-	rev.Status = types.ReviewStatusRevoked
-
-	if err := s.Persist(ctx, rev); err != nil {
-		return nil, fmt.Errorf("saving review error: %v", err)
-	}
-
-	return rev, nil
+	return s.revoke(ctx, rev)
 }
 
-func (s *Service) Revoke(ctx pgrest.OrgContext, reviewID string) (*types.Review, error) {
+// Revoke ends a jit grant and the sessions opened with it.
+// Admins are able to revoke any grant, users are able to end their own access.
+func (s *Service) Revoke(ctx *storagev2.Context, reviewID string) (*types.Review, error) {
 	rev, err := s.FindOne(ctx, reviewID)
 	if err != nil {
 		return nil, err
 	}
-	// non-jit type reviews cannot be revoked
-	if rev == nil || rev.Type != ReviewTypeJit {
+	return s.revoke(ctx, rev)
+}
+
+func (s *Service) revoke(ctx *storagev2.Context, rev *types.Review) (*types.Review, error) {
+	// non-jit type reviews and extensions cannot be revoked, the grant must be revoked instead
+	if rev == nil || rev.Type != ReviewTypeJit || rev.ExtendsReviewId != "" {
 		return nil, ErrNotFound
 	}
+	if rev.ReviewOwner.Id != ctx.UserID && !ctx.IsAdmin() {
+		return nil, ErrNotOwner
+	}
 	// only approved reviews could be revoked
 	if rev.Status != types.ReviewStatusApproved {
 		return nil, ErrWrongState
 	}
 	rev.Status = types.ReviewStatusRevoked
+	rev.StatusReason = fmt.Sprintf("revoked by %s", ctx.UserEmail)
 
 	if err := s.Persist(ctx, rev); err != nil {
 		return nil, fmt.Errorf("saving review error: %v", err)
 	}
-
+	updateJitSessions(rev)
 	return rev, nil
 }
 
@@ -154,47 +174,22 @@ func (s *Service) Review(ctx *storagev2.Context, reviewID string, status types.R
 	if rev.ReviewOwner.Id == ctx.UserID && !ctx.IsAdmin() {
 		return nil, ErrSelfApproval
 	}
-
-	isEligibleReviewer := false
-	for _, r := range rev.ReviewGroupsData {
-		if pb.IsInList(r.Group, ctx.UserGroups) {
-			isEligibleReviewer = true
-			break
-		}
-	}
-	if !isEligibleReviewer {
-		return nil, ErrNotEligible
+	if err := validateApprovePermission(ctx, rev); err != nil {
+		return nil, err
 	}
 
-	reviewsCount := len(rev.ReviewGroupsData)
-	approvedCount := 0
-	var approvedGroups []string
-
-	if status == types.ReviewStatusRejected {
-		rev.Status = status
+	reviewer := types.ReviewOwner{Id: ctx.UserID, Name: ctx.UserName, Email: ctx.UserEmail}
+	if err := applyReview(rev, reviewer, ctx.UserGroups, status, time.Now()); err != nil {
+		return nil, err
 	}
-
-	for i, r := range rev.ReviewGroupsData {
-		if pb.IsInList(r.Group, ctx.UserGroups) {
-			t := time.Now().UTC().Format(time.RFC3339)
-			rev.ReviewGroupsData[i].Status = status
-			rev.ReviewGroupsData[i].ReviewedBy = &types.ReviewOwner{
-				Id:    ctx.UserID,
-				Name:  ctx.UserName,
-				Email: ctx.UserEmail,
-			}
-			rev.ReviewGroupsData[i].ReviewDate = &t
-		}
-		if rev.ReviewGroupsData[i].Status == types.ReviewStatusApproved {
-			approvedCount++
-		}
-
-		approvedGroups = append(approvedGroups, rev.ReviewGroupsData[i].Group)
+	if rev.ExtendsReviewId != "" {
+		return s.reviewExtension(ctx, rev)
 	}
-
-	if reviewsCount == approvedCount {
+	if rev.Type == ReviewTypeBreakGlass {
+		return s.acknowledgeBreakGlass(ctx, rev)
+	}
+	if rev.Status == types.ReviewStatusApproved {
 		rev.RevokeAt = func() *time.Time { t := time.Now().UTC().Add(rev.AccessDuration); return &t }()
-		rev.Status = types.ReviewStatusApproved
 	}
 
 	if err := s.Persist(ctx, rev); err != nil {
@@ -214,3 +209,27 @@ func (s *Service) Review(ctx *storagev2.Context, reviewID string, status types.R
 
 	return rev, nil
 }
+
+// validateApprovePermission checks if the custom roles of the reviewer
+// grant approving reviews of the connection
+func validateApprovePermission(ctx *storagev2.Context, rev *types.Review) error {
+	policy, err := rbac.Load(ctx.OrgID, ctx.UserGroups)
+	if err != nil {
+		return err
+	}
+	if !policy.Enabled() {
+		return nil
+	}
+	res := rbac.Resource{Name: rev.Connection.Name}
+	conn, err := models.GetConnectionByNameOrID(ctx.OrgID, rev.Connection.Name)
+	if err != nil {
+		return fmt.Errorf("fetch connection error: %v", err)
+	}
+	if conn != nil {
+		res = rbac.ResourceFromConnection(conn)
+	}
+	if !policy.Allowed(rbac.PermissionApproveReviews, res) {
+		return ErrNotEligible
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package runbookschedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser accepts the standard format with 5 fields (minute, hour, day of month, month, day of week)
+// and the descriptors, e.g.: @daily, @weekly, @every 1h
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// NextRun returns the first activation of the cron expression after the given time,
+// the expression is evaluated in the timezone (IANA name), an empty timezone means UTC.
+// The returned time is in UTC.
+func NextRun(cronExpr, timezone string, after time.Time) (time.Time, error) {
+	loc, err := loadLocation(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	sched, err := cronParser.Parse(cronExpr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cron expression %q: %v", cronExpr, err)
+	}
+	next := sched.Next(after.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q doesn't have a next activation", cronExpr)
+	}
+	return next.UTC(), nil
+}
+
+func loadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %v", timezone, err)
+	}
+	return loc, nil
+}
@@ -0,0 +1,62 @@
+package runbookschedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextRun(t *testing.T) {
+	// Monday
+	after := time.Date(2024, time.July, 22, 10, 30, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		msg      string
+		cronExpr string
+		timezone string
+		want     time.Time
+	}{
+		{
+			msg:      "it must return the next minute",
+			cronExpr: "* * * * *",
+			want:     time.Date(2024, time.July, 22, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			msg:      "it must return the next week when the time has passed",
+			cronExpr: "0 3 * * 1",
+			timezone: "UTC",
+			want:     time.Date(2024, time.July, 29, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			msg:      "it must evaluate the expression in the timezone",
+			cronExpr: "0 9 * * *",
+			timezone: "America/Sao_Paulo",
+			want:     time.Date(2024, time.July, 22, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			msg:      "it must accept descriptors",
+			cronExpr: "@daily",
+			want:     time.Date(2024, time.July, 23, 0, 0, 0, 0, time.UTC),
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			got, err := NextRun(tt.cronExpr, tt.timezone, after)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, time.UTC, got.Location())
+		})
+	}
+}
+
+func TestNextRunErrors(t *testing.T) {
+	now := time.Now().UTC()
+	_, err := NextRun("0 3 * *", "", now)
+	assert.ErrorContains(t, err, "invalid cron expression")
+	_, err = NextRun("0 0 3 * * *", "", now)
+	assert.ErrorContains(t, err, "invalid cron expression", "it must not accept the seconds field")
+	_, err = NextRun("0 3 * * *", "Mars/Olympus", now)
+	assert.ErrorContains(t, err, "invalid timezone")
+	_, err = NextRun("0 0 30 2 *", "", now)
+	assert.ErrorContains(t, err, "doesn't have a next activation")
+}
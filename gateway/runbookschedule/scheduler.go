@@ -0,0 +1,173 @@
+package runbookschedule
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/clientexec"
+	"github.com/hoophq/hoop/gateway/models"
+)
+
+type EventType string
+
+const EventRunFailed EventType = "run_failed"
+
+// maxRunErrorSize limits the output of failed executions stored in the run history
+const maxRunErrorSize = 4000
+
+var schedulerInterval = time.Minute
+
+// Event is the outcome of a scheduled execution that must be delivered to the organization
+type Event struct {
+	Type     EventType
+	Schedule *models.RunbookSchedule
+	Run      *models.RunbookScheduleRun
+}
+
+// Notifier delivers the events of scheduled executions, e.g.: slack and webhooks
+type Notifier interface {
+	OnRunbookScheduleEvent(ev Event)
+}
+
+// Executor runs the runbook of the schedule on behalf of its service account,
+// an error means the execution couldn't start
+type Executor func(s *models.RunbookSchedule) (*clientexec.Response, error)
+
+type (
+	Service struct {
+		Executor  Executor
+		Notifiers []Notifier
+		// Store persists the schedules and their runs, it defaults to the models package
+		Store store
+	}
+
+	store interface {
+		ListDueRunbookSchedules(now time.Time) ([]*models.RunbookSchedule, error)
+		ClaimRunbookSchedule(s *models.RunbookSchedule, nextRunAt, now time.Time) (bool, error)
+		CreateRunbookScheduleRun(run *models.RunbookScheduleRun) error
+		UpdateRunbookScheduleRun(run *models.RunbookScheduleRun) error
+	}
+
+	modelsStore struct{}
+)
+
+func (modelsStore) ListDueRunbookSchedules(now time.Time) ([]*models.RunbookSchedule, error) {
+	return models.ListDueRunbookSchedules(now)
+}
+
+func (modelsStore) ClaimRunbookSchedule(s *models.RunbookSchedule, nextRunAt, now time.Time) (bool, error) {
+	return models.ClaimRunbookSchedule(s, nextRunAt, now)
+}
+
+func (modelsStore) CreateRunbookScheduleRun(run *models.RunbookScheduleRun) error {
+	return models.CreateRunbookScheduleRun(run)
+}
+
+func (modelsStore) UpdateRunbookScheduleRun(run *models.RunbookScheduleRun) error {
+	return models.UpdateRunbookScheduleRun(run)
+}
+
+// InitScheduler executes the due runbook schedules in background
+func (s *Service) InitScheduler() {
+	if s.Store == nil {
+		s.Store = modelsStore{}
+	}
+	log.Infof("initializing runbook scheduler, interval=%v, notifiers=%v", schedulerInterval, len(s.Notifiers))
+	go func() {
+		for {
+			time.Sleep(schedulerInterval)
+			s.processDueSchedules(time.Now().UTC(), func(fn func()) { go fn() })
+		}
+	}()
+}
+
+// processDueSchedules claims the due schedules and starts their executions with the spawn function
+func (s *Service) processDueSchedules(now time.Time, spawn func(func())) {
+	items, err := s.Store.ListDueRunbookSchedules(now)
+	if err != nil {
+		log.Warnf("failed fetching due runbook schedules, reason=%v", err)
+		return
+	}
+	for _, sched := range items {
+		logger := log.With("org", sched.OrgID, "schedule", sched.Name)
+		nextRunAt, err := NextRun(sched.CronExpression, sched.Timezone, now)
+		if err != nil {
+			logger.Warnf("failed calculating next run, reason=%v", err)
+			continue
+		}
+		claimed, err := s.Store.ClaimRunbookSchedule(sched, nextRunAt, now)
+		if err != nil {
+			logger.Warnf("failed claiming runbook schedule, reason=%v", err)
+			continue
+		}
+		// another gateway instance is executing it or the schedule has changed
+		if !claimed {
+			continue
+		}
+		sched := sched
+		spawn(func() { s.execute(sched, now) })
+	}
+}
+
+func (s *Service) execute(sched *models.RunbookSchedule, startedAt time.Time) {
+	logger := log.With("org", sched.OrgID, "schedule", sched.Name)
+	run := &models.RunbookScheduleRun{
+		OrgID:       sched.OrgID,
+		ID:          uuid.NewString(),
+		ScheduleID:  sched.ID,
+		Status:      models.RunbookScheduleRunStatusRunning,
+		ScheduledAt: *sched.NextRunAt,
+		StartedAt:   startedAt,
+	}
+	if err := s.Store.CreateRunbookScheduleRun(run); err != nil {
+		logger.Errorf("failed creating runbook schedule run, reason=%v", err)
+		return
+	}
+	logger.Infof("executing scheduled runbook, run=%v, connection=%v, file=%v",
+		run.ID, sched.ConnectionName, sched.RunbookFile)
+	resp, err := s.Executor(sched)
+	setRunResult(run, resp, err)
+	logger.Infof("scheduled runbook finished, run=%v, status=%v", run.ID, run.Status)
+	if err := s.Store.UpdateRunbookScheduleRun(run); err != nil {
+		logger.Errorf("failed updating runbook schedule run, reason=%v", err)
+	}
+	if run.Status == models.RunbookScheduleRunStatusFailed {
+		s.notify(Event{Type: EventRunFailed, Schedule: sched, Run: run})
+	}
+}
+
+func (s *Service) notify(ev Event) {
+	for _, n := range s.Notifiers {
+		n.OnRunbookScheduleEvent(ev)
+	}
+}
+
+// setRunResult finishes the run with the response of the execution
+func setRunResult(run *models.RunbookScheduleRun, resp *clientexec.Response, execErr error) {
+	finishedAt := time.Now().UTC()
+	run.FinishedAt = &finishedAt
+	run.Status = models.RunbookScheduleRunStatusFailed
+	if execErr != nil {
+		errMsg := execErr.Error()
+		run.Error = &errMsg
+		return
+	}
+	run.SessionID = &resp.SessionID
+	if resp.ExitCode >= 0 {
+		exitCode := resp.ExitCode
+		run.ExitCode = &exitCode
+	}
+	switch {
+	case resp.HasReview:
+		run.Status = models.RunbookScheduleRunStatusWaitingReview
+	case resp.OutputStatus == "success":
+		run.Status = models.RunbookScheduleRunStatusSuccess
+	default:
+		errMsg := resp.Output
+		if len(errMsg) > maxRunErrorSize {
+			errMsg = errMsg[:maxRunErrorSize]
+		}
+		run.Error = &errMsg
+	}
+}
@@ -0,0 +1,166 @@
+package runbookschedule
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hoophq/hoop/gateway/clientexec"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNotifier struct{ events []Event }
+
+func (n *fakeNotifier) OnRunbookScheduleEvent(ev Event) { n.events = append(n.events, ev) }
+
+// fakeStore is an in-memory store of schedules and runs
+type fakeStore struct {
+	due     []*models.RunbookSchedule
+	claimed map[string]time.Time
+	runs    map[string]*models.RunbookScheduleRun
+}
+
+func newFakeStore(due ...*models.RunbookSchedule) *fakeStore {
+	return &fakeStore{due: due, claimed: map[string]time.Time{}, runs: map[string]*models.RunbookScheduleRun{}}
+}
+
+func (s *fakeStore) ListDueRunbookSchedules(time.Time) ([]*models.RunbookSchedule, error) {
+	return s.due, nil
+}
+
+func (s *fakeStore) ClaimRunbookSchedule(sched *models.RunbookSchedule, nextRunAt, _ time.Time) (bool, error) {
+	// simulates another gateway instance claiming the schedule
+	if sched.Name == "claimed-by-other" {
+		return false, nil
+	}
+	s.claimed[sched.Name] = nextRunAt
+	return true, nil
+}
+
+func (s *fakeStore) CreateRunbookScheduleRun(run *models.RunbookScheduleRun) error {
+	r := *run
+	s.runs[run.ID] = &r
+	return nil
+}
+
+func (s *fakeStore) UpdateRunbookScheduleRun(run *models.RunbookScheduleRun) error {
+	r := *run
+	s.runs[run.ID] = &r
+	return nil
+}
+
+func newDueSchedule(name, cronExpr string, nextRunAt time.Time) *models.RunbookSchedule {
+	return &models.RunbookSchedule{
+		OrgID:          "org",
+		ID:             name + "-id",
+		Name:           name,
+		ConnectionName: "pgdemo",
+		RunbookFile:    "ops/vacuum.runbook.sql",
+		CronExpression: cronExpr,
+		Timezone:       "UTC",
+		Enabled:        true,
+		NextRunAt:      &nextRunAt,
+	}
+}
+
+func TestProcessDueSchedules(t *testing.T) {
+	now := time.Date(2024, time.July, 22, 3, 0, 10, 0, time.UTC)
+	scheduledAt := time.Date(2024, time.July, 22, 3, 0, 0, 0, time.UTC)
+	store := newFakeStore(
+		newDueSchedule("weekly", "0 3 * * 1", scheduledAt),
+		newDueSchedule("claimed-by-other", "0 3 * * 1", scheduledAt),
+		newDueSchedule("invalid", "0 3 * *", scheduledAt),
+	)
+	var executed []string
+	svc := &Service{Store: store, Executor: func(s *models.RunbookSchedule) (*clientexec.Response, error) {
+		executed = append(executed, s.Name)
+		return &clientexec.Response{SessionID: "sid", OutputStatus: "success", ExitCode: 0}, nil
+	}}
+	svc.processDueSchedules(now, func(fn func()) { fn() })
+
+	assert.Equal(t, []string{"weekly"}, executed)
+	assert.Equal(t, map[string]time.Time{"weekly": time.Date(2024, time.July, 29, 3, 0, 0, 0, time.UTC)}, store.claimed)
+	require.Len(t, store.runs, 1)
+	for _, run := range store.runs {
+		assert.Equal(t, models.RunbookScheduleRunStatusSuccess, run.Status)
+		assert.Equal(t, "weekly-id", run.ScheduleID)
+		assert.Equal(t, scheduledAt, run.ScheduledAt)
+		assert.Equal(t, now, run.StartedAt)
+		assert.Equal(t, "sid", *run.SessionID)
+		assert.Equal(t, 0, *run.ExitCode)
+		assert.NotNil(t, run.FinishedAt)
+		assert.Nil(t, run.Error)
+	}
+}
+
+func TestExecuteNotifiesFailures(t *testing.T) {
+	now := time.Now().UTC()
+	for _, tt := range []struct {
+		msg        string
+		resp       *clientexec.Response
+		err        error
+		wantStatus string
+		wantError  string
+		wantNotify bool
+	}{
+		{
+			msg:        "it must notify when the runbook fails",
+			resp:       &clientexec.Response{SessionID: "sid", Output: "relation does not exist", OutputStatus: "failed", ExitCode: 1},
+			wantStatus: models.RunbookScheduleRunStatusFailed,
+			wantError:  "relation does not exist",
+			wantNotify: true,
+		},
+		{
+			msg:        "it must notify when the execution doesn't start",
+			err:        fmt.Errorf("connection pgdemo not found"),
+			wantStatus: models.RunbookScheduleRunStatusFailed,
+			wantError:  "connection pgdemo not found",
+			wantNotify: true,
+		},
+		{
+			msg:        "it must not notify when the execution waits for a review",
+			resp:       &clientexec.Response{SessionID: "sid", HasReview: true, Output: "http://localhost/reviews/1", ExitCode: -2},
+			wantStatus: models.RunbookScheduleRunStatusWaitingReview,
+		},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			store := newFakeStore()
+			notifier := &fakeNotifier{}
+			svc := &Service{
+				Store:     store,
+				Notifiers: []Notifier{notifier},
+				Executor: func(*models.RunbookSchedule) (*clientexec.Response, error) {
+					return tt.resp, tt.err
+				},
+			}
+			sched := newDueSchedule("weekly", "0 3 * * 1", now)
+			svc.execute(sched, now)
+
+			require.Len(t, store.runs, 1)
+			var run *models.RunbookScheduleRun
+			for _, r := range store.runs {
+				run = r
+			}
+			assert.Equal(t, tt.wantStatus, run.Status)
+			if tt.wantError != "" {
+				require.NotNil(t, run.Error)
+				assert.Equal(t, tt.wantError, *run.Error)
+			} else {
+				assert.Nil(t, run.Error)
+			}
+			if tt.resp == nil || tt.resp.ExitCode < 0 {
+				assert.Nil(t, run.ExitCode)
+			}
+			if !tt.wantNotify {
+				assert.Empty(t, notifier.events)
+				return
+			}
+			require.Len(t, notifier.events, 1)
+			assert.Equal(t, EventRunFailed, notifier.events[0].Type)
+			assert.Equal(t, sched, notifier.events[0].Schedule)
+			assert.Equal(t, run.ID, notifier.events[0].Run.ID)
+		})
+	}
+}
@@ -37,6 +37,9 @@ type (
 		authWithUserInfo      bool
 		mustFetchGsuiteGroups bool
 
+		// SAML is set when the gateway authenticates users with a SAML 2.0 identity provider
+		SAML *SAMLProvider
+
 		*oidc.Provider
 		oauth2.Config
 		*oidc.IDTokenVerifier
@@ -62,6 +65,14 @@ func NewProvider(apiURL, secretKey string) *Provider {
 	if appconfig.Get().AuthMethod() == "local" {
 		return &Provider{Context: context.Background(), ApiURL: apiURL, SecretKey: secretKey}
 	}
+	// SAML users authenticate with access tokens issued by the gateway
+	if appconfig.Get().AuthMethod() == "saml" {
+		samlProvider, err := newSAMLProviderFromEnvs(apiURL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return &Provider{Context: context.Background(), ApiURL: apiURL, SecretKey: secretKey, SAML: samlProvider}
+	}
 	ctx := context.Background()
 	provider := &Provider{
 		Context: ctx,
@@ -251,6 +262,21 @@ func (p *Provider) VerifyAccessTokenHS256Alg(accessToken string) (string, error)
 	return "", fmt.Errorf("failed type casting token.Claims (%T) to jwt.MapClaims", token.Claims)
 }
 
+// NewAccessTokenHS256Alg issues an access token signed with the symmetric secret (HS256)
+func (p *Provider) NewAccessTokenHS256Alg(subject, email string) (string, error) {
+	if p.SecretKey == "" {
+		return "", fmt.Errorf("jwt secret token is not set")
+	}
+	now := time.Now().UTC()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   subject,
+		"email": email,
+		"iat":   jwt.NewNumericDate(now),
+		"exp":   jwt.NewNumericDate(now.Add(168 * time.Hour)),
+	})
+	return token.SignedString([]byte(p.SecretKey))
+}
+
 // VerifyAccessToken validate the access token against the user info endpoint (OIDC) if it's an opaque token.
 // Otherwise validate the JWT token following RFC9068 standard.
 //
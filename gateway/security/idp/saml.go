@@ -0,0 +1,243 @@
+package idp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/hoophq/hoop/common/log"
+)
+
+const (
+	samlDefaultEmailAttribute  = "email"
+	samlDefaultNameAttribute   = "name"
+	samlDefaultGroupsAttribute = "groups"
+)
+
+// SAMLProvider authenticates users with a SAML 2.0 identity provider.
+// The assertions must be signed by the certificate present in the identity provider metadata.
+type SAMLProvider struct {
+	EmailAttribute  string
+	NameAttribute   string
+	GroupsAttribute string
+
+	sp saml.ServiceProvider
+	// the id of the assertions accepted until they expire, replayed responses are rejected
+	seenAssertions map[string]time.Time
+	mu             sync.Mutex
+}
+
+func newSAMLProviderFromEnvs(apiURL string) (*SAMLProvider, error) {
+	metadataURL := os.Getenv("SAML_IDP_METADATA_URL")
+	if metadataURL == "" {
+		return nil, fmt.Errorf("missing SAML_IDP_METADATA_URL env")
+	}
+	ctx, cancelFn := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancelFn()
+	idpMetadata, err := fetchSAMLMetadata(ctx, metadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading saml identity provider metadata, reason=%v", err)
+	}
+	p, err := NewSAMLProvider(apiURL, idpMetadata)
+	if err != nil {
+		return nil, err
+	}
+	if attr := os.Getenv("SAML_EMAIL_ATTRIBUTE"); attr != "" {
+		p.EmailAttribute = attr
+	}
+	if attr := os.Getenv("SAML_NAME_ATTRIBUTE"); attr != "" {
+		p.NameAttribute = attr
+	}
+	if attr := os.Getenv("SAML_GROUPS_ATTRIBUTE"); attr != "" {
+		p.GroupsAttribute = attr
+	}
+	log.Infof("loaded saml provider configuration, entity-id=%v, metadata-url=%v, acs-url=%v, sso-url=%v, email-attribute=%v, groups-attribute=%v",
+		idpMetadata.EntityID, p.sp.MetadataURL.String(), p.sp.AcsURL.String(),
+		p.sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), p.EmailAttribute, p.GroupsAttribute)
+	return p, nil
+}
+
+// NewSAMLProvider creates a service provider for the identity provider metadata,
+// the gateway exposes the service provider metadata and the assertion consumer service in the api url
+func NewSAMLProvider(apiURL string, idpMetadata *saml.EntityDescriptor) (*SAMLProvider, error) {
+	metadataURL, err := url.Parse(apiURL + "/api/saml/metadata")
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing saml metadata url, reason=%v", err)
+	}
+	acsURL, err := url.Parse(apiURL + "/api/saml/acs")
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing saml acs url, reason=%v", err)
+	}
+	p := &SAMLProvider{
+		EmailAttribute:  samlDefaultEmailAttribute,
+		NameAttribute:   samlDefaultNameAttribute,
+		GroupsAttribute: samlDefaultGroupsAttribute,
+		sp: saml.ServiceProvider{
+			MetadataURL:       *metadataURL,
+			AcsURL:            *acsURL,
+			IDPMetadata:       idpMetadata,
+			AuthnNameIDFormat: saml.UnspecifiedNameIDFormat,
+		},
+		seenAssertions: map[string]time.Time{},
+	}
+	if p.sp.GetSSOBindingLocation(saml.HTTPRedirectBinding) == "" {
+		return nil, fmt.Errorf("saml identity provider metadata does not contain a single sign on service with the HTTP-Redirect binding")
+	}
+	return p, nil
+}
+
+// Metadata returns the service provider metadata document
+func (p *SAMLProvider) Metadata() ([]byte, error) {
+	return xml.MarshalIndent(p.sp.Metadata(), "", "  ")
+}
+
+// AuthnRequestURL returns the url to start the signin on the identity provider,
+// the state is sent as the relay state and it's part of the request id.
+func (p *SAMLProvider) AuthnRequestURL(state string) (string, error) {
+	req, err := p.sp.MakeAuthenticationRequest(
+		p.sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", err
+	}
+	req.ID = samlRequestID(state)
+	u, err := req.Redirect(url.QueryEscape(state), &p.sp)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// ParseResponse validates the signature and the conditions of the base64 encoded SAML response
+// issued for the request of the state. It returns the user information mapped from the assertion attributes.
+func (p *SAMLProvider) ParseResponse(samlResponse, state string) (*ProviderUserInfo, error) {
+	rawResponse, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding saml response, reason=%v", err)
+	}
+	assertion, err := p.sp.ParseXMLResponse(rawResponse, []string{samlRequestID(state)})
+	if err != nil {
+		if ire, ok := err.(*saml.InvalidResponseError); ok && ire.PrivateErr != nil {
+			err = ire.PrivateErr
+		}
+		return nil, fmt.Errorf("failed validating saml response, reason=%v", err)
+	}
+	if !p.markAssertionSeen(assertion, time.Now().UTC()) {
+		return nil, fmt.Errorf("failed validating saml response, reason=the assertion %q was already used", assertion.ID)
+	}
+	return p.parseAssertion(assertion)
+}
+
+// markAssertionSeen records the id of a valid assertion until it expires,
+// it returns false when the assertion was already used.
+func (p *SAMLProvider) markAssertionSeen(assertion *saml.Assertion, now time.Time) bool {
+	if assertion.ID == "" {
+		return false
+	}
+	expireAt := now.Add(saml.MaxIssueDelay)
+	if c := assertion.Conditions; c != nil && c.NotOnOrAfter.After(expireAt) {
+		expireAt = c.NotOnOrAfter
+	}
+	if s := assertion.Subject; s != nil {
+		for _, sc := range s.SubjectConfirmations {
+			if d := sc.SubjectConfirmationData; d != nil && d.NotOnOrAfter.After(expireAt) {
+				expireAt = d.NotOnOrAfter
+			}
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, seenExpireAt := range p.seenAssertions {
+		if now.After(seenExpireAt) {
+			delete(p.seenAssertions, id)
+		}
+	}
+	if _, ok := p.seenAssertions[assertion.ID]; ok {
+		return false
+	}
+	// the conditions of the assertion are validated tolerating clock drifts
+	p.seenAssertions[assertion.ID] = expireAt.Add(saml.MaxClockSkew)
+	return true
+}
+
+func (p *SAMLProvider) parseAssertion(assertion *saml.Assertion) (*ProviderUserInfo, error) {
+	var nameID *saml.NameID
+	if assertion.Subject != nil {
+		nameID = assertion.Subject.NameID
+	}
+	var uinfo ProviderUserInfo
+	attributes := map[string][]string{}
+	for _, stmt := range assertion.AttributeStatements {
+		for _, attr := range stmt.Attributes {
+			for _, val := range attr.Values {
+				attributes[attr.Name] = append(attributes[attr.Name], val.Value)
+				if attr.FriendlyName != "" && attr.FriendlyName != attr.Name {
+					attributes[attr.FriendlyName] = append(attributes[attr.FriendlyName], val.Value)
+				}
+			}
+		}
+	}
+	if emails := attributes[p.EmailAttribute]; len(emails) > 0 {
+		uinfo.Email = emails[0]
+	} else if nameID != nil && strings.Contains(nameID.Value, "@") {
+		uinfo.Email = nameID.Value
+	}
+	uinfo.Email = strings.ToLower(uinfo.Email)
+	if uinfo.Email == "" {
+		return nil, fmt.Errorf("saml assertion does not contain the %q attribute or an email name id", p.EmailAttribute)
+	}
+	if names := attributes[p.NameAttribute]; len(names) > 0 {
+		uinfo.Profile = names[0]
+	}
+	if groups, ok := attributes[p.GroupsAttribute]; ok {
+		uinfo.MustSyncGroups = true
+		for _, g := range groups {
+			if g != "" {
+				uinfo.Groups = append(uinfo.Groups, g)
+			}
+		}
+	}
+	// transient name ids change on every signin, the email is used as the stable identifier
+	uinfo.Subject = uinfo.Email
+	if nameID != nil && nameID.Value != "" && nameID.Format != string(saml.TransientNameIDFormat) {
+		uinfo.Subject = nameID.Value
+	}
+	emailVerified := true
+	uinfo.EmailVerified = &emailVerified
+	return &uinfo, nil
+}
+
+// samlRequestID must be a valid xml identifier, it can't start with a digit
+func samlRequestID(state string) string { return "id-" + state }
+
+func fetchSAMLMetadata(ctx context.Context, metadataURL string) (*saml.EntityDescriptor, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", metadataURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	var metadata saml.EntityDescriptor
+	if err := xml.Unmarshal(body, &metadata); err != nil {
+		return nil, fmt.Errorf("failed decoding metadata: %v", err)
+	}
+	return &metadata, nil
+}
@@ -0,0 +1,162 @@
+package idp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/crewjam/saml"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIdentityProvider(t *testing.T) *saml.IdentityProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.acme.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	metadataURL, _ := url.Parse("https://idp.acme.com/metadata")
+	ssoURL, _ := url.Parse("https://idp.acme.com/sso")
+	return &saml.IdentityProvider{
+		Key:         key,
+		Certificate: cert,
+		MetadataURL: *metadataURL,
+		SSOURL:      *ssoURL,
+	}
+}
+
+// newTestResponse issues a signed response of the identity provider for the request of the state
+func newTestResponse(t *testing.T, idp *saml.IdentityProvider, sp *SAMLProvider, state string, session *saml.Session) string {
+	spMetadata := sp.sp.Metadata()
+	req := &saml.IdpAuthnRequest{
+		IDP:                     idp,
+		HTTPRequest:             httptest.NewRequest("POST", "/sso", nil),
+		Request:                 saml.AuthnRequest{ID: samlRequestID(state), IssueInstant: time.Now()},
+		ServiceProviderMetadata: spMetadata,
+		SPSSODescriptor:         &spMetadata.SPSSODescriptors[0],
+		ACSEndpoint:             &spMetadata.SPSSODescriptors[0].AssertionConsumerServices[0],
+		Now:                     time.Now(),
+	}
+	require.NoError(t, saml.DefaultAssertionMaker{}.MakeAssertion(req, session))
+	require.NoError(t, req.MakeResponse())
+	doc := etree.NewDocument()
+	doc.SetRoot(req.ResponseEl)
+	data, err := doc.WriteToBytes()
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func newTestSession() *saml.Session {
+	return &saml.Session{
+		NameID:       "john@acme.com",
+		NameIDFormat: string(saml.EmailAddressNameIDFormat),
+		CustomAttributes: []saml.Attribute{
+			{Name: "email", Values: []saml.AttributeValue{{Type: "xs:string", Value: "John@acme.com"}}},
+			{Name: "name", Values: []saml.AttributeValue{{Type: "xs:string", Value: "John Doe"}}},
+			{Name: "groups", Values: []saml.AttributeValue{
+				{Type: "xs:string", Value: "sre"},
+				{Type: "xs:string", Value: "dba"},
+			}},
+		},
+	}
+}
+
+func TestSAMLParseResponse(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	sp, err := NewSAMLProvider("https://gateway.acme.com", idp.Metadata())
+	require.NoError(t, err)
+	state := uuid.NewString()
+
+	t.Run("it should map the attributes of a signed assertion", func(t *testing.T) {
+		samlResponse := newTestResponse(t, idp, sp, state, newTestSession())
+		uinfo, err := sp.ParseResponse(samlResponse, state)
+		require.NoError(t, err)
+		assert.Equal(t, "john@acme.com", uinfo.Subject)
+		assert.Equal(t, "john@acme.com", uinfo.Email)
+		assert.Equal(t, "John Doe", uinfo.Profile)
+		assert.Equal(t, []string{"sre", "dba"}, uinfo.Groups)
+		assert.True(t, uinfo.MustSyncGroups)
+	})
+
+	t.Run("it should fail when the response is replayed", func(t *testing.T) {
+		samlResponse := newTestResponse(t, idp, sp, state, newTestSession())
+		_, err := sp.ParseResponse(samlResponse, state)
+		require.NoError(t, err)
+		_, err = sp.ParseResponse(samlResponse, state)
+		assert.ErrorContains(t, err, "was already used")
+	})
+
+	t.Run("it should map custom attributes", func(t *testing.T) {
+		customSP, err := NewSAMLProvider("https://gateway.acme.com", idp.Metadata())
+		require.NoError(t, err)
+		customSP.EmailAttribute = "mail"
+		customSP.GroupsAttribute = "memberOf"
+		session := newTestSession()
+		session.NameIDFormat = string(saml.TransientNameIDFormat)
+		session.NameID = "_a8e2f1"
+		session.CustomAttributes = []saml.Attribute{
+			{Name: "mail", Values: []saml.AttributeValue{{Type: "xs:string", Value: "jane@acme.com"}}},
+		}
+		uinfo, err := customSP.ParseResponse(newTestResponse(t, idp, customSP, state, session), state)
+		require.NoError(t, err)
+		assert.Equal(t, "jane@acme.com", uinfo.Subject)
+		assert.Equal(t, "jane@acme.com", uinfo.Email)
+		assert.Nil(t, uinfo.Groups)
+		assert.False(t, uinfo.MustSyncGroups)
+	})
+
+	t.Run("it should fail when the assertion is signed by another identity provider", func(t *testing.T) {
+		otherIdp := newTestIdentityProvider(t)
+		samlResponse := newTestResponse(t, otherIdp, sp, state, newTestSession())
+		_, err := sp.ParseResponse(samlResponse, state)
+		assert.Error(t, err)
+	})
+
+	t.Run("it should fail when the assertion is tampered", func(t *testing.T) {
+		samlResponse := newTestResponse(t, idp, sp, state, newTestSession())
+		data, err := base64.StdEncoding.DecodeString(samlResponse)
+		require.NoError(t, err)
+		tampered := strings.ReplaceAll(string(data), ">sre<", ">admin<")
+		assert.NotEqual(t, string(data), tampered)
+		_, err = sp.ParseResponse(base64.StdEncoding.EncodeToString([]byte(tampered)), state)
+		assert.Error(t, err)
+	})
+
+	t.Run("it should fail when the response is issued to another request", func(t *testing.T) {
+		samlResponse := newTestResponse(t, idp, sp, state, newTestSession())
+		_, err := sp.ParseResponse(samlResponse, uuid.NewString())
+		assert.Error(t, err)
+	})
+}
+
+func TestSAMLAuthnRequestURL(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	sp, err := NewSAMLProvider("https://gateway.acme.com", idp.Metadata())
+	require.NoError(t, err)
+	state := uuid.NewString()
+	authURL, err := sp.AuthnRequestURL(state)
+	require.NoError(t, err)
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "idp.acme.com", u.Host)
+	assert.Equal(t, state, u.Query().Get("RelayState"))
+	assert.NotEmpty(t, u.Query().Get("SAMLRequest"))
+}
@@ -1,12 +1,15 @@
 package slack
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hoophq/hoop/common/log"
 	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
 	"github.com/slack-go/slack/socketmode"
 )
 
@@ -33,6 +36,8 @@ func (s *SlackService) processEvents(respCh chan *MessageReviewResponse) {
 			s.processInteractive(respCh, evt)
 		case socketmode.EventTypeSlashCommand:
 			s.processSlashCommandRequest(evt)
+		case socketmode.EventTypeEventsAPI:
+			s.processEventsAPI(respCh, evt)
 		case socketmode.EventTypeHello:
 			log.Info("socket live, received ping from slack")
 		case socketmode.EventTypeIncomingError:
@@ -56,28 +61,53 @@ func (s *SlackService) processInteractive(respCh chan *MessageReviewResponse, ev
 	switch cb.Type {
 	case slack.InteractionTypeBlockActions:
 		// See https://api.slack.com/apis/connections/socket-implement#button
+		revision, _ := strconv.Atoi(fmt.Sprintf("%v", cb.Message.Metadata.EventPayload[revisionMetadataKey]))
 		reviewResponse := MessageReviewResponse{
 			EventKind: fmt.Sprintf("%v", cb.Message.Metadata.EventType),
 			ID:        fmt.Sprintf("%v", cb.Message.Metadata.EventPayload[reviewIDMetadataKey]),
 			SessionID: fmt.Sprintf("%v", cb.Message.Metadata.EventPayload[sessionIDMetadataKey]),
-			Status:    "rejected",
+			Status:    StatusRejected,
 			SlackID:   cb.User.ID,
+			Revision:  revision,
 			item:      cb,
 		}
-		if cb.ActionCallback.BlockActions[0].ActionID == "review-approved" {
-			reviewResponse.Status = "approved"
-		}
 		// reviewUID:GroupName:IndexNo
 		blockID := cb.ActionCallback.BlockActions[0].BlockID
 		if parts := strings.Split(blockID, ":"); len(parts) == 3 {
 			reviewResponse.GroupName = parts[1]
 		}
-		select {
-		case respCh <- &reviewResponse:
-		case <-time.After(time.Second * 2):
-			log.Warnf("timeout (2s) on sending review response, id=%v, status=%v",
-				reviewResponse.ID, reviewResponse.Status)
+		switch cb.ActionCallback.BlockActions[0].ActionID {
+		case "review-approved":
+			reviewResponse.Status = StatusApproved
+		case changesRequestedCallbackID:
+			// the trigger of the modal expires in a few seconds, it must be opened right away
+			if err := s.OpenModalChangesRequest(&reviewResponse); err != nil {
+				log.Warnf("failed opening changes request modal, id=%v, reason=%v", reviewResponse.ID, err)
+			}
+			s.socketClient.Ack(*ev.Request, nil)
+			return
+		}
+		sendReviewResponse(respCh, &reviewResponse)
+	case slack.InteractionTypeViewSubmission:
+		if cb.View.CallbackID != changesRequestedCallbackID {
+			break
 		}
+		var metadata changesRequestedMetadata
+		if err := json.Unmarshal([]byte(cb.View.PrivateMetadata), &metadata); err != nil {
+			log.Warnf("failed decoding changes request metadata, reason=%v", err)
+			break
+		}
+		sendReviewResponse(respCh, &MessageReviewResponse{
+			ID:        metadata.ID,
+			EventKind: metadata.EventKind,
+			SessionID: metadata.SessionID,
+			GroupName: metadata.GroupName,
+			Revision:  metadata.Revision,
+			Status:    StatusChangesRequested,
+			SlackID:   cb.User.ID,
+			Comment:   cb.View.State.Values[feedbackBlockID][feedbackBlockID].Value,
+			item:      cb,
+		})
 	default:
 	}
 	log.Info("sending ack back to slack!")
@@ -85,6 +115,36 @@ func (s *SlackService) processInteractive(respCh chan *MessageReviewResponse, ev
 	s.socketClient.Ack(*ev.Request, ack)
 }
 
+// processEventsAPI sends the replies of users in threads as comments of the review
+func (s *SlackService) processEventsAPI(respCh chan *MessageReviewResponse, ev socketmode.Event) {
+	s.socketClient.Ack(*ev.Request)
+	eventsAPIEvent, ok := ev.Data.(slackevents.EventsAPIEvent)
+	if !ok {
+		log.Debugf("ignored %+v\n", ev)
+		return
+	}
+	msg, ok := eventsAPIEvent.InnerEvent.Data.(*slackevents.MessageEvent)
+	// ignore messages from bots and changes of messages, e.g.: edits and deletions
+	if !ok || msg.ThreadTimeStamp == "" || msg.ThreadTimeStamp == msg.TimeStamp ||
+		msg.BotID != "" || msg.SubType != "" || msg.Text == "" {
+		return
+	}
+	sendReviewResponse(respCh, &MessageReviewResponse{
+		Status:  StatusCommented,
+		SlackID: msg.User,
+		Comment: msg.Text,
+		Thread:  &MessageThread{Channel: msg.Channel, Timestamp: msg.ThreadTimeStamp},
+	})
+}
+
+func sendReviewResponse(respCh chan *MessageReviewResponse, resp *MessageReviewResponse) {
+	select {
+	case respCh <- resp:
+	case <-time.After(time.Second * 2):
+		log.Warnf("timeout (2s) on sending review response, id=%v, status=%v", resp.ID, resp.Status)
+	}
+}
+
 func (s *SlackService) processSlashCommandRequest(ev socketmode.Event) {
 	cmd, ok := ev.Data.(slack.SlashCommand)
 	if !ok {
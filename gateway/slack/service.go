@@ -2,6 +2,7 @@ package slack
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"slices"
 	"strconv"
@@ -34,8 +35,18 @@ type SlackService struct {
 const (
 	reviewIDMetadataKey  = "review_id"
 	sessionIDMetadataKey = "session_id"
+	revisionMetadataKey  = "revision"
 	EventKindOneTime     = "onetime"
 	EventKindJit         = "jit"
+
+	StatusApproved         = "approved"
+	StatusRejected         = "rejected"
+	StatusChangesRequested = "changes_requested"
+	// StatusCommented is the status of replies in the thread of review messages
+	StatusCommented = "commented"
+
+	changesRequestedCallbackID = "review-changes-requested"
+	feedbackBlockID            = "feedback"
 	// it's usually 2000, keep a more safe number
 	maxLabelSize  = 1800
 	maxGroupsSize = 50
@@ -80,6 +91,8 @@ type MessageReviewRequest struct {
 	Email          string
 	UserGroups     []string
 	ApprovalGroups []string
+	// GroupDetails are displayed next to the name of each approval group
+	GroupDetails   map[string]string
 	Connection     string
 	ConnectionType string
 	Script         string
@@ -87,6 +100,10 @@ type MessageReviewRequest struct {
 	WebappURL      string
 	SessionID      string
 	SlackChannels  []string
+	// Revision of the review input, buttons of previous revisions are outdated
+	Revision int
+	// Title of the message, defaults to Review
+	Title string
 }
 
 type MessageReviewResponse struct {
@@ -96,10 +113,30 @@ type MessageReviewResponse struct {
 	SessionID string
 	SlackID   string
 	GroupName string
+	Revision  int
+	// Comment is the feedback of a change request or the text of a thread reply
+	Comment string
+	// Thread is the review message of a reply, the ID of the review is unknown in this case
+	Thread *MessageThread
 
 	item slack.InteractionCallback
 }
 
+// MessageThread is a review message posted in a channel
+type MessageThread struct {
+	Channel   string
+	Timestamp string
+}
+
+// changesRequestedMetadata is kept in the modal requesting changes
+type changesRequestedMetadata struct {
+	ID        string `json:"id"`
+	EventKind string `json:"event_kind"`
+	SessionID string `json:"session_id"`
+	GroupName string `json:"group_name"`
+	Revision  int    `json:"revision"`
+}
+
 func (m *MessageReviewRequest) sessionTime() string {
 	if m.SessionTime != nil {
 		minutes := m.SessionTime.Minutes()
@@ -113,8 +150,12 @@ func (m *MessageReviewRequest) sessionTime() string {
 	return "-"
 }
 
-func (s *SlackService) SendMessageReview(msg *MessageReviewRequest) (result string) {
+// SendMessageReview posts the review to the channels and returns the posted messages
+func (s *SlackService) SendMessageReview(msg *MessageReviewRequest) (threads []MessageThread, result string) {
 	title := "Review"
+	if msg.Title != "" {
+		title = msg.Title
+	}
 
 	header := slack.NewHeaderBlock(&slack.TextBlockObject{
 		Type: slack.PlainTextType,
@@ -181,21 +222,30 @@ func (s *SlackService) SendMessageReview(msg *MessageReviewRequest) (result stri
 	for i, groupName := range msg.ApprovalGroups {
 		key := fmt.Sprintf("%s:%s", msg.ID, groupName)
 		blockID := fmt.Sprintf("%s:%s", key, strconv.Itoa(i))
+		groupText := fmt.Sprintf("group *%s*", groupName)
+		if details := msg.GroupDetails[groupName]; details != "" {
+			groupText = fmt.Sprintf("%s • _%s_", groupText, details)
+		}
 
+		buttons := []slack.BlockElement{
+			slack.NewButtonBlockElement("review-approved", key,
+				&slack.TextBlockObject{Type: slack.PlainTextType, Text: "Approve"}).
+				WithStyle(slack.StylePrimary),
+			slack.NewButtonBlockElement("review-rejected", key,
+				&slack.TextBlockObject{Type: slack.PlainTextType, Text: "Reject"}).
+				WithStyle(slack.StyleDanger),
+		}
+		// changes are requested on the input, it's not available for jit reviews
+		if msg.SessionTime == nil {
+			buttons = append(buttons, slack.NewButtonBlockElement(changesRequestedCallbackID, key,
+				&slack.TextBlockObject{Type: slack.PlainTextType, Text: "Request changes"}))
+		}
 		blocks = append(blocks,
 			slack.NewSectionBlock(&slack.TextBlockObject{
 				Type: slack.MarkdownType,
-				Text: fmt.Sprintf("group *%s*", groupName),
+				Text: groupText,
 			}, nil, nil),
-			slack.NewActionBlock(
-				blockID,
-				slack.NewButtonBlockElement("review-approved", key,
-					&slack.TextBlockObject{Type: slack.PlainTextType, Text: "Approve"}).
-					WithStyle(slack.StylePrimary),
-				slack.NewButtonBlockElement("review-rejected", key,
-					&slack.TextBlockObject{Type: slack.PlainTextType, Text: "Reject"}).
-					WithStyle(slack.StyleDanger),
-			),
+			slack.NewActionBlock(blockID, buttons...),
 		)
 	}
 
@@ -208,6 +258,7 @@ func (s *SlackService) SendMessageReview(msg *MessageReviewRequest) (result stri
 		EventPayload: map[string]any{
 			reviewIDMetadataKey:  msg.ID,
 			sessionIDMetadataKey: msg.SessionID,
+			revisionMetadataKey:  strconv.Itoa(msg.Revision),
 		},
 	})
 
@@ -218,15 +269,17 @@ func (s *SlackService) SendMessageReview(msg *MessageReviewRequest) (result stri
 
 	var errs []string
 	for _, slackChannel := range slackChannels {
-		_, _, err := s.apiClient.PostMessage(slackChannel, slack.MsgOptionBlocks(blocks...), metadata)
+		channelID, ts, err := s.apiClient.PostMessage(slackChannel, slack.MsgOptionBlocks(blocks...), metadata)
 		if err != nil {
 			errs = append(errs, fmt.Sprintf(`"%v - %v"`, slackChannel, err))
+		} else {
+			threads = append(threads, MessageThread{Channel: channelID, Timestamp: ts})
 		}
 
 		// Slack allows 1 post message per second. reference: https://api.slack.com/apis/rate-limits
 		time.Sleep(time.Millisecond * 1200)
 	}
-	return fmt.Sprintf("success sent channels %v/%v, errors=%v", len(slackChannels), len(slackChannels)-len(errs), errs)
+	return threads, fmt.Sprintf("success sent channels %v/%v, errors=%v", len(slackChannels), len(slackChannels)-len(errs), errs)
 }
 
 func (s *SlackService) UpdateMessage(msg *MessageReviewResponse, isApproved bool) error {
@@ -284,6 +337,40 @@ func (s *SlackService) OpenModalError(msg *MessageReviewResponse, message string
 	return err
 }
 
+// OpenModalChangesRequest asks the reviewer the feedback of the changes requested to the owner
+func (s *SlackService) OpenModalChangesRequest(msg *MessageReviewResponse) error {
+	metadata, err := json.Marshal(changesRequestedMetadata{
+		ID:        msg.ID,
+		EventKind: msg.EventKind,
+		SessionID: msg.SessionID,
+		GroupName: msg.GroupName,
+		Revision:  msg.Revision,
+	})
+	if err != nil {
+		return err
+	}
+	input := slack.NewPlainTextInputBlockElement(&slack.TextBlockObject{
+		Type: slack.PlainTextType,
+		Text: "Describe the changes required to approve the review",
+	}, feedbackBlockID)
+	input.Multiline = true
+	_, err = s.apiClient.OpenView(msg.item.TriggerID, slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      changesRequestedCallbackID,
+		PrivateMetadata: string(metadata),
+		Title:           &slack.TextBlockObject{Type: slack.PlainTextType, Text: "Request changes"},
+		Submit:          &slack.TextBlockObject{Type: slack.PlainTextType, Text: "Send"},
+		Close:           &slack.TextBlockObject{Type: slack.PlainTextType, Text: "Cancel"},
+		Blocks: slack.Blocks{
+			BlockSet: []slack.Block{
+				slack.NewInputBlock(feedbackBlockID,
+					&slack.TextBlockObject{Type: slack.PlainTextType, Text: "Feedback"}, nil, input),
+			},
+		},
+	})
+	return err
+}
+
 func (s *SlackService) UpdateMessageStatus(msg *MessageReviewResponse, message string) error {
 	blockID := msg.item.ActionCallback.BlockActions[0].BlockID
 	blocks := msg.item.Message.Blocks.BlockSet
@@ -304,6 +391,28 @@ func (s *SlackService) UpdateMessageStatus(msg *MessageReviewResponse, message s
 	return err
 }
 
+// UpdateMessageGroup replaces the description of the group that performed the review,
+// keeping its buttons available to the remaining approvers of the group.
+func (s *SlackService) UpdateMessageGroup(msg *MessageReviewResponse, message string) error {
+	blockID := msg.item.ActionCallback.BlockActions[0].BlockID
+	blocks := msg.item.Message.Blocks.BlockSet
+	for i, b := range blocks {
+		if b.BlockType() == "actions" && i > 0 {
+			bl := b.(*slack.ActionBlock)
+			if bl.BlockID == blockID {
+				blocks[i-1] = slack.NewSectionBlock(&slack.TextBlockObject{
+					Type: slack.MarkdownType,
+					Text: message,
+				}, nil, nil)
+			}
+		}
+	}
+	_, _, err := s.apiClient.PostMessage(msg.item.Channel.ID,
+		slack.MsgOptionReplaceOriginal(msg.item.ResponseURL),
+		slack.MsgOptionBlocks(blocks...))
+	return err
+}
+
 // PostMessage sends a message to a channel or direct message to a user
 func (s *SlackService) PostMessage(slackOrChannelID, message string) error {
 	_, timestamp, err := s.apiClient.PostMessage(slackOrChannelID, slack.MsgOptionText(message, false))
@@ -316,9 +425,46 @@ func (s *SlackService) PostMessage(slackOrChannelID, message string) error {
 	return nil
 }
 
+// PostChannelsMessage sends a message to the channels and the default channel of the instance
+func (s *SlackService) PostChannelsMessage(slackChannels []string, message string) error {
+	if s.slackChannel != "" && !slices.Contains(slackChannels, s.slackChannel) {
+		slackChannels = append(slackChannels, s.slackChannel)
+	}
+	var errs []string
+	for _, slackChannel := range slackChannels {
+		if _, _, err := s.apiClient.PostMessage(slackChannel, slack.MsgOptionText(message, false)); err != nil {
+			errs = append(errs, fmt.Sprintf(`"%v - %v"`, slackChannel, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed sending message to channels: %v", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// PostThreadsMessage replies the review messages
+func (s *SlackService) PostThreadsMessage(threads []MessageThread, message string) error {
+	var errs []string
+	for _, t := range threads {
+		_, _, err := s.apiClient.PostMessage(t.Channel, slack.MsgOptionText(message, false), slack.MsgOptionTS(t.Timestamp))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf(`"%v - %v"`, t.Channel, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed replying threads: %v", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// PostEphemeralMessage sends a message visible only to the user in the channel of the interaction,
+// responses without a channel (modals and thread replies) are sent as a direct message
 func (s *SlackService) PostEphemeralMessage(msg *MessageReviewResponse, message string) error {
 	channelID := msg.item.Channel.ID
 	userID := msg.item.User.ID
+	if channelID == "" {
+		return s.PostMessage(msg.SlackID, message)
+	}
 
 	timestamp, err := s.apiClient.PostEphemeral(channelID, userID, slack.MsgOptionText(message, false))
 	if err != nil {
@@ -51,4 +51,6 @@ const (
 	ReviewStatusProcessing ReviewStatus = "PROCESSING"
 	ReviewStatusExecuted   ReviewStatus = "EXECUTED"
 	ReviewStatusUnknown    ReviewStatus = "UNKNOWN"
+	// ReviewStatusChangesRequested sends the review back to the owner to resubmit an edited input
+	ReviewStatusChangesRequested ReviewStatus = "CHANGES_REQUESTED"
 )
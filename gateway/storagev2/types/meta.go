@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"time"
 
 	pb "github.com/hoophq/hoop/common/proto"
 )
@@ -37,3 +38,44 @@ func (p *PluginConnection) SetName() {
 		p.Name = p.Connection.Name
 	}
 }
+
+// CurrentStage returns the lowest stage containing groups that are not approved.
+// When all groups are approved it returns the last stage.
+func (r *Review) CurrentStage() (stage int) {
+	stage = -1
+	for _, g := range r.ReviewGroupsData {
+		if g.Status != ReviewStatusApproved && (stage == -1 || g.Stage < stage) {
+			stage = g.Stage
+		}
+	}
+	if stage >= 0 {
+		return stage
+	}
+	for _, g := range r.ReviewGroupsData {
+		stage = max(stage, g.Stage)
+	}
+	return max(stage, 0)
+}
+
+// SubmittedAt returns the time the current revision of the review was submitted
+func (r *Review) SubmittedAt() time.Time {
+	if r.RevisedAt != nil {
+		return *r.RevisedAt
+	}
+	return r.CreatedAt
+}
+
+// ApprovalsProgress returns the amount of approvals of the group, e.g.: 1/2 approvals
+func (g ReviewGroup) ApprovalsProgress() string {
+	approvals := 0
+	for _, a := range g.Approvals {
+		if a.Status == ReviewStatusApproved {
+			approvals++
+		}
+	}
+	// groups reviewed before review policies don't track approvals
+	if approvals == 0 && g.Status == ReviewStatusApproved {
+		approvals = 1
+	}
+	return fmt.Sprintf("%v/%v approvals", approvals, max(g.MinApprovals, 1))
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"time"
 
+	pb "github.com/hoophq/hoop/common/proto"
 	"olympos.io/encoding/edn"
 )
 
@@ -102,11 +103,85 @@ type ConnectionInfo struct {
 	AgentID                          string
 	AgentName                        string
 	AgentMode                        string
+	Tags                             []string
 	AccessModeRunbooks               string
 	AccessModeExec                   string
 	AccessModeConnect                string
 	AccessSchema                     string
 	JiraTransitionNameOnSessionClose string
+	SessionLimits                    *pb.SessionLimits
+	ReviewPolicy                     *ReviewPolicy
+	ReviewRules                      []ReviewRule
+	BreakGlass                       *BreakGlassPolicy
+}
+
+// BreakGlassPolicy allows emergency access to a connection without approval.
+// The access is granted immediately and a review is created to be acknowledged afterwards.
+type BreakGlassPolicy struct {
+	AccessDuration   time.Duration
+	AcknowledgeGroup string
+}
+
+// ReviewPolicy defines the approvals required by each group of a review
+type ReviewPolicy struct {
+	// Stages are reviewed in order, a stage accepts reviews when all previous stages are approved
+	Stages []ReviewPolicyStage `json:"stages"`
+	// DistinctApprovers counts the approval of a reviewer only once across all groups
+	DistinctApprovers bool `json:"distinct_approvers"`
+	// ExpireAfterSec rejects pending reviews after this amount of time
+	ExpireAfterSec int `json:"expire_after_sec"`
+	// ReminderIntervalSec notifies the reviewers of pending reviews in this interval
+	ReminderIntervalSec int `json:"reminder_interval_sec"`
+	// EscalateAfterSec allows the EscalationGroup to approve pending reviews after this amount of time
+	EscalateAfterSec int    `json:"escalate_after_sec"`
+	EscalationGroup  string `json:"escalation_group"`
+}
+
+type ReviewPolicyStage struct {
+	Groups []ReviewPolicyGroup `json:"groups"`
+}
+
+type ReviewPolicyGroup struct {
+	Name string `json:"name"`
+	// MinApprovals is the number of distinct members of the group required to approve it
+	MinApprovals int `json:"min_approvals"`
+}
+
+type ReviewRuleAction string
+
+const (
+	ReviewRuleActionRequire ReviewRuleAction = "require"
+	ReviewRuleActionSkip    ReviewRuleAction = "skip"
+)
+
+// ReviewRule decides if a session requires a review. Rules are evaluated in order,
+// the first rule matching all of its conditions is applied. A rule without conditions
+// matches any session.
+type ReviewRule struct {
+	Name   string           `json:"name"`
+	Action ReviewRuleAction `json:"action"`
+	// QueryType matches the kind of statements of the input: read or write
+	QueryType string `json:"query_type"`
+	// QueryPattern is a case insensitive regular expression matching the input
+	QueryPattern string `json:"query_pattern"`
+	// Users matches the email of the user opening the session
+	Users []string `json:"users"`
+	// Groups matches any group of the user opening the session
+	Groups     []string              `json:"groups"`
+	TimeWindow *ReviewRuleTimeWindow `json:"time_window"`
+}
+
+// ReviewRuleTimeWindow matches the time a session is opened, e.g.: mon-fri from 09:00 to 18:00
+type ReviewRuleTimeWindow struct {
+	// Timezone is the IANA name of the time zone, defaults to UTC
+	Timezone string `json:"timezone"`
+	// Weekdays are abbreviated names (mon, tue, ...), an empty list matches all days
+	Weekdays []string `json:"weekdays"`
+	// Start and End are in the format HH:MM, a window ending before it starts spans midnight
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Outside matches when the time is outside of the window
+	Outside bool `json:"outside"`
 }
 
 type ReviewOwner struct {
@@ -122,11 +197,21 @@ type ReviewConnection struct {
 }
 
 type ReviewGroup struct {
-	Id         string       `json:"id"          edn:"xt/id"`
-	Group      string       `json:"group"       edn:"review-group/group"`
-	Status     ReviewStatus `json:"status"      edn:"review-group/status"`
-	ReviewedBy *ReviewOwner `json:"reviewed_by" edn:"review-group/reviewed-by"`
-	ReviewDate *string      `json:"review_date" edn:"review-group/review_date"`
+	Id           string           `json:"id"            edn:"xt/id"`
+	Group        string           `json:"group"         edn:"review-group/group"`
+	Status       ReviewStatus     `json:"status"        edn:"review-group/status"`
+	ReviewedBy   *ReviewOwner     `json:"reviewed_by"   edn:"review-group/reviewed-by"`
+	ReviewDate   *string          `json:"review_date"   edn:"review-group/review_date"`
+	Stage        int              `json:"stage"         edn:"review-group/stage"`
+	MinApprovals int              `json:"min_approvals" edn:"review-group/min-approvals"`
+	Approvals    []ReviewApproval `json:"approvals"     edn:"review-group/approvals"`
+}
+
+// ReviewApproval is the decision of a reviewer on behalf of a group
+type ReviewApproval struct {
+	ReviewOwner
+	Status     ReviewStatus `json:"status"`
+	ReviewDate string       `json:"review_date"`
 }
 
 type Review struct {
@@ -147,6 +232,28 @@ type Review struct {
 	Connection       ReviewConnection  `edn:"review/review-connection"`
 	ReviewGroupsIds  []string          `edn:"review/review-groups"`
 	ReviewGroupsData []ReviewGroup     `edn:"review/review-groups-data"`
+	// DistinctApprovers is set from the review policy when the review is created
+	DistinctApprovers bool `edn:"review/distinct-approvers"`
+	// RuleName is the review rule of the connection that required this review
+	RuleName string `edn:"review/rule-name"`
+	// StatusReason explains the last change of status, e.g.: expiration or the feedback of a change request
+	StatusReason string `edn:"review/status-reason"`
+
+	// timeouts of pending reviews set from the review policy
+	ExpireAt         *time.Time    `edn:"review/expire-at"`
+	ReminderInterval time.Duration `edn:"review/reminder-interval"`
+	RemindedAt       *time.Time    `edn:"review/reminded-at"`
+	EscalateAt       *time.Time    `edn:"review/escalate-at"`
+	EscalationGroup  string        `edn:"review/escalation-group"`
+	EscalatedAt      *time.Time    `edn:"review/escalated-at"`
+
+	// Revision is incremented each time the owner resubmits an edited input
+	Revision  int        `edn:"review/revision"`
+	RevisedAt *time.Time `edn:"review/revised-at"`
+	// ExtendsReviewId is the jit grant extended by this review when it's approved
+	ExtendsReviewId string `edn:"review/extends-review-id"`
+	// SlackThreads are the messages sent to reviewers, comments are posted as replies
+	SlackThreads []ReviewSlackThread `edn:"review/slack-threads"`
 }
 
 type ReviewJSON struct {
@@ -165,6 +272,52 @@ type ReviewJSON struct {
 	ReviewOwner      ReviewOwner      `json:"review_owner"`
 	Connection       ReviewConnection `json:"review_connection"`
 	ReviewGroupsData []ReviewGroup    `json:"review_groups_data"`
+	// DistinctApprovers indicates a reviewer approves only one group
+	DistinctApprovers bool `json:"distinct_approvers"`
+	// CurrentStage is the stage accepting reviews
+	CurrentStage int `json:"current_stage"`
+	// RuleName is the review rule of the connection that required this review
+	RuleName string `json:"rule_name"`
+	// StatusReason explains the last change of status, e.g.: expiration or the feedback of a change request
+	StatusReason    string     `json:"status_reason"`
+	ExpireAt        *time.Time `json:"expire_at"`
+	EscalateAt      *time.Time `json:"escalate_at"`
+	EscalationGroup string     `json:"escalation_group"`
+	EscalatedAt     *time.Time `json:"escalated_at"`
+	// Revision is incremented each time the owner resubmits an edited input
+	Revision  int        `json:"revision"`
+	RevisedAt *time.Time `json:"revised_at"`
+	// ExtendsReviewId is the jit grant extended by this review when it's approved
+	ExtendsReviewId string `json:"extends_review_id"`
+}
+
+// ReviewComment is a message of the thread of a review
+type ReviewComment struct {
+	Id       string      `json:"id"`
+	OrgId    string      `json:"org_id"`
+	ReviewId string      `json:"review_id"`
+	Revision int         `json:"revision"`
+	Owner    ReviewOwner `json:"owner"`
+	Content  string      `json:"content"`
+	// ChangesRequested indicates the comment is the feedback of a change request
+	ChangesRequested bool `json:"changes_requested"`
+	// Source is where the comment was posted: api or slack
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReviewRevision is an input submitted for review and the reviews it received
+type ReviewRevision struct {
+	Revision     int           `json:"revision"`
+	Input        string        `json:"input"`
+	ReviewGroups []ReviewGroup `json:"review_groups"`
+	SubmittedAt  time.Time     `json:"submitted_at"`
+}
+
+// ReviewSlackThread is a review message sent to a slack channel
+type ReviewSlackThread struct {
+	Channel   string `json:"channel"`
+	Timestamp string `json:"ts"`
 }
 
 type SessionEventStream []any
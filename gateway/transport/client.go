@@ -3,6 +3,7 @@ package transport
 import (
 	"fmt"
 	"io"
+	"slices"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/hoophq/hoop/common/apiutils"
@@ -123,7 +124,7 @@ func (s *Server) listenClientMessages(stream *streamclient.ProxyStream) error {
 		case <-stream.Context().Done():
 			return stream.ContextCauseError()
 		case <-pctx.Context.Done():
-			return status.Error(codes.Aborted, "session ended, reached connection duration")
+			return sessionContextDoneErr(pctx.Context)
 		case dstream = <-recvCh:
 		}
 
@@ -144,6 +145,10 @@ func (s *Server) listenClientMessages(stream *streamclient.ProxyStream) error {
 		if pkt.Type == pbgateway.KeepAlive || pkt.Type == "KeepAlive" {
 			continue
 		}
+		if err := validateClientPacket(pctx.ClientVerb, pkt.Type); err != nil {
+			log.With("sid", pctx.SID, "user", pctx.UserEmail).Warnf("rejected client packet, reason=%v", err)
+			return err
+		}
 		if pkt.Spec == nil {
 			pkt.Spec = make(map[string][]byte)
 		}
@@ -237,6 +242,7 @@ func (s *Server) processClientPacket(stream *streamclient.ProxyStream, pkt *pb.P
 			ClientVerb:     pctx.ClientVerb,
 			ClientOrigin:   pctx.ClientOrigin,
 			DLPInfoTypes:   stream.GetRedactInfoTypes(),
+			SessionLimits:  pctx.ConnectionSessionLimits,
 		})
 		if err != nil {
 			return fmt.Errorf("failed encoding connection params err=%v", err)
@@ -259,6 +265,26 @@ func (s *Server) processClientPacket(stream *streamclient.ProxyStream, pkt *pb.P
 	}
 }
 
+// execPacketTypes are the packets accepted from clients that opened the session with an exec verb
+var execPacketTypes = []string{
+	pbagent.SessionOpen,
+	pbagent.SessionClose,
+	pbagent.ExecWriteStdin,
+}
+
+// validateClientPacket enforces the packets of a session match the verb informed by the client.
+// The verb is granted when the stream is authenticated, e.g.: an access token scoped to exec,
+// and it must not be used to open interactive terminals or native proxy connections.
+func validateClientPacket(clientVerb, pktType string) error {
+	switch clientVerb {
+	case pb.ClientVerbExec, pb.ClientVerbPlainExec:
+		if !slices.Contains(execPacketTypes, pktType) {
+			return status.Errorf(codes.PermissionDenied, "the packet %v is not allowed for %v sessions", pktType, clientVerb)
+		}
+	}
+	return nil
+}
+
 func clientArgsDecode(spec map[string][]byte) []string {
 	var clientArgs []string
 	if spec != nil {
@@ -286,10 +312,22 @@ func (s *Server) ReviewStatusChange(rev *types.Review) {
 	if proxyStream != nil {
 		payload := []byte(rev.Input)
 		packetType := pbclient.SessionOpenApproveOK
-		if rev.Status == types.ReviewStatusRejected {
+		switch rev.Status {
+		case types.ReviewStatusRejected:
+			errMsg := "access to connection has been denied"
+			if rev.StatusReason != "" {
+				errMsg = fmt.Sprintf("%s, %s", errMsg, rev.StatusReason)
+			}
+			packetType = pbclient.SessionClose
+			payload = []byte(errMsg)
+			proxyStream.Close(fmt.Errorf("%s", errMsg))
+		case types.ReviewStatusChangesRequested:
+			// the client can't change its input, the owner resubmits it through the review
+			errMsg := fmt.Sprintf("%s, resubmit the input at %s/reviews/%s",
+				rev.StatusReason, s.IDProvider.ApiURL, rev.Id)
 			packetType = pbclient.SessionClose
-			payload = []byte(`access to connection has been denied`)
-			proxyStream.Close(fmt.Errorf("access to connection has been denied"))
+			payload = []byte(errMsg)
+			proxyStream.Close(fmt.Errorf("%s", errMsg))
 		}
 		// TODO: return erroo to caller
 		_ = proxyStream.Send(&pb.Packet{
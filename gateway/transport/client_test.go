@@ -0,0 +1,40 @@
+package transport
+
+import (
+	"testing"
+
+	pb "github.com/hoophq/hoop/common/proto"
+	pbagent "github.com/hoophq/hoop/common/proto/agent"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidateClientPacket(t *testing.T) {
+	for _, tt := range []struct {
+		msg      string
+		verb     string
+		pktType  string
+		wantDeny bool
+	}{
+		{msg: "it must allow exec packets in exec sessions", verb: pb.ClientVerbExec, pktType: pbagent.ExecWriteStdin},
+		{msg: "it must allow opening exec sessions", verb: pb.ClientVerbExec, pktType: pbagent.SessionOpen},
+		{msg: "it must allow exec packets in plain exec sessions", verb: pb.ClientVerbPlainExec, pktType: pbagent.ExecWriteStdin},
+		// a client authenticated with an access token scoped to exec informs the exec verb
+		{msg: "it must deny native proxy connections in exec sessions", verb: pb.ClientVerbExec, pktType: pbagent.TCPConnectionWrite, wantDeny: true},
+		{msg: "it must deny postgres proxy packets in exec sessions", verb: pb.ClientVerbExec, pktType: pbagent.PGConnectionWrite, wantDeny: true},
+		{msg: "it must deny terminals in exec sessions", verb: pb.ClientVerbExec, pktType: pbagent.TerminalWriteStdin, wantDeny: true},
+		{msg: "it must deny ssh proxy packets in plain exec sessions", verb: pb.ClientVerbPlainExec, pktType: pbagent.SSHConnectionWrite, wantDeny: true},
+		{msg: "it must allow native proxy connections in connect sessions", verb: pb.ClientVerbConnect, pktType: pbagent.TCPConnectionWrite},
+		{msg: "it must allow terminals in connect sessions", verb: pb.ClientVerbConnect, pktType: pbagent.TerminalWriteStdin},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			err := validateClientPacket(tt.verb, tt.pktType)
+			if tt.wantDeny {
+				assert.Equal(t, codes.PermissionDenied, status.Code(err))
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
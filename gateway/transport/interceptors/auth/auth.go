@@ -2,9 +2,13 @@ package authinterceptor
 
 import (
 	"context"
+	"crypto/subtle"
+	"crypto/x509"
 	"errors"
+	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/google/uuid"
@@ -12,18 +16,24 @@ import (
 	commongrpc "github.com/hoophq/hoop/common/grpc"
 	"github.com/hoophq/hoop/common/log"
 	pb "github.com/hoophq/hoop/common/proto"
+	"github.com/hoophq/hoop/gateway/accesstoken"
+	"github.com/hoophq/hoop/gateway/agentca"
 	apiconnections "github.com/hoophq/hoop/gateway/api/connections"
 	"github.com/hoophq/hoop/gateway/appconfig"
 	"github.com/hoophq/hoop/gateway/clientexec"
+	"github.com/hoophq/hoop/gateway/models"
 	"github.com/hoophq/hoop/gateway/pgrest"
 	pgagents "github.com/hoophq/hoop/gateway/pgrest/agents"
 	pgorgs "github.com/hoophq/hoop/gateway/pgrest/orgs"
 	pguserauth "github.com/hoophq/hoop/gateway/pgrest/userauth"
+	"github.com/hoophq/hoop/gateway/rbac"
 	"github.com/hoophq/hoop/gateway/security/idp"
 	"github.com/hoophq/hoop/gateway/storagev2/types"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
@@ -95,14 +105,19 @@ func (i *interceptor) StreamServerInterceptor(srv any, ss grpc.ServerStream, inf
 		return status.Error(codes.InvalidArgument, "missing client origin")
 	}
 
-	if len(clientVerb) > 0 && clientVerb[0] == pb.ClientVerbPlainExec {
-		plainExecKey := md.Get("plain-exec-key")
-		if len(plainExecKey) == 0 || plainExecKey[0] != clientexec.PlainExecSecretKey {
-			errMsg := "failed validating plain execution, plain-exec-key attribute is missing or does not match"
-			log.Error(errMsg)
-			sentry.CaptureException(errors.New(errMsg))
-			return status.Errorf(codes.Unauthenticated, "invalid authentication")
-		}
+	if len(clientVerb) > 0 && clientVerb[0] == pb.ClientVerbPlainExec && !hasPlainExecKey(md) {
+		errMsg := "failed validating plain execution, plain-exec-key attribute is missing or does not match"
+		log.Error(errMsg)
+		sentry.CaptureException(errors.New(errMsg))
+		return status.Errorf(codes.Unauthenticated, "invalid authentication")
+	}
+
+	// runbooks are executed only by the gateway (clientexec), the origin grants the runbooks
+	// permission of custom roles and it can't be trusted when informed by other clients
+	if clientOrigin[0] == pb.ConnectionOriginClientAPIRunbooks && !hasPlainExecKey(md) {
+		md.Delete("authorization")
+		log.Warnf("runbooks origin without the plain-exec-key attribute, client-metadata=%v", md)
+		return status.Errorf(codes.PermissionDenied, "the origin %v is reserved to the gateway", clientOrigin[0])
 	}
 
 	bearerToken, err := parseBearerToken(md)
@@ -110,11 +125,20 @@ func (i *interceptor) StreamServerInterceptor(srv any, ss grpc.ServerStream, inf
 		return err
 	}
 
+	// executions started by the gateway on behalf of service accounts, e.g.: scheduled runbooks
+	if subject := commongrpc.MetaGet(md, "service-account-subject"); subject != "" {
+		gwctx, err := i.authenticateServiceAccountExec(subject, bearerToken, md)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStreamWrapper{ss, nil, gwctx})
+	}
+
 	var ctxVal any
 	switch clientOrigin[0] {
 	case pb.ConnectionOriginAgent:
 		// fallback to dsn agent authentication
-		ag, err := i.authenticateAgent(bearerToken, md)
+		ag, err := i.authenticateAgent(ss.Context(), bearerToken, md)
 		if err != nil {
 			return err
 		}
@@ -143,6 +167,14 @@ func (i *interceptor) StreamServerInterceptor(srv any, ss grpc.ServerStream, inf
 		}
 	// client proxy authentication (access token)
 	default:
+		if accesstoken.IsPersonalAccessToken(bearerToken) {
+			gwctx, err := i.authenticateAccessToken(bearerToken, md)
+			if err != nil {
+				return err
+			}
+			ctxVal = gwctx
+			break
+		}
 		apiKeyEnv := os.Getenv("API_KEY")
 		isOrgMultitenant := appconfig.Get().OrgMultitenant()
 		// this is a not so optimal solution, but due to the overall
@@ -177,7 +209,7 @@ func (i *interceptor) StreamServerInterceptor(srv any, ss grpc.ServerStream, inf
 
 			gwctx.UserContext.ApiURL = os.Getenv("API_URL")
 			connectionName := commongrpc.MetaGet(md, "connection-name")
-			conn, err := i.getConnection(connectionName, ctx)
+			conn, err := i.getConnection(connectionName, ctx, md)
 			if err != nil {
 				return err
 			}
@@ -209,7 +241,7 @@ func (i *interceptor) StreamServerInterceptor(srv any, ss grpc.ServerStream, inf
 		}
 		gwctx.UserContext.ApiURL = i.idp.ApiURL
 		connectionName := commongrpc.MetaGet(md, "connection-name")
-		conn, err := i.getConnection(connectionName, userCtx)
+		conn, err := i.getConnection(connectionName, userCtx, md)
 		if err != nil {
 			return err
 		}
@@ -223,6 +255,71 @@ func (i *interceptor) StreamServerInterceptor(srv any, ss grpc.ServerStream, inf
 	return handler(srv, &serverStreamWrapper{ss, nil, ctxVal})
 }
 
+// authenticateServiceAccountExec authenticates executions of the gateway (clientexec) on behalf of a service account,
+// the bearer token must be the plain exec key which is known only by the gateway process
+func (i *interceptor) authenticateServiceAccountExec(subject, bearerToken string, md metadata.MD) (*GatewayContext, error) {
+	if subtle.ConstantTimeCompare([]byte(bearerToken), []byte(clientexec.PlainExecSecretKey)) != 1 {
+		errMsg := "failed validating service account execution, the plain exec key does not match"
+		log.Error(errMsg)
+		sentry.CaptureException(errors.New(errMsg))
+		return nil, status.Errorf(codes.Unauthenticated, "invalid authentication")
+	}
+	userCtx, err := pguserauth.New().FetchUserContext(subject)
+	if err != nil || userCtx.IsEmpty() {
+		log.Errorf("failed fetching service account context, reason=%v", err)
+		return nil, status.Errorf(codes.Unauthenticated, "invalid authentication")
+	}
+	if userCtx.UserStatus != string(types.UserStatusActive) {
+		return nil, status.Errorf(codes.Unauthenticated, "service account is not active")
+	}
+	gwctx := &GatewayContext{
+		UserContext: *userCtx.ToAPIContext(),
+		BearerToken: bearerToken,
+	}
+	gwctx.UserContext.ApiURL = i.idp.ApiURL
+	connectionName := commongrpc.MetaGet(md, "connection-name")
+	conn, err := i.getConnection(connectionName, userCtx, md)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		return nil, status.Errorf(codes.NotFound, "connection not found")
+	}
+	gwctx.Connection = *conn
+	return gwctx, nil
+}
+
+// authenticateAccessToken authenticates clients with a personal access token,
+// the token must have an exec scope for the connection. The verb is informed by the client,
+// the session is kept to exec packets by the transport (validateClientPacket)
+func (i *interceptor) authenticateAccessToken(bearerToken string, md metadata.MD) (*GatewayContext, error) {
+	userCtx, scopes, err := accesstoken.Authenticate(bearerToken)
+	if err != nil {
+		log.Debugf("failed authenticating personal access token, reason=%v", err)
+		return nil, status.Errorf(codes.Unauthenticated, "invalid authentication")
+	}
+	connectionName := commongrpc.MetaGet(md, "connection-name")
+	perm := rbac.ClientPermission(commongrpc.MetaGet(md, "verb"), commongrpc.MetaGet(md, "origin"))
+	if perm == rbac.PermissionConnect || !scopes.AllowsExec(connectionName) {
+		return nil, status.Errorf(codes.PermissionDenied,
+			"the scopes of the token don't allow to %v on connection %v", perm, connectionName)
+	}
+	gwctx := &GatewayContext{
+		UserContext: *userCtx.ToAPIContext(),
+		BearerToken: bearerToken,
+	}
+	gwctx.UserContext.ApiURL = i.idp.ApiURL
+	conn, err := i.getConnection(connectionName, userCtx, md)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		return nil, status.Errorf(codes.NotFound, "connection not found")
+	}
+	gwctx.Connection = *conn
+	return gwctx, nil
+}
+
 func (i *interceptor) validateAccessToken(bearerToken string) (subject string, err error) {
 	if i.idp.HasSecretKey() {
 		return i.idp.VerifyAccessTokenHS256Alg(bearerToken)
@@ -230,7 +327,7 @@ func (i *interceptor) validateAccessToken(bearerToken string) (subject string, e
 	return i.idp.VerifyAccessToken(bearerToken)
 }
 
-func (i *interceptor) getConnection(name string, userCtx *pguserauth.Context) (*types.ConnectionInfo, error) {
+func (i *interceptor) getConnection(name string, userCtx *pguserauth.Context, md metadata.MD) (*types.ConnectionInfo, error) {
 	conn, err := apiconnections.FetchByName(userCtx, name)
 	if err != nil {
 		log.Errorf("failed retrieving connection %v, err=%v", name, err)
@@ -240,6 +337,18 @@ func (i *interceptor) getConnection(name string, userCtx *pguserauth.Context) (*
 	if conn == nil {
 		return nil, nil
 	}
+	policy, err := rbac.Load(userCtx.OrgID, userCtx.UserGroups)
+	if err != nil {
+		log.Errorf("failed loading custom roles, err=%v", err)
+		sentry.CaptureException(err)
+		return nil, status.Errorf(codes.Internal, "internal error, failed to obtain user permissions")
+	}
+	perm := rbac.ClientPermission(commongrpc.MetaGet(md, "verb"), commongrpc.MetaGet(md, "origin"))
+	if !policy.Allowed(perm, rbac.ResourceFromConnection(conn)) {
+		log.With("user", userCtx.UserEmail, "connection", conn.Name).
+			Infof("permission denied by custom roles, permission=%v", perm)
+		return nil, status.Errorf(codes.PermissionDenied, "user is not allowed to %v on connection %v", perm, conn.Name)
+	}
 	return &types.ConnectionInfo{
 		ID:                               conn.ID,
 		Name:                             conn.Name,
@@ -250,15 +359,89 @@ func (i *interceptor) getConnection(name string, userCtx *pguserauth.Context) (*
 		AgentID:                          conn.AgentID.String,
 		AgentMode:                        conn.AgentMode,
 		AgentName:                        conn.AgentName,
+		Tags:                             conn.Tags,
 		AccessModeRunbooks:               conn.AccessModeRunbooks,
 		AccessModeExec:                   conn.AccessModeExec,
 		AccessModeConnect:                conn.AccessModeConnect,
 		AccessSchema:                     conn.AccessSchema,
 		JiraTransitionNameOnSessionClose: conn.JiraTransitionNameOnClose.String,
+		SessionLimits:                    toSessionLimits(conn.SessionLimits),
+		ReviewPolicy:                     toReviewPolicy(conn.ReviewPolicy),
+		ReviewRules:                      toReviewRules(conn.ReviewRules),
+		BreakGlass:                       toBreakGlass(conn.BreakGlass),
 	}, nil
 }
 
-func (i *interceptor) authenticateAgent(bearerToken string, md metadata.MD) (*pgrest.Agent, error) {
+func toBreakGlass(b *models.BreakGlass) *types.BreakGlassPolicy {
+	if b == nil {
+		return nil
+	}
+	return &types.BreakGlassPolicy{
+		AccessDuration:   time.Duration(b.AccessDurationSec) * time.Second,
+		AcknowledgeGroup: b.AcknowledgeGroup,
+	}
+}
+
+func toReviewRules(rules []models.ReviewRule) []types.ReviewRule {
+	var items []types.ReviewRule
+	for _, r := range rules {
+		rule := types.ReviewRule{
+			Name:         r.Name,
+			Action:       types.ReviewRuleAction(r.Action),
+			QueryType:    r.QueryType,
+			QueryPattern: r.QueryPattern,
+			Users:        r.Users,
+			Groups:       r.Groups,
+		}
+		if w := r.TimeWindow; w != nil {
+			rule.TimeWindow = &types.ReviewRuleTimeWindow{
+				Timezone: w.Timezone,
+				Weekdays: w.Weekdays,
+				Start:    w.Start,
+				End:      w.End,
+				Outside:  w.Outside,
+			}
+		}
+		items = append(items, rule)
+	}
+	return items
+}
+
+func toReviewPolicy(p *models.ReviewPolicy) *types.ReviewPolicy {
+	if p == nil {
+		return nil
+	}
+	policy := &types.ReviewPolicy{
+		DistinctApprovers:   p.DistinctApprovers,
+		ExpireAfterSec:      p.ExpireAfterSec,
+		ReminderIntervalSec: p.ReminderIntervalSec,
+		EscalateAfterSec:    p.EscalateAfterSec,
+		EscalationGroup:     p.EscalationGroup,
+	}
+	for _, s := range p.Stages {
+		stage := types.ReviewPolicyStage{}
+		for _, g := range s.Groups {
+			stage.Groups = append(stage.Groups, types.ReviewPolicyGroup{Name: g.Name, MinApprovals: g.MinApprovals})
+		}
+		policy.Stages = append(policy.Stages, stage)
+	}
+	return policy
+}
+
+func toSessionLimits(l *models.SessionLimits) *pb.SessionLimits {
+	if l == nil {
+		return nil
+	}
+	return &pb.SessionLimits{
+		IdleTimeoutSec:   l.IdleTimeoutSec,
+		MaxBytesIn:       l.MaxBytesIn,
+		MaxBytesOut:      l.MaxBytesOut,
+		MaxResponseRows:  l.MaxResponseRows,
+		MaxResponseBytes: l.MaxResponseBytes,
+	}
+}
+
+func (i *interceptor) authenticateAgent(ctx context.Context, bearerToken string, md metadata.MD) (*pgrest.Agent, error) {
 	if strings.HasPrefix(bearerToken, "x-agt-") {
 		ag, err := pgagents.New().FetchOneByToken(bearerToken)
 		if err != nil || ag == nil {
@@ -266,6 +449,10 @@ func (i *interceptor) authenticateAgent(bearerToken string, md metadata.MD) (*pg
 			log.Debugf("invalid agent authentication (legacy auth), tokenlength=%v, client-metadata=%v, err=%v", len(bearerToken), md, err)
 			return nil, status.Errorf(codes.Unauthenticated, "invalid authentication")
 		}
+		if err := verifyAgentCertificate(ctx, ag); err != nil {
+			log.With("org", ag.OrgID, "agent", ag.Name).Infof("failed authenticating agent with mTLS (legacy auth), reason=%v", err)
+			return nil, status.Errorf(codes.Unauthenticated, "invalid authentication, %v", err)
+		}
 		return ag, nil
 	}
 	dsn, err := dsnkeys.Parse(bearerToken)
@@ -276,6 +463,9 @@ func (i *interceptor) authenticateAgent(bearerToken string, md metadata.MD) (*pg
 	}
 
 	ag, err := pgagents.New().FetchOneByToken(dsn.SecretKeyHash)
+	if err == nil && ag == nil {
+		ag, err = fetchAgentByPreviousKey(dsn.SecretKeyHash)
+	}
 	if err != nil || ag == nil {
 		md.Delete("authorization")
 		log.Debugf("invalid agent authentication (dsn), tokenlength=%v, client-metadata=%v, err=%v", len(bearerToken), md, err)
@@ -286,9 +476,61 @@ func (i *interceptor) authenticateAgent(bearerToken string, md metadata.MD) (*pg
 			ag.ID, dsn.Name, dsn.AgentMode)
 		return nil, status.Errorf(codes.Unauthenticated, "invalid authentication, mismatch dsn attributes")
 	}
+	if err := verifyAgentCertificate(ctx, ag); err != nil {
+		log.With("org", ag.OrgID, "agent", ag.Name).Infof("failed authenticating agent with mTLS, reason=%v", err)
+		return nil, status.Errorf(codes.Unauthenticated, "invalid authentication, %v", err)
+	}
 	return ag, nil
 }
 
+// fetchAgentByPreviousKey authenticates agents using the key
+// replaced by a rotation while it's in the grace period
+func fetchAgentByPreviousKey(keyHash string) (*pgrest.Agent, error) {
+	agAuth, err := models.GetAgentAuthByPreviousKeyHash(keyHash, time.Now().UTC())
+	switch err {
+	case models.ErrNotFound:
+		return nil, nil
+	case nil:
+	default:
+		return nil, err
+	}
+	ag, err := pgagents.New().FetchOneByID(agAuth.ID)
+	if ag != nil {
+		log.With("org", ag.OrgID, "agent", ag.Name).Warnf("agent authenticated with a rotated key, it expires at %v",
+			agAuth.PreviousKeyExpireAt.Format(time.RFC3339))
+	}
+	return ag, err
+}
+
+// verifyAgentCertificate validates the client certificate presented in the TLS handshake.
+// A certificate is optional unless the agent requires mTLS, when present it must be valid.
+func verifyAgentCertificate(ctx context.Context, ag *pgrest.Agent) error {
+	var chain []*x509.Certificate
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			chain = tlsInfo.State.PeerCertificates
+		}
+	}
+	if len(chain) == 0 {
+		agAuth, err := models.GetAgentAuth(ag.ID)
+		if err != nil {
+			return fmt.Errorf("failed fetching agent authentication attributes: %v", err)
+		}
+		if agAuth.MTLSRequired {
+			return agentca.ErrCertificateRequired
+		}
+		return nil
+	}
+	return agentca.VerifyAgentCertificate(ag.OrgID, ag.ID, chain, time.Now().UTC())
+}
+
+// hasPlainExecKey returns true if the client is an execution started by the gateway process
+func hasPlainExecKey(md metadata.MD) bool {
+	plainExecKey := commongrpc.MetaGet(md, "plain-exec-key")
+	return plainExecKey != "" &&
+		subtle.ConstantTimeCompare([]byte(plainExecKey), []byte(clientexec.PlainExecSecretKey)) == 1
+}
+
 func parseBearerToken(md metadata.MD) (string, error) {
 	t := md.Get("authorization")
 	if len(t) == 0 {
@@ -0,0 +1,60 @@
+package authinterceptor
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/hoophq/hoop/common/proto"
+	"github.com/hoophq/hoop/gateway/models"
+	"github.com/hoophq/hoop/gateway/rbac"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context { return s.ctx }
+
+func TestStreamServerInterceptorRunbooksOrigin(t *testing.T) {
+	// a role granting only runbooks, the spoofed origin would grant it to an exec session
+	policy := rbac.NewPolicy([]string{"support"}, []*models.Role{{
+		Groups:      []string{"support"},
+		Permissions: []models.RolePermission{{Actions: []string{"runbooks"}}},
+	}})
+	pgdemo := rbac.Resource{Name: "pgdemo"}
+	assert.False(t, policy.Allowed(rbac.ClientPermission(pb.ClientVerbExec, pb.ConnectionOriginClient), pgdemo))
+	assert.True(t, policy.Allowed(rbac.ClientPermission(pb.ClientVerbExec, pb.ConnectionOriginClientAPIRunbooks), pgdemo))
+
+	for _, tt := range []struct {
+		msg          string
+		plainExecKey string
+	}{
+		{msg: "it must deny the runbooks origin without the plain exec key"},
+		{msg: "it must deny the runbooks origin with a wrong plain exec key", plainExecKey: "guessed-key"},
+	} {
+		t.Run(tt.msg, func(t *testing.T) {
+			md := metadata.Pairs(
+				"origin", pb.ConnectionOriginClientAPIRunbooks,
+				"verb", pb.ClientVerbExec,
+				"connection-name", "pgdemo",
+				"authorization", "Bearer user-access-token",
+			)
+			if tt.plainExecKey != "" {
+				md.Set("plain-exec-key", tt.plainExecKey)
+			}
+			ss := &fakeServerStream{ctx: metadata.NewIncomingContext(context.Background(), md)}
+			handlerCalled := false
+			err := (&interceptor{}).StreamServerInterceptor(nil, ss, &grpc.StreamServerInfo{},
+				func(any, grpc.ServerStream) error { handlerCalled = true; return nil })
+
+			assert.Equal(t, codes.PermissionDenied, status.Code(err))
+			assert.False(t, handlerCalled)
+		})
+	}
+}
@@ -24,7 +24,7 @@ func (i *interceptor) UnaryValidator(ctx context.Context, srv any, _ *grpc.Unary
 	if err != nil {
 		return nil, err
 	}
-	ag, err := i.authenticateAgent(bearerToken, md)
+	ag, err := i.authenticateAgent(ctx, bearerToken, md)
 	if err != nil {
 		return nil, err
 	}
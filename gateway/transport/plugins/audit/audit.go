@@ -142,6 +142,16 @@ func (p *auditPlugin) OnReceive(pctx plugintypes.Context, pkt *pb.Packet) (*plug
 		if decJSONPayload != nil {
 			return nil, p.writeOnReceive(pctx.SID, eventlogv1.InputType, decJSONPayload, eventMetadata)
 		}
+	case pbclient.SessionClose:
+		// the agent rejected to dial the upstream address based on its egress policy
+		if reason := pkt.Spec[pb.SpecAgentEgressDeniedKey]; len(reason) > 0 {
+			log.With("sid", pctx.SID, "agent", pctx.AgentName, "connection", pctx.ConnectionName).
+				Warnf("agent egress policy denied upstream connection, reason=%v", string(reason))
+			metadata := map[string][]byte{pb.SpecAgentEgressDeniedKey: reason}
+			if err := p.writeOnReceive(pctx.SID, eventlogv1.ErrorType, nil, metadata); err != nil {
+				log.With("sid", pctx.SID).Warnf("failed writing egress denied event, err=%v", err)
+			}
+		}
 	case pbclient.WriteStdout,
 		pbclient.WriteStderr:
 		err := p.writeOnReceive(pctx.SID, eventlogv1.OutputType, pkt.Payload, eventMetadata)
@@ -13,6 +13,8 @@ type SessionMetric struct {
 	DataMasking *DataMaskingMetric `json:"data_masking"`
 	EventSize   int64              `json:"event_size"`
 	Truncated   bool               `json:"truncated"`
+	// EgressDenied is the reason the agent rejected to dial the upstream address
+	EgressDenied string `json:"egress_denied,omitempty"`
 }
 
 func (s *SessionMetric) addInfoType(key string, size int64) {
@@ -105,6 +105,8 @@ func (p *auditPlugin) writeOnClose(pctx plugintypes.Context, errMsg error) error
 			pctx.SID, wh.SessionID)
 	}
 	var rawJSONBlobStream string
+	// sessions opened with an emergency access are recorded in full to be reviewed afterwards
+	isBreakGlass := pctx.ParamsData.GetString(plugintypes.ParamBreakGlassReviewID) != ""
 	metrics := newSessionMetric()
 	metrics.Truncated, err = walogm.log.ReadFull(func(data []byte) error {
 		ev, err := eventlogv1.Decode(data)
@@ -149,6 +151,10 @@ func (p *auditPlugin) writeOnClose(pctx plugintypes.Context, errMsg error) error
 			}
 		}
 
+		if reason := ev.GetMetadata(pb.SpecAgentEgressDeniedKey); reason != nil {
+			metrics.EgressDenied = string(reason)
+		}
+
 		// don't process empty event streams
 		if len(ev.Payload) == 0 {
 			return nil
@@ -156,7 +162,10 @@ func (p *auditPlugin) writeOnClose(pctx plugintypes.Context, errMsg error) error
 
 		// truncate when event is greater than 5000 bytes for tcp type
 		// it avoids auditing blob content for TCP (files, images, etc)
-		eventStream := p.truncateTCPEventStream(ev.Payload, wh.ConnectionType)
+		eventStream := ev.Payload
+		if !isBreakGlass {
+			eventStream = p.truncateTCPEventStream(ev.Payload, wh.ConnectionType)
+		}
 		eventList := fmt.Sprintf("[%v, %q, %q],",
 			ev.EventTime.Sub(*wh.StartDate).Seconds(),
 			string(ev.EventType),
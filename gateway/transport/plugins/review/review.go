@@ -1,9 +1,9 @@
 package review
 
 import (
-	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -39,6 +39,9 @@ func (p *reviewPlugin) OnReceive(pctx plugintypes.Context, pkt *pb.Packet) (*plu
 	if pkt.Type != pbagent.SessionOpen {
 		return nil, nil
 	}
+	if justification, ok := pkt.Spec[pb.SpecBreakGlassJustification]; ok {
+		return p.openBreakGlass(pctx, string(justification))
+	}
 	if pctx.OrgLicenseType == license.OSSType {
 		return p.onReceiveOSS(pctx, pkt)
 	}
@@ -71,6 +74,13 @@ func (p *reviewPlugin) OnReceive(pctx plugintypes.Context, pkt *pb.Packet) (*plu
 		return nil, nil
 	}
 
+	rule := matchReviewRule(pctx, pkt)
+	if rule != nil && rule.Action == types.ReviewRuleActionSkip {
+		log.With("sid", pctx.SID, "user", pctx.UserEmail, "org", pctx.OrgID, "rule", rule.Name).
+			Infof("review skipped by rule")
+		return nil, nil
+	}
+
 	jitr, err := pgreview.New().FetchJit(pctx, pctx.UserID, pctx.ConnectionID)
 	if err != nil {
 		return nil, plugintypes.InternalErr("failed listing time based reviews", err)
@@ -83,7 +93,8 @@ func (p *reviewPlugin) OnReceive(pctx plugintypes.Context, pkt *pb.Packet) (*plu
 			log.With("sid", pctx.SID, "id", jitr.Id, "user", jitr.CreatedBy, "org", pctx.OrgID,
 				"revoke-at", jitr.RevokeAt.Format(time.RFC3339),
 				"duration", fmt.Sprintf("%vm", jitr.AccessDuration.Minutes())).Infof("jit access granted")
-			newCtx, _ := context.WithTimeout(pctx.Context, jitr.AccessDuration)
+			// the session follows the changes of the grant, e.g.: extensions and revocation
+			newCtx := review.NewJitContext(pctx.Context, jitr, pctx.SID)
 			return &plugintypes.ConnectResponse{Context: newCtx, ClientPacket: nil}, nil
 		default:
 			return nil, err
@@ -101,24 +112,20 @@ func (p *reviewPlugin) OnReceive(pctx plugintypes.Context, pkt *pb.Packet) (*plu
 		if err != nil {
 			return nil, plugintypes.InvalidArgument("invalid access time duration, got=%v", string(durationStr))
 		}
-		if accessDuration.Hours() > 48 {
+		if accessDuration > review.MaxJitDuration {
 			return nil, plugintypes.InvalidArgument("jit access input must not be greater than 48 hours")
 		}
 	}
 
-	if len(pctx.PluginConnectionConfig) == 0 {
+	// the review policy of the connection takes precedence over the groups of the plugin
+	reviewGroups := review.NewReviewGroups(pctx.ConnectionReviewPolicy, pctx.PluginConnectionConfig)
+	if len(reviewGroups) == 0 {
 		err = fmt.Errorf("missing approval groups for connection")
 		return nil, plugintypes.InternalErr(err.Error(), err)
 	}
-
-	reviewGroups := make([]types.ReviewGroup, 0)
 	groups := make([]string, 0)
-	for _, s := range pctx.PluginConnectionConfig {
-		groups = append(groups, s)
-		reviewGroups = append(reviewGroups, types.ReviewGroup{
-			Group:  s,
-			Status: types.ReviewStatusPending,
-		})
+	for _, g := range reviewGroups {
+		groups = append(groups, g.Group)
 	}
 
 	var inputClientArgs []string
@@ -153,6 +160,10 @@ func (p *reviewPlugin) OnReceive(pctx plugintypes.Context, pkt *pb.Packet) (*plu
 		ReviewGroupsIds:  groups,
 		ReviewGroupsData: reviewGroups,
 	}
+	review.SetReviewPolicy(newRev, pctx.ConnectionReviewPolicy)
+	if rule != nil {
+		newRev.RuleName = rule.Name
+	}
 
 	if !isJitReview {
 		// only onetime reviews has inputs
@@ -188,6 +199,61 @@ func (p *reviewPlugin) OnShutdown()                                            {
 // it will allow applying special logic for these cases
 func (p *reviewPlugin) setSpecReview(pkt *pb.Packet) { pkt.Spec[pb.SpecHasReviewKey] = []byte("true") }
 
+// matchReviewRule returns the first review rule of the connection matching the session
+func matchReviewRule(pctx plugintypes.Context, pkt *pb.Packet) *types.ReviewRule {
+	return review.MatchRule(pctx.ConnectionReviewRules, review.RuleInput{
+		Input:      string(pkt.Payload),
+		UserEmail:  pctx.UserEmail,
+		UserGroups: pctx.UserGroups,
+		Now:        time.Now().UTC(),
+	})
+}
+
+// openBreakGlass grants the emergency access of the session without approval,
+// the access ends when the duration of the break-glass policy of the connection is reached
+func (p *reviewPlugin) openBreakGlass(pctx plugintypes.Context, justification string) (*plugintypes.ConnectResponse, error) {
+	if pctx.ConnectionBreakGlass == nil {
+		return nil, plugintypes.InvalidArgument("break-glass access is not enabled for the connection %v", pctx.ConnectionName)
+	}
+	if strings.TrimSpace(justification) == "" {
+		return nil, plugintypes.InvalidArgument("missing the justification of the break-glass access")
+	}
+	rev, err := pgreview.New().FetchOneBySid(pctx, pctx.SID)
+	if err != nil {
+		return nil, plugintypes.InternalErr("failed fetching review", err)
+	}
+	switch {
+	// the session is opened again when the client retries, e.g.: agent offline
+	case rev != nil && rev.Type == review.ReviewTypeBreakGlass:
+		if err := validateJit(rev, time.Now().UTC()); err != nil {
+			if err == errJitExpired {
+				return nil, plugintypes.InvalidArgument("break-glass access has expired")
+			}
+			return nil, err
+		}
+	case rev != nil:
+		return nil, plugintypes.InvalidArgument("session %v is already under review", pctx.SID)
+	default:
+		rev, err = p.reviewSvc.OpenBreakGlass(pctx, review.BreakGlassInput{
+			SID: pctx.SID,
+			Owner: types.ReviewOwner{
+				Id:      pctx.UserID,
+				Name:    pctx.UserName,
+				Email:   pctx.UserEmail,
+				SlackID: pctx.UserSlackID,
+			},
+			Connection:    types.ReviewConnection{Id: pctx.ConnectionID, Name: pctx.ConnectionName},
+			Justification: justification,
+			Policy:        pctx.ConnectionBreakGlass,
+		})
+		if err != nil {
+			return nil, plugintypes.InternalErr("failed opening break-glass access", err)
+		}
+	}
+	pctx.ParamsData[plugintypes.ParamBreakGlassReviewID] = rev.Id
+	return &plugintypes.ConnectResponse{Context: review.NewJitContext(pctx.Context, rev, pctx.SID)}, nil
+}
+
 var errJitExpired = errors.New("jit expired")
 
 func validateJit(jit *types.Review, t time.Time) error {
@@ -1,7 +1,6 @@
 package review
 
 import (
-	"context"
 	"fmt"
 	"time"
 
@@ -19,6 +18,12 @@ func (r *reviewPlugin) onReceiveOSS(pctx plugintypes.Context, pkt *pb.Packet) (*
 	if pctx.ClientVerb != pb.ClientVerbConnect {
 		return nil, fmt.Errorf(`Accessing a connection with review from the web requires an Enterprise plan. Contact us for instant access to a 15-day trial license - no strings attached. If you want to continue using the OSS version, you can access your connection from the CLI or the Hoop desktop app. Check our docs for more information: https://hoop.dev/docs/getting-started/cli`)
 	}
+	rule := matchReviewRule(pctx, pkt)
+	if rule != nil && rule.Action == types.ReviewRuleActionSkip {
+		log.With("sid", pctx.SID, "user", pctx.UserEmail, "org", pctx.OrgID, "rule", rule.Name).
+			Infof("review skipped by rule")
+		return nil, nil
+	}
 	jitr, err := pgreview.New().FetchJit(pctx, pctx.UserID, pctx.ConnectionID)
 	if err != nil {
 		return nil, plugintypes.InternalErr("failed listing time based reviews", err)
@@ -31,7 +36,8 @@ func (r *reviewPlugin) onReceiveOSS(pctx plugintypes.Context, pkt *pb.Packet) (*
 			log.With("sid", pctx.SID, "id", jitr.Id, "user", jitr.CreatedBy, "org", pctx.OrgID,
 				"revoke-at", jitr.RevokeAt.Format(time.RFC3339),
 				"duration", fmt.Sprintf("%vm", jitr.AccessDuration.Minutes())).Infof("jit access granted")
-			newCtx, _ := context.WithTimeout(pctx.Context, jitr.AccessDuration)
+			// the session follows the changes of the grant, e.g.: extensions and revocation
+			newCtx := review.NewJitContext(pctx.Context, jitr, pctx.SID)
 			return &plugintypes.ConnectResponse{Context: newCtx, ClientPacket: nil}, nil
 		default:
 			return nil, err
@@ -47,23 +53,19 @@ func (r *reviewPlugin) onReceiveOSS(pctx plugintypes.Context, pkt *pb.Packet) (*
 		if err != nil {
 			return nil, plugintypes.InvalidArgument("invalid access time duration, got=%#v", string(durationStr))
 		}
-		if accessDuration.Hours() > 48 {
+		if accessDuration > review.MaxJitDuration {
 			return nil, plugintypes.InvalidArgument("jit access input must not be greater than 48 hours")
 		}
 	}
-	if len(pctx.PluginConnectionConfig) == 0 {
+	// the review policy of the connection takes precedence over the groups of the plugin
+	reviewGroups := review.NewReviewGroups(pctx.ConnectionReviewPolicy, pctx.PluginConnectionConfig)
+	if len(reviewGroups) == 0 {
 		err = fmt.Errorf("missing approval groups for connection")
 		return nil, plugintypes.InternalErr(err.Error(), err)
 	}
-
-	reviewGroups := make([]types.ReviewGroup, 0)
 	groups := make([]string, 0)
-	for _, s := range pctx.PluginConnectionConfig {
-		groups = append(groups, s)
-		reviewGroups = append(reviewGroups, types.ReviewGroup{
-			Group:  s,
-			Status: types.ReviewStatusPending,
-		})
+	for _, g := range reviewGroups {
+		groups = append(groups, g.Group)
 	}
 
 	var inputClientArgs []string
@@ -99,6 +101,10 @@ func (r *reviewPlugin) onReceiveOSS(pctx plugintypes.Context, pkt *pb.Packet) (*
 		ReviewGroupsIds:  groups,
 		ReviewGroupsData: reviewGroups,
 	}
+	review.SetReviewPolicy(newRev, pctx.ConnectionReviewPolicy)
+	if rule != nil {
+		newRev.RuleName = rule.Name
+	}
 	log.With("session", pctx.SID, "id", newRev.Id, "user", pctx.UserID, "org", pctx.OrgID,
 		"type", review.ReviewTypeJit, "duration", fmt.Sprintf("%vm", accessDuration.Minutes())).
 		Infof("creating review")
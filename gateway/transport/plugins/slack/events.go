@@ -3,10 +3,12 @@ package slack
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hoophq/hoop/common/log"
 	pb "github.com/hoophq/hoop/common/proto"
 	"github.com/hoophq/hoop/gateway/models"
+	pgreview "github.com/hoophq/hoop/gateway/pgrest/review"
 	"github.com/hoophq/hoop/gateway/review"
 	slackservice "github.com/hoophq/hoop/gateway/slack"
 	"github.com/hoophq/hoop/gateway/storagev2"
@@ -50,6 +52,14 @@ func (p *slackPlugin) processEventResponse(ev *event) {
 	for _, group := range slackApproverGroups {
 		slackApproverGroupsList = append(slackApproverGroupsList, group.Name)
 	}
+	userContext := storagev2.NewContext(slackApprover.ID, ev.orgID).
+		WithUserInfo(slackApprover.Name, slackApprover.Email, slackApprover.Status, slackApprover.Picture, slackApproverGroupsList)
+	userContext.SlackID = ev.msg.SlackID
+	// replies of threads are not bound to a group
+	if ev.msg.Status == slackservice.StatusCommented {
+		p.performComment(ev, userContext)
+		return
+	}
 	if !pb.IsInList(ev.msg.GroupName, slackApproverGroupsList) {
 		log.With("sid", sid).Infof("approver not allowed, it does not belong to %s", ev.msg.GroupName)
 		_ = ev.ss.PostEphemeralMessage(ev.msg,
@@ -59,22 +69,24 @@ func (p *slackPlugin) processEventResponse(ev *event) {
 	}
 	log.With("sid", sid).Infof("found a valid approver user=%s, slackid=%s",
 		slackApprover.Email, ev.msg.SlackID)
-	userContext := storagev2.NewContext(slackApprover.ID, ev.orgID)
-	userContext.UserGroups = slackApproverGroupsList
 
 	// perform the review in the system
 	log.With("sid", sid).Infof("performing review, kind=%v, id=%v, status=%s, group=%v",
 		ev.msg.EventKind, ev.msg.ID, ev.msg.Status, ev.msg.GroupName)
 	switch ev.msg.EventKind {
 	case slackservice.EventKindOneTime:
+		if ev.msg.Status == slackservice.StatusChangesRequested {
+			p.performChangesRequest(ev, userContext)
+			return
+		}
 		status := types.ReviewStatusRejected
-		if ev.msg.Status == "approved" {
+		if ev.msg.Status == slackservice.StatusApproved {
 			status = types.ReviewStatusApproved
 		}
 		p.performExecReview(ev, userContext, status)
 	case slackservice.EventKindJit:
 		status := types.ReviewStatusRejected
-		if ev.msg.Status == "approved" {
+		if ev.msg.Status == slackservice.StatusApproved {
 			status = types.ReviewStatusApproved
 		}
 		p.performJitReview(ev, userContext, status)
@@ -84,8 +96,15 @@ func (p *slackPlugin) processEventResponse(ev *event) {
 }
 
 func (p *slackPlugin) performExecReview(ev *event, ctx *storagev2.Context, status types.ReviewStatus) {
-	rev, err := p.reviewSvc.Review(ctx, ev.msg.ID, status)
 	sid := ev.msg.SessionID
+	if p.isOutdatedRevision(ev, ctx) {
+		err := ev.ss.UpdateMessageStatus(ev.msg, "• _the input of this review was resubmitted, review the latest message_")
+		if err != nil {
+			log.With("sid", sid).Warnf("failed updating slack review, reason=%v", err)
+		}
+		return
+	}
+	rev, err := p.reviewSvc.Review(ctx, ev.msg.ID, status)
 	switch err {
 	case review.ErrWrongState, review.ErrNotFound:
 		status := "not-found"
@@ -95,6 +114,11 @@ func (p *slackPlugin) performExecReview(ev *event, ctx *storagev2.Context, statu
 		err = ev.ss.UpdateMessageStatus(ev.msg, fmt.Sprintf("• _review has already been `%s`_", status))
 	case nil:
 		isApproved := rev.Status == types.ReviewStatusApproved
+		if g := pendingGroup(rev, ev.msg.GroupName); g != nil {
+			err = ev.ss.UpdateMessageGroup(ev.msg, fmt.Sprintf("group *%s* • _stage %v, %s_",
+				g.Group, g.Stage+1, g.ApprovalsProgress()))
+			break
+		}
 		err = ev.ss.UpdateMessage(ev.msg, isApproved)
 
 		log.With("sid", sid).Infof("review id=%s, isapproved=%v, status=%v, update-msg-err=%v",
@@ -121,9 +145,20 @@ func (p *slackPlugin) performJitReview(ev *event, ctx *storagev2.Context, status
 		err = ev.ss.UpdateMessageStatus(ev.msg, fmt.Sprintf("• _jit has already been `%s`_", status))
 	case nil:
 		isApproved := j.Status == types.ReviewStatusApproved
+		if g := pendingGroup(j, ev.msg.GroupName); g != nil {
+			err = ev.ss.UpdateMessageGroup(ev.msg, fmt.Sprintf("group *%s* • _stage %v, %s_",
+				g.Group, g.Stage+1, g.ApprovalsProgress()))
+			break
+		}
 		err = ev.ss.UpdateMessage(ev.msg, isApproved)
 
-		if isApproved {
+		switch {
+		case isApproved && j.ExtendsReviewId != "":
+			ev.ss.PostMessage(j.ReviewOwner.SlackID,
+				fmt.Sprintf("Your access to the connection %s was extended until %s.\n"+
+					"Follow this link to see the details: %s/reviews/%s", j.Connection.Name,
+					j.RevokeAt.Format(time.RFC1123), p.idpProvider.ApiURL, j.ExtendsReviewId))
+		case isApproved:
 			ev.ss.PostMessage(j.ReviewOwner.SlackID,
 				fmt.Sprintf("Your interactive session is open.\n"+
 					"Follow this link to see the details: %s/sessions/%s", p.idpProvider.ApiURL, ev.msg.SessionID))
@@ -139,3 +174,84 @@ func (p *slackPlugin) performJitReview(ev *event, ctx *storagev2.Context, status
 		log.With("sid", sid).Warnf("failed updating slack jit review, reason=%v", err)
 	}
 }
+
+// performChangesRequest sends the review back to the owner with the feedback of the modal
+func (p *slackPlugin) performChangesRequest(ev *event, ctx *storagev2.Context) {
+	sid := ev.msg.SessionID
+	var err error
+	switch {
+	case p.isOutdatedRevision(ev, ctx):
+		err = ev.ss.PostEphemeralMessage(ev.msg, "The input of the review was resubmitted, review the latest message.")
+	case strings.TrimSpace(ev.msg.Comment) == "":
+		err = ev.ss.PostEphemeralMessage(ev.msg, "The feedback of the requested changes is required.")
+	default:
+		var rev *types.Review
+		rev, err = p.reviewSvc.RequestChanges(ctx, ev.msg.ID, ev.msg.Comment, review.CommentSourceSlack)
+		switch err {
+		case nil:
+			log.With("sid", sid).Infof("changes requested for review id=%s", ev.msg.ID)
+		case review.ErrWrongState:
+			err = ev.ss.PostEphemeralMessage(ev.msg, fmt.Sprintf("The review has already been `%s`.",
+				strings.ToLower(string(rev.Status))))
+		default:
+			log.With("sid", sid).Warnf("failed requesting changes, id=%s, reason=%v", ev.msg.ID, err)
+			err = ev.ss.PostEphemeralMessage(ev.msg, fmt.Sprintf("Failed requesting changes: %v", err))
+		}
+	}
+	if err != nil {
+		log.With("sid", sid).Warnf("failed sending slack changes request response, reason=%v", err)
+	}
+}
+
+// performComment adds the reply of a review message as a comment of the review
+func (p *slackPlugin) performComment(ev *event, ctx *storagev2.Context) {
+	rev, err := pgreview.New().FetchOneBySlackThread(ctx, ev.msg.Thread.Channel, ev.msg.Thread.Timestamp)
+	if err != nil {
+		log.With("org", ev.orgID).Warnf("failed fetching review of slack thread, reason=%v", err)
+		return
+	}
+	// it's a reply of a message not related to reviews
+	if rev == nil {
+		return
+	}
+	_, err = p.reviewSvc.Comment(ctx, rev.Id, ev.msg.Comment, review.CommentSourceSlack)
+	switch err {
+	case nil:
+		log.With("sid", rev.Session).Infof("slack reply added as comment of review id=%s", rev.Id)
+	case review.ErrNotEligible:
+		err = ev.ss.PostEphemeralMessage(ev.msg, fmt.Sprintf(
+			"Your reply to the review of the connection %s was not added as a comment, "+
+				"only the owner and reviewers of the review are able to comment.", rev.Connection.Name))
+	default:
+		log.With("sid", rev.Session).Warnf("failed adding slack reply as comment, id=%s, reason=%v", rev.Id, err)
+	}
+	if err != nil {
+		log.With("sid", rev.Session).Warnf("failed sending slack comment response, reason=%v", err)
+	}
+}
+
+// isOutdatedRevision reports if the message belongs to a revision replaced by a resubmission
+func (p *slackPlugin) isOutdatedRevision(ev *event, ctx *storagev2.Context) bool {
+	// messages sent before revisions don't contain it
+	if ev.msg.Revision == 0 {
+		return false
+	}
+	rev, err := p.reviewSvc.FindOne(ctx, ev.msg.ID)
+	if err != nil || rev == nil {
+		return false
+	}
+	return rev.Revision != ev.msg.Revision
+}
+
+// pendingGroup returns the group when it requires more approvals to be approved
+func pendingGroup(rev *types.Review, groupName string) *types.ReviewGroup {
+	if rev.Status != types.ReviewStatusPending {
+		return nil
+	}
+	for _, g := range rev.ReviewGroupsData {
+		if g.Group == groupName && g.Status == types.ReviewStatusPending {
+			return &g
+		}
+	}
+	return nil
+}
@@ -3,16 +3,21 @@ package slack
 import (
 	"encoding/base64"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hoophq/hoop/common/log"
 	pb "github.com/hoophq/hoop/common/proto"
 	pbagent "github.com/hoophq/hoop/common/proto/agent"
+	localauthapi "github.com/hoophq/hoop/gateway/api/localauth"
+	"github.com/hoophq/hoop/gateway/models"
 	"github.com/hoophq/hoop/gateway/pgrest"
 	pgorgs "github.com/hoophq/hoop/gateway/pgrest/orgs"
 	pgplugins "github.com/hoophq/hoop/gateway/pgrest/plugins"
 	pgreview "github.com/hoophq/hoop/gateway/pgrest/review"
 	"github.com/hoophq/hoop/gateway/review"
+	"github.com/hoophq/hoop/gateway/runbookschedule"
 	"github.com/hoophq/hoop/gateway/security/idp"
 	"github.com/hoophq/hoop/gateway/slack"
 	"github.com/hoophq/hoop/gateway/storagev2"
@@ -208,16 +213,19 @@ func (p *slackPlugin) OnReceive(pctx plugintypes.Context, pkt *pb.Packet) (*plug
 		return nil, plugintypes.InternalErr("internal error, failed fetching review", err)
 	}
 	if rev != nil {
-		if rev.Status != types.ReviewStatusPending {
+		// emergency accesses are notified by the review service
+		if rev.Status != types.ReviewStatusPending || rev.Type == review.ReviewTypeBreakGlass {
 			return nil, nil
 		}
 		sreq.ID = rev.Id
 		sreq.WebappURL = fmt.Sprintf("%s/reviews/%s", p.idpProvider.ApiURL, rev.Id)
 		sreq.ApprovalGroups = parseGroups(rev.ReviewGroupsData)
+		sreq.GroupDetails = parseGroupDetails(rev.ReviewGroupsData)
 		if rev.AccessDuration > 0 {
 			sreq.SessionTime = &rev.AccessDuration
 		}
 		sreq.Script = rev.Input
+		sreq.Revision = rev.Revision
 	}
 
 	if sreq.WebappURL == "" || len(sreq.ApprovalGroups) == 0 || len(sreq.ApprovalGroups) >= slackMaxButtons {
@@ -226,8 +234,9 @@ func (p *slackPlugin) OnReceive(pctx plugintypes.Context, pkt *pb.Packet) (*plug
 		return nil, nil
 	}
 	log.With("sid", pctx.SID).Infof("sending slack review message, conn=%v, jit=%v", sreq.Connection, sreq.SessionTime != nil)
-	result := slackSvc.SendMessageReview(sreq)
+	threads, result := slackSvc.SendMessageReview(sreq)
 	log.With("sid", pctx.SID).Infof("review slack message sent, %v", result)
+	saveSlackThreads(rev, threads)
 	return nil, nil
 }
 
@@ -258,6 +267,19 @@ func parseSlackConfig(pconf *types.PluginConfig) (*slackConfig, error) {
 	return &sc, nil
 }
 
+// parseGroupDetails describes the stage and the required approvals of groups
+// created by a review policy
+func parseGroupDetails(reviewGroups []types.ReviewGroup) map[string]string {
+	details := map[string]string{}
+	for _, g := range reviewGroups {
+		if g.Stage == 0 && g.MinApprovals <= 1 {
+			continue
+		}
+		details[g.Group] = fmt.Sprintf("stage %v, %s", g.Stage+1, g.ApprovalsProgress())
+	}
+	return details
+}
+
 func parseGroups(reviewGroups []types.ReviewGroup) []string {
 	groups := make([]string, 0)
 	for _, g := range reviewGroups {
@@ -265,3 +287,262 @@ func parseGroups(reviewGroups []types.ReviewGroup) []string {
 	}
 	return groups
 }
+
+// OnReviewEvent notifies the channels of the connection about timeouts of pending reviews.
+// Escalated and resubmitted reviews are sent with the buttons to the reviewers,
+// comments and change requests are posted as replies of the review messages.
+func (p *slackPlugin) OnReviewEvent(ev review.Event) {
+	rev := ev.Review
+	slackSvc := getSlackServiceInstance(rev.OrgId)
+	if slackSvc == nil {
+		return
+	}
+	switch ev.Type {
+	case review.EventCommented, review.EventChangesRequested:
+		p.onReviewComment(slackSvc, ev)
+		return
+	}
+	slackChannels, err := connectionSlackChannels(rev.OrgId, rev.Connection.Id)
+	if err != nil {
+		log.With("id", rev.Id, "sid", rev.Session).Warnf("failed obtaining slack channels, reason=%v", err)
+		return
+	}
+	webappURL := fmt.Sprintf("%s/reviews/%s", p.idpProvider.ApiURL, rev.Id)
+	switch ev.Type {
+	case review.EventEscalated:
+		sreq := &slack.MessageReviewRequest{
+			ID:             rev.Id,
+			Name:           rev.ReviewOwner.Name,
+			Email:          rev.ReviewOwner.Email,
+			Connection:     rev.Connection.Name,
+			SessionID:      rev.Session,
+			Script:         rev.Input,
+			WebappURL:      webappURL,
+			SlackChannels:  slackChannels,
+			ApprovalGroups: ev.Groups,
+			GroupDetails:   map[string]string{rev.EscalationGroup: "escalated, a single approval approves the review"},
+		}
+		if rev.AccessDuration > 0 {
+			sreq.SessionTime = &rev.AccessDuration
+		}
+		threads, result := slackSvc.SendMessageReview(sreq)
+		log.With("id", rev.Id, "sid", rev.Session).Infof("review escalation slack message sent, %v", result)
+		saveSlackThreads(rev, threads)
+		return
+	case review.EventResubmitted:
+		err = slackSvc.PostThreadsMessage(toMessageThreads(rev.SlackThreads), fmt.Sprintf(
+			"_The input was resubmitted as revision %v, the review continues in a new message._", rev.Revision))
+		if err != nil {
+			log.With("id", rev.Id, "sid", rev.Session).Warnf("failed replying slack review threads, reason=%v", err)
+		}
+		sreq := &slack.MessageReviewRequest{
+			ID:             rev.Id,
+			Name:           rev.ReviewOwner.Name,
+			Email:          rev.ReviewOwner.Email,
+			Connection:     rev.Connection.Name,
+			SessionID:      rev.Session,
+			Script:         rev.Input,
+			WebappURL:      webappURL,
+			SlackChannels:  slackChannels,
+			ApprovalGroups: parseGroups(rev.ReviewGroupsData),
+			GroupDetails:   parseGroupDetails(rev.ReviewGroupsData),
+			Revision:       rev.Revision,
+		}
+		threads, result := slackSvc.SendMessageReview(sreq)
+		log.With("id", rev.Id, "sid", rev.Session).Infof("review resubmission slack message sent, %v", result)
+		saveSlackThreads(rev, threads)
+		return
+	case review.EventExtensionRequested:
+		sreq := &slack.MessageReviewRequest{
+			ID:             rev.Id,
+			Name:           rev.ReviewOwner.Name,
+			Email:          rev.ReviewOwner.Email,
+			Connection:     rev.Connection.Name,
+			SessionID:      rev.Session,
+			SessionTime:    &rev.AccessDuration,
+			WebappURL:      webappURL,
+			SlackChannels:  slackChannels,
+			ApprovalGroups: parseGroups(rev.ReviewGroupsData),
+			GroupDetails:   parseGroupDetails(rev.ReviewGroupsData),
+		}
+		threads, result := slackSvc.SendMessageReview(sreq)
+		log.With("id", rev.Id, "grant", rev.ExtendsReviewId).Infof("jit extension slack message sent, %v", result)
+		saveSlackThreads(rev, threads)
+		return
+	case review.EventBreakGlass:
+		err = slackSvc.PostChannelsMessage(slackChannels, fmt.Sprintf(
+			":rotating_light: *%s* opened a break-glass access to the connection *%s* until %s.\n"+
+				"Justification: _%s_", rev.ReviewOwner.Email, rev.Connection.Name,
+			rev.RevokeAt.Format(time.RFC1123), rev.Input))
+		if err != nil {
+			log.With("id", rev.Id, "sid", rev.Session).Warnf("failed sending break-glass slack message, reason=%v", err)
+		}
+		if rev.ReviewOwner.SlackID != "" {
+			_ = slackSvc.PostMessage(rev.ReviewOwner.SlackID, fmt.Sprintf(
+				"Your break-glass access to the connection %s is open until %s. "+
+					"The session is recorded and it will be reviewed by the group %s.\nMore details: %s",
+				rev.Connection.Name, rev.RevokeAt.Format(time.RFC1123), rev.ReviewGroupsData[0].Group, webappURL))
+		}
+		// the acknowledge group reviews the access afterwards
+		sreq := &slack.MessageReviewRequest{
+			ID:             rev.Id,
+			Title:          "Break-glass access",
+			Name:           rev.ReviewOwner.Name,
+			Email:          rev.ReviewOwner.Email,
+			Connection:     rev.Connection.Name,
+			SessionID:      rev.Session,
+			Script:         rev.Input,
+			WebappURL:      webappURL,
+			SlackChannels:  slackChannels,
+			ApprovalGroups: ev.Groups,
+			GroupDetails: map[string]string{rev.ReviewGroupsData[0].Group: fmt.Sprintf(
+				"access granted for %v, approve to acknowledge it", rev.AccessDuration)},
+		}
+		threads, result := slackSvc.SendMessageReview(sreq)
+		log.With("id", rev.Id, "sid", rev.Session).Infof("break-glass review slack message sent, %v", result)
+		saveSlackThreads(rev, threads)
+		return
+	case review.EventBreakGlassReviewed:
+		err = slackSvc.PostThreadsMessage(toMessageThreads(rev.SlackThreads), fmt.Sprintf(
+			"_The break-glass access of *%s* was `%s` by the acknowledge group._",
+			rev.ReviewOwner.Email, strings.ToLower(string(rev.Status))))
+	case review.EventReminder:
+		err = slackSvc.PostChannelsMessage(slackChannels, fmt.Sprintf(
+			"*Reminder:* the review of *%s* for the connection *%s* is pending approval of the groups *%s*.\n"+
+				"More details: %s", rev.ReviewOwner.Email, rev.Connection.Name, strings.Join(ev.Groups, ", "), webappURL))
+	case review.EventExpired:
+		err = slackSvc.PostChannelsMessage(slackChannels, fmt.Sprintf(
+			"The review of *%s* for the connection *%s* was rejected: _%s_.\nMore details: %s",
+			rev.ReviewOwner.Email, rev.Connection.Name, rev.StatusReason, webappURL))
+		if rev.ReviewOwner.SlackID != "" {
+			_ = slackSvc.PostMessage(rev.ReviewOwner.SlackID, fmt.Sprintf(
+				"Your review for the connection %s was rejected: %s.\nMore details: %s",
+				rev.Connection.Name, rev.StatusReason, webappURL))
+		}
+	}
+	if err != nil {
+		log.With("id", rev.Id, "sid", rev.Session).Warnf("failed sending review %v slack message, reason=%v", ev.Type, err)
+	}
+}
+
+// OnRunbookScheduleEvent notifies the channels of the connection about failed executions of runbook schedules
+func (p *slackPlugin) OnRunbookScheduleEvent(ev runbookschedule.Event) {
+	sched, run := ev.Schedule, ev.Run
+	if ev.Type != runbookschedule.EventRunFailed {
+		return
+	}
+	slackSvc := getSlackServiceInstance(sched.OrgID)
+	if slackSvc == nil {
+		return
+	}
+	logger := log.With("org", sched.OrgID, "schedule", sched.Name)
+	var slackChannels []string
+	conn, err := models.GetConnectionByNameOrID(sched.OrgID, sched.ConnectionName)
+	if err != nil {
+		logger.Warnf("failed obtaining connection of runbook schedule, reason=%v", err)
+		return
+	}
+	if conn != nil {
+		if slackChannels, err = connectionSlackChannels(sched.OrgID, conn.ID); err != nil {
+			logger.Warnf("failed obtaining slack channels, reason=%v", err)
+			return
+		}
+	}
+	reason := "-"
+	if run.Error != nil && *run.Error != "" {
+		reason = *run.Error
+		if len(reason) > 500 {
+			reason = reason[:500] + "..."
+		}
+	}
+	message := fmt.Sprintf("The scheduled runbook *%s* (%s) failed on the connection *%s*:\n```%s```",
+		sched.Name, sched.RunbookFile, sched.ConnectionName, reason)
+	if run.SessionID != nil {
+		message += fmt.Sprintf("\nMore details: %s/sessions/%s", p.idpProvider.ApiURL, *run.SessionID)
+	}
+	if err := slackSvc.PostChannelsMessage(slackChannels, message); err != nil {
+		logger.Warnf("failed sending runbook schedule slack message, reason=%v", err)
+	}
+}
+
+// OnPasswordResetEvent delivers the self-service password reset link to the user by direct message
+func (p *slackPlugin) OnPasswordResetEvent(ev localauthapi.PasswordResetEvent) {
+	slackSvc := getSlackServiceInstance(ev.OrgID)
+	if slackSvc == nil || ev.SlackID == "" {
+		return
+	}
+	message := fmt.Sprintf("A password reset was requested for your account (%s).\n"+
+		"Follow this link to set a new password, it expires at %s: %s/reset-password?token=%s\n"+
+		"If you didn't request it, you can ignore this message.",
+		ev.UserEmail, ev.ExpireAt.Format(time.RFC1123), p.idpProvider.ApiURL, ev.Token)
+	if err := slackSvc.PostMessage(ev.SlackID, message); err != nil {
+		log.With("org", ev.OrgID).Warnf("failed sending password reset slack message, reason=%v", err)
+	}
+}
+
+// onReviewComment replies the review messages with the comment and
+// notifies the owner when it's from another user
+func (p *slackPlugin) onReviewComment(slackSvc *slack.SlackService, ev review.Event) {
+	rev, comment := ev.Review, ev.Comment
+	if comment == nil {
+		return
+	}
+	webappURL := fmt.Sprintf("%s/reviews/%s", p.idpProvider.ApiURL, rev.Id)
+	var threadMsg, ownerMsg string
+	switch ev.Type {
+	case review.EventChangesRequested:
+		threadMsg = fmt.Sprintf("*%s* requested changes:\n>%s\n_The review continues when the owner resubmits the input._",
+			comment.Owner.Email, comment.Content)
+		ownerMsg = fmt.Sprintf("*%s* requested changes to your review of the connection %s:\n>%s\n"+
+			"Resubmit the input at %s", comment.Owner.Email, rev.Connection.Name, comment.Content, webappURL)
+	default:
+		threadMsg = fmt.Sprintf("*%s* commented:\n>%s", comment.Owner.Email, comment.Content)
+		ownerMsg = fmt.Sprintf("*%s* commented on your review of the connection %s:\n>%s\nMore details: %s",
+			comment.Owner.Email, rev.Connection.Name, comment.Content, webappURL)
+	}
+	// replies from slack are already displayed in the thread
+	if comment.Source != review.CommentSourceSlack || ev.Type == review.EventChangesRequested {
+		if err := slackSvc.PostThreadsMessage(toMessageThreads(rev.SlackThreads), threadMsg); err != nil {
+			log.With("id", rev.Id, "sid", rev.Session).Warnf("failed replying slack review threads, reason=%v", err)
+		}
+	}
+	if rev.ReviewOwner.SlackID != "" && rev.ReviewOwner.Id != comment.Owner.Id {
+		_ = slackSvc.PostMessage(rev.ReviewOwner.SlackID, ownerMsg)
+	}
+}
+
+// saveSlackThreads keeps the review messages sent to slack to post replies
+func saveSlackThreads(rev *types.Review, threads []slack.MessageThread) {
+	if rev == nil || len(threads) == 0 {
+		return
+	}
+	for _, t := range threads {
+		rev.SlackThreads = append(rev.SlackThreads, types.ReviewSlackThread{Channel: t.Channel, Timestamp: t.Timestamp})
+	}
+	err := pgreview.New().PatchSlackThreads(pgrest.NewOrgContext(rev.OrgId), rev.Id, rev.SlackThreads)
+	if err != nil {
+		log.With("id", rev.Id, "sid", rev.Session).Warnf("failed saving slack threads of review, reason=%v", err)
+	}
+}
+
+func toMessageThreads(threads []types.ReviewSlackThread) []slack.MessageThread {
+	var items []slack.MessageThread
+	for _, t := range threads {
+		items = append(items, slack.MessageThread{Channel: t.Channel, Timestamp: t.Timestamp})
+	}
+	return items
+}
+
+// connectionSlackChannels returns the slack channels configured in the plugin for a connection
+func connectionSlackChannels(orgID, connectionID string) ([]string, error) {
+	pl, err := pgplugins.New().FetchOne(pgrest.NewOrgContext(orgID), plugintypes.PluginSlackName)
+	if err != nil || pl == nil {
+		return nil, err
+	}
+	for _, conn := range pl.Connections {
+		if conn.ConnectionID == connectionID {
+			return conn.Config, nil
+		}
+	}
+	return nil, nil
+}
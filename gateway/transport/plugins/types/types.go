@@ -12,6 +12,9 @@ import (
 
 type GenericMap map[string]any
 
+// ParamBreakGlassReviewID is set in the params of sessions opened with an emergency access
+const ParamBreakGlassReviewID = "break_glass_review_id"
+
 type PacketErr struct {
 	exitCode *int
 	msg      string
@@ -45,8 +48,13 @@ type Context struct {
 	ConnectionType                      string
 	ConnectionSubType                   string
 	ConnectionCommand                   []string
+	ConnectionTags                      []string
 	ConnectionSecret                    map[string]any
 	ConnectionJiraTransitionNameOnClose string
+	ConnectionSessionLimits             *pb.SessionLimits
+	ConnectionReviewPolicy              *types.ReviewPolicy
+	ConnectionReviewRules               []types.ReviewRule
+	ConnectionBreakGlass                *types.BreakGlassPolicy
 
 	// Agent attributes
 	AgentID   string
@@ -1,8 +1,13 @@
 package webhooks
 
 const (
-	eventSessionOpenType         = "session.open"
-	eventSessionCloseType        = "session.close"
-	eventMSTeamsReviewCreateType = "microsoftteams.review.create"
-	maxInputSize                 = 10 * 1000 // 10KB
+	eventSessionOpenType           = "session.open"
+	eventSessionCloseType          = "session.close"
+	eventMSTeamsReviewCreateType   = "microsoftteams.review.create"
+	eventReviewReminderType        = "review.reminder"
+	eventReviewEscalatedType       = "review.escalated"
+	eventReviewExpiredType         = "review.expired"
+	eventReviewBreakGlassType      = "review.break_glass"
+	eventRunbookScheduleFailedType = "runbook_schedule.run_failed"
+	maxInputSize                   = 10 * 1000 // 10KB
 )
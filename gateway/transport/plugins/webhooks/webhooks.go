@@ -16,6 +16,8 @@ import (
 	pbclient "github.com/hoophq/hoop/common/proto/client"
 	"github.com/hoophq/hoop/gateway/appconfig"
 	pgreview "github.com/hoophq/hoop/gateway/pgrest/review"
+	"github.com/hoophq/hoop/gateway/review"
+	"github.com/hoophq/hoop/gateway/runbookschedule"
 	"github.com/hoophq/hoop/gateway/storagev2/types"
 	plugintypes "github.com/hoophq/hoop/gateway/transport/plugins/types"
 	svix "github.com/svix/svix-webhooks/go"
@@ -284,6 +286,109 @@ func (p *plugin) processSessionCloseEvent(ctx plugintypes.Context, pkt *pb.Packe
 	}
 }
 
+// OnReviewEvent sends the timeouts of pending reviews and the emergency accesses
+// to the application of the organization
+func (p *plugin) OnReviewEvent(ev review.Event) {
+	rev := ev.Review
+	if !p.hasLoadedApp(rev.OrgId) {
+		return
+	}
+	var eventType string
+	switch ev.Type {
+	case review.EventReminder:
+		eventType = eventReviewReminderType
+	case review.EventEscalated:
+		eventType = eventReviewEscalatedType
+	case review.EventExpired:
+		eventType = eventReviewExpiredType
+	case review.EventBreakGlass:
+		eventType = eventReviewBreakGlassType
+	default:
+		return
+	}
+	payload := map[string]any{
+		"event_type":       eventType,
+		"id":               rev.Id,
+		"type":             rev.Type,
+		"session_id":       rev.Session,
+		"connection_name":  rev.Connection.Name,
+		"owner_email":      rev.ReviewOwner.Email,
+		"status":           rev.Status,
+		"status_reason":    rev.StatusReason,
+		"groups":           ev.Groups,
+		"escalation_group": rev.EscalationGroup,
+		"expire_at":        rev.ExpireAt,
+		"url":              fmt.Sprintf("%s/reviews/%s", appconfig.Get().FullApiURL(), rev.Id),
+	}
+	if ev.Type == review.EventBreakGlass {
+		payload["justification"] = rev.Input
+		payload["revoke_at"] = rev.RevokeAt
+	}
+	appID := rev.OrgId
+	eventID := uuid.NewString()
+	ctxtimeout, cancelFn := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFn()
+	out, err := p.client.Message.Create(ctxtimeout, appID, &svix.MessageIn{
+		EventType: eventType,
+		EventId:   *svix.NullableString(func() *string { v := eventID; return &v }()),
+		Payload:   payload,
+	})
+	if err != nil {
+		log.With("appid", appID).Warnf("failed sending webhook event to remote source, event=%s, err=%v",
+			eventType, err)
+		return
+	}
+	if out != nil {
+		log.With("appid", appID).Infof("sent webhook with success, id=%s, event=%s, eventid=%s",
+			out.Id, out.EventType, eventID)
+	}
+}
+
+// OnRunbookScheduleEvent sends the failed executions of runbook schedules
+// to the application of the organization
+func (p *plugin) OnRunbookScheduleEvent(ev runbookschedule.Event) {
+	sched, run := ev.Schedule, ev.Run
+	if ev.Type != runbookschedule.EventRunFailed || !p.hasLoadedApp(sched.OrgID) {
+		return
+	}
+	payload := map[string]any{
+		"event_type":      eventRunbookScheduleFailedType,
+		"schedule_id":     sched.ID,
+		"schedule_name":   sched.Name,
+		"connection_name": sched.ConnectionName,
+		"runbook_file":    sched.RunbookFile,
+		"service_account": sched.ServiceAccountSubject,
+		"run_id":          run.ID,
+		"session_id":      run.SessionID,
+		"status":          run.Status,
+		"exit_code":       run.ExitCode,
+		"error":           run.Error,
+		"scheduled_at":    run.ScheduledAt,
+		"finished_at":     run.FinishedAt,
+	}
+	if run.SessionID != nil {
+		payload["url"] = fmt.Sprintf("%s/sessions/%s", appconfig.Get().FullApiURL(), *run.SessionID)
+	}
+	appID := sched.OrgID
+	eventID := uuid.NewString()
+	ctxtimeout, cancelFn := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFn()
+	out, err := p.client.Message.Create(ctxtimeout, appID, &svix.MessageIn{
+		EventType: eventRunbookScheduleFailedType,
+		EventId:   *svix.NullableString(func() *string { v := eventID; return &v }()),
+		Payload:   payload,
+	})
+	if err != nil {
+		log.With("appid", appID).Warnf("failed sending webhook event to remote source, event=%s, err=%v",
+			eventRunbookScheduleFailedType, err)
+		return
+	}
+	if out != nil {
+		log.With("appid", appID).Infof("sent webhook with success, id=%s, event=%s, eventid=%s",
+			out.Id, out.EventType, eventID)
+	}
+}
+
 func (p *plugin) OnDisconnect(_ plugintypes.Context, _ error) error { return nil }
 func (p *plugin) OnShutdown()                                       {}
 
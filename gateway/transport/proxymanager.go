@@ -72,7 +72,7 @@ func (s *Server) listenProxyManagerMessages(stream *streamclient.ProxyStream) er
 		case <-stream.Context().Done():
 			return stream.ContextCauseError()
 		case <-pctx.Context.Done():
-			return status.Error(codes.Aborted, "session ended, reached connection duration")
+			return sessionContextDoneErr(pctx.Context)
 		case dstream = <-recvCh:
 		}
 
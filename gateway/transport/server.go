@@ -3,6 +3,7 @@ package transport
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -63,9 +64,13 @@ func (s *Server) StartRPCServer() {
 	)
 	var grpcServer *grpc.Server
 	if s.TLSConfig != nil {
+		// agents may present a client certificate issued by the certificate authority
+		// of its organization, it's verified by the auth interceptor along with the agent key
+		tlsConfig := s.TLSConfig.Clone()
+		tlsConfig.ClientAuth = tls.RequestClientCert
 		grpcServer = grpc.NewServer(
 			grpc.MaxRecvMsgSize(commongrpc.MaxRecvMsgSize),
-			grpc.Creds(credentials.NewTLS(s.TLSConfig)),
+			grpc.Creds(credentials.NewTLS(tlsConfig)),
 			grpcInterceptors,
 			authinterceptor.WithUnaryValidator(s.IDProvider),
 		)
@@ -156,8 +161,13 @@ func (s *Server) Connect(stream pb.Transport_ConnectServer) (err error) {
 		ConnectionType:                      gwctx.Connection.Type,
 		ConnectionSubType:                   gwctx.Connection.SubType,
 		ConnectionCommand:                   gwctx.Connection.CmdEntrypoint,
+		ConnectionTags:                      gwctx.Connection.Tags,
 		ConnectionSecret:                    gwctx.Connection.Secrets,
 		ConnectionJiraTransitionNameOnClose: gwctx.Connection.JiraTransitionNameOnSessionClose,
+		ConnectionSessionLimits:             gwctx.Connection.SessionLimits,
+		ConnectionReviewPolicy:              gwctx.Connection.ReviewPolicy,
+		ConnectionReviewRules:               gwctx.Connection.ReviewRules,
+		ConnectionBreakGlass:                gwctx.Connection.BreakGlass,
 
 		AgentID:   gwctx.Connection.AgentID,
 		AgentName: gwctx.Connection.AgentName,
@@ -203,6 +213,15 @@ func validateConnectionAccessMode(clientVerb, clientOrigin string, connInfo type
 	return nil
 }
 
+// sessionContextDoneErr returns the error of a session ended by its connection context,
+// jit sessions are ended when the access expires or when it's revoked
+func sessionContextDoneErr(ctx context.Context) error {
+	if errors.Is(context.Cause(ctx), review.ErrJitRevoked) {
+		return status.Error(codes.Aborted, "session ended, the access to the connection has been revoked")
+	}
+	return status.Error(codes.Aborted, "session ended, reached connection duration")
+}
+
 func handleGracefulShutdown() {
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc,
@@ -0,0 +1,51 @@
+package streamclient
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hoophq/hoop/common/log"
+	"github.com/hoophq/hoop/gateway/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// killedSessionsInterval is the interval to check if the sessions served by this gateway instance were killed
+const killedSessionsInterval = time.Second * 10
+
+// InitKilledSessionsWatcher closes the streams of sessions killed through the api.
+// A session could be killed by any gateway instance, the one serving its stream terminates it.
+func InitKilledSessionsWatcher() {
+	go func() {
+		for {
+			time.Sleep(killedSessionsInterval)
+			if err := closeKilledProxies(models.ListKilledSessions); err != nil {
+				log.Warnf("failed closing killed sessions, reason=%v", err)
+			}
+		}
+	}()
+}
+
+func closeKilledProxies(listKilledFn func(sids []string) ([]models.KilledSession, error)) error {
+	var sids []string
+	for sid := range proxyStore.List() {
+		// the session id could be informed by clients, only the valid ones are stored
+		if _, err := uuid.Parse(sid); err == nil {
+			sids = append(sids, sid)
+		}
+	}
+	if len(sids) == 0 {
+		return nil
+	}
+	items, err := listKilledFn(sids)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		reason := status.Errorf(codes.Aborted, "session killed by %v, reason=%v", item.KilledBy, item.Reason)
+		if DisconnectProxy(item.OrgID, item.ID, reason) {
+			log.With("sid", item.ID).Infof("session killed by %v, reason=%v", item.KilledBy, item.Reason)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package streamclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hoophq/hoop/gateway/models"
+	plugintypes "github.com/hoophq/hoop/gateway/transport/plugins/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProxyStream(orgID, sid string) *ProxyStream {
+	ctx, cancelFn := context.WithCancelCause(context.Background())
+	s := &ProxyStream{
+		context:   ctx,
+		cancelFn:  cancelFn,
+		pluginCtx: &plugintypes.Context{OrgID: orgID, SID: sid},
+	}
+	proxyStore.Set(sid, s)
+	return s
+}
+
+func TestCloseKilledProxies(t *testing.T) {
+	// the sessions were killed through the api of another gateway instance
+	invalid := newTestProxyStream("org-id", "not-a-session-uuid")
+	defer proxyStore.Del(invalid.pluginCtx.SID)
+	killed := newTestProxyStream("org-id", "1CBC8DB5-FBF8-4293-8E35-59A6EEA40207")
+	running := newTestProxyStream("org-id", "A2D4D4CE-3C1F-4C1B-9A54-8D5E4B2A1F3C")
+	otherOrg := newTestProxyStream("org-id", "5B7E5B2E-7E3C-4B8A-9F3D-2C1A0E6D4F10")
+	defer proxyStore.Del(running.pluginCtx.SID)
+	defer proxyStore.Del(otherOrg.pluginCtx.SID)
+
+	var listedSids []string
+	err := closeKilledProxies(func(sids []string) ([]models.KilledSession, error) {
+		listedSids = sids
+		return []models.KilledSession{
+			{ID: "1CBC8DB5-FBF8-4293-8E35-59A6EEA40207", OrgID: "org-id", KilledBy: "admin@bad.org", Reason: "compromised account"},
+			{ID: "5B7E5B2E-7E3C-4B8A-9F3D-2C1A0E6D4F10", OrgID: "other-org-id", KilledBy: "admin@bad.org", Reason: "compromised account"},
+		}, nil
+	})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"1CBC8DB5-FBF8-4293-8E35-59A6EEA40207", "A2D4D4CE-3C1F-4C1B-9A54-8D5E4B2A1F3C", "5B7E5B2E-7E3C-4B8A-9F3D-2C1A0E6D4F10"}, listedSids)
+	assert.False(t, proxyStore.Has("1CBC8DB5-FBF8-4293-8E35-59A6EEA40207"))
+	assert.ErrorContains(t, killed.ContextCauseError(), "session killed by admin@bad.org, reason=compromised account")
+	assert.True(t, proxyStore.Has("A2D4D4CE-3C1F-4C1B-9A54-8D5E4B2A1F3C"))
+	assert.NoError(t, running.ContextCauseError())
+	assert.True(t, proxyStore.Has("5B7E5B2E-7E3C-4B8A-9F3D-2C1A0E6D4F10"))
+	assert.NoError(t, otherOrg.ContextCauseError())
+
+	t.Run("it must return the error listing the killed sessions", func(t *testing.T) {
+		err := closeKilledProxies(func([]string) ([]models.KilledSession, error) {
+			return nil, fmt.Errorf("database unavailable")
+		})
+		assert.EqualError(t, err, "database unavailable")
+		assert.True(t, proxyStore.Has("A2D4D4CE-3C1F-4C1B-9A54-8D5E4B2A1F3C"))
+	})
+}
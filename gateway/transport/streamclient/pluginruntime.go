@@ -3,6 +3,7 @@ package streamclient
 import (
 	"github.com/hoophq/hoop/common/log"
 	pb "github.com/hoophq/hoop/common/proto"
+	"github.com/hoophq/hoop/gateway/models"
 	pgplugins "github.com/hoophq/hoop/gateway/pgrest/plugins"
 	pluginsslack "github.com/hoophq/hoop/gateway/transport/plugins/slack"
 	plugintypes "github.com/hoophq/hoop/gateway/transport/plugins/types"
@@ -38,23 +39,33 @@ func loadRuntimePlugins(ctx plugintypes.Context) ([]runtimePlugin, error) {
 			}
 		}
 
+		// the configuration assigned by tag selectors enables the plugin
+		// even if the connection is not directly associated with it
+		selectorConfig, err := policySelectorsConfig(ctx, p.Name())
+		if err != nil {
+			log.Errorf("failed retrieving policy selectors of plugin %q, err=%v", p.Name(), err)
+			return nil, status.Errorf(codes.Internal, "failed registering plugins")
+		}
+		enabled := len(selectorConfig) > 0
+		var config []string
 		for _, c := range p1.Connections {
 			if c.Name == ctx.ConnectionName {
-				config := removePluginConfigDuplicates(c.Config)
-				ep := runtimePlugin{
-					Plugin: p,
-					config: config,
-				}
-
-				if err = p.OnConnect(ctx); err != nil {
-					log.Warnf("plugin %q refused to accept connection %q, err=%v", p1.Name, ctx.SID, err)
-					return pluginsConfig, status.Errorf(codes.FailedPrecondition, err.Error())
-				}
-
-				pluginsConfig = append(pluginsConfig, ep)
+				config, enabled = c.Config, true
 				break
 			}
 		}
+		if !enabled {
+			continue
+		}
+		ep := runtimePlugin{
+			Plugin: p,
+			config: removePluginConfigDuplicates(append(config, selectorConfig...)),
+		}
+		if err = p.OnConnect(ctx); err != nil {
+			log.Warnf("plugin %q refused to accept connection %q, err=%v", p1.Name, ctx.SID, err)
+			return pluginsConfig, status.Errorf(codes.FailedPrecondition, err.Error())
+		}
+		pluginsConfig = append(pluginsConfig, ep)
 	}
 	if len(nonRegisteredPlugins) > 0 {
 		log.With("sid", ctx.SID).Infof("non registered plugins %v", nonRegisteredPlugins)
@@ -62,6 +73,19 @@ func loadRuntimePlugins(ctx plugintypes.Context) ([]runtimePlugin, error) {
 	return pluginsConfig, nil
 }
 
+// policySelectorsConfig returns the configuration of the plugin assigned by
+// the policy selectors matching the tags of the connection
+func policySelectorsConfig(ctx plugintypes.Context, pluginName string) ([]string, error) {
+	if pluginName != models.PolicySelectorAccessControl && pluginName != models.PolicySelectorReview {
+		return nil, nil
+	}
+	selectors, err := models.ListPolicySelectorsByType(ctx.OrgID, pluginName)
+	if err != nil {
+		return nil, err
+	}
+	return models.PolicySelectorsConfig(models.MatchPolicySelectors(selectors, ctx.ConnectionTags)), nil
+}
+
 func (s *ProxyStream) PluginExecOnReceive(pctx plugintypes.Context, pkt *pb.Packet) (*plugintypes.ConnectResponse, error) {
 	var response *plugintypes.ConnectResponse
 	for _, p := range s.runtimePlugins {
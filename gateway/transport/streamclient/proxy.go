@@ -193,6 +193,18 @@ func DisconnectAllProxies(reason error) chan struct{} {
 	return donec
 }
 
+// DisconnectProxy closes the client stream of a session and the resources of the agent.
+// It returns false if the session doesn't have a stream in this gateway instance.
+func DisconnectProxy(orgID, sid string, reason error) bool {
+	s := GetProxyStream(sid)
+	if s == nil || s.pluginCtx.OrgID != orgID {
+		return false
+	}
+	log.With("sid", sid).Infof("disconnecting proxy, reason=%v", reason)
+	_ = s.Close(reason)
+	return true
+}
+
 func disconnectProxiesByAgent(pctx plugintypes.Context, errMsg error) {
 	for _, obj := range proxyStore.List() {
 		s, _ := obj.(*ProxyStream)
@@ -0,0 +1,7 @@
+BEGIN;
+
+SET search_path TO private;
+
+ALTER TABLE private.connections DROP COLUMN session_limits;
+
+COMMIT;
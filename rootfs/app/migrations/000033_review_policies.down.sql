@@ -0,0 +1,13 @@
+BEGIN;
+
+SET search_path TO private;
+
+ALTER TABLE private.review_groups DROP COLUMN approvals;
+ALTER TABLE private.review_groups DROP COLUMN min_approvals;
+ALTER TABLE private.review_groups DROP COLUMN stage;
+
+ALTER TABLE private.reviews DROP COLUMN distinct_approvers;
+
+ALTER TABLE private.connections DROP COLUMN review_policy;
+
+COMMIT;
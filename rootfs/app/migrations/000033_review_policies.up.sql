@@ -0,0 +1,13 @@
+BEGIN;
+
+SET search_path TO private;
+
+ALTER TABLE private.connections ADD COLUMN review_policy JSONB NULL;
+
+ALTER TABLE private.reviews ADD COLUMN distinct_approvers BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE private.review_groups ADD COLUMN stage INT NOT NULL DEFAULT 0;
+ALTER TABLE private.review_groups ADD COLUMN min_approvals INT NOT NULL DEFAULT 1;
+ALTER TABLE private.review_groups ADD COLUMN approvals JSONB NULL;
+
+COMMIT;
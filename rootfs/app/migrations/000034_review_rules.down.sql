@@ -0,0 +1,8 @@
+BEGIN;
+
+SET search_path TO private;
+
+ALTER TABLE private.reviews DROP COLUMN rule_name;
+ALTER TABLE private.connections DROP COLUMN review_rules;
+
+COMMIT;
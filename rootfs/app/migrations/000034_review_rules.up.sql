@@ -0,0 +1,8 @@
+BEGIN;
+
+SET search_path TO private;
+
+ALTER TABLE private.connections ADD COLUMN review_rules JSONB NULL;
+ALTER TABLE private.reviews ADD COLUMN rule_name VARCHAR(128) NULL;
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+SET search_path TO private;
+
+ALTER TABLE private.reviews DROP COLUMN escalated_at;
+ALTER TABLE private.reviews DROP COLUMN escalation_group;
+ALTER TABLE private.reviews DROP COLUMN escalate_at;
+ALTER TABLE private.reviews DROP COLUMN reminded_at;
+ALTER TABLE private.reviews DROP COLUMN reminder_interval_sec;
+ALTER TABLE private.reviews DROP COLUMN expire_at;
+ALTER TABLE private.reviews DROP COLUMN status_reason;
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+SET search_path TO private;
+
+ALTER TABLE private.reviews ADD COLUMN status_reason TEXT NULL;
+ALTER TABLE private.reviews ADD COLUMN expire_at TIMESTAMP NULL;
+ALTER TABLE private.reviews ADD COLUMN reminder_interval_sec INT NOT NULL DEFAULT 0;
+ALTER TABLE private.reviews ADD COLUMN reminded_at TIMESTAMP NULL;
+ALTER TABLE private.reviews ADD COLUMN escalate_at TIMESTAMP NULL;
+ALTER TABLE private.reviews ADD COLUMN escalation_group VARCHAR(100) NULL;
+ALTER TABLE private.reviews ADD COLUMN escalated_at TIMESTAMP NULL;
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+SET search_path TO private;
+
+DROP TABLE private.review_revisions;
+DROP TABLE private.review_comments;
+
+ALTER TABLE private.reviews DROP COLUMN slack_threads;
+ALTER TABLE private.reviews DROP COLUMN revised_at;
+ALTER TABLE private.reviews DROP COLUMN revision;
+
+COMMIT;
@@ -0,0 +1,44 @@
+BEGIN;
+
+SET search_path TO private;
+
+ALTER TYPE enum_reviews_status ADD VALUE IF NOT EXISTS 'CHANGES_REQUESTED';
+
+ALTER TABLE private.reviews ADD COLUMN revision INT NOT NULL DEFAULT 1;
+ALTER TABLE private.reviews ADD COLUMN revised_at TIMESTAMP NULL;
+ALTER TABLE private.reviews ADD COLUMN slack_threads JSONB NULL;
+
+CREATE TABLE review_comments(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES orgs (id),
+    review_id UUID NOT NULL REFERENCES reviews (id) ON DELETE CASCADE,
+    revision INT NOT NULL DEFAULT 1,
+
+    owner_id VARCHAR(255) NOT NULL,
+    owner_email VARCHAR(255) NOT NULL,
+    owner_name VARCHAR(255) NULL,
+    owner_slack_id VARCHAR(50) NULL,
+
+    content TEXT NOT NULL,
+    changes_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    source VARCHAR(32) NOT NULL DEFAULT 'api',
+
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE review_revisions(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES orgs (id),
+    review_id UUID NOT NULL REFERENCES reviews (id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+
+    input TEXT NULL,
+    review_groups JSONB NULL,
+
+    submitted_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE(review_id, revision)
+);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+SET search_path TO private;
+
+ALTER TABLE private.reviews DROP COLUMN extends_review_id;
+
+COMMIT;
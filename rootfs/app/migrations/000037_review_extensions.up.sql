@@ -0,0 +1,7 @@
+BEGIN;
+
+SET search_path TO private;
+
+ALTER TABLE private.reviews ADD COLUMN extends_review_id UUID NULL REFERENCES reviews (id) ON DELETE CASCADE;
+
+COMMIT;
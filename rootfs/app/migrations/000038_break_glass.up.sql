@@ -0,0 +1,9 @@
+BEGIN;
+
+SET search_path TO private;
+
+ALTER TYPE enum_reviews_type ADD VALUE IF NOT EXISTS 'break_glass';
+
+ALTER TABLE private.connections ADD COLUMN break_glass JSONB NULL;
+
+COMMIT;
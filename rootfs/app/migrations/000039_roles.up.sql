@@ -0,0 +1,20 @@
+BEGIN;
+
+SET search_path TO private;
+
+CREATE TABLE roles(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES orgs (id),
+
+    name VARCHAR(128) NOT NULL,
+    description TEXT NULL,
+    permissions JSONB NOT NULL DEFAULT '[]',
+    groups TEXT[] NOT NULL DEFAULT '{}',
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE(org_id, name)
+);
+
+COMMIT;
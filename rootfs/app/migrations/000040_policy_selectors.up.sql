@@ -0,0 +1,20 @@
+BEGIN;
+
+SET search_path TO private;
+
+CREATE TABLE policy_selectors(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES orgs (id),
+
+    name VARCHAR(128) NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    selector TEXT NOT NULL,
+    config TEXT[] NOT NULL DEFAULT '{}',
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE(org_id, name)
+);
+
+COMMIT;
@@ -0,0 +1,25 @@
+BEGIN;
+
+SET search_path TO private;
+
+CREATE TABLE scim_tokens(
+    org_id UUID PRIMARY KEY REFERENCES orgs (id),
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE scim_groups(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES orgs (id),
+
+    display_name VARCHAR(100) NOT NULL,
+    external_id VARCHAR(255) NULL,
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE(org_id, display_name)
+);
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+SET search_path TO private;
+
+DROP TABLE IF EXISTS runbook_schedule_runs;
+DROP TABLE IF EXISTS runbook_schedules;
+
+COMMIT;
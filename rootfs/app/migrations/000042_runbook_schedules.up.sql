@@ -0,0 +1,45 @@
+BEGIN;
+
+SET search_path TO private;
+
+CREATE TABLE runbook_schedules(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES orgs (id),
+    service_account_id UUID NOT NULL REFERENCES service_accounts (id) ON DELETE CASCADE,
+
+    name VARCHAR(128) NOT NULL,
+    connection_name VARCHAR(128) NOT NULL,
+    runbook_file TEXT NOT NULL,
+    parameters JSONB NULL,
+    cron_expression VARCHAR(128) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+
+    next_run_at TIMESTAMP NULL,
+    last_run_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE(org_id, name)
+);
+
+CREATE INDEX runbook_schedules_next_run_at_idx ON runbook_schedules (next_run_at) WHERE enabled = TRUE;
+
+CREATE TABLE runbook_schedule_runs(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES orgs (id),
+    schedule_id UUID NOT NULL REFERENCES runbook_schedules (id) ON DELETE CASCADE,
+    session_id UUID NULL,
+
+    status VARCHAR(32) NOT NULL,
+    exit_code INT NULL,
+    error TEXT NULL,
+
+    scheduled_at TIMESTAMP NOT NULL,
+    started_at TIMESTAMP DEFAULT NOW(),
+    finished_at TIMESTAMP NULL
+);
+
+CREATE INDEX runbook_schedule_runs_schedule_id_idx ON runbook_schedule_runs (schedule_id, started_at DESC);
+
+COMMIT;
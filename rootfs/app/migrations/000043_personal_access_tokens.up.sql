@@ -0,0 +1,23 @@
+BEGIN;
+
+SET search_path TO private;
+
+CREATE TABLE personal_access_tokens(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES orgs (id),
+    user_subject VARCHAR(255) NOT NULL,
+
+    name VARCHAR(128) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(16) NOT NULL,
+    scopes TEXT[] NOT NULL,
+
+    expire_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE(org_id, user_subject, name)
+);
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+SET search_path TO private;
+
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS user_local_auth;
+
+COMMIT;
@@ -0,0 +1,33 @@
+BEGIN;
+
+SET search_path TO private;
+
+CREATE TABLE user_local_auth(
+    user_id UUID NOT NULL PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    org_id UUID NOT NULL REFERENCES orgs (id),
+
+    totp_secret TEXT NULL,
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_last_counter BIGINT NOT NULL DEFAULT 0,
+
+    failed_attempts INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP NULL,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE password_reset_tokens(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES orgs (id),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_by VARCHAR(255) NOT NULL,
+
+    expire_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX password_reset_tokens_user_id_idx ON password_reset_tokens (user_id);
+
+COMMIT;
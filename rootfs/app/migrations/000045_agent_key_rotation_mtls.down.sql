@@ -0,0 +1,12 @@
+BEGIN;
+
+SET search_path TO private;
+
+DROP TABLE IF EXISTS agent_certificate_authorities;
+
+DROP INDEX IF EXISTS agents_previous_key_hash_idx;
+ALTER TABLE agents DROP COLUMN IF EXISTS mtls_required;
+ALTER TABLE agents DROP COLUMN IF EXISTS previous_key_expire_at;
+ALTER TABLE agents DROP COLUMN IF EXISTS previous_key_hash;
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+SET search_path TO private;
+
+-- the previous key remains valid until it expires, it allows rotating keys without downtime
+ALTER TABLE agents ADD COLUMN previous_key_hash VARCHAR(255) NULL;
+ALTER TABLE agents ADD COLUMN previous_key_expire_at TIMESTAMP NULL;
+ALTER TABLE agents ADD COLUMN mtls_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX agents_previous_key_hash_idx ON agents (previous_key_hash) WHERE previous_key_hash IS NOT NULL;
+
+CREATE TABLE agent_certificate_authorities(
+    org_id UUID NOT NULL PRIMARY KEY REFERENCES orgs (id),
+
+    certificate TEXT NOT NULL,
+    private_key TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+COMMIT;